@@ -0,0 +1,209 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/planpreview"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+// AuditRecord is the structured record of an RPC handled by the plugin.
+type AuditRecord struct {
+	// Time is the time when the plugin started handling the RPC.
+	Time time.Time `json:"time"`
+	// Method is the full gRPC method name, e.g. "/grpc.plugin.deploymentapi.v1alpha1.DeploymentService/ExecuteStage".
+	Method string `json:"method"`
+	// ApplicationID is the ID of the application which the RPC is for.
+	// It's empty when the request is not for a specific application.
+	ApplicationID string `json:"applicationId,omitempty"`
+	// DeploymentID is the ID of the deployment which the RPC is for.
+	// It's empty when the request is not for a specific deployment.
+	DeploymentID string `json:"deploymentId,omitempty"`
+	// StageID is the ID of the stage which the RPC is for.
+	// It's empty when the request is not for a specific stage.
+	StageID string `json:"stageId,omitempty"`
+	// Requester is the ID of the piped which sent the request.
+	// It's the address of the peer when the request doesn't contain the piped ID.
+	Requester string `json:"requester,omitempty"`
+	// CorrelationID is the ID given by piped to correlate the logs for the request.
+	// It's empty when piped didn't set one.
+	CorrelationID string `json:"correlationId,omitempty"`
+	// Duration is the time taken to handle the RPC.
+	Duration time.Duration `json:"duration"`
+	// Code is the gRPC status code of the RPC, e.g. "OK".
+	Code string `json:"code"`
+	// Error is the error message returned to piped with the sensitive values masked.
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink is the destination of the audit records.
+type AuditSink interface {
+	// Write writes the given record.
+	// It's called concurrently from the RPCs handled in parallel.
+	Write(ctx context.Context, record AuditRecord) error
+}
+
+// NewAuditLogSink returns the AuditSink writing the records to w as JSON lines.
+func NewAuditLogSink(w io.Writer) AuditSink {
+	return &auditLogSink{w: w}
+}
+
+type auditLogSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *auditLogSink) Write(_ context.Context, record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the audit record: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(data); err != nil {
+		return fmt.Errorf("failed to write the audit record: %w", err)
+	}
+	return nil
+}
+
+// auditor records the RPCs handled by the plugin to the sinks.
+type auditor struct {
+	sinks []AuditSink
+	// sampleRate is the ratio of the succeeded RPCs to record, in the range of [0, 1].
+	// The failed RPCs are always recorded.
+	sampleRate float64
+	// random returns a pseudo-random number in [0, 1).
+	random func() float64
+	clock  clock.Clock
+	logger *zap.Logger
+}
+
+func newAuditor(sinks []AuditSink, sampleRate float64, clk clock.Clock, logger *zap.Logger) *auditor {
+	return &auditor{
+		sinks:      sinks,
+		sampleRate: sampleRate,
+		random:     rand.Float64,
+		clock:      clock.OrReal(clk),
+		logger:     logger.Named("audit"),
+	}
+}
+
+// sampled reports whether the RPC finished with the given code should be recorded.
+func (a *auditor) sampled(code codes.Code) bool {
+	if code != codes.OK {
+		return true
+	}
+	return a.random() < a.sampleRate
+}
+
+// record writes the record to all sinks.
+// The failures are logged, but they don't affect the result of the RPC.
+func (a *auditor) record(ctx context.Context, record AuditRecord) {
+	for _, sink := range a.sinks {
+		if err := sink.Write(ctx, record); err != nil {
+			a.logger.Warn("failed to write the audit record", zap.String("method", record.Method), zap.Error(err))
+		}
+	}
+}
+
+// newAuditRecord builds the record of the RPC from the request and the result.
+func newAuditRecord(ctx context.Context, method string, request any, start time.Time, d time.Duration, err error) AuditRecord {
+	record := AuditRecord{
+		Time:          start,
+		Method:        method,
+		Duration:      d,
+		Code:          status.Code(err).String(),
+		CorrelationID: CorrelationID(ctx),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	switch r := request.(type) {
+	case *deployment.DetermineVersionsRequest:
+		dep := r.GetInput().GetDeployment()
+		record.ApplicationID, record.DeploymentID, record.Requester = dep.GetApplicationId(), dep.GetId(), dep.GetPipedId()
+	case *deployment.DetermineStrategyRequest:
+		dep := r.GetInput().GetDeployment()
+		record.ApplicationID, record.DeploymentID, record.Requester = dep.GetApplicationId(), dep.GetId(), dep.GetPipedId()
+	case *deployment.ExecuteStageRequest:
+		dep := r.GetInput().GetDeployment()
+		record.ApplicationID, record.DeploymentID, record.Requester = dep.GetApplicationId(), dep.GetId(), dep.GetPipedId()
+		record.StageID = r.GetInput().GetStage().GetId()
+	case *livestate.GetLivestateRequest:
+		record.ApplicationID, record.Requester = r.GetApplicationId(), r.GetPipedId()
+	case *planpreview.GetPlanPreviewRequest:
+		record.ApplicationID, record.Requester = r.GetApplicationId(), r.GetPipedId()
+	}
+
+	if record.Requester == "" {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			record.Requester = p.Addr.String()
+		}
+	}
+	return record
+}
+
+// auditingRegistrar registers the services recording the handled RPCs to the auditor.
+type auditingRegistrar struct {
+	grpc.ServiceRegistrar
+	auditor *auditor
+}
+
+func (r auditingRegistrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	wrapped := *desc
+	wrapped.Methods = make([]grpc.MethodDesc, 0, len(desc.Methods))
+	for _, m := range desc.Methods {
+		handler := m.Handler
+		method := "/" + desc.ServiceName + "/" + m.MethodName
+		m.Handler = func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			var request any
+			decode := func(v any) error {
+				request = v
+				return dec(v)
+			}
+
+			start := r.auditor.clock.Now()
+			resp, err := handler(srv, ctx, decode, interceptor)
+			d := r.auditor.clock.Since(start)
+
+			if r.auditor.sampled(status.Code(err)) {
+				r.auditor.record(ctx, newAuditRecord(ctx, method, request, start, d, err))
+			}
+			return resp, err
+		}
+		wrapped.Methods = append(wrapped.Methods, m)
+	}
+	r.ServiceRegistrar.RegisterService(&wrapped, impl)
+}
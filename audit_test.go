@@ -0,0 +1,195 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+type fakeAuditSink struct {
+	records []AuditRecord
+}
+
+func (s *fakeAuditSink) Write(_ context.Context, record AuditRecord) error {
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestNewAuditRecord(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	peerCtx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}})
+
+	testcases := []struct {
+		name     string
+		ctx      context.Context
+		request  any
+		err      error
+		expected AuditRecord
+	}{
+		{
+			name: "execute stage",
+			ctx:  peerCtx,
+			request: &deployment.ExecuteStageRequest{
+				Input: &deployment.ExecutePluginInput{
+					Deployment: &model.Deployment{Id: "deployment-1", ApplicationId: "app-1", PipedId: "piped-1"},
+					Stage:      &model.PipelineStage{Id: "stage-1"},
+				},
+			},
+			expected: AuditRecord{
+				Time:          start,
+				Method:        "/test.Service/Method",
+				ApplicationID: "app-1",
+				DeploymentID:  "deployment-1",
+				StageID:       "stage-1",
+				Requester:     "piped-1",
+				Duration:      time.Second,
+				Code:          "OK",
+			},
+		},
+		{
+			name:    "get livestate failed",
+			ctx:     context.Background(),
+			request: &livestate.GetLivestateRequest{ApplicationId: "app-1", PipedId: "piped-1"},
+			err:     status.Error(codes.Internal, "failed"),
+			expected: AuditRecord{
+				Time:          start,
+				Method:        "/test.Service/Method",
+				ApplicationID: "app-1",
+				Requester:     "piped-1",
+				Duration:      time.Second,
+				Code:          "Internal",
+				Error:         "rpc error: code = Internal desc = failed",
+			},
+		},
+		{
+			name:    "request without the piped ID",
+			ctx:     peerCtx,
+			request: &deployment.FetchDefinedStagesRequest{},
+			expected: AuditRecord{
+				Time:      start,
+				Method:    "/test.Service/Method",
+				Requester: "127.0.0.1:8080",
+				Duration:  time.Second,
+				Code:      "OK",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			record := newAuditRecord(tc.ctx, "/test.Service/Method", tc.request, start, time.Second, tc.err)
+			assert.Equal(t, tc.expected, record)
+		})
+	}
+}
+
+func TestAuditingRegistrar(t *testing.T) {
+	t.Parallel()
+
+	clk := clocktest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sink := &fakeAuditSink{}
+	a := newAuditor([]AuditSink{sink}, 0.5, clk, zap.NewNop())
+	random := []float64{0.9, 0.1}
+	a.random = func() float64 {
+		v := random[0]
+		random = random[1:]
+		return v
+	}
+
+	desc := &grpc.ServiceDesc{
+		ServiceName: "test.Service",
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Get",
+				Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+					req := &livestate.GetLivestateRequest{}
+					if err := dec(req); err != nil {
+						return nil, err
+					}
+					clk.Advance(time.Second)
+					if req.ApplicationId == "failing" {
+						return nil, status.Error(codes.Internal, "failed")
+					}
+					return &livestate.GetLivestateResponse{}, nil
+				},
+			},
+		},
+	}
+	fake := &fakeServiceRegistrar{}
+	auditingRegistrar{ServiceRegistrar: fake, auditor: a}.RegisterService(desc, nil)
+	require.Len(t, fake.desc.Methods, 1)
+
+	call := func(appID string) error {
+		dec := func(v any) error {
+			v.(*livestate.GetLivestateRequest).ApplicationId = appID
+			return nil
+		}
+		_, err := fake.desc.Methods[0].Handler(nil, context.Background(), dec, nil)
+		return err
+	}
+
+	// The first call is not sampled, and the second one is.
+	require.NoError(t, call("app-1"))
+	require.NoError(t, call("app-2"))
+	// The failed call is always recorded without sampling.
+	require.Error(t, call("failing"))
+
+	require.Len(t, sink.records, 2)
+	assert.Equal(t, "app-2", sink.records[0].ApplicationID)
+	assert.Equal(t, "/test.Service/Get", sink.records[0].Method)
+	assert.Equal(t, time.Second, sink.records[0].Duration)
+	assert.Equal(t, "OK", sink.records[0].Code)
+	assert.Equal(t, "failing", sink.records[1].ApplicationID)
+	assert.Equal(t, "Internal", sink.records[1].Code)
+	assert.Empty(t, random)
+}
+
+func TestAuditLogSink(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	sink := NewAuditLogSink(&buf)
+	require.NoError(t, sink.Write(context.Background(), AuditRecord{Method: "/test.Service/A", Code: "OK"}))
+	require.NoError(t, sink.Write(context.Background(), AuditRecord{Method: "/test.Service/B", Code: "Internal", Error: "failed"}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var record AuditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, AuditRecord{Method: "/test.Service/B", Code: "Internal", Error: "failed"}, record)
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// The keys of the response header metadata telling piped how loaded the plugin is,
+// so that piped can throttle the requests to the overloaded plugin.
+const (
+	// SaturationHeader is the key of the saturation of the plugin, the ratio of the work in progress and waiting
+	// to the capacity of the plugin, e.g. "1.5" when 150% of the capacity is requested.
+	// It's "0" when the plugin has no limit of the capacity.
+	SaturationHeader = "x-pipecd-plugin-saturation"
+	// QueuedStagesHeader is the key of the number of the stages waiting to be executed.
+	QueuedStagesHeader = "x-pipecd-plugin-queued-stages"
+	// QueuedLivestatesHeader is the key of the number of the livestate requests waiting to be handled.
+	QueuedLivestatesHeader = "x-pipecd-plugin-queued-livestates"
+)
+
+var (
+	saturationRatio = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "plugin_saturation_ratio",
+			Help: "Ratio of the work in progress and waiting to the capacity of the plugin, reported to piped.",
+		},
+	)
+)
+
+// registerBackpressureMetrics registers the metrics of the load reported to piped to the given registerer.
+func registerBackpressureMetrics(r prometheus.Registerer) {
+	r.MustRegister(saturationRatio)
+}
+
+// loadReport is the load of the plugin reported to piped.
+type loadReport struct {
+	// Saturation is the largest ratio of the work in progress and waiting to the capacity among the limited work.
+	Saturation       float64
+	RunningStages    int
+	QueuedStages     int
+	StageCapacity    int
+	RunningLivestate int
+	QueuedLivestate  int
+	LivestateWorkers int
+}
+
+// loadReporter reports the load of the stage limiter and the livestate pool.
+type loadReporter struct {
+	stages    *stageLimiter
+	livestate *livestatePool
+	logger    *zap.Logger
+}
+
+// report returns the current load.
+func (r loadReporter) report() loadReport {
+	var l loadReport
+	l.RunningStages, l.QueuedStages, l.StageCapacity = r.stages.load()
+	l.RunningLivestate, l.QueuedLivestate, l.LivestateWorkers = r.livestate.load()
+	if l.StageCapacity > 0 {
+		l.Saturation = max(l.Saturation, float64(l.RunningStages+l.QueuedStages)/float64(l.StageCapacity))
+	}
+	if l.LivestateWorkers > 0 {
+		l.Saturation = max(l.Saturation, float64(l.RunningLivestate+l.QueuedLivestate)/float64(l.LivestateWorkers))
+	}
+	saturationRatio.Set(l.Saturation)
+	return l
+}
+
+// header returns the response header metadata of the load.
+func (l loadReport) header() metadata.MD {
+	return metadata.Pairs(
+		SaturationHeader, strconv.FormatFloat(l.Saturation, 'f', -1, 64),
+		QueuedStagesHeader, strconv.Itoa(l.QueuedStages),
+		QueuedLivestatesHeader, strconv.Itoa(l.QueuedLivestate),
+	)
+}
+
+// backpressureRegistrar registers the services sending the load of the plugin in the response header metadata.
+// The load is measured when the request arrives, so that piped knows it even when the request is rejected.
+type backpressureRegistrar struct {
+	grpc.ServiceRegistrar
+	reporter loadReporter
+}
+
+func (r backpressureRegistrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	wrapped := *desc
+	wrapped.Methods = make([]grpc.MethodDesc, 0, len(desc.Methods))
+	for _, m := range desc.Methods {
+		handler := m.Handler
+		method := m.MethodName
+		m.Handler = func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			if err := grpc.SetHeader(ctx, r.reporter.report().header()); err != nil {
+				r.reporter.logger.Debug("failed to set the load to the response header", zap.String("method", method), zap.Error(err))
+			}
+			return handler(srv, ctx, dec, interceptor)
+		}
+		wrapped.Methods = append(wrapped.Methods, m)
+	}
+	r.ServiceRegistrar.RegisterService(&wrapped, impl)
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLoadReporter_Report(t *testing.T) {
+	t.Parallel()
+
+	// The unlimited work is not counted in the saturation.
+	assert.Equal(t, loadReport{}, loadReporter{}.report())
+
+	stages := newStageLimiter(4, 0, 0, nil)
+	releaseStage, err := stages.acquire(context.Background(), nil)
+	require.NoError(t, err)
+	defer releaseStage()
+
+	livestate := newLivestatePool(1, 10)
+	releaseLivestate, err := livestate.acquire(context.Background(), "app-1")
+	require.NoError(t, err)
+	_, _ = acquireQueued(t, context.Background(), livestate, "app-2")
+	defer func() {
+		releaseLivestate()
+		livestate.release()
+	}()
+
+	report := loadReporter{stages: stages, livestate: livestate, logger: zap.NewNop()}.report()
+	assert.Equal(t, loadReport{
+		// The livestate requests are twice the workers.
+		Saturation:       2,
+		RunningStages:    1,
+		StageCapacity:    4,
+		RunningLivestate: 1,
+		QueuedLivestate:  1,
+		LivestateWorkers: 1,
+	}, report)
+
+	header := report.header()
+	assert.Equal(t, []string{"2"}, header.Get(SaturationHeader))
+	assert.Equal(t, []string{"0"}, header.Get(QueuedStagesHeader))
+	assert.Equal(t, []string{"1"}, header.Get(QueuedLivestatesHeader))
+}
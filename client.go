@@ -16,15 +16,23 @@ package sdk
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"iter"
+	"path"
+	"reflect"
 	"slices"
+	"sync"
 	"time"
 
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/retry"
 	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/pipe-cd/pipecd/pkg/model"
 	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
@@ -40,27 +48,125 @@ const (
 	MetadataKeyStageApprovedUsers = model.MetadataKeyStageApprovedUsers
 
 	listStageCommandsInterval = 5 * time.Second
+	// maxStageCommandPollFailures is the number of the consecutive transient failures of listing the stage commands
+	// after which WaitStageCommand gives up.
+	maxStageCommandPollFailures = 5
 )
 
 type pluginServiceClient struct {
 	pipedservice.PluginServiceClient
 	conn *grpc.ClientConn
+
+	mu sync.Mutex
+	// handledCommands holds the IDs of the stage commands returned by WaitStageCommand by the stage ID,
+	// so that the same command is not handled twice in the stage.
+	// The IDs are dropped when the stage finishes.
+	handledCommands map[string]map[string]struct{}
+}
+
+// commandHandled returns whether the command has been returned by WaitStageCommand in the stage.
+func (c *pluginServiceClient) commandHandled(stageID, id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.handledCommands[stageID][id]
+	return ok
+}
+
+// markCommandHandled records the command as handled in the stage, and returns false if it has been handled already.
+func (c *pluginServiceClient) markCommandHandled(stageID, id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.handledCommands[stageID][id]; ok {
+		return false
+	}
+	if c.handledCommands == nil {
+		c.handledCommands = make(map[string]map[string]struct{})
+	}
+	if c.handledCommands[stageID] == nil {
+		c.handledCommands[stageID] = make(map[string]struct{})
+	}
+	c.handledCommands[stageID][id] = struct{}{}
+	return true
+}
+
+// forgetHandledCommands drops the IDs of the commands handled in the finished stage.
+func (c *pluginServiceClient) forgetHandledCommands(stageID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.handledCommands, stageID)
+}
+
+// inProcessConn is the connection calling the methods of the given piped service client directly through the interceptors,
+// so that the in-memory service used in the tests is called in the same way as piped.
+type inProcessConn struct {
+	service      pipedservice.PluginServiceClient
+	interceptors []grpc.UnaryClientInterceptor
+}
+
+func (c *inProcessConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return c.invoker(0)(ctx, method, args, reply, nil, opts...)
+}
+
+func (c *inProcessConn) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Error(codes.Unimplemented, "streaming is not supported")
+}
+
+// invoker returns the invoker calling the i-th interceptor, or the method of the service after all the interceptors.
+func (c *inProcessConn) invoker(i int) grpc.UnaryInvoker {
+	if i == len(c.interceptors) {
+		return c.call
+	}
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return c.interceptors[i](ctx, method, req, reply, cc, c.invoker(i+1), opts...)
+	}
+}
+
+// call calls the method of the service of the given full method name, and copies the response into the reply.
+func (c *inProcessConn) call(ctx context.Context, method string, req, reply any, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+	m := reflect.ValueOf(c.service).MethodByName(path.Base(method))
+	if !m.IsValid() {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+	out := m.CallSlice([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(req), reflect.ValueOf(opts)})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return err
+	}
+	proto.Reset(reply.(proto.Message))
+	if !out[0].IsNil() {
+		proto.Merge(reply.(proto.Message), out[0].Interface().(proto.Message))
+	}
+	return nil
 }
 
-func newPluginServiceClient(ctx context.Context, address string, opts ...rpcclient.DialOption) (*pluginServiceClient, error) {
+func newPluginServiceClient(ctx context.Context, address string, interceptors []grpc.UnaryClientInterceptor, opts ...rpcclient.DialOption) (*pluginServiceClient, error) {
 	// Clone the opts to avoid modifying the original opts slice.
 	opts = slices.Clone(opts)
 
 	// Append the required options.
-	// The WithBlock option is required to make the client wait until the connection is up.
 	// The WithInsecure option is required to disable the transport security.
 	// The piped service does not require transport security because it is only used in localhost.
-	opts = append(opts, rpcclient.WithBlock(), rpcclient.WithInsecure())
+	opts = append(opts, rpcclient.WithInsecure())
+
+	dialOpts, err := rpcclient.DialOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(interceptors) > 0 {
+		dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(interceptors...))
+	}
 
-	conn, err := rpcclient.DialContext(ctx, address, opts...)
+	conn, err := grpc.NewClient(address, dialOpts...)
 	if err != nil {
 		return nil, err
 	}
+	// grpc.NewClient doesn't connect until the first call, so wait until the connection is up here.
+	if err := waitForReady(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to piped at %s: %w", address, err)
+	}
 
 	return &pluginServiceClient{
 		PluginServiceClient: pipedservice.NewPluginServiceClient(conn),
@@ -68,6 +174,22 @@ func newPluginServiceClient(ctx context.Context, address string, opts ...rpcclie
 	}, nil
 }
 
+// waitForReady connects to the server and waits until the connection is ready or the context is done.
+func waitForReady(ctx context.Context, conn *grpc.ClientConn) error {
+	for {
+		state := conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Idle:
+			conn.Connect()
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return ctx.Err()
+		}
+	}
+}
+
 func (c *pluginServiceClient) Close() error {
 	return c.conn.Close()
 }
@@ -97,6 +219,15 @@ type Client struct {
 	// toolRegistry is used to install and get the path of the tools used in the plugin.
 	// TODO: We should consider installing the tools in other way.
 	toolRegistry *toolregistry.ToolRegistry
+
+	// clock is used to expire the caches and to poll the stage commands.
+	// The real clock is used when it's nil.
+	clock clock.Clock
+}
+
+// clockOrReal returns the clock of the client, or the real clock if it's not set.
+func (c *Client) clockOrReal() clock.Clock {
+	return clock.OrReal(c.clock)
 }
 
 // NewClient creates a new client.
@@ -233,6 +364,99 @@ func (c *Client) PutApplicationSharedObject(ctx context.Context, key string, obj
 	return err
 }
 
+// cacheKeyPrefix is the prefix of the keys of the application shared objects used as the cache,
+// which separates them from the objects stored by PutApplicationSharedObject.
+const cacheKeyPrefix = "cache/"
+
+// cachedObject is the envelope of the value stored by PutCache.
+type cachedObject struct {
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+}
+
+// GetCache gets the value cached by PutCache for the current application.
+// It returns false when the value is not found or has expired.
+// The cache is stored in piped as an application shared object, so it's shared among the plugin replicas and survives restarts.
+// An expired entry is evicted when it's read. Since piped doesn't support deleting shared objects, it's overwritten with an empty object.
+func (c *Client) GetCache(ctx context.Context, key string) ([]byte, bool, error) {
+	obj, found, err := c.GetApplicationSharedObject(ctx, cacheKeyPrefix+key)
+	if err != nil || !found || len(obj) == 0 {
+		return nil, false, err
+	}
+	var cached cachedObject
+	if err := json.Unmarshal(obj, &cached); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal the cached object %s: %w", key, err)
+	}
+	if !cached.ExpiresAt.IsZero() && c.clockOrReal().Now().After(cached.ExpiresAt) {
+		// Evicting is best effort; the entry is evicted on the next read if it fails.
+		_ = c.PutApplicationSharedObject(ctx, cacheKeyPrefix+key, []byte{})
+		return nil, false, nil
+	}
+	return cached.Value, true, nil
+}
+
+// PutCache caches the value for the current application for the given TTL.
+// The value never expires when the TTL is zero.
+// The cache is namespaced by the plugin and the application.
+func (c *Client) PutCache(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	cached := cachedObject{Value: value}
+	if ttl > 0 {
+		cached.ExpiresAt = c.clockOrReal().Now().Add(ttl)
+	}
+	obj, err := json.Marshal(cached)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the cached object %s: %w", key, err)
+	}
+	return c.PutApplicationSharedObject(ctx, cacheKeyPrefix+key, obj)
+}
+
+// GetStageMetadataJSON gets the metadata of the current stage and decodes it as JSON into a value of type T.
+func GetStageMetadataJSON[T any](ctx context.Context, c *Client, key string) (T, bool, error) {
+	return decodeMetadata[T](c.GetStageMetadata(ctx, key))
+}
+
+// PutStageMetadataJSON encodes the given value as JSON and stores it as the metadata of the current stage.
+func PutStageMetadataJSON[T any](ctx context.Context, c *Client, key string, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the metadata %s: %w", key, err)
+	}
+	return c.PutStageMetadata(ctx, key, string(data))
+}
+
+// GetDeploymentPluginMetadataJSON gets the metadata of the current deployment and plugin and decodes it as JSON into a value of type T.
+func GetDeploymentPluginMetadataJSON[T any](ctx context.Context, c *Client, key string) (T, bool, error) {
+	return decodeMetadata[T](c.GetDeploymentPluginMetadata(ctx, key))
+}
+
+// PutDeploymentPluginMetadataJSON encodes the given value as JSON and stores it as the metadata of the current deployment and plugin.
+func PutDeploymentPluginMetadataJSON[T any](ctx context.Context, c *Client, key string, value T) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the metadata %s: %w", key, err)
+	}
+	return c.PutDeploymentPluginMetadata(ctx, key, string(data))
+}
+
+// GetDeploymentSharedMetadataJSON gets the metadata of the current deployment
+// which is shared among piped and plugins, and decodes it as JSON into a value of type T.
+func GetDeploymentSharedMetadataJSON[T any](ctx context.Context, c *Client, key string) (T, bool, error) {
+	return decodeMetadata[T](c.GetDeploymentSharedMetadata(ctx, key))
+}
+
+// decodeMetadata decodes the metadata value returned by the client as JSON.
+// The zero value is returned when the metadata is not found.
+func decodeMetadata[T any](value string, found bool, err error) (T, bool, error) {
+	var v T
+	if err != nil || !found {
+		return v, false, err
+	}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return v, true, fmt.Errorf("failed to unmarshal the metadata: %w", err)
+	}
+	return v, true, nil
+}
+
 // StageLogPersister returns the stage log persister.
 // Use this to persist the stage logs and make it viewable on the UI.
 // This method should be called only when the client is working with a specific stage, for example, when this client is passed as the ExecuteStage method's argument.
@@ -260,6 +484,8 @@ func (c *Client) ToolRegistry() *toolregistry.ToolRegistry {
 }
 
 // ListStageCommands returns the list of stage commands of the given command types.
+// It keeps polling piped for the new commands until the context is done or the caller stops the iteration.
+// Each command is returned only once.
 func (c Client) ListStageCommands(ctx context.Context, commandTypes ...CommandType) iter.Seq2[*StageCommand, error] {
 	return func(yield func(*StageCommand, error) bool) {
 		returned := map[string]struct{}{}
@@ -276,19 +502,24 @@ func (c Client) ListStageCommands(ctx context.Context, commandTypes ...CommandTy
 			modelCommandTypes = append(modelCommandTypes, modelType)
 		}
 
+		ticker := c.clockOrReal().NewTicker(listStageCommandsInterval)
+		defer ticker.Stop()
+
 		for {
 			resp, err := c.base.ListStageCommands(ctx, &pipedservice.ListStageCommandsRequest{
 				DeploymentId: c.deploymentID,
 				StageId:      c.stageID,
 			})
 			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
 				if !yield(nil, err) {
 					return
 				}
-				continue
 			}
 
-			for _, command := range resp.Commands {
+			for _, command := range resp.GetCommands() {
 				if !slices.Contains(modelCommandTypes, command.Type) {
 					continue
 				}
@@ -311,7 +542,73 @@ func (c Client) ListStageCommands(ctx context.Context, commandTypes ...CommandTy
 				}
 			}
 
-			time.Sleep(listStageCommandsInterval)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+		}
+	}
+}
+
+// WaitStageCommand blocks until a stage command of the given command types is issued for the current stage, and returns it.
+// It's useful for the interactive stages, e.g. waiting for the approval of the stage.
+// Each command is returned only once in the stage, so the next call waits for another command.
+// The transient errors while polling piped are tolerated unless they occur several times in a row,
+// and the other errors are returned immediately.
+func (c Client) WaitStageCommand(ctx context.Context, commandTypes ...CommandType) (*StageCommand, error) {
+	modelCommandTypes := make([]model.Command_Type, 0, len(commandTypes))
+	for _, cmdType := range commandTypes {
+		modelType, err := cmdType.toModelEnum()
+		if err != nil {
+			return nil, err
+		}
+		modelCommandTypes = append(modelCommandTypes, modelType)
+	}
+
+	ticker := c.clockOrReal().NewTicker(listStageCommandsInterval)
+	defer ticker.Stop()
+
+	var failures int
+	for {
+		resp, err := c.base.ListStageCommands(ctx, &pipedservice.ListStageCommandsRequest{
+			DeploymentId: c.deploymentID,
+			StageId:      c.stageID,
+		})
+		switch {
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case err == nil:
+			failures = 0
+		case !retry.IsTransientGRPC(err):
+			return nil, fmt.Errorf("failed to list the stage commands: %w", err)
+		default:
+			failures++
+			if failures >= maxStageCommandPollFailures {
+				return nil, fmt.Errorf("failed to list the stage commands %d times in a row: %w", failures, err)
+			}
+		}
+
+		for _, command := range resp.GetCommands() {
+			if !slices.Contains(modelCommandTypes, command.Type) || c.base.commandHandled(c.stageID, command.Id) {
+				continue
+			}
+			stageCommand, err := newStageCommand(command)
+			if err != nil {
+				return nil, err
+			}
+			// The command is marked only after it's converted, so that it's returned by the next call on the failure.
+			if !c.base.markCommandHandled(c.stageID, command.Id) {
+				// It's returned by another call in the meantime.
+				continue
+			}
+			return &stageCommand, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C():
 		}
 	}
 }
@@ -0,0 +1,226 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/retry"
+)
+
+// nonRetriedMethods are the methods which are not retried by retryUnaryClientInterceptor.
+// They are keyed by the method name without the service name.
+var nonRetriedMethods = map[string]bool{
+	// piped appends the given log blocks, so retrying a call which piped has applied duplicates them.
+	"ReportStageLogs": true,
+	// The tool registry retries the installation by itself, see toolregistry.WithInstallRetry.
+	"InstallTool": true,
+}
+
+// retryUnaryClientInterceptor retries the calls failed with transient codes
+// up to the given number of attempts with exponential backoff.
+// The intervals are waited on the given clock.
+// The methods in nonRetriedMethods are not retried because they are not idempotent or retried by the callers.
+func retryUnaryClientInterceptor(attempts int, baseInterval, maxInterval time.Duration, clk clock.Clock) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if attempts <= 1 || nonRetriedMethods[path.Base(method)] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		policy := retry.Policy{
+			MaxAttempts:     attempts,
+			InitialInterval: baseInterval,
+			MaxInterval:     maxInterval,
+			Jitter:          1,
+			Retryable:       retry.IsTransientGRPC,
+			OnRetry: func(int, error, time.Duration) {
+				clientRetried(method)
+			},
+			Clock: clk,
+		}
+		return retry.Do(ctx, policy, func(ctx context.Context) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+// circuitState represents the state of the circuit breaker.
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker stops sending requests to piped for a while
+// when the calls continuously fail with transient codes,
+// so that the plugin does not pile up the requests while piped is unavailable.
+type circuitBreaker struct {
+	// threshold is the number of consecutive failures to open the circuit.
+	threshold int
+	// cooldown is how long the circuit stays open before allowing a trial call.
+	cooldown time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	clock    clock.Clock
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, clk clock.Clock) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		clock:     clock.OrReal(clk),
+	}
+}
+
+// allow returns true if a call is allowed to be sent.
+// Only one trial call is allowed while the circuit is half-open.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.clock.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(circuitHalfOpen)
+		return true
+	case circuitHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the state of the circuit by the result of a call.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil || !retry.IsTransientGRPC(err) {
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.clock.Now()
+		b.setState(circuitOpen)
+	}
+}
+
+// setState must be called while holding the lock.
+func (b *circuitBreaker) setState(s circuitState) {
+	b.state = s
+	clientCircuitStateChanged(s)
+}
+
+// unaryClientInterceptor rejects the calls with Unavailable while the circuit is open.
+func (b *circuitBreaker) unaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if b.threshold <= 0 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		if !b.allow() {
+			clientRejected(method)
+			return status.Errorf(codes.Unavailable, "the circuit breaker for the piped plugin service is open, %s is rejected", method)
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		b.record(err)
+		return err
+	}
+}
+
+// defaultMethodTimeouts are the timeouts of the methods which take longer than the others.
+var defaultMethodTimeouts = map[string]time.Duration{
+	// Installing a tool may download a large binary.
+	"InstallTool": 10 * time.Minute,
+}
+
+// parseMethodTimeouts parses the timeouts given as the map from the method name to the duration string, e.g. "PutStageMetadata": "5s".
+// The result includes the default timeouts of the methods not given.
+func parseMethodTimeouts(timeouts map[string]string) (map[string]time.Duration, error) {
+	return parseMethodDurations(defaultMethodTimeouts, timeouts, "timeout")
+}
+
+// parseMethodDurations parses the durations given as the map from the method name to the duration string,
+// and returns them merged into the defaults. The kind is the name of the durations used in the error.
+func parseMethodDurations(defaults map[string]time.Duration, durations map[string]string, kind string) (map[string]time.Duration, error) {
+	parsed := maps.Clone(defaults)
+	if parsed == nil {
+		parsed = make(map[string]time.Duration, len(durations))
+	}
+	for method, v := range durations {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q for the method %s: %w", kind, v, method, err)
+		}
+		parsed[method] = d
+	}
+	return parsed, nil
+}
+
+// timeoutUnaryClientInterceptor sets the deadline of each call by the timeout of the method,
+// so that a stuck call to piped does not block the plugin indefinitely.
+// The earlier deadline of the context is kept, so callers can shorten the timeout per call,
+// while the calls made in the handlers, whose contexts have the long deadlines of the RPCs from piped, are still bounded.
+// The timeouts are keyed by the method name without the service name, e.g. "PutStageMetadata".
+// A zero timeout means no timeout.
+func timeoutUnaryClientInterceptor(defaultTimeout time.Duration, methodTimeouts map[string]time.Duration) grpc.UnaryClientInterceptor {
+	return func(parent context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		name := path.Base(method)
+		timeout, ok := methodTimeouts[name]
+		if !ok {
+			timeout = defaultTimeout
+		}
+		if timeout <= 0 {
+			return invoker(parent, method, req, reply, cc, opts...)
+		}
+
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		// Explain the timeout only when it's the one of the method rather than the deadline of the caller.
+		if status.Code(err) == codes.DeadlineExceeded && ctx.Err() != nil && parent.Err() == nil {
+			return status.Errorf(codes.DeadlineExceeded, "%s did not complete within %s; piped may be overloaded or unreachable. Configure the timeout of %s if it needs longer: %v", name, timeout, name, err)
+		}
+		return err
+	}
+}
+
+// compressionUnaryClientInterceptor compresses the requests to piped with the given compressor.
+// The compressor must be registered to grpc, e.g. "gzip".
+func compressionUnaryClientInterceptor(compressor string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ctx, method, req, reply, cc, append(opts, grpc.UseCompressor(compressor))...)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+// failingInvoker returns an invoker which fails with the given errors in order and succeeds after that.
+func failingInvoker(calls *int, errs ...error) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestRetryUnaryClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		method        string
+		attempts      int
+		errs          []error
+		expectedCalls int
+		expectedCode  codes.Code
+	}{
+		{
+			name:          "succeed after transient failures",
+			attempts:      3,
+			errs:          []error{status.Error(codes.Unavailable, ""), status.Error(codes.ResourceExhausted, "")},
+			expectedCalls: 3,
+			expectedCode:  codes.OK,
+		},
+		{
+			name:          "give up after the max attempts",
+			attempts:      2,
+			errs:          []error{status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, "")},
+			expectedCalls: 2,
+			expectedCode:  codes.Unavailable,
+		},
+		{
+			name:          "not retry on non-transient failure",
+			attempts:      3,
+			errs:          []error{status.Error(codes.InvalidArgument, "")},
+			expectedCalls: 1,
+			expectedCode:  codes.InvalidArgument,
+		},
+		{
+			name:          "retry is disabled",
+			attempts:      1,
+			errs:          []error{status.Error(codes.Unavailable, "")},
+			expectedCalls: 1,
+			expectedCode:  codes.Unavailable,
+		},
+		{
+			name:          "not retry non-idempotent method",
+			method:        "/grpc.piped.service.PluginService/ReportStageLogs",
+			attempts:      3,
+			errs:          []error{status.Error(codes.Aborted, "")},
+			expectedCalls: 1,
+			expectedCode:  codes.Aborted,
+		},
+		{
+			name:          "not retry method retried by the caller",
+			method:        "/grpc.piped.service.PluginService/InstallTool",
+			attempts:      3,
+			errs:          []error{status.Error(codes.Unavailable, "")},
+			expectedCalls: 1,
+			expectedCode:  codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			method := tt.method
+			if method == "" {
+				method = "/method"
+			}
+			var calls int
+			interceptor := retryUnaryClientInterceptor(tt.attempts, time.Millisecond, time.Millisecond, nil)
+			err := interceptor(context.Background(), method, nil, nil, nil, failingInvoker(&calls, tt.errs...))
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			assert.Equal(t, tt.expectedCalls, calls)
+		})
+	}
+}
+
+func TestRetryUnaryClientInterceptor_Clock(t *testing.T) {
+	t.Parallel()
+
+	clk := clocktest.NewFakeClock(time.Now())
+	interceptor := retryUnaryClientInterceptor(3, time.Minute, time.Minute, clk)
+
+	var calls int
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- interceptor(context.Background(), "/method", nil, nil, nil, failingInvoker(&calls, status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, "")))
+	}()
+
+	// The retries wait for the backoff on the clock.
+	for range 2 {
+		clk.BlockUntil(1)
+		clk.Advance(time.Minute)
+	}
+	require.NoError(t, <-errCh)
+	assert.Equal(t, 3, calls)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	clk := clocktest.NewFakeClock(time.Now())
+	b := newCircuitBreaker(2, time.Minute, clk)
+	interceptor := b.unaryClientInterceptor()
+
+	var calls int
+	invoker := failingInvoker(&calls, status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""), status.Error(codes.Unavailable, ""))
+
+	// The circuit is opened after the consecutive failures.
+	for range 2 {
+		err := interceptor(context.Background(), "/method", nil, nil, nil, invoker)
+		require.Error(t, err)
+	}
+	assert.Equal(t, circuitOpen, b.state)
+
+	// The calls are rejected without calling piped while the circuit is open.
+	err := interceptor(context.Background(), "/method", nil, nil, nil, invoker)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 2, calls)
+
+	// A failed trial call after the cooldown opens the circuit again.
+	clk.Advance(time.Minute)
+	err = interceptor(context.Background(), "/method", nil, nil, nil, invoker)
+	require.Error(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, circuitOpen, b.state)
+
+	// A successful trial call closes the circuit.
+	clk.Advance(time.Minute)
+	err = interceptor(context.Background(), "/method", nil, nil, nil, invoker)
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, circuitClosed, b.state)
+}
+
+func TestTimeoutUnaryClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	methodTimeouts, err := parseMethodTimeouts(map[string]string{"PutStageMetadata": "1ms"})
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, methodTimeouts["InstallTool"])
+
+	_, err = parseMethodTimeouts(map[string]string{"PutStageMetadata": "invalid"})
+	require.Error(t, err)
+
+	interceptor := timeoutUnaryClientInterceptor(time.Hour, methodTimeouts)
+	blockingInvoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		<-ctx.Done()
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	tests := []struct {
+		name             string
+		method           string
+		ctxTimeout       time.Duration
+		expectedDeadline time.Duration
+		expectMessage    bool
+	}{
+		{
+			name:             "method timeout",
+			method:           "/grpc.piped.service.PluginService/PutStageMetadata",
+			expectedDeadline: time.Millisecond,
+			expectMessage:    true,
+		},
+		{
+			name:             "default timeout",
+			method:           "/grpc.piped.service.PluginService/GetStageMetadata",
+			expectedDeadline: time.Hour,
+		},
+		{
+			name:             "shorter deadline of the caller",
+			method:           "/grpc.piped.service.PluginService/GetStageMetadata",
+			ctxTimeout:       time.Minute,
+			expectedDeadline: time.Minute,
+		},
+		{
+			name:             "longer deadline of the caller",
+			method:           "/grpc.piped.service.PluginService/PutStageMetadata",
+			ctxTimeout:       6 * time.Hour,
+			expectedDeadline: time.Millisecond,
+			expectMessage:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+				defer cancel()
+			}
+
+			var deadline time.Time
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				deadline, _ = ctx.Deadline()
+				if tt.expectMessage {
+					return blockingInvoker(ctx, method, req, reply, cc, opts...)
+				}
+				return nil
+			}
+
+			err := interceptor(ctx, tt.method, nil, nil, nil, invoker)
+			assert.WithinDuration(t, time.Now().Add(tt.expectedDeadline), deadline, time.Second)
+			if tt.expectMessage {
+				assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+				assert.Contains(t, err.Error(), "PutStageMetadata did not complete within 1ms")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCompressionUnaryClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	var compressors []string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		for _, opt := range opts {
+			if c, ok := opt.(grpc.CompressorCallOption); ok {
+				compressors = append(compressors, c.CompressorType)
+			}
+		}
+		return nil
+	}
+
+	err := compressionUnaryClientInterceptor("gzip")(context.Background(), "/method", nil, nil, nil, invoker)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gzip"}, compressors)
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	methodKey = "method"
+)
+
+var (
+	clientRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_piped_client_retries_total",
+			Help: "Total number of retried calls to the piped plugin service.",
+		},
+		[]string{methodKey},
+	)
+	clientRejectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_piped_client_circuit_breaker_rejections_total",
+			Help: "Total number of calls to the piped plugin service rejected by the circuit breaker.",
+		},
+		[]string{methodKey},
+	)
+	clientCircuitState = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "plugin_piped_client_circuit_breaker_state",
+			Help: "State of the circuit breaker for the piped plugin service. 0: closed, 1: open, 2: half-open.",
+		},
+	)
+)
+
+func clientRetried(method string) {
+	clientRetriesTotal.With(prometheus.Labels{methodKey: method}).Inc()
+}
+
+func clientRejected(method string) {
+	clientRejectionsTotal.With(prometheus.Labels{methodKey: method}).Inc()
+}
+
+func clientCircuitStateChanged(s circuitState) {
+	clientCircuitState.Set(float64(s))
+}
+
+// registerClientMetrics registers the metrics of the piped plugin service client to the given registerer.
+func registerClientMetrics(r prometheus.Registerer) {
+	r.MustRegister(
+		clientRetriesTotal,
+		clientRejectionsTotal,
+		clientCircuitState,
+	)
+}
@@ -0,0 +1,301 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+// fakePluginServiceClient is a fake piped service client storing the metadata in memory.
+type fakePluginServiceClient struct {
+	pipedservice.PluginServiceClient
+
+	mu             sync.Mutex
+	stageMetadata  map[string]string
+	pluginMetadata map[string]string
+	sharedMetadata map[string]string
+	commands       []*model.Command
+	commandsErr    error
+	commandsCalls  int
+	sharedObjects  map[string][]byte
+}
+
+func newFakePluginServiceClient() *fakePluginServiceClient {
+	return &fakePluginServiceClient{
+		stageMetadata:  make(map[string]string),
+		pluginMetadata: make(map[string]string),
+		sharedMetadata: make(map[string]string),
+		sharedObjects:  make(map[string][]byte),
+	}
+}
+
+func (c *fakePluginServiceClient) GetStageMetadata(_ context.Context, in *pipedservice.GetStageMetadataRequest, _ ...grpc.CallOption) (*pipedservice.GetStageMetadataResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.stageMetadata[in.GetKey()]
+	return &pipedservice.GetStageMetadataResponse{Value: v, Found: ok}, nil
+}
+
+func (c *fakePluginServiceClient) PutStageMetadata(_ context.Context, in *pipedservice.PutStageMetadataRequest, _ ...grpc.CallOption) (*pipedservice.PutStageMetadataResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stageMetadata[in.GetKey()] = in.GetValue()
+	return &pipedservice.PutStageMetadataResponse{}, nil
+}
+
+func (c *fakePluginServiceClient) GetDeploymentPluginMetadata(_ context.Context, in *pipedservice.GetDeploymentPluginMetadataRequest, _ ...grpc.CallOption) (*pipedservice.GetDeploymentPluginMetadataResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.pluginMetadata[in.GetKey()]
+	return &pipedservice.GetDeploymentPluginMetadataResponse{Value: v, Found: ok}, nil
+}
+
+func (c *fakePluginServiceClient) PutDeploymentPluginMetadata(_ context.Context, in *pipedservice.PutDeploymentPluginMetadataRequest, _ ...grpc.CallOption) (*pipedservice.PutDeploymentPluginMetadataResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pluginMetadata[in.GetKey()] = in.GetValue()
+	return &pipedservice.PutDeploymentPluginMetadataResponse{}, nil
+}
+
+func (c *fakePluginServiceClient) GetDeploymentSharedMetadata(_ context.Context, in *pipedservice.GetDeploymentSharedMetadataRequest, _ ...grpc.CallOption) (*pipedservice.GetDeploymentSharedMetadataResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.sharedMetadata[in.GetKey()]
+	return &pipedservice.GetDeploymentSharedMetadataResponse{Value: v, Found: ok}, nil
+}
+
+func (c *fakePluginServiceClient) ListStageCommands(_ context.Context, in *pipedservice.ListStageCommandsRequest, _ ...grpc.CallOption) (*pipedservice.ListStageCommandsResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commandsCalls++
+	if c.commandsErr != nil {
+		return nil, c.commandsErr
+	}
+	return &pipedservice.ListStageCommandsResponse{Commands: c.commands}, nil
+}
+
+func (c *fakePluginServiceClient) GetApplicationSharedObject(_ context.Context, in *pipedservice.GetApplicationSharedObjectRequest, _ ...grpc.CallOption) (*pipedservice.GetApplicationSharedObjectResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	obj, ok := c.sharedObjects[in.GetApplicationId()+"/"+in.GetPluginName()+"/"+in.GetKey()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	return &pipedservice.GetApplicationSharedObjectResponse{Object: obj}, nil
+}
+
+func (c *fakePluginServiceClient) PutApplicationSharedObject(_ context.Context, in *pipedservice.PutApplicationSharedObjectRequest, _ ...grpc.CallOption) (*pipedservice.PutApplicationSharedObjectResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sharedObjects[in.GetApplicationId()+"/"+in.GetPluginName()+"/"+in.GetKey()] = in.GetObject()
+	return &pipedservice.PutApplicationSharedObjectResponse{}, nil
+}
+
+func TestClient_MetadataJSON(t *testing.T) {
+	t.Parallel()
+
+	type state struct {
+		Replicas int    `json:"replicas"`
+		Revision string `json:"revision"`
+	}
+
+	base := newFakePluginServiceClient()
+	c := &Client{base: &pluginServiceClient{PluginServiceClient: base}, pluginName: "plugin"}
+	ctx := context.Background()
+
+	// Stage metadata.
+	_, found, err := GetStageMetadataJSON[state](ctx, c, "state")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, PutStageMetadataJSON(ctx, c, "state", state{Replicas: 3, Revision: "abc"}))
+	assert.JSONEq(t, `{"replicas":3,"revision":"abc"}`, base.stageMetadata["state"])
+
+	got, found, err := GetStageMetadataJSON[state](ctx, c, "state")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, state{Replicas: 3, Revision: "abc"}, got)
+
+	// Deployment plugin metadata.
+	require.NoError(t, PutDeploymentPluginMetadataJSON(ctx, c, "revisions", []string{"a", "b"}))
+	revisions, found, err := GetDeploymentPluginMetadataJSON[[]string](ctx, c, "revisions")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"a", "b"}, revisions)
+
+	// Deployment shared metadata which is not valid JSON.
+	base.sharedMetadata["invalid"] = "not json"
+	_, found, err = GetDeploymentSharedMetadataJSON[state](ctx, c, "invalid")
+	require.Error(t, err)
+	assert.True(t, found)
+}
+
+func TestClient_WaitStageCommand(t *testing.T) {
+	t.Parallel()
+
+	base := newFakePluginServiceClient()
+	base.commands = []*model.Command{
+		{Id: "cmd-1", Type: model.Command_SKIP_STAGE, Commander: "user-1", CreatedAt: 100},
+		{Id: "cmd-2", Type: model.Command_APPROVE_STAGE, Commander: "user-2", CreatedAt: 200},
+	}
+	c := &Client{base: &pluginServiceClient{PluginServiceClient: base}, stageID: "stage"}
+
+	cmd, err := c.WaitStageCommand(context.Background(), CommandTypeApproveStage)
+	require.NoError(t, err)
+	assert.Equal(t, &StageCommand{
+		ID:        "cmd-2",
+		Commander: "user-2",
+		Type:      CommandTypeApproveStage,
+		CreatedAt: time.Unix(200, 0),
+	}, cmd)
+
+	// The handled command should not be returned again.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = c.WaitStageCommand(ctx, CommandTypeApproveStage)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The handled commands are tracked per stage, and dropped when the stage finishes.
+	other := &Client{base: c.base, stageID: "other"}
+	cmd, err = other.WaitStageCommand(context.Background(), CommandTypeApproveStage)
+	require.NoError(t, err)
+	assert.Equal(t, "cmd-2", cmd.ID)
+	c.base.forgetHandledCommands("stage")
+	c.base.forgetHandledCommands("other")
+	assert.Empty(t, c.base.handledCommands)
+
+	// It should return as soon as the context is done when there is no command.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = (&Client{base: &pluginServiceClient{PluginServiceClient: newFakePluginServiceClient()}}).WaitStageCommand(ctx, CommandTypeApproveStage)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// It should return the error which is not transient without waiting for the context.
+	base = newFakePluginServiceClient()
+	base.commandsErr = status.Error(codes.PermissionDenied, "denied")
+	_, err = (&Client{base: &pluginServiceClient{PluginServiceClient: base}}).WaitStageCommand(context.Background(), CommandTypeApproveStage)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestClient_WaitStageCommand_TransientErrors(t *testing.T) {
+	t.Parallel()
+
+	base := newFakePluginServiceClient()
+	base.commandsErr = status.Error(codes.Unavailable, "unavailable")
+	clk := clocktest.NewFakeClock(time.Now())
+	c := &Client{base: &pluginServiceClient{PluginServiceClient: base}, clock: clk}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := c.WaitStageCommand(context.Background(), CommandTypeApproveStage)
+		errCh <- err
+	}()
+	// The transient errors are tolerated until they occur maxStageCommandPollFailures times in a row.
+	for i := 1; i < maxStageCommandPollFailures; i++ {
+		require.Eventually(t, func() bool {
+			base.mu.Lock()
+			defer base.mu.Unlock()
+			return base.commandsCalls == i
+		}, time.Second, time.Millisecond)
+		clk.Advance(listStageCommandsInterval)
+	}
+	err := <-errCh
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestClient_Cache(t *testing.T) {
+	t.Parallel()
+
+	base := newFakePluginServiceClient()
+	clk := clocktest.NewFakeClock(time.Now())
+	c := &Client{base: &pluginServiceClient{PluginServiceClient: base}, pluginName: "plugin", applicationID: "app", clock: clk}
+	ctx := context.Background()
+
+	_, found, err := c.GetCache(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, c.PutCache(ctx, "key", []byte("value"), time.Hour))
+	value, found, err := c.GetCache(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), value)
+
+	// The cache of another application should not be visible.
+	other := &Client{base: c.base, pluginName: "plugin", applicationID: "other"}
+	_, found, err = other.GetCache(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// The expired value should not be returned.
+	require.NoError(t, c.PutCache(ctx, "expired", []byte("value"), time.Minute))
+	_, found, err = c.GetCache(ctx, "expired")
+	require.NoError(t, err)
+	assert.True(t, found)
+	clk.Advance(time.Minute + time.Second)
+	_, found, err = c.GetCache(ctx, "expired")
+	require.NoError(t, err)
+	assert.False(t, found)
+	// The expired value should be evicted.
+	obj, ok := base.sharedObjects["app/plugin/cache/expired"]
+	require.True(t, ok)
+	assert.Empty(t, obj)
+
+	// The value without TTL should not expire.
+	require.NoError(t, c.PutCache(ctx, "forever", []byte("value"), 0))
+	_, found, err = c.GetCache(ctx, "forever")
+	require.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestInProcessConn(t *testing.T) {
+	t.Parallel()
+
+	base := newFakePluginServiceClient()
+	base.stageMetadata["key"] = "value"
+
+	var methods []string
+	conn := &inProcessConn{
+		service: base,
+		interceptors: []grpc.UnaryClientInterceptor{
+			func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+				methods = append(methods, method)
+				return invoker(ctx, method, req, reply, cc, opts...)
+			},
+		},
+	}
+	client := pipedservice.NewPluginServiceClient(conn)
+
+	// The service is called through the interceptors.
+	resp, err := client.GetStageMetadata(context.Background(), &pipedservice.GetStageMetadataRequest{Key: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, "value", resp.GetValue())
+	assert.True(t, resp.GetFound())
+	assert.Equal(t, []string{"/grpc.piped.service.PluginService/GetStageMetadata"}, methods)
+}
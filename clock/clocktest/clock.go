@@ -0,0 +1,208 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clocktest provides the fake clock for the tests depending on the time.
+package clocktest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+// FakeClock is the clock.Clock whose time only moves by Advance or Set.
+// The tickers and the timers created by it fire when the time passes their deadlines,
+// so the tests can drive the time-dependent behavior without sleeps, e.g.
+//
+//	c := clocktest.NewFakeClock(time.Now())
+//	go p.Run(ctx) // Flushes the logs every 5 seconds on c.
+//	c.BlockUntil(1)
+//	c.Advance(5 * time.Second)
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFakeClock creates a new FakeClock starting at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+var _ clock.Clock = (*FakeClock)(nil)
+
+// Now returns the current time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed since t on the clock.
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// NewTicker returns a new ticker firing every d on the clock.
+func (c *FakeClock) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("clocktest: non-positive interval for NewTicker")
+	}
+	return fakeTicker{c.addWaiter(d, d)}
+}
+
+// NewTimer returns a new timer firing after d on the clock.
+func (c *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	return fakeTimer{c.addWaiter(d, 0)}
+}
+
+// Advance moves the clock forward by d, and fires the tickers and the timers in the order of their deadlines.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set moves the clock to the given time, and fires the tickers and the timers in the order of their deadlines.
+// The clock never goes back, so the time before the current one is ignored.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		w := c.nextWaiter(t)
+		if w == nil {
+			break
+		}
+		c.now = w.deadline
+		w.fire()
+		if w.interval > 0 {
+			w.deadline = w.deadline.Add(w.interval)
+		} else {
+			c.removeWaiter(w)
+		}
+	}
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+// Waiters returns the number of the active tickers and timers.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until there are at least n active tickers and timers.
+// Use it to wait for the code under test to start waiting before advancing the clock.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) addWaiter(d, interval time.Duration) *fakeWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &fakeWaiter{
+		clock:    c,
+		ch:       make(chan time.Time, 1),
+		deadline: c.now.Add(d),
+		interval: interval,
+	}
+	if d <= 0 {
+		w.fire()
+		return w
+	}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return w
+}
+
+// nextWaiter returns the waiter with the earliest deadline not after t.
+// It must be called while holding the lock.
+func (c *FakeClock) nextWaiter(t time.Time) *fakeWaiter {
+	var next *fakeWaiter
+	for _, w := range c.waiters {
+		if w.deadline.After(t) {
+			continue
+		}
+		if next == nil || w.deadline.Before(next.deadline) {
+			next = w
+		}
+	}
+	return next
+}
+
+// removeWaiter must be called while holding the lock.
+func (c *FakeClock) removeWaiter(w *fakeWaiter) bool {
+	i := slices.Index(c.waiters, w)
+	if i < 0 {
+		return false
+	}
+	c.waiters = slices.Delete(c.waiters, i, i+1)
+	return true
+}
+
+// fakeWaiter is the ticker or the timer of FakeClock.
+type fakeWaiter struct {
+	clock    *FakeClock
+	ch       chan time.Time
+	deadline time.Time
+	// interval is zero for the timers.
+	interval time.Duration
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+// stop returns false if the waiter has already fired or been stopped.
+func (w *fakeWaiter) stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	return w.clock.removeWaiter(w)
+}
+
+// fire sends the deadline without blocking.
+// Like time.Ticker, the ticks are dropped when the receiver is slow.
+func (w *fakeWaiter) fire() {
+	select {
+	case w.ch <- w.deadline:
+	default:
+	}
+}
+
+type fakeTicker struct {
+	*fakeWaiter
+}
+
+func (t fakeTicker) Stop() {
+	t.stop()
+}
+
+type fakeTimer struct {
+	*fakeWaiter
+}
+
+func (t fakeTimer) Stop() bool {
+	return t.stop()
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clocktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock_Timer(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	timer := c.NewTimer(time.Minute)
+	assert.Equal(t, 1, c.Waiters())
+
+	c.Advance(59 * time.Second)
+	assertNotFired(t, timer.C())
+	assert.Equal(t, 59*time.Second, c.Since(start))
+
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Minute), <-timer.C())
+	assert.Equal(t, 0, c.Waiters())
+	assert.False(t, timer.Stop())
+
+	// The stopped timer never fires.
+	timer = c.NewTimer(time.Minute)
+	assert.True(t, timer.Stop())
+	c.Advance(time.Hour)
+	assertNotFired(t, timer.C())
+
+	// The timer of the non-positive duration fires immediately.
+	timer = c.NewTimer(0)
+	assert.Equal(t, c.Now(), <-timer.C())
+}
+
+func TestFakeClock_Ticker(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	ticker := c.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-ticker.C())
+
+	// The ticks are dropped when the receiver is slow.
+	c.Advance(3 * time.Second)
+	assert.Equal(t, start.Add(2*time.Second), <-ticker.C())
+	assertNotFired(t, ticker.C())
+
+	ticker.Stop()
+	c.Advance(time.Second)
+	assertNotFired(t, ticker.C())
+}
+
+func TestFakeClock_Order(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	late := c.NewTimer(2 * time.Second)
+	early := c.NewTimer(time.Second)
+
+	c.Set(start.Add(time.Hour))
+	assert.Equal(t, start.Add(time.Second), <-early.C())
+	assert.Equal(t, start.Add(2*time.Second), <-late.C())
+	assert.Equal(t, start.Add(time.Hour), c.Now())
+
+	// The clock never goes back.
+	c.Set(start)
+	assert.Equal(t, start.Add(time.Hour), c.Now())
+}
+
+func TestFakeClock_BlockUntil(t *testing.T) {
+	t.Parallel()
+
+	c := NewFakeClock(time.Now())
+	done := make(chan struct{})
+	go func() {
+		timer := c.NewTimer(time.Minute)
+		<-timer.C()
+		close(done)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	<-done
+}
+
+func assertNotFired(t *testing.T, ch <-chan time.Time) {
+	t.Helper()
+	select {
+	case v := <-ch:
+		t.Errorf("unexpectedly fired at %v", v)
+	default:
+	}
+}
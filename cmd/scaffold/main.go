@@ -0,0 +1,56 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command scaffold generates the skeleton of a new plugin built on the SDK.
+//
+//	go run github.com/pipe-cd/piped-plugin-sdk-go/cmd/scaffold@latest \
+//	  -module github.com/example/plugin -name example -roles deployment,livestate,plan-preview -out ./plugin
+//
+// It can be used with go:generate as well.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/scaffold"
+)
+
+func main() {
+	var (
+		module     = flag.String("module", "", "The module path of the plugin, e.g. github.com/example/plugin.")
+		name       = flag.String("name", "", "The name of the plugin in the piped config and the application config.")
+		roles      = flag.String("roles", "deployment,livestate,plan-preview", fmt.Sprintf("The comma-separated roles of the plugin, some of %v.", scaffold.Roles))
+		sdkVersion = flag.String("sdk-version", "", "The version of the SDK required in go.mod. The requirement is left to `make tidy` when it's empty.")
+		out        = flag.String("out", ".", "The directory to generate the plugin into.")
+	)
+	flag.Parse()
+
+	rs, err := scaffold.ParseRoles(*roles)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	opts := scaffold.Options{
+		Module:     *module,
+		Name:       *name,
+		Roles:      rs,
+		SDKVersion: *sdkVersion,
+	}
+	if err := scaffold.Generate(*out, opts); err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Fprintf(os.Stdout, "Generated the plugin %s into %s. Run `make tidy test` there to get started.\n", *name, *out)
+}
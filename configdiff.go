@@ -0,0 +1,138 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/diff"
+)
+
+// redactedDiffValue is shown instead of the old and new values of the changed sensitive fields.
+const redactedDiffValue = "<redacted>"
+
+// configDiff returns the changed fields between the two configs in the form of "path: old -> new".
+// The changed sensitive fields are shown as "path: <redacted> -> <redacted>", and the values of
+// the sensitive fields under the changed objects and arrays are masked.
+func configDiff(old, new any) ([]string, error) {
+	x, err := toJSONObject(old)
+	if err != nil {
+		return nil, err
+	}
+	y, err := toJSONObject(new)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := diff.DiffUnstructureds(unstructured.Unstructured{Object: x}, unstructured.Unstructured{Object: y}, "", diff.WithEquateEmpty())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare the configs: %w", err)
+	}
+
+	paths := append(sensitivePaths(old), sensitivePaths(new)...)
+	// Mask the sensitive values in the other fields as well, e.g. the password in a URL.
+	r := &redactor{}
+	r.set(append(sensitiveValues(old), sensitiveValues(new)...))
+
+	lines := make([]string, 0, result.NumNodes())
+	for _, n := range result.Nodes() {
+		path := make([]string, 0, len(n.Path))
+		for _, s := range n.Path {
+			path = append(path, s.String())
+		}
+		if slices.ContainsFunc(paths, func(p []string) bool { return hasPathPrefix(path, p) }) {
+			lines = append(lines, fmt.Sprintf("%s: %s -> %s", n.PathString, redactedDiffValue, redactedDiffValue))
+			continue
+		}
+		// The sensitive fields under the changed node, e.g. the ones in the added object.
+		var under [][]string
+		for _, p := range paths {
+			if len(p) > len(path) && hasPathPrefix(p, path) {
+				under = append(under, p[len(path):])
+			}
+		}
+		lines = append(lines, r.redact(fmt.Sprintf("%s: %s -> %s", n.PathString, renderDiffValue(n.ValueX, under), renderDiffValue(n.ValueY, under))))
+	}
+	return lines, nil
+}
+
+// hasPathPrefix returns true if the path is the prefix or under it.
+func hasPathPrefix(path, prefix []string) bool {
+	return len(path) >= len(prefix) && slices.Equal(path[:len(prefix)], prefix)
+}
+
+// toJSONObject converts the value into the JSON object.
+func toJSONObject(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the config: %w", err)
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the config as an object: %w", err)
+	}
+	return obj, nil
+}
+
+// renderDiffValue renders the value of the diff node in JSON, or "<none>" if the field does not exist.
+// The values at the given sensitive paths relative to the node are masked.
+func renderDiffValue(v reflect.Value, sensitivePaths [][]string) string {
+	if !v.IsValid() || !v.CanInterface() {
+		return "<none>"
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return diff.RenderPrimitiveValue(v)
+	}
+	if len(sensitivePaths) == 0 {
+		return string(data)
+	}
+	var obj any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return diff.RenderPrimitiveValue(v)
+	}
+	for _, p := range sensitivePaths {
+		obj = maskJSONPath(obj, p)
+	}
+	if data, err = json.Marshal(obj); err != nil {
+		return diff.RenderPrimitiveValue(v)
+	}
+	return string(data)
+}
+
+// logDeployTargetChanges logs the changes of the deploy targets with the changed fields of the updated ones.
+// The old deploy targets are used to compute the changed fields.
+func logDeployTargetChanges[DeployTargetConfig any](logger *zap.Logger, old map[string]*DeployTarget[DeployTargetConfig], changes DeployTargetChanges[DeployTargetConfig]) {
+	for _, dt := range changes.Added {
+		logger.Info("deploy target has been added", zap.String("deploy-target", dt.Name))
+	}
+	for _, dt := range changes.Removed {
+		logger.Info("deploy target has been removed", zap.String("deploy-target", dt.Name))
+	}
+	for _, dt := range changes.Updated {
+		lines, err := configDiff(old[dt.Name], dt)
+		if err != nil {
+			logger.Warn("deploy target has been updated, but failed to compute the diff", zap.String("deploy-target", dt.Name), zap.Error(err))
+			continue
+		}
+		logger.Info("deploy target has been updated", zap.String("deploy-target", dt.Name), zap.Strings("diff", lines))
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type diffedAuth struct {
+	User string `json:"user"`
+	Key  string `json:"key" sensitive:"true"`
+}
+
+type diffedConfig struct {
+	Region   string            `json:"region"`
+	Replicas int               `json:"replicas,omitempty"`
+	Password string            `json:"password,omitempty" sensitive:"true"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Auth     *diffedAuth       `json:"auth,omitempty"`
+}
+
+func TestConfigDiff(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		old      diffedConfig
+		new      diffedConfig
+		expected []string
+	}{
+		{
+			name:     "no change",
+			old:      diffedConfig{Region: "us"},
+			new:      diffedConfig{Region: "us"},
+			expected: []string{},
+		},
+		{
+			name: "changed, added, and removed fields",
+			old:  diffedConfig{Region: "us", Replicas: 2, Tags: map[string]string{"a": "1"}},
+			new:  diffedConfig{Region: "eu", Tags: map[string]string{"a": "1", "b": "2"}},
+			expected: []string{
+				`region: "us" -> "eu"`,
+				`replicas: 2 -> <none>`,
+				`tags.b: <none> -> "2"`,
+			},
+		},
+		{
+			name: "sensitive field",
+			old:  diffedConfig{Region: "us", Password: "old-password"},
+			new:  diffedConfig{Region: "us", Password: "new-password"},
+			expected: []string{
+				`password: <redacted> -> <redacted>`,
+			},
+		},
+		{
+			name: "short sensitive field",
+			old:  diffedConfig{Region: "us", Password: "abc"},
+			new:  diffedConfig{Region: "us", Password: "xyz"},
+			expected: []string{
+				`password: <redacted> -> <redacted>`,
+			},
+		},
+		{
+			name: "sensitive field in the added object",
+			old:  diffedConfig{Region: "us"},
+			new:  diffedConfig{Region: "us", Auth: &diffedAuth{User: "admin", Key: "k"}},
+			expected: []string{
+				`auth: <none> -> {"key":"******","user":"admin"}`,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := configDiff(tc.old, tc.new)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestLogDeployTargetChanges(t *testing.T) {
+	t.Parallel()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	old := map[string]*DeployTarget[diffedConfig]{
+		"updated": {Name: "updated", Labels: map[string]string{"env": "dev"}, Config: diffedConfig{Region: "us"}},
+		"removed": {Name: "removed"},
+	}
+	logDeployTargetChanges(zap.New(core), old, DeployTargetChanges[diffedConfig]{
+		Added:   []*DeployTarget[diffedConfig]{{Name: "added"}},
+		Updated: []*DeployTarget[diffedConfig]{{Name: "updated", Labels: map[string]string{"env": "prod"}, Config: diffedConfig{Region: "eu"}}},
+		Removed: []*DeployTarget[diffedConfig]{old["removed"]},
+	})
+
+	entries := logs.All()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "deploy target has been added", entries[0].Message)
+	assert.Equal(t, "deploy target has been removed", entries[1].Message)
+	assert.Equal(t, "deploy target has been updated", entries[2].Message)
+	assert.Equal(t, []any{`config.region: "us" -> "eu"`, `labels.env: "dev" -> "prod"`}, entries[2].ContextMap()["diff"])
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/connectivity"
+)
+
+// ConnectionState represents the state of the connection from the plugin to piped.
+type ConnectionState string
+
+const (
+	// ConnectionStateIdle means the connection is idle and will be established on the next call.
+	ConnectionStateIdle ConnectionState = "IDLE"
+	// ConnectionStateConnecting means the connection is being established.
+	ConnectionStateConnecting ConnectionState = "CONNECTING"
+	// ConnectionStateReady means the connection is ready to be used.
+	ConnectionStateReady ConnectionState = "READY"
+	// ConnectionStateTransientFailure means the connection has failed and will be re-established.
+	ConnectionStateTransientFailure ConnectionState = "TRANSIENT_FAILURE"
+	// ConnectionStateShutdown means the connection has been closed.
+	ConnectionStateShutdown ConnectionState = "SHUTDOWN"
+)
+
+// ConnectionStateObserver is an optional interface for the plugins to be notified
+// when the state of the connection to piped changes.
+// It's useful to pause the work which requires piped while the plugin is isolated from it.
+type ConnectionStateObserver interface {
+	// OnConnectionStateChange is called with the new state every time the connection state changes.
+	OnConnectionStateChange(state ConnectionState)
+}
+
+// Healthy returns true if the plugin can reach piped, that is, the connection is ready or idle.
+func (s ConnectionState) Healthy() bool {
+	return s == ConnectionStateReady || s == ConnectionStateIdle
+}
+
+// ConnectionState returns the current state of the connection to piped.
+func (c *pluginServiceClient) ConnectionState() ConnectionState {
+	return ConnectionState(c.conn.GetState().String())
+}
+
+// watchConnection watches the state of the connection to piped until the context is done.
+// It re-dials piped when the connection becomes idle or fails,
+// so that the connection is re-established without waiting for the next call.
+// The given observers are notified every time the state changes.
+func (c *pluginServiceClient) watchConnection(ctx context.Context, logger *zap.Logger, observers ...ConnectionStateObserver) error {
+	state := c.conn.GetState()
+	for {
+		switch state {
+		case connectivity.Idle, connectivity.TransientFailure:
+			c.conn.Connect()
+		case connectivity.Shutdown:
+			return nil
+		}
+
+		if !c.conn.WaitForStateChange(ctx, state) {
+			// The context is done.
+			return nil
+		}
+
+		prev := state
+		state = c.conn.GetState()
+		logger.Info("the connection state to piped has changed",
+			zap.String("from", prev.String()),
+			zap.String("to", state.String()),
+		)
+		for _, o := range observers {
+			o.OnConnectionStateChange(ConnectionState(state.String()))
+		}
+	}
+}
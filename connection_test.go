@@ -0,0 +1,85 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+)
+
+type recordingObserver struct {
+	mu     sync.Mutex
+	states []ConnectionState
+}
+
+func (o *recordingObserver) OnConnectionStateChange(state ConnectionState) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.states = append(o.states, state)
+}
+
+func (o *recordingObserver) observed(state ConnectionState) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, s := range o.states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPluginServiceClient_WatchConnection(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	go server.Serve(lis)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := newPluginServiceClient(ctx, lis.Addr().String(), nil)
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, ConnectionStateReady, client.ConnectionState())
+
+	observer := &recordingObserver{}
+	done := make(chan error)
+	go func() {
+		done <- client.watchConnection(ctx, zaptest.NewLogger(t), observer)
+	}()
+
+	// The connection should become unhealthy after piped is stopped.
+	server.Stop()
+	assert.Eventually(t, func() bool {
+		return !client.ConnectionState().Healthy()
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return observer.observed(ConnectionStateTransientFailure) || observer.observed(ConnectionStateConnecting)
+	}, 10*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// CorrelationIDHeader is the gRPC metadata key of the ID correlating the logs of piped and the plugin for one request.
+// The plugin propagates the ID given by piped to its logger, the stage logs, and the calls back to piped.
+const CorrelationIDHeader = "x-pipecd-correlation-id"
+
+// correlationIDKey is the key of the correlation ID in the log fields.
+const correlationIDKey = "correlation-id"
+
+// CorrelationID returns the correlation ID of the request from piped handled with the given context.
+// It's empty when piped didn't set one.
+func CorrelationID(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, CorrelationIDHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// withCorrelationID returns the logger with the correlation ID of the request as a field.
+// It returns the given logger as is when the request has no correlation ID.
+func withCorrelationID(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	id := CorrelationID(ctx)
+	if id == "" || logger == nil {
+		return logger
+	}
+	return logger.With(zap.String(correlationIDKey, id))
+}
+
+// correlationUnaryClientInterceptor sends the correlation ID of the request from piped being handled
+// with the calls made to piped for it, so that piped can join them to the request.
+// The calls already carrying a correlation ID are not changed.
+func correlationUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if id := CorrelationID(ctx); id != "" {
+			if md, ok := metadata.FromOutgoingContext(ctx); !ok || len(md.Get(CorrelationIDHeader)) == 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, CorrelationIDHeader, id)
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/common"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+)
+
+func TestCorrelationID(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", CorrelationID(context.Background()))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(CorrelationIDHeader, "correlation-1"))
+	assert.Equal(t, "correlation-1", CorrelationID(ctx))
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	withCorrelationID(ctx, zap.New(core)).Info("message")
+	withCorrelationID(context.Background(), zap.New(core)).Info("message")
+	require.Equal(t, 2, logs.Len())
+	assert.Equal(t, map[string]any{correlationIDKey: "correlation-1"}, logs.All()[0].ContextMap())
+	assert.Empty(t, logs.All()[1].ContextMap())
+}
+
+func TestCorrelationUnaryClientInterceptor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []string
+	}{
+		{
+			name: "no correlation ID",
+			ctx:  context.Background(),
+		},
+		{
+			name: "propagated from the incoming request",
+			ctx:  metadata.NewIncomingContext(context.Background(), metadata.Pairs(CorrelationIDHeader, "correlation-1")),
+			want: []string{"correlation-1"},
+		},
+		{
+			name: "given by the caller",
+			ctx: metadata.AppendToOutgoingContext(
+				metadata.NewIncomingContext(context.Background(), metadata.Pairs(CorrelationIDHeader, "correlation-1")),
+				CorrelationIDHeader, "correlation-2",
+			),
+			want: []string{"correlation-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got []string
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				got = md.Get(CorrelationIDHeader)
+				return nil
+			}
+			require.NoError(t, correlationUnaryClientInterceptor()(tt.ctx, "/method", nil, nil, nil, invoker))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCorrelationID_StageLogs(t *testing.T) {
+	t.Parallel()
+
+	config := strings.TrimSpace(`
+apiVersion: pipecd.dev/v1beta1
+kind: Appilcation
+spec: {}
+`)
+	source := &common.DeploymentSource{
+		ApplicationDirectory:      "app-dir",
+		ApplicationConfig:         []byte(config),
+		ApplicationConfigFilename: "app-config-filename",
+	}
+	request := &deployment.ExecuteStageRequest{
+		Input: &deployment.ExecutePluginInput{
+			Stage:                  &model.PipelineStage{Name: "stage1"},
+			Deployment:             &model.Deployment{Trigger: &model.DeploymentTrigger{Commit: &model.Commit{}}},
+			TargetDeploymentSource: source,
+		},
+	}
+
+	lp := logpersistertest.NewRecordingLogPersister(t)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(CorrelationIDHeader, "correlation-1"))
+	_, err := executeStage[struct{}, struct{}, struct{}](ctx, "test", &mockStagePlugin{result: StageStatusSuccess}, nil, nil, &Client{stageLogPersister: lp}, request, zap.NewNop())
+	require.NoError(t, err)
+	assert.True(t, lp.ContainsLine("Correlation ID: correlation-1"), lp.Lines())
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maskedCredential is shown instead of the value of the credential.
+const maskedCredential = "******"
+
+// Credential is a credential used in the plugin config or the deploy target config, such as an API token.
+// In the config, it's given as the value itself or as a reference to where the value is stored:
+//
+//	token: "plain-value"
+//	token:
+//	  file: /etc/plugin/token
+//	token:
+//	  env: PLUGIN_TOKEN
+//
+// The reference is resolved when the config is decoded, so the value is available before Initialize.
+// The value is never shown when the credential is printed or marshaled.
+// It's also masked in the logs, the stage logs and the error messages when it's at least 4 bytes long.
+type Credential struct {
+	value string
+	// source describes where the value comes from, e.g. "file:/etc/plugin/token".
+	source string
+}
+
+// credentialRef is the reference form of the Credential in the config.
+type credentialRef struct {
+	Value string `json:"value,omitempty"`
+	File  string `json:"file,omitempty"`
+	Env   string `json:"env,omitempty"`
+}
+
+// Value returns the resolved value of the credential.
+func (c Credential) Value() string {
+	return c.value
+}
+
+// Source returns where the value of the credential comes from, e.g. "file:/etc/plugin/token", "env:PLUGIN_TOKEN", or "inline".
+func (c Credential) Source() string {
+	return c.source
+}
+
+// String returns the masked value, so that the credential is not leaked to the logs.
+func (c Credential) String() string {
+	if c.value == "" {
+		return ""
+	}
+	return maskedCredential
+}
+
+// MarshalJSON marshals the credential as the masked value.
+func (c Credential) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// JSONSchema returns the schema accepting the value itself or the reference to it.
+func (Credential) JSONSchema() map[string]any {
+	return map[string]any{
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"value": map[string]any{"type": "string"},
+					"file":  map[string]any{"type": "string"},
+					"env":   map[string]any{"type": "string"},
+				},
+				"minProperties":        1,
+				"maxProperties":        1,
+				"additionalProperties": false,
+			},
+		},
+	}
+}
+
+// UnmarshalJSON unmarshals the credential and resolves its reference.
+func (c *Credential) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*c = Credential{value: s, source: "inline"}
+		return nil
+	}
+
+	var ref credentialRef
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return fmt.Errorf("invalid credential: it must be a string or an object with one of value, file, env: %w", err)
+	}
+	resolved, err := ref.resolve()
+	if err != nil {
+		return err
+	}
+	*c = resolved
+	return nil
+}
+
+// resolve resolves the reference into the credential.
+func (r credentialRef) resolve() (Credential, error) {
+	var n int
+	for _, v := range []string{r.Value, r.File, r.Env} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return Credential{}, errors.New("invalid credential: exactly one of value, file, env must be set")
+	}
+
+	switch {
+	case r.File != "":
+		data, err := os.ReadFile(r.File)
+		if err != nil {
+			return Credential{}, fmt.Errorf("failed to read the credential file %s: %w", r.File, err)
+		}
+		// Files usually end with a newline which is not a part of the credential.
+		return Credential{value: strings.TrimRight(string(data), "\r\n"), source: "file:" + r.File}, nil
+	case r.Env != "":
+		v, ok := os.LookupEnv(r.Env)
+		if !ok {
+			return Credential{}, fmt.Errorf("the environment variable %s for the credential is not set", r.Env)
+		}
+		return Credential{value: v, source: "env:" + r.Env}, nil
+	default:
+		return Credential{value: r.Value, source: "inline"}, nil
+	}
+}
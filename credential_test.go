@@ -0,0 +1,113 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredential_UnmarshalJSON(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(file, []byte("file-token\n"), 0o600))
+	t.Setenv("TEST_CREDENTIAL_TOKEN", "env-token")
+
+	tests := []struct {
+		name           string
+		input          string
+		expectedValue  string
+		expectedSource string
+		expectErr      bool
+	}{
+		{
+			name:           "plain string",
+			input:          `"plain-token"`,
+			expectedValue:  "plain-token",
+			expectedSource: "inline",
+		},
+		{
+			name:           "inline value",
+			input:          `{"value":"inline-token"}`,
+			expectedValue:  "inline-token",
+			expectedSource: "inline",
+		},
+		{
+			name:           "file reference",
+			input:          fmt.Sprintf(`{"file":%q}`, file),
+			expectedValue:  "file-token",
+			expectedSource: "file:" + file,
+		},
+		{
+			name:           "env reference",
+			input:          `{"env":"TEST_CREDENTIAL_TOKEN"}`,
+			expectedValue:  "env-token",
+			expectedSource: "env:TEST_CREDENTIAL_TOKEN",
+		},
+		{
+			name:      "missing env",
+			input:     `{"env":"TEST_CREDENTIAL_MISSING"}`,
+			expectErr: true,
+		},
+		{
+			name:      "missing file",
+			input:     `{"file":"/not/exist"}`,
+			expectErr: true,
+		},
+		{
+			name:      "multiple sources",
+			input:     `{"value":"a","env":"TEST_CREDENTIAL_TOKEN"}`,
+			expectErr: true,
+		},
+		{
+			name:      "invalid type",
+			input:     `1`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Credential
+			err := json.Unmarshal([]byte(tt.input), &c)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedValue, c.Value())
+			assert.Equal(t, tt.expectedSource, c.Source())
+		})
+	}
+}
+
+func TestCredential_Masked(t *testing.T) {
+	t.Parallel()
+
+	cfg := struct {
+		Token Credential `json:"token"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(`{"token":"secret"}`), &cfg))
+
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"token":"******"}`, string(data))
+	assert.NotContains(t, fmt.Sprintf("%v %+v", cfg, cfg), "secret")
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+)
+
+const (
+	deadlineSourceKey = "deadline_source"
+
+	// deadlineSourcePiped indicates the deadline was set by piped.
+	deadlineSourcePiped = "piped"
+	// deadlineSourceDefault indicates the deadline was derived by the plugin since piped didn't set one.
+	deadlineSourceDefault = "default"
+)
+
+var (
+	rpcDeadlineUsage = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_rpc_deadline_usage_ratio",
+			Help:    "Ratio of the duration of the RPCs from piped to the time they had until the deadline.",
+			Buckets: []float64{0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 1},
+		},
+		[]string{methodKey, deadlineSourceKey},
+	)
+	rpcNearDeadlineTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_rpc_near_deadline_total",
+			Help: "Total number of the RPCs from piped completed after using up most of the time until the deadline.",
+		},
+		[]string{methodKey, deadlineSourceKey},
+	)
+)
+
+// registerDeadlineMetrics registers the metrics of the deadlines to the given registerer.
+func registerDeadlineMetrics(r prometheus.Registerer) {
+	r.MustRegister(
+		rpcDeadlineUsage,
+		rpcNearDeadlineTotal,
+	)
+}
+
+// defaultMethodDeadlines are the deadlines of the methods which take longer than the others.
+var defaultMethodDeadlines = map[string]time.Duration{
+	// A stage may wait for the resources to be ready, or for the approval as long as it takes.
+	"ExecuteStage": 0,
+}
+
+// deadlinePolicy derives the deadlines of the RPCs from piped not setting one,
+// so that the contexts handed to the plugin have a deadline unless it's disabled for the method.
+type deadlinePolicy struct {
+	// deadline is the deadline of the methods not in methodDeadlines. Zero means no deadline is derived.
+	deadline time.Duration
+	// methodDeadlines are the deadlines keyed by the method name without the service name, e.g. "ExecuteStage".
+	// Zero means no deadline is derived for the method.
+	methodDeadlines map[string]time.Duration
+	// nearRatio is the ratio of the time until the deadline to count the RPCs as completed near the deadline.
+	nearRatio float64
+}
+
+// newDeadlinePolicy returns the policy with the given deadlines.
+// The method deadlines are given as the map from the method name to the duration string, e.g. "GetLivestate": "1m".
+// A zero deadline means no deadline is derived.
+func newDeadlinePolicy(deadline time.Duration, methodDeadlines map[string]string, nearRatio float64) (*deadlinePolicy, error) {
+	if deadline < 0 {
+		return nil, fmt.Errorf("the deadline must not be negative: %s", deadline)
+	}
+	parsed, err := parseMethodDurations(defaultMethodDeadlines, methodDeadlines, "deadline")
+	if err != nil {
+		return nil, err
+	}
+	for method, d := range parsed {
+		if d < 0 {
+			return nil, fmt.Errorf("the deadline of the method %s must not be negative: %s", method, d)
+		}
+	}
+	if nearRatio <= 0 || nearRatio > 1 {
+		return nil, fmt.Errorf("the near deadline ratio must be in the range of (0, 1]: %v", nearRatio)
+	}
+	return &deadlinePolicy{
+		deadline:        deadline,
+		methodDeadlines: parsed,
+		nearRatio:       nearRatio,
+	}, nil
+}
+
+// deadlineOf returns the deadline of the method, or zero when no deadline is derived for it.
+func (p *deadlinePolicy) deadlineOf(method string) time.Duration {
+	if d, ok := p.methodDeadlines[method]; ok {
+		return d
+	}
+	return p.deadline
+}
+
+// observe records how much of the time until the deadline the RPC used.
+// The RPCs with no time left at the start are counted as using all of it.
+func (p *deadlinePolicy) observe(method, source string, elapsed, available time.Duration) {
+	ratio := 1.0
+	if available > 0 {
+		ratio = float64(elapsed) / float64(available)
+	}
+	labels := prometheus.Labels{methodKey: method, deadlineSourceKey: source}
+	rpcDeadlineUsage.With(labels).Observe(ratio)
+	if ratio >= p.nearRatio {
+		rpcNearDeadlineTotal.With(labels).Inc()
+	}
+}
+
+// deadlineOf returns the deadline of the context, or zero when it has no deadline.
+func deadlineOf(ctx context.Context) time.Time {
+	d, _ := ctx.Deadline()
+	return d
+}
+
+// deadlineRegistrar registers the services setting the default deadline to the RPCs from piped without one,
+// and recording how close to the deadline they complete.
+// The deadlines are measured on the real time since the contexts are canceled on it.
+type deadlineRegistrar struct {
+	grpc.ServiceRegistrar
+	policy *deadlinePolicy
+}
+
+func (r deadlineRegistrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	wrapped := *desc
+	wrapped.Methods = make([]grpc.MethodDesc, 0, len(desc.Methods))
+	for _, m := range desc.Methods {
+		handler := m.Handler
+		name := m.MethodName
+		timeout := r.policy.deadlineOf(name)
+		m.Handler = func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			source := deadlineSourcePiped
+			if _, ok := ctx.Deadline(); !ok {
+				if timeout <= 0 {
+					// There is no deadline to observe.
+					return handler(srv, ctx, dec, interceptor)
+				}
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+				source = deadlineSourceDefault
+			}
+			start := time.Now()
+			deadline, _ := ctx.Deadline()
+
+			resp, err := handler(srv, ctx, dec, interceptor)
+			r.policy.observe(name, source, time.Since(start), deadline.Sub(start))
+			return resp, err
+		}
+		wrapped.Methods = append(wrapped.Methods, m)
+	}
+	r.ServiceRegistrar.RegisterService(&wrapped, impl)
+}
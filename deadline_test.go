@@ -0,0 +1,165 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+)
+
+func TestNewDeadlinePolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		deadline        time.Duration
+		methodDeadlines map[string]string
+		nearRatio       float64
+		wantErr         bool
+	}{
+		{
+			name:            "valid",
+			deadline:        time.Minute,
+			methodDeadlines: map[string]string{"GetLivestate": "30s"},
+			nearRatio:       0.9,
+		},
+		{
+			name:      "negative deadline",
+			deadline:  -time.Minute,
+			nearRatio: 0.9,
+			wantErr:   true,
+		},
+		{
+			name:            "negative method deadline",
+			deadline:        time.Minute,
+			methodDeadlines: map[string]string{"GetLivestate": "-1s"},
+			nearRatio:       0.9,
+			wantErr:         true,
+		},
+		{
+			name:            "invalid method deadline",
+			deadline:        time.Minute,
+			methodDeadlines: map[string]string{"GetLivestate": "invalid"},
+			nearRatio:       0.9,
+			wantErr:         true,
+		},
+		{
+			name:      "ratio out of range",
+			deadline:  time.Minute,
+			nearRatio: 1.5,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p, err := newDeadlinePolicy(tt.deadline, tt.methodDeadlines, tt.nearRatio)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, time.Minute, p.deadlineOf("DetermineVersions"))
+			assert.Equal(t, 30*time.Second, p.deadlineOf("GetLivestate"))
+			assert.Equal(t, time.Duration(0), p.deadlineOf("ExecuteStage"))
+		})
+	}
+}
+
+func TestDeadlineRegistrar(t *testing.T) {
+	t.Parallel()
+
+	p, err := newDeadlinePolicy(time.Hour, map[string]string{"TestDeadlineRegistrarNear": "1ms", "TestDeadlineRegistrarNone": "0s"}, 0.9)
+	require.NoError(t, err)
+
+	var got time.Time
+	handler := func(_ any, ctx context.Context, _ func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+		got = deadlineOf(ctx)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < time.Second {
+			// Complete near the short deadline.
+			<-ctx.Done()
+		}
+		return nil, nil
+	}
+	desc := &grpc.ServiceDesc{
+		ServiceName: "test.Service",
+		Methods: []grpc.MethodDesc{
+			{MethodName: "TestDeadlineRegistrar", Handler: handler},
+			{MethodName: "TestDeadlineRegistrarNear", Handler: handler},
+			{MethodName: "TestDeadlineRegistrarNone", Handler: handler},
+		},
+	}
+	fake := &fakeServiceRegistrar{}
+	deadlineRegistrar{ServiceRegistrar: fake, policy: p}.RegisterService(desc, nil)
+	require.Len(t, fake.desc.Methods, 3)
+
+	near := testutil.ToFloat64(rpcNearDeadlineTotal.WithLabelValues("TestDeadlineRegistrarNear", deadlineSourceDefault))
+
+	// The default deadline is set when piped doesn't set one.
+	start := time.Now()
+	_, err = fake.desc.Methods[0].Handler(nil, context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.WithinRange(t, got, start.Add(time.Hour), time.Now().Add(time.Hour))
+
+	// The deadline set by piped is kept.
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	_, err = fake.desc.Methods[0].Handler(nil, ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, deadline, got)
+
+	// The RPCs completed near the deadline are counted.
+	_, err = fake.desc.Methods[1].Handler(nil, context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, near+1, testutil.ToFloat64(rpcNearDeadlineTotal.WithLabelValues("TestDeadlineRegistrarNear", deadlineSourceDefault)))
+
+	// No deadline is set when it's disabled for the method.
+	_, err = fake.desc.Methods[2].Handler(nil, context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+}
+
+func TestDeadlineOf_Input(t *testing.T) {
+	t.Parallel()
+
+	plugin := &mockStagePlugin{}
+	server := newTestStagePluginServiceServer(t, plugin)
+	request := &deployment.BuildPipelineSyncStagesRequest{
+		Stages: []*deployment.BuildPipelineSyncStagesRequest_StageConfig{
+			{Index: 0, Name: "stage1"},
+			{Index: 1, Name: "stage2"},
+		},
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	_, err := server.BuildPipelineSyncStages(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, deadline, plugin.deadline)
+
+	_, err = server.BuildPipelineSyncStages(context.Background(), request)
+	require.NoError(t, err)
+	assert.True(t, plugin.deadline.IsZero())
+}
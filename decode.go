@@ -0,0 +1,216 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// StrictDecoder is an optional interface for the Config, the DeployTargetConfig, the ApplicationConfigSpec,
+// and the stage configs decoded by DecodeStageConfig to reject the unknown fields.
+// When StrictDecoding returns true, the unknown fields are reported with their paths and the suggestions,
+// e.g. `unknown field "relpicas" at deployTargets[dt1].config.relpicas, did you mean "replicas"?`,
+// instead of being silently dropped.
+type StrictDecoder interface {
+	StrictDecoding() bool
+}
+
+// DecodeStageConfig decodes the config of the stage given as ExecuteStageRequest.StageConfig.
+// It's decoded in the same way as the other configs: the defaults are applied, the unknown fields are rejected
+// if T implements StrictDecoder, and it's validated if T implements the Validate method.
+func DecodeStageConfig[T any](data []byte) (*T, error) {
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	var c T
+	if err := decodeConfig(data, &c, "with"); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the stage config: %w", err)
+	}
+	if err := setDefaults(&c); err != nil {
+		return nil, fmt.Errorf("failed to set defaults of the stage config: %w", err)
+	}
+	if err := validate(&c); err != nil {
+		return nil, fmt.Errorf("invalid stage config: %w", err)
+	}
+	return &c, nil
+}
+
+// decodeConfig unmarshals the data into v.
+// If v implements StrictDecoder and enables it, the unknown fields are reported as errors with the path under the given root.
+func decodeConfig[T any](data []byte, v *T, root string) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if d, ok := any(v).(StrictDecoder); !ok || !d.StrictDecoding() {
+		return nil
+	}
+
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	return unknownFields(raw, reflect.TypeFor[T](), root)
+}
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// unknownFields returns the errors for the keys in raw which are not decoded into the type t.
+func unknownFields(raw any, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// The types decoding themselves may accept any keys.
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+
+	var errs []error
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		for _, name := range slices.Sorted(maps.Keys(obj)) {
+			field, ok := lookupJSONField(fields, name)
+			if !ok {
+				errs = append(errs, unknownFieldError(name, path+"."+name, fields))
+				continue
+			}
+			if err := unknownFields(obj[name], field.Type, path+"."+name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		arr, ok := raw.([]any)
+		if !ok {
+			return nil
+		}
+		for i, v := range arr {
+			if err := unknownFields(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	case reflect.Map:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for _, k := range slices.Sorted(maps.Keys(obj)) {
+			if err := unknownFields(obj[k], t.Elem(), fmt.Sprintf("%s[%s]", path, k)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// jsonField is a field of the struct with the name used in JSON.
+type jsonField struct {
+	Name string
+	Type reflect.Type
+}
+
+// jsonFields returns the fields of the struct decoded by encoding/json, including the ones of the embedded structs.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, jsonFields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{Name: name, Type: f.Type})
+	}
+	return fields
+}
+
+// lookupJSONField finds the field in the same way as encoding/json, preferring an exact match over a case-insensitive one.
+func lookupJSONField(fields []jsonField, name string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// unknownFieldError returns the error for the unknown field with the most similar known field as the suggestion.
+func unknownFieldError(name, path string, fields []jsonField) error {
+	// Allow roughly one typo per three characters.
+	var (
+		suggestion string
+		best       = len(name)/3 + 2
+	)
+	for _, f := range fields {
+		if d := levenshtein(strings.ToLower(name), strings.ToLower(f.Name)); d < best {
+			suggestion, best = f.Name, d
+		}
+	}
+	if suggestion == "" {
+		return fmt.Errorf("unknown field %q at %s", name, path)
+	}
+	return fmt.Errorf("unknown field %q at %s, did you mean %q?", name, path, suggestion)
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/unit"
+)
+
+type strictEmbedded struct {
+	Namespace string `json:"namespace"`
+}
+
+type strictItem struct {
+	Name string `json:"name"`
+}
+
+type strictConfig struct {
+	strictEmbedded
+	Replicas int                   `json:"replicas"`
+	Timeout  unit.Duration         `json:"timeout"`
+	Items    []strictItem          `json:"items"`
+	ByName   map[string]strictItem `json:"byName"`
+	Ignored  string                `json:"-"`
+}
+
+func (strictConfig) StrictDecoding() bool { return true }
+
+type lenientConfig struct {
+	Replicas int `json:"replicas"`
+}
+
+func TestDecodeConfig(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name        string
+		input       string
+		expectedErr string
+	}{
+		{
+			name:  "known fields",
+			input: `{"namespace":"ns","replicas":1,"timeout":"1m","items":[{"name":"a"}],"byName":{"a":{"name":"a"}}}`,
+		},
+		{
+			name:  "case-insensitive match",
+			input: `{"Replicas":1}`,
+		},
+		{
+			name:        "typo with suggestion",
+			input:       `{"relpicas":1}`,
+			expectedErr: `unknown field "relpicas" at config.relpicas, did you mean "replicas"?`,
+		},
+		{
+			name:        "unknown field without suggestion",
+			input:       `{"foo":1}`,
+			expectedErr: `unknown field "foo" at config.foo`,
+		},
+		{
+			name:        "nested in slice",
+			input:       `{"items":[{"name":"a"},{"nmae":"b"}]}`,
+			expectedErr: `unknown field "nmae" at config.items[1].nmae, did you mean "name"?`,
+		},
+		{
+			name:        "nested in map",
+			input:       `{"byName":{"a":{"names":"a"}}}`,
+			expectedErr: `unknown field "names" at config.byName[a].names, did you mean "name"?`,
+		},
+		{
+			name:        "ignored field",
+			input:       `{"Ignored":"x"}`,
+			expectedErr: `unknown field "Ignored" at config.Ignored`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var c strictConfig
+			err := decodeConfig([]byte(tc.input), &c, "config")
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestDecodeConfig_Lenient(t *testing.T) {
+	t.Parallel()
+
+	var c lenientConfig
+	require.NoError(t, decodeConfig([]byte(`{"replicas":1,"unknown":true}`), &c, "config"))
+	assert.Equal(t, 1, c.Replicas)
+}
+
+func TestDecodeStageConfig(t *testing.T) {
+	t.Parallel()
+
+	c, err := DecodeStageConfig[strictConfig]([]byte(`{"replicas":2}`))
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Replicas)
+
+	_, err = DecodeStageConfig[strictConfig]([]byte(`{"replica":2}`))
+	assert.ErrorContains(t, err, `unknown field "replica" at with.replica, did you mean "replicas"?`)
+
+	c, err = DecodeStageConfig[strictConfig](nil)
+	require.NoError(t, err)
+	assert.Zero(t, c.Replicas)
+}
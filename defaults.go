@@ -0,0 +1,113 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// defaultTag is the struct tag to declare the default value of the field.
+// The value is decoded as JSON, or as a JSON string when it's not valid JSON for the field, e.g.
+//
+//	Timeout  unit.Duration `json:"timeout" default:"5m"`
+//	Replicas int           `json:"replicas" default:"3"`
+//	Prune    *bool         `json:"prune" default:"true"`
+const defaultTag = "default"
+
+// Defaulter is an optional interface for the Config, the DeployTargetConfig, the ApplicationConfigSpec,
+// and the stage configs decoded by DecodeStageConfig to set the default values which can't be declared
+// with the `default` struct tag, e.g. the ones derived from the other fields.
+// Default is called after the config is decoded and the `default` struct tags are applied, and before it's validated.
+// The Config and the DeployTargetConfig are defaulted before Initialize is called, and the DeployTargetConfig
+// is defaulted again after the override by DeployTargetOverrider is merged.
+type Defaulter interface {
+	Default()
+}
+
+// setDefaults applies the `default` struct tags to the zero fields of the given value,
+// and then calls the Default method if it's implemented.
+//
+// Since a field having the zero value is regarded as not set, use a pointer field
+// when the zero value should be distinguishable from the default value, e.g. *bool with `default:"true"`.
+func setDefaults[T any](v *T) error {
+	if err := applyDefaultTags(reflect.ValueOf(v).Elem()); err != nil {
+		return err
+	}
+	if d, ok := any(v).(Defaulter); ok {
+		d.Default()
+	}
+	return nil
+}
+
+// applyDefaultTags applies the `default` struct tags recursively to the fields of the given value,
+// including the ones of the structs in the slices, the arrays and the maps.
+func applyDefaultTags(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field, fv := t.Field(i), v.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if tag, ok := field.Tag.Lookup(defaultTag); ok && fv.IsZero() {
+				if err := decodeDefault(tag, fv); err != nil {
+					return fmt.Errorf("invalid default value %q of the field %s: %w", tag, field.Name, err)
+				}
+				continue
+			}
+			if err := applyDefaultTags(fv); err != nil {
+				return err
+			}
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return applyDefaultTags(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := applyDefaultTags(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// The map values aren't addressable, so they are defaulted in a copy and set back.
+		for iter := v.MapRange(); iter.Next(); {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			if err := applyDefaultTags(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+	return nil
+}
+
+// decodeDefault decodes the default value into the given field.
+func decodeDefault(value string, field reflect.Value) error {
+	ptr := reflect.New(field.Type())
+	if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+		// Allow to omit the quotes for the string values, e.g. `default:"5m"`.
+		if err := json.Unmarshal([]byte(strconv.Quote(value)), ptr.Interface()); err != nil {
+			return err
+		}
+	}
+	field.Set(ptr.Elem())
+	return nil
+}
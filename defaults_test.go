@@ -0,0 +1,151 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/pipe-cd/pipecd/pkg/configv1"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/unit"
+)
+
+type defaultedNested struct {
+	Namespace string `json:"namespace" default:"default"`
+}
+
+type defaultedConfig struct {
+	Timeout  unit.Duration    `json:"timeout" default:"5m"`
+	Replicas int              `json:"replicas" default:"3"`
+	Prune    *bool            `json:"prune" default:"true"`
+	Tags     []string         `json:"tags" default:"[\"a\",\"b\"]"`
+	Nested   defaultedNested  `json:"nested"`
+	Optional *defaultedNested `json:"optional"`
+	Region   string           `json:"region"`
+}
+
+func (c *defaultedConfig) Default() {
+	if c.Region == "" {
+		c.Region = "us-" + c.Nested.Namespace
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	t.Parallel()
+
+	t.Run("zero fields are defaulted", func(t *testing.T) {
+		t.Parallel()
+
+		var c defaultedConfig
+		require.NoError(t, setDefaults(&c))
+		assert.Equal(t, unit.Duration(5*time.Minute), c.Timeout)
+		assert.Equal(t, 3, c.Replicas)
+		if assert.NotNil(t, c.Prune) {
+			assert.True(t, *c.Prune)
+		}
+		assert.Equal(t, []string{"a", "b"}, c.Tags)
+		assert.Equal(t, "default", c.Nested.Namespace)
+		assert.Nil(t, c.Optional)
+		// Default is called after the tags are applied.
+		assert.Equal(t, "us-default", c.Region)
+	})
+
+	t.Run("set fields are kept", func(t *testing.T) {
+		t.Parallel()
+
+		prune := false
+		c := defaultedConfig{
+			Replicas: 1,
+			Prune:    &prune,
+			Optional: &defaultedNested{},
+			Region:   "eu",
+		}
+		require.NoError(t, setDefaults(&c))
+		assert.Equal(t, 1, c.Replicas)
+		assert.False(t, *c.Prune)
+		assert.Equal(t, "default", c.Optional.Namespace)
+		assert.Equal(t, "eu", c.Region)
+	})
+
+	t.Run("elements of slices and maps are defaulted", func(t *testing.T) {
+		t.Parallel()
+
+		var c struct {
+			Clusters   []defaultedNested          `json:"clusters"`
+			Pointers   []*defaultedNested         `json:"pointers"`
+			Registries map[string]defaultedNested `json:"registries"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"clusters": [{}, {"namespace": "app"}],
+			"pointers": [{}, null],
+			"registries": {"docker": {}, "gcr": {"namespace": "app"}}
+		}`), &c))
+		require.NoError(t, setDefaults(&c))
+		assert.Equal(t, []defaultedNested{{Namespace: "default"}, {Namespace: "app"}}, c.Clusters)
+		assert.Equal(t, []*defaultedNested{{Namespace: "default"}, nil}, c.Pointers)
+		assert.Equal(t, map[string]defaultedNested{"docker": {Namespace: "default"}, "gcr": {Namespace: "app"}}, c.Registries)
+	})
+
+	t.Run("invalid default value", func(t *testing.T) {
+		t.Parallel()
+
+		var c struct {
+			Replicas int `default:"three"`
+		}
+		assert.Error(t, setDefaults(&c))
+	})
+}
+
+func TestParseDeployTargets_Defaults(t *testing.T) {
+	t.Parallel()
+
+	dts, err := parseDeployTargets[defaultedConfig]([]config.PipedDeployTarget{
+		{Name: "dt", Config: []byte(`{"replicas": 2}`)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, dts["dt"].Config.Replicas)
+	assert.Equal(t, unit.Duration(5*time.Minute), dts["dt"].Config.Timeout)
+}
+
+func TestOverrideDeployTargets_Defaults(t *testing.T) {
+	t.Parallel()
+
+	dts, err := parseDeployTargets[defaultedConfig]([]config.PipedDeployTarget{
+		{Name: "dt", Config: []byte(`{"replicas": 2, "region": "eu"}`)},
+	})
+	require.NoError(t, err)
+
+	// The fields cleared by the override are defaulted again.
+	spec := &overridingSpec{Overrides: map[string]json.RawMessage{"dt": json.RawMessage(`{"replicas": 0, "region": "", "nested": {"namespace": "app"}}`)}}
+	got, err := overrideDeployTargets(spec, []*DeployTarget[defaultedConfig]{dts["dt"]})
+	require.NoError(t, err)
+	assert.Equal(t, 3, got[0].Config.Replicas)
+	assert.Equal(t, "us-app", got[0].Config.Region)
+	assert.Equal(t, "eu", dts["dt"].Config.Region)
+}
+
+func TestDecodeStageConfig_Defaults(t *testing.T) {
+	t.Parallel()
+
+	c, err := DecodeStageConfig[defaultedConfig]([]byte(`{"nested": {"namespace": "stage"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 3, c.Replicas)
+	assert.Equal(t, "us-stage", c.Region)
+}
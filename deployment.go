@@ -15,11 +15,14 @@
 package sdk
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"path"
 	"time"
 
 	"github.com/pipe-cd/piped-plugin-sdk-go/signalhandler"
+	"github.com/pipe-cd/piped-plugin-sdk-go/version"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -78,7 +81,7 @@ type DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationConfig
 
 // Register registers the server to the given gRPC server.
 func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]) Register(server *grpc.Server) {
-	deployment.RegisterDeploymentServiceServer(server, s)
+	deployment.RegisterDeploymentServiceServer(s.registrar(server), s)
 }
 
 func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]) FetchDefinedStages(context.Context, *deployment.FetchDefinedStagesRequest) (*deployment.FetchDefinedStagesResponse, error) {
@@ -92,6 +95,7 @@ func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationCo
 		applicationID: request.GetInput().GetDeployment().GetApplicationId(),
 		deploymentID:  request.GetInput().GetDeployment().GetId(),
 		toolRegistry:  s.toolRegistry,
+		clock:         s.clock,
 	}
 
 	req, err := newDetermineVersionsRequest[ApplicationConfigSpec](s.name, request)
@@ -99,12 +103,13 @@ func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationCo
 		return nil, status.Errorf(codes.Internal, "failed to parse deployment source: %v", err)
 	}
 	input := &DetermineVersionsInput[ApplicationConfigSpec]{
-		Request: req,
-		Client:  client,
-		Logger:  s.logger,
+		Request:  req,
+		Client:   client,
+		Logger:   withCorrelationID(ctx, s.logger),
+		Deadline: deadlineOf(ctx),
 	}
 
-	versions, err := s.base.DetermineVersions(ctx, s.pluginConfig, input)
+	versions, err := s.base.DetermineVersions(ctx, s.currentPluginConfig(), input)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to determine versions: %v", err)
 	}
@@ -119,6 +124,7 @@ func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationCo
 		applicationID: request.GetInput().GetDeployment().GetApplicationId(),
 		deploymentID:  request.GetInput().GetDeployment().GetId(),
 		toolRegistry:  s.toolRegistry,
+		clock:         s.clock,
 	}
 
 	req, err := newDetermineStrategyRequest[ApplicationConfigSpec](s.name, request)
@@ -126,12 +132,13 @@ func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationCo
 		return nil, status.Errorf(codes.Internal, "failed to parse deployment source: %v", err)
 	}
 	input := &DetermineStrategyInput[ApplicationConfigSpec]{
-		Request: req,
-		Client:  client,
-		Logger:  s.logger,
+		Request:  req,
+		Client:   client,
+		Logger:   withCorrelationID(ctx, s.logger),
+		Deadline: deadlineOf(ctx),
 	}
 
-	response, err := s.base.DetermineStrategy(ctx, s.pluginConfig, input)
+	response, err := s.base.DetermineStrategy(ctx, s.currentPluginConfig(), input)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to determine strategy: %v", err)
 	}
@@ -149,8 +156,9 @@ func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationCo
 	client := &Client{
 		base:       s.client,
 		pluginName: s.name,
+		clock:      s.clock,
 	}
-	return buildPipelineSyncStages(ctx, s.base, s.pluginConfig, client, request, s.logger)
+	return buildPipelineSyncStages(ctx, s.base, s.currentPluginConfig(), client, request, s.logger)
 }
 func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]) BuildQuickSyncStages(ctx context.Context, request *deployment.BuildQuickSyncStagesRequest) (*deployment.BuildQuickSyncStagesResponse, error) {
 	input := &BuildQuickSyncStagesInput{
@@ -160,15 +168,17 @@ func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationCo
 		Client: &Client{
 			base:       s.client,
 			pluginName: s.name,
+			clock:      s.clock,
 		},
-		Logger: s.logger,
+		Logger:   withCorrelationID(ctx, s.logger),
+		Deadline: deadlineOf(ctx),
 	}
 
-	response, err := s.base.BuildQuickSyncStages(ctx, s.pluginConfig, input)
+	response, err := s.base.BuildQuickSyncStages(ctx, s.currentPluginConfig(), input)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to build quick sync stages: %v", err)
 	}
-	return newQuickSyncStagesResponse(time.Now(), response), nil
+	return newQuickSyncStagesResponse(s.now(), response), nil
 }
 func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]) ExecuteStage(ctx context.Context, request *deployment.ExecuteStageRequest) (response *deployment.ExecuteStageResponse, _ error) {
 	slp := s.logPersister.StageLogPersister(request.GetInput().GetDeployment().GetId(), request.GetInput().GetStage().GetId())
@@ -189,13 +199,14 @@ func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationCo
 		stageID:           request.GetInput().GetStage().GetId(),
 		stageLogPersister: slp,
 		toolRegistry:      s.toolRegistry,
+		clock:             s.clock,
 	}
 
 	// Get the deploy targets set on the deployment from the piped plugin config.
 	dtNames := request.GetInput().GetDeployment().GetDeployTargets(s.config.Name)
 	deployTargets := make([]*DeployTarget[DeployTargetConfig], 0, len(dtNames))
 	for _, name := range dtNames {
-		dt, ok := s.deployTargets[name]
+		dt, ok := s.deployTargets.get(name)
 		if !ok {
 			return nil, status.Errorf(codes.Internal, "the deploy target %s is not found in the piped plugin config", name)
 		}
@@ -203,7 +214,13 @@ func (s *DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationCo
 		deployTargets = append(deployTargets, dt)
 	}
 
-	return executeStage(ctx, s.name, s.base, s.pluginConfig, deployTargets, client, request, s.logger)
+	release, err := s.stageLimiter.acquire(ctx, dtNames)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return executeStage(ctx, s.name, s.base, s.currentPluginConfig(), deployTargets, client, request, s.logger)
 }
 
 // StagePluginServiceServer is the gRPC server that handles requests from the piped.
@@ -216,7 +233,7 @@ type StagePluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec
 
 // Register registers the server to the given gRPC server.
 func (s *StagePluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]) Register(server *grpc.Server) {
-	deployment.RegisterDeploymentServiceServer(server, s)
+	deployment.RegisterDeploymentServiceServer(s.registrar(server), s)
 }
 
 func (s *StagePluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]) FetchDefinedStages(context.Context, *deployment.FetchDefinedStagesRequest) (*deployment.FetchDefinedStagesResponse, error) {
@@ -232,9 +249,10 @@ func (s *StagePluginServiceServer[Config, DeployTargetConfig, ApplicationConfigS
 	client := &Client{
 		base:       s.client,
 		pluginName: s.name,
+		clock:      s.clock,
 	}
 
-	return buildPipelineSyncStages(ctx, s.base, s.pluginConfig, client, request, s.logger)
+	return buildPipelineSyncStages(ctx, s.base, s.currentPluginConfig(), client, request, s.logger)
 }
 func (s *StagePluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]) BuildQuickSyncStages(context.Context, *deployment.BuildQuickSyncStagesRequest) (*deployment.BuildQuickSyncStagesResponse, error) {
 	// Return an empty response in case the plugin does not support the QuickSync strategy.
@@ -259,18 +277,27 @@ func (s *StagePluginServiceServer[Config, DeployTargetConfig, ApplicationConfigS
 		stageID:           request.GetInput().GetStage().GetId(),
 		stageLogPersister: slp,
 		toolRegistry:      s.toolRegistry,
+		clock:             s.clock,
 	}
 
-	return executeStage(ctx, s.name, s.base, s.pluginConfig, nil, client, request, s.logger) // TODO: pass the deployTargets
+	release, err := s.stageLimiter.acquire(ctx, request.GetInput().GetDeployment().GetDeployTargets(s.name))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return executeStage(ctx, s.name, s.base, s.currentPluginConfig(), nil, client, request, s.logger) // TODO: pass the deployTargets
 }
 
 // buildPipelineSyncStages builds the stages that will be executed by the plugin.
 func buildPipelineSyncStages[Config, DeployTargetConfig, ApplicationConfigSpec any](ctx context.Context, plugin StagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec], config *Config, client *Client, request *deployment.BuildPipelineSyncStagesRequest, logger *zap.Logger) (*deployment.BuildPipelineSyncStagesResponse, error) {
-	resp, err := plugin.BuildPipelineSyncStages(ctx, config, newPipelineSyncStagesInput(request, client, logger))
+	input := newPipelineSyncStagesInput(request, client, withCorrelationID(ctx, logger))
+	input.Deadline = deadlineOf(ctx)
+	resp, err := plugin.BuildPipelineSyncStages(ctx, config, input)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to build pipeline sync stages: %v", err)
 	}
-	return newPipelineSyncStagesResponse(time.Now(), request, resp)
+	return newPipelineSyncStagesResponse(client.clockOrReal().Now(), request, resp)
 }
 
 func executeStage[Config, DeployTargetConfig, ApplicationConfigSpec any](
@@ -283,21 +310,58 @@ func executeStage[Config, DeployTargetConfig, ApplicationConfigSpec any](
 	request *deployment.ExecuteStageRequest,
 	logger *zap.Logger,
 ) (*deployment.ExecuteStageResponse, error) {
-	targetDeploymentSource, err := newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetTargetDeploymentSource())
+	in, err := newExecuteStageInput[ApplicationConfigSpec](ctx, pluginName, client, request, logger)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	deployTargets, err = overrideDeployTargets(in.Request.TargetDeploymentSource.ApplicationConfig.Spec, deployTargets)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create target deployment source: %v", err)
+		return nil, status.Errorf(codes.InvalidArgument, "failed to apply the deploy target overrides: %v", err)
+	}
+
+	defer client.base.forgetHandledCommands(client.stageID)
+
+	if id := CorrelationID(ctx); id != "" && client.stageLogPersister != nil {
+		// Show the ID in the stage logs, so that operators can find the logs of piped and the plugin for the stage.
+		client.stageLogPersister.Infof("Correlation ID: %s", id)
+	}
+
+	start := client.clockOrReal().Now()
+	resp, err := plugin.ExecuteStage(ctx, config, deployTargets, in)
+	stageExecuted(in.Request.StageName, resp, err, client.clockOrReal().Since(start))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to execute stage: %v", err)
+	}
+	if len(resp.Resources) > 0 {
+		// The stage has been done, so the failure to store the outputs doesn't fail it.
+		if err := putStageResourceKeys(ctx, client, resp.Resources); err != nil {
+			logger.Warn("failed to store the resource keys of the stage", zap.Error(err))
+		}
+	}
+
+	return &deployment.ExecuteStageResponse{
+		Status: resp.Status.toModelEnum(),
+	}, nil
+}
+
+// newExecuteStageInput converts the request from piped to the input of ExecuteStage.
+func newExecuteStageInput[ApplicationConfigSpec any](ctx context.Context, pluginName string, client *Client, request *deployment.ExecuteStageRequest, logger *zap.Logger) (*ExecuteStageInput[ApplicationConfigSpec], error) {
+	targetDeploymentSource, err := newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetTargetDeploymentSource(), deploymentPlaceholders(pluginName, request.GetInput().GetDeployment()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create target deployment source: %v", err)
 	}
 
 	// running deploy source is empty on the first deployment
 	runningDeploymentSource := DeploymentSource[ApplicationConfigSpec]{}
 	if request.GetInput().GetRunningDeploymentSource() != nil {
-		runningDeploymentSource, err = newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetRunningDeploymentSource())
+		runningDeploymentSource, err = newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetRunningDeploymentSource(), deploymentPlaceholders(pluginName, request.GetInput().GetDeployment()))
 		if err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to create running deployment source: %v", err)
+			return nil, fmt.Errorf("failed to create running deployment source: %v", err)
 		}
 	}
 
-	in := &ExecuteStageInput[ApplicationConfigSpec]{
+	return &ExecuteStageInput[ApplicationConfigSpec]{
 		Request: ExecuteStageRequest[ApplicationConfigSpec]{
 			StageName:               request.GetInput().GetStage().GetName(),
 			StageIndex:              int(request.GetInput().GetStage().GetIndex()),
@@ -306,17 +370,9 @@ func executeStage[Config, DeployTargetConfig, ApplicationConfigSpec any](
 			TargetDeploymentSource:  targetDeploymentSource,
 			Deployment:              newDeployment(request.GetInput().GetDeployment()),
 		},
-		Client: client,
-		Logger: logger,
-	}
-
-	resp, err := plugin.ExecuteStage(ctx, config, deployTargets, in)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to execute stage: %v", err)
-	}
-
-	return &deployment.ExecuteStageResponse{
-		Status: resp.Status.toModelEnum(),
+		Client:   client,
+		Logger:   withCorrelationID(ctx, logger),
+		Deadline: deadlineOf(ctx),
 	}, nil
 }
 
@@ -409,6 +465,9 @@ type BuildPipelineSyncStagesInput struct {
 	Client *Client
 	// Logger is the logger to log the events.
 	Logger *zap.Logger
+	// Deadline is the time when the given context is canceled, so the plugin can bound its own operations by it.
+	// It's zero when the context has no deadline.
+	Deadline time.Time
 }
 
 // BuildPipelineSyncStagesRequest is the request to build pipeline sync stages.
@@ -428,6 +487,9 @@ type BuildQuickSyncStagesInput struct {
 	Client *Client
 	// Logger is the logger to log the events.
 	Logger *zap.Logger
+	// Deadline is the time when the given context is canceled, so the plugin can bound its own operations by it.
+	// It's zero when the context has no deadline.
+	Deadline time.Time
 }
 
 // BuildQuickSyncStagesRequest is the request to build quick sync stages.
@@ -533,6 +595,9 @@ type ExecuteStageInput[ApplicationConfigSpec any] struct {
 	Client *Client
 	// Logger is the logger to log the events.
 	Logger *zap.Logger
+	// Deadline is the time when the given context is canceled, so the plugin can bound its own operations by it.
+	// It's zero when the context has no deadline.
+	Deadline time.Time
 }
 
 // ExecuteStageRequest is the request to execute a stage.
@@ -542,6 +607,7 @@ type ExecuteStageRequest[ApplicationConfigSpec any] struct {
 	// The index of the stage to execute.
 	StageIndex int
 	// Json encoded configuration of the stage.
+	// Use DecodeStageConfig to decode it in the same way as the other configs.
 	StageConfig []byte
 
 	// RunningDeploymentSource is the source of the running deployment.
@@ -614,6 +680,10 @@ type ExecuteStageResponse struct {
 	// across multiple deploy targets. Nil means the plugin did not report
 	// per-target detail (piped treats it as absent, not as failure).
 	DeployTargetStatuses []DeployTargetStatus
+	// Resources are the keys of the resources applied or deleted by the stage.
+	// They are stored in the stage metadata with ResourceKeysStageMetadataKey if set,
+	// so that the tools built on top of the plugins can correlate them with the live resources.
+	Resources []ResourceKey
 }
 
 // StageStatus represents the current status of a stage of a deployment.
@@ -663,8 +733,12 @@ func (o StageStatus) String() string {
 
 // StageCommand represents a command for a stage.
 type StageCommand struct {
+	// ID is the unique identifier of the command.
+	ID        string
 	Commander string
 	Type      CommandType
+	// CreatedAt is the time when the command was issued.
+	CreatedAt time.Time
 }
 
 // CommandType represents the type of the command.
@@ -691,13 +765,17 @@ func newStageCommand(c *model.Command) (StageCommand, error) {
 	switch c.Type {
 	case model.Command_APPROVE_STAGE:
 		return StageCommand{
+			ID:        c.GetId(),
 			Commander: c.GetCommander(),
 			Type:      CommandTypeApproveStage,
+			CreatedAt: time.Unix(c.GetCreatedAt(), 0),
 		}, nil
 	case model.Command_SKIP_STAGE:
 		return StageCommand{
+			ID:        c.GetId(),
 			Commander: c.GetCommander(),
 			Type:      CommandTypeSkipStage,
+			CreatedAt: time.Unix(c.GetCreatedAt(), 0),
 		}, nil
 	default:
 		return StageCommand{}, fmt.Errorf("invalid command type: %d", c.Type)
@@ -712,6 +790,9 @@ type DetermineVersionsInput[ApplicationConfigSpec any] struct {
 	Client *Client
 	// Logger is the logger to log the events.
 	Logger *zap.Logger
+	// Deadline is the time when the given context is canceled, so the plugin can bound its own operations by it.
+	// It's zero when the context has no deadline.
+	Deadline time.Time
 }
 
 // DetermineVersionsRequest is the request to determine versions.
@@ -724,7 +805,7 @@ type DetermineVersionsRequest[ApplicationConfigSpec any] struct {
 
 // newDetermineVersionsRequest converts the common.DetermineVersionsRequest to the internal representation.
 func newDetermineVersionsRequest[ApplicationConfigSpec any](pluginName string, request *deployment.DetermineVersionsRequest) (DetermineVersionsRequest[ApplicationConfigSpec], error) {
-	ds, err := newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetTargetDeploymentSource())
+	ds, err := newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetTargetDeploymentSource(), deploymentPlaceholders(pluginName, request.GetInput().GetDeployment()))
 	if err != nil {
 		return DetermineVersionsRequest[ApplicationConfigSpec]{}, fmt.Errorf("failed to parse target deployment source: %w", err)
 	}
@@ -759,6 +840,18 @@ type ArtifactVersion struct {
 	URL string
 }
 
+// NewContainerImageArtifactVersion returns the ArtifactVersion of the given container image reference.
+// The name is the last element of the repository, and the version is the tag, the digest if no tag is specified,
+// or "latest" if neither is specified, e.g. "ghcr.io/pipe-cd/piped:v0.50.0" results in the name "piped" and the version "v0.50.0".
+func NewContainerImageArtifactVersion(image string) ArtifactVersion {
+	repository, tag, digest := version.SplitImage(image)
+	return ArtifactVersion{
+		Version: cmp.Or(tag, digest, "latest"),
+		Name:    path.Base(repository),
+		URL:     image,
+	}
+}
+
 // toModel converts the ArtifactVersion to the model.ArtifactVersion.
 func (v *ArtifactVersion) toModel() *model.ArtifactVersion {
 	return &model.ArtifactVersion{
@@ -776,6 +869,9 @@ type DetermineStrategyInput[ApplicationConfigSpec any] struct {
 	Client *Client
 	// Logger is the logger to log the events.
 	Logger *zap.Logger
+	// Deadline is the time when the given context is canceled, so the plugin can bound its own operations by it.
+	// It's zero when the context has no deadline.
+	Deadline time.Time
 }
 
 // DetermineStrategyRequest is the request to determine the strategy.
@@ -790,11 +886,11 @@ type DetermineStrategyRequest[ApplicationConfigSpec any] struct {
 
 // newDetermineStrategyRequest converts the common.DetermineStrategyRequest to the internal representation.
 func newDetermineStrategyRequest[ApplicationConfigSpec any](pluginName string, request *deployment.DetermineStrategyRequest) (DetermineStrategyRequest[ApplicationConfigSpec], error) {
-	rds, err := newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetRunningDeploymentSource())
+	rds, err := newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetRunningDeploymentSource(), deploymentPlaceholders(pluginName, request.GetInput().GetDeployment()))
 	if err != nil {
 		return DetermineStrategyRequest[ApplicationConfigSpec]{}, fmt.Errorf("failed to parse running deployment source: %w", err)
 	}
-	tds, err := newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetTargetDeploymentSource())
+	tds, err := newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetInput().GetTargetDeploymentSource(), deploymentPlaceholders(pluginName, request.GetInput().GetDeployment()))
 	if err != nil {
 		return DetermineStrategyRequest[ApplicationConfigSpec]{}, fmt.Errorf("failed to parse target deployment source: %w", err)
 	}
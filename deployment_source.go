@@ -40,13 +40,18 @@ type DeploymentSource[Spec any] struct {
 }
 
 // newDeploymentSource converts the common.DeploymentSource to the internal representation.
-func newDeploymentSource[Spec any](pluginName string, source *common.DeploymentSource) (DeploymentSource[Spec], error) {
+// The placeholders in the plugin spec are substituted with the given values and the commit hash of the source.
+func newDeploymentSource[Spec any](pluginName string, source *common.DeploymentSource, p placeholders) (DeploymentSource[Spec], error) {
 	cfg, err := config.DecodeYAML[*ApplicationConfig[Spec]](source.GetApplicationConfig())
 	if err != nil {
 		return DeploymentSource[Spec]{}, fmt.Errorf("failed to decode application config: %w", err)
 	}
 
-	if err := cfg.Spec.parsePluginConfig(pluginName); err != nil {
+	p.commitHash = source.GetCommitHash()
+	if p.appName == "" {
+		p.appName = cfg.Spec.commonSpec.Name
+	}
+	if err := cfg.Spec.parsePluginConfig(pluginName, &p); err != nil {
 		return DeploymentSource[Spec]{}, fmt.Errorf("failed to parse plugin config: %w", err)
 	}
 
@@ -97,7 +102,7 @@ func LoadApplicationConfigForTest[Spec any](t *testing.T, filename string, plugi
 	if cfg.Spec == nil {
 		t.Fatal("application config is not set")
 	}
-	if err := cfg.Spec.parsePluginConfig(pluginName); err != nil {
+	if err := cfg.Spec.parsePluginConfig(pluginName, nil); err != nil {
 		t.Fatalf("failed to parse plugin config: %s", err)
 	}
 	return cfg.Spec
@@ -105,8 +110,9 @@ func LoadApplicationConfigForTest[Spec any](t *testing.T, filename string, plugi
 
 // parsePluginConfig parses the plugin config for the given plugin name.
 // It returns nil if no config is set for the plugin.
+// The placeholders are substituted before decoding unless p is nil or the Spec opts out by PlaceholderSubstituter.
 // After calling this method, the pluginConfigs is cleared to avoid leaking the internal data.
-func (c *ApplicationConfig[Spec]) parsePluginConfig(pluginName string) error {
+func (c *ApplicationConfig[Spec]) parsePluginConfig(pluginName string, p *placeholders) error {
 	defer func() {
 		// Clear the plugin configs after using it to avoid leaking the internal data.
 		c.pluginConfigs = nil
@@ -120,22 +126,22 @@ func (c *ApplicationConfig[Spec]) parsePluginConfig(pluginName string) error {
 	}
 
 	var spec Spec
-	if err := json.Unmarshal(data, &spec); err != nil {
+	if s, ok := any(&spec).(PlaceholderSubstituter); p != nil && (!ok || s.SubstitutePlaceholders()) {
+		var err error
+		if data, err = p.substitute(data); err != nil {
+			return fmt.Errorf("failed to substitute placeholders in application config: plugin spec: %w", err)
+		}
+	}
+	if err := decodeConfig(data, &spec, "spec.plugins."+pluginName); err != nil {
 		return fmt.Errorf("failed to unmarshal application config: plugin spec: %w", err)
 	}
 
-	// Validate the spec if it implements the Validate method.
-	if v, ok := any(spec).(interface{ Validate() error }); ok {
-		if err := v.Validate(); err != nil {
-			return fmt.Errorf("failed to validate plugin spec: %w", err)
-		}
+	if err := setDefaults(&spec); err != nil {
+		return fmt.Errorf("failed to set defaults of plugin spec: %w", err)
 	}
 
-	// Sometimes the receiver of Validate method is pointer to the spec.
-	if v, ok := any(&spec).(interface{ Validate() error }); ok {
-		if err := v.Validate(); err != nil {
-			return fmt.Errorf("failed to validate plugin spec: %w", err)
-		}
+	if err := validate(&spec); err != nil {
+		return fmt.Errorf("failed to validate plugin spec: %w", err)
 	}
 
 	c.Spec = &spec
@@ -178,6 +184,14 @@ func (c *ApplicationConfig[Spec]) Validate() error {
 	return nil
 }
 
+// Labels returns the labels of the application defined in the application config.
+func (c *ApplicationConfig[Spec]) Labels() map[string]string {
+	if c == nil || c.commonSpec == nil {
+		return nil
+	}
+	return c.commonSpec.Labels
+}
+
 // HasStage returns true if the application config has a stage with the given name.
 func (c *ApplicationConfig[Spec]) HasStage(name string) bool {
 	if c.commonSpec.Pipeline == nil {
@@ -191,3 +205,33 @@ func (c *ApplicationConfig[Spec]) HasStage(name string) bool {
 	}
 	return false
 }
+
+// ConfigValidator is an optional interface for the Config, the DeployTargetConfig, the ApplicationConfigSpec,
+// and the stage configs decoded by DecodeStageConfig to validate the constraints which can't be declared
+// with the `validate` struct tags, e.g. the ones across the fields.
+// Validate is called after the defaults are applied and the struct tags are validated.
+// The invalid Config and DeployTargetConfig prevent the plugin from starting,
+// so that they don't surface deep inside the deployments later.
+// It can be implemented with either the value receiver or the pointer receiver.
+type ConfigValidator interface {
+	Validate() error
+}
+
+// validate validates the given value by the `validate` struct tags,
+// and then by the Validate method if it implements ConfigValidator.
+func validate[T any](v *T) error {
+	if err := validateTags(v); err != nil {
+		return err
+	}
+
+	if v, ok := any(*v).(ConfigValidator); ok {
+		return v.Validate()
+	}
+
+	// Sometimes the receiver of Validate method is pointer to the value.
+	if v, ok := any(v).(ConfigValidator); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+)
+
+// ApplicationFS gives the access to the files in the application directory without reading them into memory.
+// The files are opened and the directories are walked lazily, so the plugins can stream the large files,
+// and stop walking when they find what they need.
+// The access is confined to the application directory, including through the symbolic links.
+// It implements fs.FS, fs.StatFS, fs.ReadDirFS, and fs.ReadFileFS.
+type ApplicationFS struct {
+	fs.FS
+	root *os.Root
+	// configFilename is the name of the application config file relative to the application directory.
+	configFilename string
+}
+
+// OpenApplicationDirectory opens the application directory of the deployment source.
+// The returned ApplicationFS must be closed after use.
+func (d *DeploymentSource[Spec]) OpenApplicationDirectory() (*ApplicationFS, error) {
+	if d.ApplicationDirectory == "" {
+		return nil, fmt.Errorf("application directory is not set")
+	}
+	root, err := os.OpenRoot(d.ApplicationDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the application directory: %w", err)
+	}
+	return &ApplicationFS{
+		FS:             root.FS(),
+		root:           root,
+		configFilename: d.ApplicationConfigFilename,
+	}, nil
+}
+
+// Close closes the application directory.
+// The files opened from the ApplicationFS are still readable after closing it.
+func (a *ApplicationFS) Close() error {
+	return a.root.Close()
+}
+
+// Stat returns the file info of the named file without opening it.
+func (a *ApplicationFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(a.FS, name)
+}
+
+// ReadDir reads the named directory and returns its entries sorted by filename.
+func (a *ApplicationFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(a.FS, name)
+}
+
+// ReadFile reads the whole named file into memory.
+// Prefer Open for the large files to stream their contents.
+func (a *ApplicationFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(a.FS, name)
+}
+
+// Walk walks the file tree rooted at the named directory in lexical order, reading each directory only when it's visited.
+// See fs.WalkDir for how fn controls the walk.
+func (a *ApplicationFS) Walk(name string, fn fs.WalkDirFunc) error {
+	return fs.WalkDir(a.FS, name, fn)
+}
+
+// Files returns the iterator of the slash-separated paths of the regular files in the application directory in lexical order,
+// except the application config file and the files in the hidden directories such as .git.
+// The directories are read as the iteration proceeds, and breaking the loop stops reading them.
+// The iteration yields the error and stops when it fails to read a directory.
+func (a *ApplicationFS) Files() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		errStop := errors.New("stop")
+		err := a.Walk(".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if name != "." && path.Base(name)[0] == '.' {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || name == a.configFilename {
+				return nil
+			}
+			if !yield(name, nil) {
+				return errStop
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStop) {
+			yield("", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes the files to the given directory. The key is the slash-separated path.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+	}
+}
+
+func TestDeploymentSource_OpenApplicationDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.pipecd.yaml":        "kind: Application",
+		"deployment.yaml":        "kind: Deployment",
+		"manifests/service.yaml": "kind: Service",
+		"manifests/z/large.bin":  "large",
+		".git/HEAD":              "ref: refs/heads/main",
+	})
+	outside := t.TempDir()
+	writeFiles(t, outside, map[string]string{"secret": "secret"})
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "link")))
+
+	source := &DeploymentSource[any]{ApplicationDirectory: dir, ApplicationConfigFilename: "app.pipecd.yaml"}
+	afs, err := source.OpenApplicationDirectory()
+	require.NoError(t, err)
+	defer afs.Close()
+
+	t.Run("files", func(t *testing.T) {
+		var files []string
+		for name, err := range afs.Files() {
+			require.NoError(t, err)
+			files = append(files, name)
+		}
+		assert.Equal(t, []string{"deployment.yaml", "manifests/service.yaml", "manifests/z/large.bin"}, files)
+	})
+
+	t.Run("break", func(t *testing.T) {
+		var files []string
+		for name := range afs.Files() {
+			files = append(files, name)
+			break
+		}
+		assert.Equal(t, []string{"deployment.yaml"}, files)
+	})
+
+	t.Run("open", func(t *testing.T) {
+		f, err := afs.Open("manifests/z/large.bin")
+		require.NoError(t, err)
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, "large", string(data))
+	})
+
+	t.Run("stat", func(t *testing.T) {
+		info, err := afs.Stat("manifests/service.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, int64(len("kind: Service")), info.Size())
+
+		_, err = afs.Stat("missing.yaml")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("escape", func(t *testing.T) {
+		_, err := afs.Open("../secret")
+		assert.Error(t, err)
+		_, err = afs.ReadFile("link")
+		assert.Error(t, err)
+	})
+
+	t.Run("fs", func(t *testing.T) {
+		data, err := fs.ReadFile(afs, "deployment.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, "kind: Deployment", string(data))
+	})
+}
+
+func TestDeploymentSource_OpenApplicationDirectory_Error(t *testing.T) {
+	t.Parallel()
+
+	_, err := (&DeploymentSource[any]{}).OpenApplicationDirectory()
+	assert.Error(t, err)
+
+	_, err = (&DeploymentSource[any]{ApplicationDirectory: filepath.Join(t.TempDir(), "missing")}).OpenApplicationDirectory()
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
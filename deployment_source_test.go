@@ -105,6 +105,24 @@ func TestApplicationConfig_HasStage(t *testing.T) {
 	}
 }
 
+func TestApplicationConfig_Labels(t *testing.T) {
+	t.Parallel()
+
+	appConfig := &ApplicationConfig[any]{
+		commonSpec: &config.GenericApplicationSpec{
+			Labels: map[string]string{"env": "prod"},
+		},
+	}
+	if got := appConfig.Labels(); got["env"] != "prod" {
+		t.Errorf("Labels() = %v, want env=prod", got)
+	}
+
+	var nilConfig *ApplicationConfig[any]
+	if got := nilConfig.Labels(); got != nil {
+		t.Errorf("Labels() = %v, want nil", got)
+	}
+}
+
 type testPluginSpec struct {
 	Name  string `json:"name"`
 	Value int    `json:"value"`
@@ -179,7 +197,7 @@ func TestApplicationConfig_ParsePluginConfig(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			err := tc.config.parsePluginConfig(tc.pluginName)
+			err := tc.config.parsePluginConfig(tc.pluginName, nil)
 			if tc.wantErr {
 				assert.Error(t, err)
 				return
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
 	"github.com/stretchr/testify/assert"
@@ -33,6 +34,8 @@ import (
 type mockStagePlugin struct {
 	result StageStatus
 	err    error
+	// deadline is the deadline given in the last input.
+	deadline time.Time
 }
 
 func (m *mockStagePlugin) FetchDefinedStages() []string {
@@ -40,6 +43,7 @@ func (m *mockStagePlugin) FetchDefinedStages() []string {
 }
 
 func (m *mockStagePlugin) BuildPipelineSyncStages(ctx context.Context, config *struct{}, input *BuildPipelineSyncStagesInput) (*BuildPipelineSyncStagesResponse, error) {
+	m.deadline = input.Deadline
 	return &BuildPipelineSyncStagesResponse{
 		Stages: []PipelineStage{
 			{
@@ -453,6 +457,34 @@ spec: {}
 	}
 }
 
+func TestNewContainerImageArtifactVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		image    string
+		expected ArtifactVersion
+	}{
+		{
+			image:    "ghcr.io/pipe-cd/piped:v0.50.0",
+			expected: ArtifactVersion{Version: "v0.50.0", Name: "piped", URL: "ghcr.io/pipe-cd/piped:v0.50.0"},
+		},
+		{
+			image:    "localhost:5000/nginx@sha256:abc",
+			expected: ArtifactVersion{Version: "sha256:abc", Name: "nginx", URL: "localhost:5000/nginx@sha256:abc"},
+		},
+		{
+			image:    "nginx",
+			expected: ArtifactVersion{Version: "latest", Name: "nginx", URL: "nginx"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, NewContainerImageArtifactVersion(tt.image))
+		})
+	}
+}
+
 func TestArtifactVersion_toModel(t *testing.T) {
 	// Use the default value for expected "Kind" for now.
 	// They will be removed after deleting Kind from model.ArtifactVersion.
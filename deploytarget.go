@@ -0,0 +1,470 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	config "github.com/pipe-cd/pipecd/pkg/configv1"
+)
+
+// DeployTargetChanges represents the changes of the deploy targets while the plugin is running.
+// Each list is sorted by the name of the deploy targets.
+type DeployTargetChanges[DeployTargetConfig any] struct {
+	// Added is the deploy targets which are newly added.
+	Added []*DeployTarget[DeployTargetConfig]
+	// Updated is the deploy targets whose labels or config are changed.
+	Updated []*DeployTarget[DeployTargetConfig]
+	// Removed is the deploy targets which are removed.
+	Removed []*DeployTarget[DeployTargetConfig]
+}
+
+// Empty returns true if there is no change.
+func (c DeployTargetChanges[DeployTargetConfig]) Empty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0 && len(c.Removed) == 0
+}
+
+// DeployTargetObserver is an optional interface for the plugins to be notified
+// when the deploy targets are added, updated, or removed while the plugin is running,
+// by reloading the plugin config or by the DeployTargetRegistry.
+// It's useful to create or destroy the clients and the watchers per deploy target incrementally.
+// The deploy targets given to Initialize are not notified.
+type DeployTargetObserver[DeployTargetConfig any] interface {
+	// OnDeployTargetsChanged is called with the changes after they are applied.
+	// The returned error is logged, but it does not revert the changes.
+	OnDeployTargetsChanged(ctx context.Context, changes DeployTargetChanges[DeployTargetConfig]) error
+}
+
+// deployTargetStore holds the deploy targets which can be replaced while the plugin is running.
+type deployTargetStore[DeployTargetConfig any] struct {
+	mu      sync.RWMutex
+	targets map[string]*DeployTarget[DeployTargetConfig]
+	// runtime is the names of the deploy targets added by the DeployTargetRegistry, not by the plugin config.
+	runtime map[string]bool
+}
+
+func newDeployTargetStore[DeployTargetConfig any](targets map[string]*DeployTarget[DeployTargetConfig]) *deployTargetStore[DeployTargetConfig] {
+	return &deployTargetStore[DeployTargetConfig]{
+		targets: targets,
+		runtime: make(map[string]bool),
+	}
+}
+
+// get returns the deploy target of the given name.
+func (s *deployTargetStore[DeployTargetConfig]) get(name string) (*DeployTarget[DeployTargetConfig], bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dt, ok := s.targets[name]
+	return dt, ok
+}
+
+// snapshot returns a copy of the current deploy targets.
+func (s *deployTargetStore[DeployTargetConfig]) snapshot() map[string]*DeployTarget[DeployTargetConfig] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.targets)
+}
+
+// replace replaces the deploy targets given by the plugin config with the given ones and returns the changes.
+// The deploy targets added at runtime are kept unless the given ones have the same name.
+func (s *deployTargetStore[DeployTargetConfig]) replace(targets map[string]*DeployTarget[DeployTargetConfig]) DeployTargetChanges[DeployTargetConfig] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := maps.Clone(targets)
+	if next == nil {
+		next = make(map[string]*DeployTarget[DeployTargetConfig])
+	}
+	for name := range s.runtime {
+		if _, ok := next[name]; ok {
+			// The plugin config takes over the deploy target.
+			delete(s.runtime, name)
+			continue
+		}
+		next[name] = s.targets[name]
+	}
+	return s.swap(next)
+}
+
+// put adds or updates the deploy target at runtime and returns the changes.
+// The deploy targets given by the plugin config can't be updated.
+func (s *deployTargetStore[DeployTargetConfig]) put(dt *DeployTarget[DeployTargetConfig]) (DeployTargetChanges[DeployTargetConfig], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.targets[dt.Name]; ok && !s.runtime[dt.Name] {
+		return DeployTargetChanges[DeployTargetConfig]{}, fmt.Errorf("deploy target %s is given by the plugin config", dt.Name)
+	}
+	next := maps.Clone(s.targets)
+	next[dt.Name] = dt
+	s.runtime[dt.Name] = true
+	return s.swap(next), nil
+}
+
+// delete removes the deploy target added at runtime and returns the changes.
+// The deploy targets given by the plugin config can't be removed.
+func (s *deployTargetStore[DeployTargetConfig]) delete(name string) (DeployTargetChanges[DeployTargetConfig], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.targets[name]; !ok {
+		return DeployTargetChanges[DeployTargetConfig]{}, fmt.Errorf("deploy target %s is not found", name)
+	}
+	if !s.runtime[name] {
+		return DeployTargetChanges[DeployTargetConfig]{}, fmt.Errorf("deploy target %s is given by the plugin config", name)
+	}
+	next := maps.Clone(s.targets)
+	delete(next, name)
+	delete(s.runtime, name)
+	return s.swap(next), nil
+}
+
+// swap replaces the deploy targets with the given ones and returns the changes.
+// The caller must hold the lock.
+func (s *deployTargetStore[DeployTargetConfig]) swap(targets map[string]*DeployTarget[DeployTargetConfig]) DeployTargetChanges[DeployTargetConfig] {
+	var changes DeployTargetChanges[DeployTargetConfig]
+	for name, dt := range targets {
+		old, ok := s.targets[name]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, dt)
+		case !reflect.DeepEqual(old.Labels, dt.Labels) || !reflect.DeepEqual(old.Config, dt.Config):
+			changes.Updated = append(changes.Updated, dt)
+		}
+	}
+	for name, dt := range s.targets {
+		if _, ok := targets[name]; !ok {
+			changes.Removed = append(changes.Removed, dt)
+		}
+	}
+
+	byName := func(a, b *DeployTarget[DeployTargetConfig]) int {
+		return strings.Compare(a.Name, b.Name)
+	}
+	slices.SortFunc(changes.Added, byName)
+	slices.SortFunc(changes.Updated, byName)
+	slices.SortFunc(changes.Removed, byName)
+
+	s.targets = targets
+	return changes
+}
+
+// DeployTargetRegistry adds and removes the deploy targets while the plugin is running,
+// e.g. to follow the clusters of a fleet discovered by the plugin instead of listing them in the plugin config.
+// The registered plugins implementing DeployTargetObserver are notified of the changes,
+// so that they can set up and tear down the clients per deploy target.
+//
+// The deploy targets added by the registry are kept when the plugin config is reloaded,
+// unless the plugin config has the deploy target of the same name, which takes it over.
+// The deploy targets given by the plugin config can't be updated or removed by the registry.
+type DeployTargetRegistry[DeployTargetConfig any] struct {
+	store    *deployTargetStore[DeployTargetConfig]
+	redactor *redactor
+	// notify notifies the plugins of the changes applied to the store.
+	notify func(ctx context.Context, old map[string]*DeployTarget[DeployTargetConfig], changes DeployTargetChanges[DeployTargetConfig])
+}
+
+// Add adds the deploy target with the given labels and config in JSON, or updates the one added before.
+// The config is decoded, defaulted, and validated in the same way as the deploy targets in the plugin config.
+func (r *DeployTargetRegistry[DeployTargetConfig]) Add(ctx context.Context, name string, labels map[string]string, rawConfig json.RawMessage) (*DeployTarget[DeployTargetConfig], error) {
+	if name == "" {
+		return nil, errors.New("the name of the deploy target is required")
+	}
+	if len(rawConfig) == 0 {
+		rawConfig = json.RawMessage("{}")
+	}
+	dts, err := parseDeployTargets[DeployTargetConfig]([]config.PipedDeployTarget{{Name: name, Labels: labels, Config: rawConfig}})
+	if err != nil {
+		return nil, err
+	}
+	dt := dts[name]
+
+	r.redactor.add(sensitiveValues(dt))
+	old := r.store.snapshot()
+	changes, err := r.store.put(dt)
+	if err != nil {
+		return nil, err
+	}
+	if r.notify != nil {
+		r.notify(ctx, old, changes)
+	}
+	return dt, nil
+}
+
+// Remove removes the deploy target added by Add.
+func (r *DeployTargetRegistry[DeployTargetConfig]) Remove(ctx context.Context, name string) error {
+	old := r.store.snapshot()
+	changes, err := r.store.delete(name)
+	if err != nil {
+		return err
+	}
+	if r.notify != nil {
+		r.notify(ctx, old, changes)
+	}
+	return nil
+}
+
+// Get returns the deploy target of the given name, including the ones given by the plugin config.
+func (r *DeployTargetRegistry[DeployTargetConfig]) Get(name string) (*DeployTarget[DeployTargetConfig], bool) {
+	return r.store.get(name)
+}
+
+// List returns all the current deploy targets by their names, including the ones given by the plugin config.
+func (r *DeployTargetRegistry[DeployTargetConfig]) List() map[string]*DeployTarget[DeployTargetConfig] {
+	return r.store.snapshot()
+}
+
+// LabelSelector selects the deploy targets by their labels.
+// It's given as comma-separated requirements, and all of them must be satisfied:
+//
+//	region=us-*        the label matches the glob pattern
+//	env!=dev           the label does not match the glob pattern, or it's not set
+//	canary             the label is set
+//	!canary            the label is not set
+//
+// In the config, it's given as the string above or as a map of the labels which must match exactly.
+// The empty selector selects all deploy targets.
+type LabelSelector struct {
+	requirements []labelRequirement
+}
+
+// labelRequirement is a single requirement of the LabelSelector.
+type labelRequirement struct {
+	key      string
+	operator string
+	pattern  string
+}
+
+const (
+	labelOperatorEquals    = "="
+	labelOperatorNotEquals = "!="
+	labelOperatorExists    = "exists"
+	labelOperatorNotExists = "!"
+)
+
+// ParseLabelSelector parses the string representation of the LabelSelector, e.g. "region=us-*,env!=dev".
+func ParseLabelSelector(s string) (LabelSelector, error) {
+	var selector LabelSelector
+	for part := range strings.SplitSeq(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var r labelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			key, pattern, _ := strings.Cut(part, "!=")
+			r = labelRequirement{key: key, operator: labelOperatorNotEquals, pattern: pattern}
+		case strings.Contains(part, "="):
+			key, pattern, _ := strings.Cut(part, "=")
+			// Allow "==" as well as "=".
+			r = labelRequirement{key: key, operator: labelOperatorEquals, pattern: strings.TrimPrefix(pattern, "=")}
+		case strings.HasPrefix(part, "!"):
+			r = labelRequirement{key: strings.TrimPrefix(part, "!"), operator: labelOperatorNotExists}
+		default:
+			r = labelRequirement{key: part, operator: labelOperatorExists}
+		}
+
+		r.key, r.pattern = strings.TrimSpace(r.key), strings.TrimSpace(r.pattern)
+		if r.key == "" {
+			return LabelSelector{}, fmt.Errorf("invalid label selector %q: empty key in %q", s, part)
+		}
+		if _, err := path.Match(r.pattern, ""); err != nil {
+			return LabelSelector{}, fmt.Errorf("invalid label selector %q: invalid pattern in %q: %w", s, part, err)
+		}
+		selector.requirements = append(selector.requirements, r)
+	}
+	return selector, nil
+}
+
+// Matches returns true if the given labels satisfy all requirements of the selector.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	for _, r := range s.requirements {
+		v, ok := labels[r.key]
+		switch r.operator {
+		case labelOperatorExists:
+			if !ok {
+				return false
+			}
+		case labelOperatorNotExists:
+			if ok {
+				return false
+			}
+		case labelOperatorEquals:
+			if matched, _ := path.Match(r.pattern, v); !ok || !matched {
+				return false
+			}
+		case labelOperatorNotEquals:
+			if matched, _ := path.Match(r.pattern, v); ok && matched {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Empty returns true if the selector has no requirement, that is, it selects everything.
+func (s LabelSelector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// String returns the string representation of the selector.
+func (s LabelSelector) String() string {
+	parts := make([]string, 0, len(s.requirements))
+	for _, r := range s.requirements {
+		switch r.operator {
+		case labelOperatorExists:
+			parts = append(parts, r.key)
+		case labelOperatorNotExists:
+			parts = append(parts, "!"+r.key)
+		default:
+			parts = append(parts, r.key+r.operator+r.pattern)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// MarshalJSON marshals the selector as its string representation.
+func (s LabelSelector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// JSONSchema returns the schema accepting the string representation or the map of the labels.
+func (LabelSelector) JSONSchema() map[string]any {
+	return map[string]any{
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+		},
+	}
+}
+
+// UnmarshalJSON unmarshals the selector from a string like "region=us-*,env!=dev" or a map of the labels.
+func (s *LabelSelector) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		selector, err := ParseLabelSelector(str)
+		if err != nil {
+			return err
+		}
+		*s = selector
+		return nil
+	}
+
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return fmt.Errorf("invalid label selector: it must be a string or a map of the labels: %w", err)
+	}
+	var selector LabelSelector
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		// Escape the glob characters since the values of the map must match exactly.
+		pattern := globEscaper.Replace(labels[k])
+		selector.requirements = append(selector.requirements, labelRequirement{key: k, operator: labelOperatorEquals, pattern: pattern})
+	}
+	*s = selector
+	return nil
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+
+// SelectDeployTargets returns the deploy targets matching the selector, sorted by their names.
+// Use slices.Collect(maps.Values(m)) to select from the map given to Initialize.
+func SelectDeployTargets[DeployTargetConfig any](deployTargets []*DeployTarget[DeployTargetConfig], selector LabelSelector) []*DeployTarget[DeployTargetConfig] {
+	selected := make([]*DeployTarget[DeployTargetConfig], 0, len(deployTargets))
+	for _, dt := range deployTargets {
+		if selector.Matches(dt.Labels) {
+			selected = append(selected, dt)
+		}
+	}
+	slices.SortFunc(selected, func(a, b *DeployTarget[DeployTargetConfig]) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return selected
+}
+
+// The labels of the deploy targets to declare how they are rolled out.
+// They are parsed into the Group, Order, and Weight of the DeployTarget, e.g.
+//
+//	deployTargets:
+//	  - name: us-east
+//	    labels:
+//	      pipecd.dev/group: us
+//	      pipecd.dev/order: "1"
+//	      pipecd.dev/weight: "50"
+const (
+	DeployTargetLabelGroup  = "pipecd.dev/group"
+	DeployTargetLabelOrder  = "pipecd.dev/order"
+	DeployTargetLabelWeight = "pipecd.dev/weight"
+)
+
+// parseRolloutLabels sets the Group, Order, and Weight from the labels.
+func (dt *DeployTarget[Config]) parseRolloutLabels() error {
+	dt.Group = dt.Labels[DeployTargetLabelGroup]
+	if v, ok := dt.Labels[DeployTargetLabelOrder]; ok {
+		order, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s label %q: %w", DeployTargetLabelOrder, v, err)
+		}
+		dt.Order = order
+	}
+	if v, ok := dt.Labels[DeployTargetLabelWeight]; ok {
+		weight, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s label %q: %w", DeployTargetLabelWeight, v, err)
+		}
+		if weight < 0 {
+			return fmt.Errorf("invalid %s label %q: must not be negative", DeployTargetLabelWeight, v)
+		}
+		dt.Weight = weight
+	}
+	return nil
+}
+
+// DeployTargetWaves splits the deploy targets into the waves to be rolled out one by one.
+// The deploy targets having the same Order are in the same wave, and the waves are sorted by the Order.
+// The deploy targets in each wave are sorted by the Group and then the Name, so the result is deterministic.
+func DeployTargetWaves[DeployTargetConfig any](deployTargets []*DeployTarget[DeployTargetConfig]) [][]*DeployTarget[DeployTargetConfig] {
+	sorted := slices.Clone(deployTargets)
+	slices.SortFunc(sorted, func(a, b *DeployTarget[DeployTargetConfig]) int {
+		if c := cmp.Compare(a.Order, b.Order); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Group, b.Group); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var waves [][]*DeployTarget[DeployTargetConfig]
+	for i, dt := range sorted {
+		if i == 0 || sorted[i-1].Order != dt.Order {
+			waves = append(waves, nil)
+		}
+		waves[len(waves)-1] = append(waves[len(waves)-1], dt)
+	}
+	return waves
+}
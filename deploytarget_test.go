@@ -0,0 +1,321 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	config "github.com/pipe-cd/pipecd/pkg/configv1"
+)
+
+type testDeployTargetConfig struct {
+	Region string `json:"region"`
+}
+
+func names(dts []*DeployTarget[testDeployTargetConfig]) []string {
+	var names []string
+	for _, dt := range dts {
+		names = append(names, dt.Name)
+	}
+	return names
+}
+
+func TestDeployTargetStore_Replace(t *testing.T) {
+	t.Parallel()
+
+	store := newDeployTargetStore(map[string]*DeployTarget[testDeployTargetConfig]{
+		"kept":    {Name: "kept", Config: testDeployTargetConfig{Region: "us"}},
+		"updated": {Name: "updated", Config: testDeployTargetConfig{Region: "us"}},
+		"removed": {Name: "removed", Config: testDeployTargetConfig{Region: "us"}},
+		"relabel": {Name: "relabel", Labels: map[string]string{"env": "dev"}},
+	})
+
+	changes := store.replace(map[string]*DeployTarget[testDeployTargetConfig]{
+		"kept":    {Name: "kept", Config: testDeployTargetConfig{Region: "us"}},
+		"updated": {Name: "updated", Config: testDeployTargetConfig{Region: "eu"}},
+		"relabel": {Name: "relabel", Labels: map[string]string{"env": "prod"}},
+		"added-b": {Name: "added-b"},
+		"added-a": {Name: "added-a"},
+	})
+
+	assert.Equal(t, []string{"added-a", "added-b"}, names(changes.Added))
+	assert.Equal(t, []string{"relabel", "updated"}, names(changes.Updated))
+	assert.Equal(t, []string{"removed"}, names(changes.Removed))
+
+	dt, ok := store.get("updated")
+	assert.True(t, ok)
+	assert.Equal(t, "eu", dt.Config.Region)
+	_, ok = store.get("removed")
+	assert.False(t, ok)
+
+	// Replacing with the same targets should result in no change.
+	assert.True(t, store.replace(store.snapshot()).Empty())
+}
+
+type observingLivestatePlugin struct {
+	ExampleLivestatePlugin
+	changes []DeployTargetChanges[ExampleDeployTargetConfig]
+}
+
+func (p *observingLivestatePlugin) OnDeployTargetsChanged(_ context.Context, changes DeployTargetChanges[ExampleDeployTargetConfig]) error {
+	p.changes = append(p.changes, changes)
+	return nil
+}
+
+func TestPlugin_UpdateDeployTargets(t *testing.T) {
+	t.Parallel()
+
+	observer := &observingLivestatePlugin{}
+	plugin, err := NewPlugin("1.0.0", WithLivestatePlugin(observer))
+	assert.NoError(t, err)
+
+	store := newDeployTargetStore(map[string]*DeployTarget[ExampleDeployTargetConfig]{
+		"dt1": {Name: "dt1"},
+	})
+
+	// No notification without changes.
+	plugin.updateDeployTargets(context.Background(), store, map[string]*DeployTarget[ExampleDeployTargetConfig]{
+		"dt1": {Name: "dt1"},
+	}, zaptest.NewLogger(t))
+	assert.Empty(t, observer.changes)
+
+	plugin.updateDeployTargets(context.Background(), store, map[string]*DeployTarget[ExampleDeployTargetConfig]{
+		"dt2": {Name: "dt2"},
+	}, zaptest.NewLogger(t))
+	if assert.Len(t, observer.changes, 1) {
+		assert.Equal(t, "dt2", observer.changes[0].Added[0].Name)
+		assert.Equal(t, "dt1", observer.changes[0].Removed[0].Name)
+	}
+}
+
+// multiRoleObservingPlugin is registered for multiple roles and counts the notifications.
+type multiRoleObservingPlugin struct {
+	mockLivestatePlugin
+	mockPlanPreviewPlugin
+	notified int
+}
+
+func (p *multiRoleObservingPlugin) OnDeployTargetsChanged(context.Context, DeployTargetChanges[struct{}]) error {
+	p.notified++
+	return nil
+}
+
+func TestPlugin_UpdateDeployTargets_MultipleRoles(t *testing.T) {
+	t.Parallel()
+
+	observer := &multiRoleObservingPlugin{}
+	plugin, err := NewPlugin("1.0.0",
+		WithLivestatePlugin[struct{}, struct{}, struct{}](observer),
+		WithPlanPreviewPlugin[struct{}, struct{}, struct{}](observer),
+	)
+	require.NoError(t, err)
+
+	// The plugin registered for multiple roles is notified once.
+	store := newDeployTargetStore(map[string]*DeployTarget[struct{}]{})
+	plugin.updateDeployTargets(context.Background(), store, map[string]*DeployTarget[struct{}]{
+		"dt1": {Name: "dt1"},
+	}, zaptest.NewLogger(t))
+	assert.Equal(t, 1, observer.notified)
+}
+
+func TestDeployTargetRegistry(t *testing.T) {
+	t.Parallel()
+
+	observer := &observingLivestatePlugin{}
+	plugin, err := NewPlugin("1.0.0", WithLivestatePlugin(observer))
+	require.NoError(t, err)
+
+	store := newDeployTargetStore(map[string]*DeployTarget[ExampleDeployTargetConfig]{
+		"dt1": {Name: "dt1"},
+	})
+	registry := plugin.newDeployTargetRegistry(commonFields[ExampleConfig, ExampleDeployTargetConfig]{deployTargets: store, redactor: &redactor{}}, zaptest.NewLogger(t))
+	ctx := context.Background()
+
+	dt, err := registry.Add(ctx, "dt2", map[string]string{DeployTargetLabelGroup: "us"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "us", dt.Group)
+	got, ok := registry.Get("dt2")
+	assert.True(t, ok)
+	assert.Same(t, dt, got)
+	require.Len(t, observer.changes, 1)
+	assert.Equal(t, "dt2", observer.changes[0].Added[0].Name)
+
+	// The deploy target added at runtime can be updated.
+	_, err = registry.Add(ctx, "dt2", map[string]string{DeployTargetLabelGroup: "eu"}, json.RawMessage(`{}`))
+	require.NoError(t, err)
+	require.Len(t, observer.changes, 2)
+	assert.Equal(t, "dt2", observer.changes[1].Updated[0].Name)
+
+	// The invalid deploy targets are not added.
+	_, err = registry.Add(ctx, "", nil, nil)
+	assert.Error(t, err)
+	_, err = registry.Add(ctx, "dt3", nil, json.RawMessage(`"invalid"`))
+	assert.ErrorContains(t, err, "failed to unmarshal the config of the deploy target dt3")
+
+	// The deploy targets given by the plugin config can't be changed.
+	_, err = registry.Add(ctx, "dt1", nil, nil)
+	assert.EqualError(t, err, "deploy target dt1 is given by the plugin config")
+	assert.EqualError(t, registry.Remove(ctx, "dt1"), "deploy target dt1 is given by the plugin config")
+	assert.EqualError(t, registry.Remove(ctx, "unknown"), "deploy target unknown is not found")
+	assert.Len(t, observer.changes, 2)
+
+	// The deploy targets added at runtime are kept when the ones given by the plugin config are replaced.
+	_, err = registry.Add(ctx, "dt3", nil, nil)
+	require.NoError(t, err)
+	plugin.updateDeployTargets(ctx, store, map[string]*DeployTarget[ExampleDeployTargetConfig]{"dt4": {Name: "dt4"}}, zaptest.NewLogger(t))
+	assert.ElementsMatch(t, []string{"dt2", "dt3", "dt4"}, slices.Collect(maps.Keys(registry.List())))
+
+	// The plugin config takes over the deploy target of the same name.
+	plugin.updateDeployTargets(ctx, store, map[string]*DeployTarget[ExampleDeployTargetConfig]{"dt3": {Name: "dt3"}}, zaptest.NewLogger(t))
+	assert.ElementsMatch(t, []string{"dt2", "dt3"}, slices.Collect(maps.Keys(registry.List())))
+	assert.EqualError(t, registry.Remove(ctx, "dt3"), "deploy target dt3 is given by the plugin config")
+
+	require.NoError(t, registry.Remove(ctx, "dt2"))
+	last := observer.changes[len(observer.changes)-1]
+	assert.Equal(t, "dt2", last.Removed[0].Name)
+	_, ok = registry.Get("dt2")
+	assert.False(t, ok)
+}
+
+func TestLabelSelector(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		selector string
+		labels   map[string]string
+		expected bool
+	}{
+		{name: "empty selector", selector: "", labels: map[string]string{"region": "us-east"}, expected: true},
+		{name: "exact match", selector: "region=us-east", labels: map[string]string{"region": "us-east"}, expected: true},
+		{name: "double equals", selector: "region==us-east", labels: map[string]string{"region": "us-east"}, expected: true},
+		{name: "glob match", selector: "region=us-*", labels: map[string]string{"region": "us-west"}, expected: true},
+		{name: "glob mismatch", selector: "region=us-*", labels: map[string]string{"region": "eu-west"}, expected: false},
+		{name: "missing label", selector: "region=us-*", labels: nil, expected: false},
+		{name: "not equals", selector: "env!=dev", labels: map[string]string{"env": "prod"}, expected: true},
+		{name: "not equals without label", selector: "env!=dev", labels: nil, expected: true},
+		{name: "not equals mismatch", selector: "env!=dev", labels: map[string]string{"env": "dev"}, expected: false},
+		{name: "exists", selector: "canary", labels: map[string]string{"canary": ""}, expected: true},
+		{name: "not exists", selector: "!canary", labels: map[string]string{"canary": ""}, expected: false},
+		{name: "multiple requirements", selector: "region=us-*, env!=dev", labels: map[string]string{"region": "us-east", "env": "dev"}, expected: false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			selector, err := ParseLabelSelector(tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, selector.Matches(tc.labels))
+		})
+	}
+}
+
+func TestParseLabelSelector_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"=us", "region=[", "!"} {
+		_, err := ParseLabelSelector(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestLabelSelector_JSON(t *testing.T) {
+	t.Parallel()
+
+	var cfg struct {
+		FromString LabelSelector `json:"fromString"`
+		FromMap    LabelSelector `json:"fromMap"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"fromString":"region=us-*,!canary","fromMap":{"region":"us-*","env":"prod"}}`), &cfg))
+
+	assert.True(t, cfg.FromString.Matches(map[string]string{"region": "us-east"}))
+	// The values of the map must match exactly.
+	assert.False(t, cfg.FromMap.Matches(map[string]string{"region": "us-east", "env": "prod"}))
+	assert.True(t, cfg.FromMap.Matches(map[string]string{"region": "us-*", "env": "prod"}))
+
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fromString":"region=us-*,!canary","fromMap":"env=prod,region=us-\\*"}`, string(data))
+}
+
+func TestSelectDeployTargets(t *testing.T) {
+	t.Parallel()
+
+	dts := []*DeployTarget[testDeployTargetConfig]{
+		{Name: "us-2", Labels: map[string]string{"region": "us-west"}},
+		{Name: "eu-1", Labels: map[string]string{"region": "eu-west"}},
+		{Name: "us-1", Labels: map[string]string{"region": "us-east"}},
+	}
+	selector, err := ParseLabelSelector("region=us-*")
+	require.NoError(t, err)
+
+	selected := SelectDeployTargets(dts, selector)
+	if assert.Len(t, selected, 2) {
+		assert.Equal(t, "us-1", selected[0].Name)
+		assert.Equal(t, "us-2", selected[1].Name)
+	}
+}
+
+func TestParseDeployTargets_RolloutLabels(t *testing.T) {
+	t.Parallel()
+
+	dts, err := parseDeployTargets[testDeployTargetConfig]([]config.PipedDeployTarget{
+		{Name: "dt1", Labels: map[string]string{DeployTargetLabelGroup: "us", DeployTargetLabelOrder: "2", DeployTargetLabelWeight: "30"}, Config: []byte("{}")},
+		{Name: "dt2", Config: []byte("{}")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "us", dts["dt1"].Group)
+	assert.Equal(t, 2, dts["dt1"].Order)
+	assert.Equal(t, 30, dts["dt1"].Weight)
+	assert.Zero(t, dts["dt2"].Order)
+
+	for _, labels := range []map[string]string{
+		{DeployTargetLabelOrder: "first"},
+		{DeployTargetLabelWeight: "-1"},
+	} {
+		_, err := parseDeployTargets[testDeployTargetConfig]([]config.PipedDeployTarget{{Name: "dt", Labels: labels, Config: []byte("{}")}})
+		assert.Error(t, err, labels)
+	}
+}
+
+func TestDeployTargetWaves(t *testing.T) {
+	t.Parallel()
+
+	dts := []*DeployTarget[testDeployTargetConfig]{
+		{Name: "us-2", Group: "us", Order: 1},
+		{Name: "eu-1", Group: "eu", Order: 2},
+		{Name: "canary", Order: 0},
+		{Name: "us-1", Group: "us", Order: 1},
+		{Name: "ap-1", Group: "ap", Order: 1},
+	}
+
+	var got [][]string
+	for _, wave := range DeployTargetWaves(dts) {
+		got = append(got, names(wave))
+	}
+	assert.Equal(t, [][]string{{"canary"}, {"ap-1", "us-1", "us-2"}, {"eu-1"}}, got)
+	// The given deploy targets are not reordered.
+	assert.Equal(t, "us-2", dts[0].Name)
+
+	assert.Empty(t, DeployTargetWaves[testDeployTargetConfig](nil))
+}
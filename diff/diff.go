@@ -12,15 +12,19 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package diff provides the structural diffs of the manifests and the configs in YAML or JSON,
+// and the unified diffs of the texts, shared by the livestate drift detection, the plan preview and the deployment strategy.
 package diff
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
 )
 
 type differ struct {
@@ -108,6 +112,79 @@ func DiffUnstructureds(x, y unstructured.Unstructured, key string, opts ...Optio
 	return d.result, nil
 }
 
+// DiffStructureds calculates the diff between two values which can be encoded in JSON, e.g. structs or maps.
+// The values are compared in their JSON representation, so the paths are the JSON field names.
+// The paths ignored by WithIgnoreConfig are the ones under the empty key.
+func DiffStructureds(x, y any, opts ...Option) (*Result, error) {
+	vx, err := toJSONValue(x)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the first value: %w", err)
+	}
+	vy, err := toJSONValue(y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the second value: %w", err)
+	}
+	return diffJSONValues(vx, vy, opts...)
+}
+
+// DiffJSON calculates the diff between two JSON documents.
+func DiffJSON(x, y []byte, opts ...Option) (*Result, error) {
+	var vx, vy any
+	if err := json.Unmarshal(x, &vx); err != nil {
+		return nil, fmt.Errorf("failed to parse the first JSON: %w", err)
+	}
+	if err := json.Unmarshal(y, &vy); err != nil {
+		return nil, fmt.Errorf("failed to parse the second JSON: %w", err)
+	}
+	return diffJSONValues(vx, vy, opts...)
+}
+
+// DiffYAML calculates the diff between two YAML documents.
+// The documents are compared in their JSON representation, so the keys must be strings.
+func DiffYAML(x, y []byte, opts ...Option) (*Result, error) {
+	jx, err := yaml.YAMLToJSON(x)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the first YAML: %w", err)
+	}
+	jy, err := yaml.YAMLToJSON(y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the second YAML: %w", err)
+	}
+	return DiffJSON(jx, jy, opts...)
+}
+
+// toJSONValue converts the value into the generic value decoded from its JSON representation.
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func diffJSONValues(x, y any, opts ...Option) (*Result, error) {
+	d := &differ{result: &Result{}}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.initIgnoredPaths("")
+
+	// Both values are null.
+	if x == nil && y == nil {
+		return d.result, nil
+	}
+	if err := d.diff([]PathStep{}, reflect.ValueOf(x), reflect.ValueOf(y)); err != nil {
+		return nil, err
+	}
+
+	d.result.sort()
+	return d.result, nil
+}
+
 func (d *differ) diff(path []PathStep, vx, vy reflect.Value) error {
 	if !vx.IsValid() {
 		if d.equateEmpty && isEmptyInterface(vy) {
@@ -320,3 +320,97 @@ func TestIsEmptyInterface(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffYAML(t *testing.T) {
+	testcases := []struct {
+		name        string
+		x, y        string
+		opts        []Option
+		expected    []string
+		expectedErr bool
+	}{
+		{
+			name:     "no diff",
+			x:        "a: 1\nb: [x, y]\n",
+			y:        "b: [x, y]\na: 1.0\n",
+			expected: []string{},
+		},
+		{
+			name:     "changed, added and removed",
+			x:        "a: 1\nb:\n  c: x\nd: [1, 2]\n",
+			y:        "a: 2\nb:\n  e: x\nd: [1]\n",
+			expected: []string{"a", "b.c", "b.e", "d.1"},
+		},
+		{
+			name:     "ignored paths",
+			x:        "a: 1\nb: 2\n",
+			y:        "a: 2\nb: 3\n",
+			opts:     []Option{WithIgnoreConfig(map[string][]string{"": {"a"}})},
+			expected: []string{"b"},
+		},
+		{
+			name:     "scalar documents",
+			x:        "1",
+			y:        "2",
+			expected: []string{""},
+		},
+		{
+			name:        "invalid",
+			x:           "a: [",
+			y:           "a: 1",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := DiffYAML([]byte(tc.x), []byte(tc.y), tc.opts...)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			paths := make([]string, 0, result.NumNodes())
+			for _, n := range result.Nodes() {
+				paths = append(paths, n.PathString)
+			}
+			assert.Equal(t, tc.expected, paths)
+		})
+	}
+}
+
+func TestDiffStructureds(t *testing.T) {
+	type config struct {
+		Name    string            `json:"name"`
+		Labels  map[string]string `json:"labels,omitempty"`
+		Enabled bool              `json:"enabled"`
+	}
+
+	result, err := DiffStructureds(
+		config{Name: "app", Labels: map[string]string{"env": "dev"}},
+		config{Name: "app", Labels: map[string]string{"env": "prod"}, Enabled: true},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.NumNodes())
+	assert.Equal(t, "enabled", result.Nodes()[0].PathString)
+	assert.Equal(t, "labels.env", result.Nodes()[1].PathString)
+	assert.Equal(t, "dev", result.Nodes()[1].StringX())
+	assert.Equal(t, "prod", result.Nodes()[1].StringY())
+
+	result, err = DiffStructureds(nil, nil)
+	require.NoError(t, err)
+	assert.False(t, result.HasDiff())
+
+	_, err = DiffStructureds(func() {}, nil)
+	assert.Error(t, err)
+}
+
+func TestDiffJSON(t *testing.T) {
+	result, err := DiffJSON([]byte(`{"a": {"b": "x"}}`), []byte(`{"a": {"b": "y"}}`))
+	require.NoError(t, err)
+	require.Equal(t, 1, result.NumNodes())
+	assert.Equal(t, "a.b", result.Nodes()[0].PathString)
+
+	_, err = DiffJSON([]byte(`{`), []byte(`{}`))
+	assert.Error(t, err)
+}
@@ -25,6 +25,7 @@ import (
 type Renderer struct {
 	leftPadding    int
 	maskPathPrefix string
+	maskFunc       func(Node) bool
 }
 
 type RenderOption func(*Renderer)
@@ -43,6 +44,15 @@ func WithMaskPath(prefix string) RenderOption {
 	}
 }
 
+// WithMaskFunc configures the renderer to mask the values of the nodes for which the given function returns true,
+// e.g. the nodes of the secrets or the values matching credentials.
+// It's applied in addition to WithMaskPath.
+func WithMaskFunc(f func(Node) bool) RenderOption {
+	return func(r *Renderer) {
+		r.maskFunc = f
+	}
+}
+
 func NewRenderer(opts ...RenderOption) *Renderer {
 	r := &Renderer{}
 	for _, opt := range opts {
@@ -100,6 +110,11 @@ func (r *Renderer) Render(ns Nodes) string {
 		duplicateDepth := pathDuplicateDepth(n.Path, prePath)
 		prePath = n.Path
 		pathLen := len(n.Path)
+		if pathLen == 0 {
+			// The whole values differ, e.g. the compared documents are scalars.
+			r.renderRoot(&b, n)
+			continue
+		}
 
 		var array bool
 		for i := duplicateDepth; i < pathLen-1; i++ {
@@ -121,7 +136,7 @@ func (r *Renderer) Render(ns Nodes) string {
 
 		lastStep := n.Path[pathLen-1]
 		valueX, valueY := n.ValueX, n.ValueY
-		if r.maskPathPrefix != "" && strings.HasPrefix(n.PathString, r.maskPathPrefix) {
+		if r.masked(n) {
 			valueX = reflect.ValueOf(maskString)
 			valueY = reflect.ValueOf(maskString)
 		}
@@ -135,6 +150,34 @@ func (r *Renderer) Render(ns Nodes) string {
 	return b.String()
 }
 
+// renderRoot renders the node of the whole values.
+func (r *Renderer) renderRoot(b *strings.Builder, n Node) {
+	for _, v := range []struct {
+		mark  string
+		value reflect.Value
+	}{{"-", n.ValueX}, {"+", n.ValueY}} {
+		if !v.value.IsValid() {
+			continue
+		}
+		s := maskString
+		if !r.masked(n) {
+			s, _ = renderNodeValue(v.value, "")
+		}
+		for _, line := range strings.Split(s, "\n") {
+			fmt.Fprintf(b, "%s %*s%s\n", v.mark, r.leftPadding*2, "", line)
+		}
+	}
+	b.WriteString("\n")
+}
+
+// masked reports whether the values of the node should be masked.
+func (r *Renderer) masked(n Node) bool {
+	if r.maskPathPrefix != "" && strings.HasPrefix(n.PathString, r.maskPathPrefix) {
+		return true
+	}
+	return r.maskFunc != nil && r.maskFunc(n)
+}
+
 func pathDuplicateDepth(x, y []PathStep) int {
 	minLen := len(x)
 	if minLen > len(y) {
@@ -232,3 +232,27 @@ func TestRenderPrimitiveValue(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderer_MaskFunc(t *testing.T) {
+	result, err := DiffYAML([]byte("password: old\nuser: alice\n"), []byte("password: new\nuser: bob\n"))
+	require.NoError(t, err)
+
+	r := NewRenderer(WithMaskFunc(func(n Node) bool {
+		return n.PathString == "password"
+	}))
+	expected := `#password
+- password: *****
++ password: *****
+
+#user
+- user: alice
++ user: bob
+
+`
+	assert.Equal(t, expected, r.Render(result.Nodes()))
+
+	// The whole values are rendered when the documents are scalars.
+	result, err = DiffYAML([]byte("old"), []byte("new"))
+	require.NoError(t, err)
+	assert.Equal(t, "- old\n+ new\n\n", NewRenderer().Render(result.Nodes()))
+}
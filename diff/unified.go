@@ -0,0 +1,234 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// defaultContextLines is the number of the unchanged lines shown around the changes by default, same as diff -u.
+const defaultContextLines = 3
+
+type unifiedConfig struct {
+	contextLines int
+	nameX, nameY string
+}
+
+// UnifiedOption configures the unified diff.
+type UnifiedOption func(*unifiedConfig)
+
+// WithContextLines configures the number of the unchanged lines shown around the changes.
+func WithContextLines(n int) UnifiedOption {
+	return func(c *unifiedConfig) {
+		c.contextLines = max(n, 0)
+	}
+}
+
+// WithFileNames configures the names shown in the "---" and "+++" header lines.
+// The header lines are omitted when the names are not given.
+func WithFileNames(x, y string) UnifiedOption {
+	return func(c *unifiedConfig) {
+		c.nameX, c.nameY = x, y
+	}
+}
+
+// Unified returns the line-based diff between two texts in the unified format, e.g. for the plan preview.
+// It returns an empty string when the texts are the same.
+func Unified(x, y string, opts ...UnifiedOption) string {
+	if x == y {
+		return ""
+	}
+	c := &unifiedConfig{contextLines: defaultContextLines}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	a, b := splitLines(x), splitLines(y)
+	edits := diffLines(a, b)
+
+	var out strings.Builder
+	if c.nameX != "" || c.nameY != "" {
+		fmt.Fprintf(&out, "--- %s\n+++ %s\n", c.nameX, c.nameY)
+	}
+	for _, h := range hunks(edits, c.contextLines) {
+		writeHunk(&out, a, b, edits[h.start:h.end])
+	}
+	return out.String()
+}
+
+// splitLines splits the text into the lines keeping the line breaks,
+// so that the missing line break at the end is detected as a change.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+type editKind int
+
+const (
+	editEqual editKind = iota
+	editDelete
+	editInsert
+)
+
+// edit is a step to transform the first lines into the second ones.
+// x and y are the indexes of the lines in the first and the second ones before the step.
+type edit struct {
+	kind editKind
+	x, y int
+}
+
+// diffLines returns the shortest edit script between the lines with the Myers' algorithm.
+// It keeps only the reachable diagonals of each step, so the memory is O(D^2) for D changes.
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] holds v[k] for k in [-d, d] before the step d.
+	var trace [][]int
+
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack reconstructs the edit script from the trace of diffLines.
+func backtrack(trace [][]int, n, m int) []edit {
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		at := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{kind: editEqual, x: x, y: y})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{kind: editInsert, x: x, y: y})
+		} else {
+			x--
+			edits = append(edits, edit{kind: editDelete, x: x, y: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, edit{kind: editEqual, x: x, y: y})
+	}
+	slices.Reverse(edits)
+	return edits
+}
+
+// hunk is the range of the edits shown together.
+type hunk struct {
+	start, end int
+}
+
+// hunks groups the changes with the surrounding unchanged lines into the hunks.
+// The changes whose context lines overlap are merged into one hunk.
+func hunks(edits []edit, contextLines int) []hunk {
+	var result []hunk
+	for i, e := range edits {
+		if e.kind == editEqual {
+			continue
+		}
+		start, end := max(i-contextLines, 0), min(i+contextLines+1, len(edits))
+		if n := len(result); n > 0 && start <= result[n-1].end {
+			result[n-1].end = end
+			continue
+		}
+		result = append(result, hunk{start: start, end: end})
+	}
+	return result
+}
+
+// writeHunk writes the hunk of the edits in the unified format.
+func writeHunk(out *strings.Builder, a, b []string, edits []edit) {
+	var countX, countY int
+	for _, e := range edits {
+		if e.kind != editInsert {
+			countX++
+		}
+		if e.kind != editDelete {
+			countY++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(edits[0].x, countX), hunkRange(edits[0].y, countY))
+
+	for _, e := range edits {
+		switch e.kind {
+		case editEqual:
+			writeLine(out, " ", a[e.x])
+		case editDelete:
+			writeLine(out, "-", a[e.x])
+		case editInsert:
+			writeLine(out, "+", b[e.y])
+		}
+	}
+}
+
+// hunkRange returns the range of the lines in the hunk header, e.g. "1,3".
+// The start is the line before the hunk when the hunk has no lines, same as diff -u.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func writeLine(out *strings.Builder, mark, line string) {
+	out.WriteString(mark)
+	out.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		out.WriteString("\n\\ No newline at end of file\n")
+	}
+}
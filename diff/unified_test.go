@@ -0,0 +1,105 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnified(t *testing.T) {
+	testcases := []struct {
+		name     string
+		x, y     string
+		opts     []UnifiedOption
+		expected string
+	}{
+		{
+			name:     "same",
+			x:        "a\nb\n",
+			y:        "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "changed line with headers",
+			x:    "a\nb\nc\n",
+			y:    "a\nB\nc\n",
+			opts: []UnifiedOption{WithFileNames("old.yaml", "new.yaml")},
+			expected: `--- old.yaml
++++ new.yaml
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+		},
+		{
+			name: "separate hunks",
+			x:    "1\n2\n3\n4\n5\n6\n7\n8\n",
+			y:    "0\n2\n3\n4\n5\n6\n7\n9\n",
+			opts: []UnifiedOption{WithContextLines(1)},
+			expected: `@@ -1,2 +1,2 @@
+-1
++0
+ 2
+@@ -7,2 +7,2 @@
+ 7
+-8
++9
+`,
+		},
+		{
+			name: "merged hunks",
+			x:    "1\n2\n3\n4\n",
+			y:    "0\n2\n3\n5\n",
+			opts: []UnifiedOption{WithContextLines(1)},
+			expected: `@@ -1,4 +1,4 @@
+-1
++0
+ 2
+ 3
+-4
++5
+`,
+		},
+		{
+			name: "added to empty",
+			x:    "",
+			y:    "a\n",
+			expected: `@@ -0,0 +1 @@
++a
+`,
+		},
+		{
+			name: "missing line break at end",
+			x:    "a\nb\n",
+			y:    "a\nb",
+			expected: `@@ -1,2 +1,2 @@
+ a
+-b
++b
+\ No newline at end of file
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Unified(tc.x, tc.y, tc.opts...))
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package digest provides the SHA-256 digests to identify the artifacts, e.g. to report their versions,
+// to build the cache keys of the plans, and to detect that nothing has changed since the last deployment.
+//
+// The digests are formatted as "sha256:<hex>" in the same way as the container image digests.
+// The files and the readers are hashed by streaming, so the large artifacts are not loaded in memory.
+package digest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Algorithm is the name of the hash algorithm used for the digests.
+const Algorithm = "sha256"
+
+// shortLength is the number of the hex characters of the short form of the digest.
+const shortLength = 12
+
+// ErrInvalidDigest is returned when the given string is not a valid digest.
+var ErrInvalidDigest = errors.New("invalid digest")
+
+// Digest is the SHA-256 digest of a content.
+// The zero value represents no digest.
+type Digest [sha256.Size]byte
+
+// FromBytes returns the digest of the given bytes.
+func FromBytes(b []byte) Digest {
+	return sha256.Sum256(b)
+}
+
+// FromString returns the digest of the given string, e.g. the rendered manifests.
+func FromString(s string) Digest {
+	return sha256.Sum256([]byte(s))
+}
+
+// FromReader returns the digest of the content read from r until EOF.
+func FromReader(r io.Reader) (Digest, error) {
+	w := NewWriter()
+	if _, err := io.Copy(w, r); err != nil {
+		return Digest{}, err
+	}
+	return w.Digest(), nil
+}
+
+// File returns the digest of the content of the file at the path.
+func File(path string) (Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Digest{}, err
+	}
+	defer f.Close()
+
+	d, err := FromReader(f)
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Combine returns the digest of the given digests in the given order,
+// e.g. to build the cache key from the digests of the inputs.
+func Combine(digests ...Digest) Digest {
+	h := sha256.New()
+	for _, d := range digests {
+		h.Write(d[:])
+	}
+	return sum(h)
+}
+
+// Parse parses the digest in the form of "sha256:<hex>".
+func Parse(s string) (Digest, error) {
+	algo, encoded, ok := strings.Cut(s, ":")
+	if !ok || algo != Algorithm {
+		return Digest{}, fmt.Errorf("%w %q: it must be in the form of %s:<hex>", ErrInvalidDigest, s, Algorithm)
+	}
+	var d Digest
+	if len(encoded) != hex.EncodedLen(len(d)) {
+		return Digest{}, fmt.Errorf("%w %q: it must have %d hex characters", ErrInvalidDigest, s, hex.EncodedLen(len(d)))
+	}
+	if _, err := hex.Decode(d[:], []byte(encoded)); err != nil {
+		return Digest{}, fmt.Errorf("%w %q: %w", ErrInvalidDigest, s, err)
+	}
+	return d, nil
+}
+
+// String returns the digest in the form of "sha256:<hex>".
+func (d Digest) String() string {
+	return Algorithm + ":" + d.Hex()
+}
+
+// Hex returns the hex-encoded digest without the algorithm.
+func (d Digest) Hex() string {
+	return hex.EncodeToString(d[:])
+}
+
+// Short returns the first 12 hex characters of the digest, e.g. to show it as a version.
+func (d Digest) Short() string {
+	return d.Hex()[:shortLength]
+}
+
+// IsZero reports whether the digest is the zero value.
+func (d Digest) IsZero() bool {
+	return d == Digest{}
+}
+
+// MarshalText encodes the digest in the form of "sha256:<hex>".
+func (d Digest) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes the digest in the form of "sha256:<hex>".
+func (d *Digest) UnmarshalText(b []byte) error {
+	v, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// Writer computes the digest of the content written to it, e.g. by io.MultiWriter while writing the rendered output to a file.
+type Writer struct {
+	h hash.Hash
+	n int64
+}
+
+// NewWriter returns a new Writer.
+func NewWriter() *Writer {
+	return &Writer{h: sha256.New()}
+}
+
+// Write adds the content to the digest. It never returns an error.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return w.h.Write(p)
+}
+
+// Digest returns the digest of the content written so far.
+func (w *Writer) Digest() Digest {
+	return sum(w.h)
+}
+
+// Size returns the number of the bytes written so far.
+func (w *Writer) Size() int64 {
+	return w.n
+}
+
+type dirOptions struct {
+	exclude func(path string, d fs.DirEntry) bool
+}
+
+// DirOption configures how to compute the digest of a directory.
+type DirOption func(*dirOptions)
+
+// WithExclude excludes the files and the directories for which the given function returns true.
+// The path is relative to the root directory and slash-separated, e.g. ".git" or "charts/values.yaml".
+func WithExclude(exclude func(path string, d fs.DirEntry) bool) DirOption {
+	return func(o *dirOptions) {
+		o.exclude = exclude
+	}
+}
+
+// Dir returns the digest of the directory tree at the root.
+// It depends on the relative paths, the contents and the executable bits of the regular files,
+// and the targets of the symbolic links, which are not followed.
+// The directories themselves are not included, so the empty directories don't change the digest, same as in git.
+func Dir(root string, opts ...DirOption) (Digest, error) {
+	var o dirOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	h := sha256.New()
+	// WalkDir walks in the lexical order, so the digest doesn't depend on the order of the entries in the file system.
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if o.exclude != nil && o.exclude(rel, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch {
+		case d.IsDir():
+			return nil
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "l %s\x00%s\n", rel, filepath.ToSlash(target))
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			mode := "f"
+			if info.Mode().Perm()&0o111 != 0 {
+				mode = "x"
+			}
+			fd, err := File(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s %s\x00%s\n", mode, rel, fd.Hex())
+		}
+		// The other types such as the sockets are ignored.
+		return nil
+	})
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed to compute the digest of %s: %w", root, err)
+	}
+	return sum(h), nil
+}
+
+func sum(h hash.Hash) Digest {
+	var d Digest
+	h.Sum(d[:0])
+	return d
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helloDigest is the digest of "hello".
+const helloDigest = "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestDigest(t *testing.T) {
+	t.Parallel()
+
+	d := FromString("hello")
+	assert.Equal(t, helloDigest, d.String())
+	assert.Equal(t, strings.TrimPrefix(helloDigest, "sha256:"), d.Hex())
+	assert.Equal(t, "2cf24dba5fb0", d.Short())
+	assert.Equal(t, d, FromBytes([]byte("hello")))
+	assert.False(t, d.IsZero())
+	assert.True(t, Digest{}.IsZero())
+
+	r, err := FromReader(strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, d, r)
+
+	w := NewWriter()
+	w.Write([]byte("hel"))
+	w.Write([]byte("lo"))
+	assert.Equal(t, d, w.Digest())
+	assert.Equal(t, int64(5), w.Size())
+
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o644))
+	f, err := File(path)
+	require.NoError(t, err)
+	assert.Equal(t, d, f)
+
+	_, err = File(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestCombine(t *testing.T) {
+	t.Parallel()
+
+	a, b := FromString("a"), FromString("b")
+	assert.Equal(t, Combine(a, b), Combine(a, b))
+	assert.NotEqual(t, Combine(a, b), Combine(b, a))
+	assert.NotEqual(t, Combine(a), a)
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{input: helloDigest},
+		{input: strings.ToUpper(helloDigest[:7]) + helloDigest[7:], wantErr: true},
+		{input: strings.TrimPrefix(helloDigest, "sha256:"), wantErr: true},
+		{input: "md5:5d41402abc4b2a76b9719d911017c592", wantErr: true},
+		{input: helloDigest[:len(helloDigest)-1], wantErr: true},
+		{input: helloDigest[:len(helloDigest)-1] + "z", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			d, err := Parse(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidDigest)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.input, d.String())
+		})
+	}
+}
+
+func TestDigest_JSON(t *testing.T) {
+	t.Parallel()
+
+	type artifact struct {
+		Digest Digest `json:"digest"`
+	}
+	data, err := json.Marshal(artifact{Digest: FromString("hello")})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"digest":"`+helloDigest+`"}`, string(data))
+
+	var a artifact
+	require.NoError(t, json.Unmarshal(data, &a))
+	assert.Equal(t, FromString("hello"), a.Digest)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"digest":"invalid"}`), &a))
+}
+
+func TestDir(t *testing.T) {
+	t.Parallel()
+
+	build := func(t *testing.T, files map[string]string) string {
+		dir := t.TempDir()
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		}
+		return dir
+	}
+	digestOf := func(t *testing.T, dir string, opts ...DirOption) Digest {
+		d, err := Dir(dir, opts...)
+		require.NoError(t, err)
+		return d
+	}
+
+	files := map[string]string{
+		"app.pipecd.yaml":       "kind: Application",
+		"manifests/deploy.yaml": "kind: Deployment",
+	}
+	dir := build(t, files)
+	base := digestOf(t, dir)
+
+	// The same tree in another directory has the same digest.
+	assert.Equal(t, base, digestOf(t, build(t, files)))
+
+	// The empty directories don't matter.
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "empty"), 0o755))
+	assert.Equal(t, base, digestOf(t, dir))
+
+	// The content, the path and the executable bit matter.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifests/deploy.yaml"), []byte("kind: Service"), 0o644))
+	changed := digestOf(t, dir)
+	assert.NotEqual(t, base, changed)
+
+	require.NoError(t, os.Chmod(filepath.Join(dir, "manifests/deploy.yaml"), 0o755))
+	assert.NotEqual(t, changed, digestOf(t, dir))
+
+	moved := build(t, map[string]string{
+		"app.pipecd.yaml":      "kind: Application",
+		"manifest/deploy.yaml": "kind: Deployment",
+	})
+	assert.NotEqual(t, base, digestOf(t, moved))
+
+	// The symbolic links are hashed by their targets.
+	require.NoError(t, os.Symlink("app.pipecd.yaml", filepath.Join(moved, "link")))
+	withLink := digestOf(t, moved)
+	require.NoError(t, os.Remove(filepath.Join(moved, "link")))
+	require.NoError(t, os.Symlink("manifest/deploy.yaml", filepath.Join(moved, "link")))
+	assert.NotEqual(t, withLink, digestOf(t, moved))
+
+	// The excluded files don't matter.
+	withGit := build(t, map[string]string{
+		"app.pipecd.yaml":       "kind: Application",
+		"manifests/deploy.yaml": "kind: Deployment",
+		".git/HEAD":             "ref: refs/heads/main",
+	})
+	assert.NotEqual(t, base, digestOf(t, withGit))
+	assert.Equal(t, base, digestOf(t, withGit, WithExclude(func(path string, _ fs.DirEntry) bool {
+		return path == ".git"
+	})))
+
+	_, err := Dir(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
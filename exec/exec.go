@@ -0,0 +1,249 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package exec runs the commands of the tools from the stage plugins.
+// It resolves the binaries via the tool registry, stops the commands when the context is done,
+// streams their output to the stage logs line by line, and returns the errors with the tail of their stderr.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry"
+)
+
+const (
+	// defaultWaitDelay is the time to wait for the command to exit after it's interrupted by the context.
+	defaultWaitDelay = 10 * time.Second
+	// stderrTailSize is the size of the tail of stderr kept for the error.
+	stderrTailSize = 4 << 10
+)
+
+// Installer installs the tools and returns their paths.
+// It's implemented by *toolregistry.ToolRegistry.
+type Installer interface {
+	InstallTool(ctx context.Context, name, version, script string, opts ...toolregistry.InstallOption) (string, error)
+}
+
+// Tool is the tool installed by the tool registry.
+type Tool struct {
+	// Name is the name of the tool, e.g. "kubectl".
+	Name string
+	// Version is the version of the tool, e.g. "1.30.0".
+	Version string
+	// Script is the script to install the tool, same as the one given to ToolRegistry.InstallTool.
+	Script string
+}
+
+// Cmd is the command to run.
+type Cmd struct {
+	// Path is the path of the binary.
+	Path string
+	// Args are the arguments of the command.
+	Args []string
+	// Dir is the working directory of the command. It's the current directory of the plugin if empty.
+	Dir string
+	// Env is the environment variables added to the ones of the plugin, in the form of "KEY=value".
+	Env []string
+	// Stdin is the input of the command. It's empty if nil.
+	Stdin io.Reader
+	// Logs is where each line of the output is written to, e.g. the StageLogPersister of the stage.
+	// The output is discarded if nil.
+	Logs io.Writer
+	// WaitDelay is the time to wait for the command to exit after it's interrupted by the context,
+	// before it's killed. It defaults to 10 seconds.
+	WaitDelay time.Duration
+}
+
+// Command returns the command to run the binary at the path with the arguments.
+func Command(path string, args ...string) *Cmd {
+	return &Cmd{Path: path, Args: args}
+}
+
+// ToolCommand returns the command to run the tool with the arguments.
+// The tool is installed by the installer if it's not installed yet.
+func ToolCommand(ctx context.Context, installer Installer, tool Tool, args ...string) (*Cmd, error) {
+	path, err := installer.InstallTool(ctx, tool.Name, tool.Version, tool.Script)
+	if err != nil {
+		return nil, fmt.Errorf("failed to install %s %s: %w", tool.Name, tool.Version, err)
+	}
+	return Command(path, args...), nil
+}
+
+// Run runs the command and waits for it to complete.
+// The command is interrupted when the context is done, and killed if it doesn't exit within WaitDelay.
+// It returns *Error if the command fails.
+func (c *Cmd) Run(ctx context.Context) error {
+	return c.run(ctx, nil)
+}
+
+// Output runs the command and returns its stdout, e.g. to parse the output of "kubectl get -o json".
+// The stdout is not written to Logs, but the stderr is.
+func (c *Cmd) Output(ctx context.Context) ([]byte, error) {
+	var stdout bytes.Buffer
+	err := c.run(ctx, &stdout)
+	return stdout.Bytes(), err
+}
+
+func (c *Cmd) run(ctx context.Context, stdout io.Writer) error {
+	cmd := osexec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	cmd.Stdin = c.Stdin
+	cmd.Cancel = func() error {
+		// Let the tool clean up, e.g. release the state lock.
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = c.WaitDelay
+	if cmd.WaitDelay <= 0 {
+		cmd.WaitDelay = defaultWaitDelay
+	}
+
+	// Split stdout and stderr into lines separately, since they are written concurrently.
+	var mu sync.Mutex
+	stderrLogs := newLineWriter(c.Logs, &mu)
+	defer stderrLogs.Flush()
+	if stdout == nil {
+		stdoutLogs := newLineWriter(c.Logs, &mu)
+		defer stdoutLogs.Flush()
+		stdout = stdoutLogs
+	}
+	tail := &tailBuffer{size: stderrTailSize}
+	cmd.Stdout = stdout
+	cmd.Stderr = io.MultiWriter(stderrLogs, tail)
+
+	if err := cmd.Run(); err != nil {
+		e := &Error{
+			Path:     c.Path,
+			Args:     c.Args,
+			ExitCode: -1,
+			Stderr:   tail.String(),
+			Err:      err,
+		}
+		var exitErr *osexec.ExitError
+		if errors.As(err, &exitErr) {
+			e.ExitCode = exitErr.ExitCode()
+		}
+		// Report the reason why the command was interrupted instead of the signal.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			e.Err = ctxErr
+		}
+		return e
+	}
+	return nil
+}
+
+// Error is the error of the failed command.
+type Error struct {
+	// Path is the path of the binary.
+	Path string
+	// Args are the arguments of the command.
+	Args []string
+	// ExitCode is the exit code of the command. It's -1 when the command didn't exit by itself,
+	// e.g. when it failed to start or was interrupted by the context.
+	ExitCode int
+	// Stderr is the tail of the stderr of the command.
+	Stderr string
+	// Err is the underlying error, e.g. *os/exec.ExitError, or the error of the context when it's interrupted.
+	Err error
+}
+
+func (e *Error) Error() string {
+	name := strings.Join(append([]string{e.Path}, e.Args...), " ")
+	msg := fmt.Sprintf("command %q failed: %v", name, e.Err)
+	if e.ExitCode >= 0 {
+		msg = fmt.Sprintf("command %q exited with code %d", name, e.ExitCode)
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// lineWriter writes each line of a stream to the underlying writer with a separate call without the line break,
+// so that each line becomes a log block of the stage logs.
+// The writes to the underlying writer are serialized by the mutex shared between the streams.
+type lineWriter struct {
+	mu  *sync.Mutex
+	w   io.Writer
+	buf []byte
+}
+
+func newLineWriter(w io.Writer, mu *sync.Mutex) *lineWriter {
+	return &lineWriter{w: w, mu: mu}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if w.w == nil {
+		return len(p), nil
+	}
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(bytes.TrimSuffix(w.buf[:i], []byte{'\r'}))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes the last line not terminated by the line break.
+func (w *lineWriter) Flush() {
+	if w.w == nil || len(w.buf) == 0 {
+		return
+	}
+	w.writeLine(w.buf)
+	w.buf = nil
+}
+
+func (w *lineWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.w.Write(line)
+}
+
+// tailBuffer keeps the last bytes written to it up to the size.
+type tailBuffer struct {
+	size int
+	buf  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.size; over > 0 {
+		b.buf = b.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrytest"
+)
+
+func TestCmd_Run(t *testing.T) {
+	t.Parallel()
+
+	logs := logpersistertest.NewRecordingLogPersister(t)
+	cmd := Command("/bin/sh", "-c", `echo "$GREETING from $(pwd)"; printf 'out\r\n'; echo err >&2; printf last`)
+	cmd.Dir = t.TempDir()
+	cmd.Env = []string{"GREETING=hello"}
+	cmd.Logs = logs
+	require.NoError(t, cmd.Run(context.Background()))
+
+	lines := logs.Lines()
+	assert.Len(t, lines, 4)
+	assert.Contains(t, lines, "hello from "+cmd.Dir)
+	assert.Contains(t, lines, "out")
+	assert.Contains(t, lines, "err")
+	assert.Contains(t, lines, "last")
+}
+
+func TestCmd_Output(t *testing.T) {
+	t.Parallel()
+
+	logs := logpersistertest.NewRecordingLogPersister(t)
+	cmd := Command("/bin/sh", "-c", `cat; echo progress >&2`)
+	cmd.Stdin = strings.NewReader(`{"kind": "Pod"}`)
+	cmd.Logs = logs
+	out, err := cmd.Output(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, `{"kind": "Pod"}`, string(out))
+	// Only the stderr is written to the logs.
+	assert.Equal(t, []string{"progress"}, logs.Lines())
+}
+
+func TestCmd_Error(t *testing.T) {
+	t.Parallel()
+
+	err := Command("/bin/sh", "-c", `echo "first" >&2; echo "the resource is invalid" >&2; exit 3`).Run(context.Background())
+	var e *Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, 3, e.ExitCode)
+	assert.Equal(t, "first\nthe resource is invalid\n", e.Stderr)
+	assert.Equal(t, `command "/bin/sh -c echo \"first\" >&2; echo \"the resource is invalid\" >&2; exit 3" exited with code 3: first
+the resource is invalid`, err.Error())
+
+	// The stderr is kept up to the tail.
+	err = Command("/bin/sh", "-c", `head -c 10000 /dev/zero | tr '\0' x >&2; echo end >&2; exit 1`).Run(context.Background())
+	require.ErrorAs(t, err, &e)
+	assert.Len(t, e.Stderr, stderrTailSize)
+	assert.True(t, strings.HasSuffix(e.Stderr, "xend\n"))
+
+	err = Command("/not/found").Run(context.Background())
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, -1, e.ExitCode)
+}
+
+func TestCmd_Context(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The command is interrupted, and can clean up before exiting.
+	logs := logpersistertest.NewRecordingLogPersister(t)
+	cmd := Command("/bin/sh", "-c", `trap 'echo cleanup; exit 1' INT; while true; do sleep 0.01; done`)
+	cmd.Logs = logs
+	err := cmd.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []string{"cleanup"}, logs.Lines())
+
+	// The command ignoring the interruption is killed after the wait delay.
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmd = Command("/bin/sh", "-c", `trap '' INT; while true; do sleep 0.01; done`)
+	cmd.WaitDelay = 100 * time.Millisecond
+	err = cmd.Run(ctx)
+	var e *Error
+	require.ErrorAs(t, err, &e)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestToolCommand(t *testing.T) {
+	t.Parallel()
+
+	registry := toolregistrytest.NewTestToolRegistry(t)
+	tool := Tool{
+		Name:    "greet",
+		Version: "1.0.0",
+		Script:  `printf '#!/bin/sh\necho "greet {{ .Version }} $@"\n' > {{ .OutPath }}`,
+	}
+	cmd, err := ToolCommand(context.Background(), registry, tool, "world")
+	require.NoError(t, err)
+
+	out, err := cmd.Output(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "greet 1.0.0 world\n", string(out))
+
+	_, err = ToolCommand(context.Background(), registry, Tool{Name: "broken", Version: "1.0.0", Script: "exit 1"})
+	assert.ErrorContains(t, err, "failed to install broken 1.0.0")
+}
@@ -0,0 +1,305 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filelock provides the exclusive lock of a local file, so that the stages running concurrently,
+// including the ones in different plugin processes, can serialize their access to the shared local resources
+// such as state files and caches.
+//
+// The lock is an advisory lock taken by flock(2), so it's released by the OS even if the holder process crashes.
+// The lock file records its holder, and the lock held longer than the stale threshold is reported
+// to help finding the stages stuck with the lock.
+package filelock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/filelock/filelockmetrics"
+)
+
+const (
+	defaultPollInterval = 100 * time.Millisecond
+	defaultStaleAfter   = 10 * time.Minute
+)
+
+// Holder is the information of the holder of a lock recorded in the lock file.
+type Holder struct {
+	// PID is the process ID of the holder.
+	PID int `json:"pid"`
+	// Owner is the description of the holder given by WithOwner, e.g. the stage ID.
+	Owner string `json:"owner,omitempty"`
+	// AcquiredAt is the time when the lock was acquired.
+	AcquiredAt time.Time `json:"acquiredAt"`
+}
+
+// Lock is the acquired lock of a file.
+type Lock struct {
+	path       string
+	name       string
+	file       *os.File
+	clock      clock.Clock
+	acquiredAt time.Time
+
+	releaseOnce sync.Once
+	releaseErr  error
+}
+
+type options struct {
+	name         string
+	owner        string
+	pollInterval time.Duration
+	staleAfter   time.Duration
+	logger       *zap.Logger
+	clock        clock.Clock
+}
+
+// Option configures how to acquire the lock.
+type Option func(*options)
+
+// WithName sets the name of the lock used as the label of the metrics.
+// It defaults to the base name of the lock file.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithOwner sets the description of the holder recorded in the lock file, e.g. the stage ID.
+func WithOwner(owner string) Option {
+	return func(o *options) {
+		o.owner = owner
+	}
+}
+
+// WithPollInterval sets the interval to retry taking the lock while it's held by another. It defaults to 100ms.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = d
+	}
+}
+
+// WithStaleAfter sets the duration after which the lock held by another is reported as stale. It defaults to 10 minutes.
+// The stale lock is only reported by the log and the metrics, since the holder may still be working.
+// Set 0 to disable the detection.
+func WithStaleAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.staleAfter = d
+	}
+}
+
+// WithLogger sets the logger to report the stale lock.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithClock sets the clock used to wait and measure the lock. It's mainly for testing.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+func newOptions(path string, opts []Option) options {
+	o := options{
+		name:         filepath.Base(path),
+		pollInterval: defaultPollInterval,
+		staleAfter:   defaultStaleAfter,
+		logger:       zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.clock = clock.OrReal(o.clock)
+	if o.pollInterval <= 0 {
+		o.pollInterval = defaultPollInterval
+	}
+	return o
+}
+
+// Acquire acquires the lock of the file at the path, waiting until it's released by the current holder.
+// The file and its parent directories are created if they don't exist.
+// It returns the error of the context if the context is done before acquiring the lock.
+// The returned lock must be released by Release.
+func Acquire(ctx context.Context, path string, opts ...Option) (*Lock, error) {
+	o := newOptions(path, opts)
+	start := o.clock.Now()
+
+	f, err := openFile(path)
+	if err != nil {
+		filelockmetrics.Waited(o.name, filelockmetrics.ResultError, o.clock.Since(start))
+		return nil, err
+	}
+
+	reportedStale := false
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			filelockmetrics.Waited(o.name, filelockmetrics.ResultError, o.clock.Since(start))
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if ok {
+			break
+		}
+		if !reportedStale {
+			reportedStale = reportStale(f, path, o)
+		}
+
+		timer := o.clock.NewTimer(o.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			f.Close()
+			filelockmetrics.Waited(o.name, filelockmetrics.ResultCanceled, o.clock.Since(start))
+			return nil, fmt.Errorf("failed to acquire the lock %s: %w", path, ctx.Err())
+		case <-timer.C():
+		}
+	}
+	filelockmetrics.Waited(o.name, filelockmetrics.ResultAcquired, o.clock.Since(start))
+	return newLock(f, path, o), nil
+}
+
+// TryAcquire acquires the lock of the file at the path without waiting.
+// It returns false if the lock is held by another.
+func TryAcquire(path string, opts ...Option) (*Lock, bool, error) {
+	o := newOptions(path, opts)
+
+	f, err := openFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := tryLock(f)
+	if err != nil {
+		f.Close()
+		return nil, false, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	if !ok {
+		reportStale(f, path, o)
+		f.Close()
+		return nil, false, nil
+	}
+	filelockmetrics.Waited(o.name, filelockmetrics.ResultAcquired, 0)
+	return newLock(f, path, o), true, nil
+}
+
+// ReadHolder returns the holder recorded in the lock file at the path.
+// It returns false if the lock is not held, or the holder is unknown.
+func ReadHolder(path string) (Holder, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Holder{}, false
+	}
+	defer f.Close()
+	return readHolder(f)
+}
+
+// Path returns the path of the lock file.
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// Release releases the lock. It's safe to call it multiple times.
+func (l *Lock) Release() error {
+	l.releaseOnce.Do(func() {
+		filelockmetrics.Held(l.name, l.clock.Since(l.acquiredAt))
+		// Clear the holder before unlocking, so that the next holder is not reported with the stale information.
+		if err := l.file.Truncate(0); err != nil {
+			l.releaseErr = fmt.Errorf("failed to clear the holder of %s: %w", l.path, err)
+		}
+		if err := unlock(l.file); err != nil && l.releaseErr == nil {
+			l.releaseErr = fmt.Errorf("failed to unlock %s: %w", l.path, err)
+		}
+		if err := l.file.Close(); err != nil && l.releaseErr == nil {
+			l.releaseErr = fmt.Errorf("failed to close %s: %w", l.path, err)
+		}
+	})
+	return l.releaseErr
+}
+
+func openFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the lock file %s: %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the lock file %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func newLock(f *os.File, path string, o options) *Lock {
+	l := &Lock{
+		path:       path,
+		name:       o.name,
+		file:       f,
+		clock:      o.clock,
+		acquiredAt: o.clock.Now(),
+	}
+	// The holder is only informative, so the failure to record it doesn't fail the lock.
+	h := Holder{PID: os.Getpid(), Owner: o.owner, AcquiredAt: l.acquiredAt}
+	if b, err := json.Marshal(h); err == nil {
+		if err := f.Truncate(0); err == nil {
+			f.WriteAt(b, 0)
+		}
+	}
+	return l
+}
+
+// reportStale reports the lock if its holder has held it longer than the stale threshold.
+// It returns true if the lock was reported.
+func reportStale(f *os.File, path string, o options) bool {
+	if o.staleAfter <= 0 {
+		return false
+	}
+	h, ok := readHolder(f)
+	if !ok {
+		return false
+	}
+	held := o.clock.Since(h.AcquiredAt)
+	if held < o.staleAfter {
+		return false
+	}
+	filelockmetrics.DetectedStale(o.name)
+	o.logger.Warn("the lock has been held longer than expected, the holder may be stuck",
+		zap.String("path", path),
+		zap.Int("holder-pid", h.PID),
+		zap.String("holder-owner", h.Owner),
+		zap.Time("acquired-at", h.AcquiredAt),
+		zap.Duration("held", held),
+	)
+	return true
+}
+
+func readHolder(f *os.File) (Holder, bool) {
+	b, err := io.ReadAll(io.NewSectionReader(f, 0, 1<<20))
+	if err != nil || len(b) == 0 {
+		return Holder{}, false
+	}
+	var h Holder
+	if err := json.Unmarshal(b, &h); err != nil {
+		return Holder{}, false
+	}
+	return h, true
+}
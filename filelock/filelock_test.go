@@ -0,0 +1,148 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filelock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "locks", "state.lock")
+	l, err := Acquire(context.Background(), path, WithOwner("stage-1"))
+	require.NoError(t, err)
+	assert.Equal(t, path, l.Path())
+
+	h, ok := ReadHolder(path)
+	require.True(t, ok)
+	assert.Equal(t, os.Getpid(), h.PID)
+	assert.Equal(t, "stage-1", h.Owner)
+
+	// The lock is exclusive even in the same process.
+	_, ok, err = TryAcquire(path)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, l.Release())
+	require.NoError(t, l.Release())
+	_, ok = ReadHolder(path)
+	assert.False(t, ok)
+
+	l, ok, err = TryAcquire(path)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, l.Release())
+}
+
+func TestAcquire_Wait(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.lock")
+
+	var (
+		mu      sync.Mutex
+		holding int
+		wg      sync.WaitGroup
+	)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := Acquire(context.Background(), path, WithPollInterval(time.Millisecond))
+			if !assert.NoError(t, err) {
+				return
+			}
+			mu.Lock()
+			holding++
+			assert.Equal(t, 1, holding)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			holding--
+			mu.Unlock()
+			assert.NoError(t, l.Release())
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAcquire_Context(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.lock")
+	l, err := Acquire(context.Background(), path)
+	require.NoError(t, err)
+	defer l.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = Acquire(ctx, path, WithPollInterval(time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAcquire_Stale(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state.lock")
+	clk := clocktest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	l, err := Acquire(context.Background(), path, WithOwner("stuck-stage"), WithClock(clk))
+	require.NoError(t, err)
+	defer l.Release()
+
+	core, logs := observer.New(zap.WarnLevel)
+	opts := []Option{WithClock(clk), WithStaleAfter(time.Minute), WithLogger(zap.New(core))}
+
+	// The lock held shorter than the threshold is not reported.
+	_, ok, err := TryAcquire(path, opts...)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Zero(t, logs.Len())
+
+	clk.Advance(2 * time.Minute)
+	_, ok, err = TryAcquire(path, opts...)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, "stuck-stage", logs.All()[0].ContextMap()["holder-owner"])
+
+	// The stale lock is reported only once while waiting.
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := Acquire(ctx, path, opts...)
+		errCh <- err
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	clk.BlockUntil(1)
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+	assert.Equal(t, 2, logs.Len())
+}
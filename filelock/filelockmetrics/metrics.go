@@ -0,0 +1,88 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filelockmetrics provides the prometheus metrics of the file locks.
+package filelockmetrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	lockKey   = "lock"
+	resultKey = "result"
+)
+
+type Result string
+
+const (
+	ResultAcquired Result = "acquired"
+	ResultCanceled Result = "canceled"
+	ResultError    Result = "error"
+)
+
+var (
+	waitSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_file_lock_wait_seconds",
+			Help:    "Histogram of the seconds waited to acquire a file lock.",
+			Buckets: []float64{0.001, 0.01, 0.1, 1, 5, 10, 30, 60, 300, 600},
+		},
+		[]string{lockKey, resultKey},
+	)
+	holdSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_file_lock_hold_seconds",
+			Help:    "Histogram of the seconds a file lock was held.",
+			Buckets: []float64{0.01, 0.1, 1, 5, 10, 30, 60, 300, 600, 1800},
+		},
+		[]string{lockKey},
+	)
+	staleTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_file_lock_stale_total",
+			Help: "Total number of times a file lock was found held longer than the stale threshold.",
+		},
+		[]string{lockKey},
+	)
+)
+
+func Waited(lock string, r Result, d time.Duration) {
+	waitSeconds.With(prometheus.Labels{
+		lockKey:   lock,
+		resultKey: string(r),
+	}).Observe(d.Seconds())
+}
+
+func Held(lock string, d time.Duration) {
+	holdSeconds.With(prometheus.Labels{
+		lockKey: lock,
+	}).Observe(d.Seconds())
+}
+
+func DetectedStale(lock string) {
+	staleTotal.With(prometheus.Labels{
+		lockKey: lock,
+	}).Inc()
+}
+
+func Register(r prometheus.Registerer) {
+	r.MustRegister(
+		waitSeconds,
+		holdSeconds,
+		staleTotal,
+	)
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// Finalizer is an optional interface for the plugins to release the resources at shutdown,
+// e.g. to close the connections, flush the caches, or delete the cloud resources created in Initialize.
+type Finalizer interface {
+	// Shutdown is called once during the graceful shutdown, after the gRPC server stops accepting requests
+	// and the in-flight ones finish. The context is canceled when the grace period expires.
+	// It's called once even if the plugin is registered for multiple roles.
+	Shutdown(context.Context) error
+}
+
+// WithFinalizer is a function that appends the finalizer called at shutdown.
+// The finalizers are called in the reverse order of the initialization: the registered plugins implementing Finalizer,
+// then the ones added by WithFinalizer in the reverse order in which they are added.
+func WithFinalizer[Config, DeployTargetConfig, ApplicationConfigSpec any](finalizer Finalizer) PluginOption[Config, DeployTargetConfig, ApplicationConfigSpec] {
+	return func(plugin *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) {
+		plugin.finalizers = append(plugin.finalizers, finalizer)
+	}
+}
+
+// roleFinalizers returns the finalizers in the order they are called at shutdown.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) roleFinalizers() []Finalizer {
+	finalizers := slices.Clone(p.finalizers)
+	for _, plugin := range p.plugins() {
+		f, ok := plugin.(Finalizer)
+		if !ok {
+			continue
+		}
+		// Call the plugin registered for multiple roles only once. The non-comparable ones can't be the same.
+		if reflect.TypeOf(f).Comparable() && slices.ContainsFunc(finalizers, func(e Finalizer) bool {
+			return reflect.TypeOf(e).Comparable() && e == f
+		}) {
+			continue
+		}
+		finalizers = append(finalizers, f)
+	}
+	slices.Reverse(finalizers)
+	return finalizers
+}
+
+// finalize calls the finalizers in order, and returns the errors of them joined.
+// All finalizers are called even if some of them fail.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) finalize(ctx context.Context, logger *zap.Logger) error {
+	var errs []error
+	for _, f := range p.roleFinalizers() {
+		if err := f.Shutdown(ctx); err != nil {
+			logger.Error("failed to shut down the plugin", zap.String("finalizer", fmt.Sprintf("%T", f)), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%T: %w", f, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// shutdownContext returns the context for the finalizers, which is canceled when the grace period has passed
+// on the clock of the plugin since the given context is done. The returned function must be called to release the resources.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) shutdownContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	shutdownCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		timer := p.clock.NewTimer(p.gracePeriod)
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+			cancel()
+		case <-shutdownCtx.Done():
+		}
+	})
+	return shutdownCtx, func() {
+		stop()
+		cancel()
+	}
+}
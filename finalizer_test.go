@@ -0,0 +1,90 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+// finalizingPlugin records the order of the Shutdown calls.
+type finalizingPlugin struct {
+	mockLivestatePlugin
+	mockPlanPreviewPlugin
+	name  string
+	calls *[]string
+	err   error
+}
+
+func (p *finalizingPlugin) Shutdown(context.Context) error {
+	*p.calls = append(*p.calls, p.name)
+	return p.err
+}
+
+func TestPlugin_finalize(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	errFailed := errors.New("failed")
+	shared := &finalizingPlugin{name: "shared", calls: &calls, err: errFailed}
+	plugin, err := NewPlugin("1.0.0",
+		WithFinalizer[struct{}, struct{}, struct{}](&finalizingPlugin{name: "first", calls: &calls}),
+		WithFinalizer[struct{}, struct{}, struct{}](&finalizingPlugin{name: "second", calls: &calls}),
+		WithLivestatePlugin[struct{}, struct{}, struct{}](shared),
+		WithPlanPreviewPlugin[struct{}, struct{}, struct{}](shared),
+	)
+	require.NoError(t, err)
+
+	// The plugin registered for multiple roles is finalized once, and the failure doesn't stop the others.
+	err = plugin.finalize(context.Background(), zap.NewNop())
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, []string{"shared", "second", "first"}, calls)
+}
+
+func TestPlugin_shutdownContext(t *testing.T) {
+	t.Parallel()
+
+	clk := clocktest.NewFakeClock(time.Now())
+	plugin := &Plugin[struct{}, struct{}, struct{}]{clock: clk, gracePeriod: time.Minute}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	shutdownCtx, cancelShutdown := plugin.shutdownContext(ctx)
+	defer cancelShutdown()
+
+	// The shutdown context is alive while the plugin is running, and for the grace period on the clock after that.
+	cancel()
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute - time.Second)
+	assert.NoError(t, shutdownCtx.Err())
+	clk.Advance(time.Second)
+	select {
+	case <-shutdownCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the shutdown context was not canceled after the grace period")
+	}
+
+	// It's released by the returned function.
+	shutdownCtx, cancelShutdown = plugin.shutdownContext(context.Background())
+	cancelShutdown()
+	assert.Error(t, shutdownCtx.Err())
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+const checkKey = "check"
+
+var (
+	healthCheckStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "plugin_health_check_status",
+			Help: "The result of the last health check, 1 if healthy and 0 if not.",
+		},
+		[]string{checkKey},
+	)
+)
+
+// registerHealthMetrics registers the metrics of the health checks to the given registerer.
+func registerHealthMetrics(r prometheus.Registerer) {
+	r.MustRegister(healthCheckStatus)
+}
+
+// pipedConnectionHealthCheck is the name of the health check of the connection to piped, which is always registered.
+const pipedConnectionHealthCheck = "piped-connection"
+
+// HealthChecker checks whether a dependency of the plugin is healthy,
+// e.g. whether the API server of the deploy target is reachable, or whether the cloud credentials are valid.
+type HealthChecker interface {
+	// CheckHealth returns an error if the dependency is unhealthy.
+	// The context is canceled when the timeout of the health check expires.
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthCheckFunc is a function implementing HealthChecker.
+type HealthCheckFunc func(ctx context.Context) error
+
+// CheckHealth calls the function.
+func (f HealthCheckFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
+
+// WithHealthCheck is a function that adds the named health check served on the /healthz endpoint of the admin server
+// together with the connection to piped. The plugin is reported as unhealthy when any of the checks fails.
+// The checks are run in parallel on each request, so they should be cheap enough to be probed periodically.
+// The names must be unique, and "piped-connection" is reserved.
+func WithHealthCheck[Config, DeployTargetConfig, ApplicationConfigSpec any](name string, checker HealthChecker) PluginOption[Config, DeployTargetConfig, ApplicationConfigSpec] {
+	return func(plugin *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) {
+		plugin.healthChecks = append(plugin.healthChecks, namedHealthCheck{name: name, checker: checker})
+	}
+}
+
+type namedHealthCheck struct {
+	name    string
+	checker HealthChecker
+}
+
+// validateHealthChecks validates the names of the health checks are unique.
+func validateHealthChecks(checks []namedHealthCheck) error {
+	names := map[string]bool{pipedConnectionHealthCheck: true}
+	for _, c := range checks {
+		if c.name == "" {
+			return fmt.Errorf("the name of the health check is required")
+		}
+		if names[c.name] {
+			return fmt.Errorf("duplicated health check %s", c.name)
+		}
+		names[c.name] = true
+	}
+	return nil
+}
+
+// HealthReport is the result of the health checks served on the /healthz endpoint.
+type HealthReport struct {
+	// Healthy is true if all the checks have succeeded.
+	Healthy bool `json:"healthy"`
+	// Checks are the results of the checks in the order they are registered.
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+// HealthCheckResult is the result of a health check.
+type HealthCheckResult struct {
+	// Name is the name of the check.
+	Name string `json:"name"`
+	// Healthy is true if the check has succeeded.
+	Healthy bool `json:"healthy"`
+	// Error is the error of the failed check.
+	Error string `json:"error,omitempty"`
+	// Duration is how long the check took.
+	Duration time.Duration `json:"durationNanos"`
+}
+
+// healthHandler serves the results of the health checks.
+type healthHandler struct {
+	checks  []namedHealthCheck
+	timeout time.Duration
+	clock   clock.Clock
+	// redactor masks the sensitive values in the errors of the checks. It's set once the configs are loaded.
+	redactor atomic.Pointer[redactor]
+}
+
+func newHealthHandler(checks []namedHealthCheck, timeout time.Duration, clk clock.Clock) *healthHandler {
+	return &healthHandler{
+		checks:  checks,
+		timeout: timeout,
+		clock:   clock.OrReal(clk),
+	}
+}
+
+// check runs all the checks in parallel and returns their results.
+// The check not finished within the timeout is reported as unhealthy.
+func (h *healthHandler) check(ctx context.Context) HealthReport {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	report := HealthReport{Healthy: true, Checks: make([]HealthCheckResult, len(h.checks))}
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = h.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		status := 1.0
+		if !r.Healthy {
+			report.Healthy = false
+			status = 0
+		}
+		healthCheckStatus.With(prometheus.Labels{checkKey: r.Name}).Set(status)
+	}
+	return report
+}
+
+// run runs the check and waits for it until the context is done.
+func (h *healthHandler) run(ctx context.Context, c namedHealthCheck) HealthCheckResult {
+	start := h.clock.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.checker.CheckHealth(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("health check did not finish in time: %w", ctx.Err())
+	}
+
+	result := HealthCheckResult{Name: c.name, Healthy: err == nil, Duration: h.clock.Since(start)}
+	if err != nil {
+		result.Error = h.redactor.Load().redact(err.Error())
+	}
+	return result
+}
+
+// ServeHTTP responds the report of the health checks in JSON,
+// with the status 200 if all the checks have succeeded, otherwise 503.
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.check(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	t.Parallel()
+
+	healthy := HealthCheckFunc(func(context.Context) error { return nil })
+	failing := HealthCheckFunc(func(context.Context) error { return errors.New("invalid token secret-token") })
+	ignoring := HealthCheckFunc(func(context.Context) error {
+		time.Sleep(time.Hour)
+		return nil
+	})
+
+	testcases := []struct {
+		name       string
+		checks     []namedHealthCheck
+		wantStatus int
+		want       []HealthCheckResult
+	}{
+		{
+			name:       "all healthy",
+			checks:     []namedHealthCheck{{name: "a", checker: healthy}, {name: "b", checker: healthy}},
+			wantStatus: http.StatusOK,
+			want:       []HealthCheckResult{{Name: "a", Healthy: true}, {Name: "b", Healthy: true}},
+		},
+		{
+			name:       "the error is masked",
+			checks:     []namedHealthCheck{{name: "a", checker: healthy}, {name: "b", checker: failing}},
+			wantStatus: http.StatusServiceUnavailable,
+			want:       []HealthCheckResult{{Name: "a", Healthy: true}, {Name: "b", Error: "invalid token ******"}},
+		},
+		{
+			name:       "the check timed out",
+			checks:     []namedHealthCheck{{name: "a", checker: ignoring}},
+			wantStatus: http.StatusServiceUnavailable,
+			want:       []HealthCheckResult{{Name: "a", Error: "health check did not finish in time: context deadline exceeded"}},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := newHealthHandler(tc.checks, 50*time.Millisecond, nil)
+			r := &redactor{}
+			r.set([]string{"secret-token"})
+			h.redactor.Store(r)
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var report HealthReport
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+			assert.Equal(t, tc.wantStatus == http.StatusOK, report.Healthy)
+			for i := range report.Checks {
+				report.Checks[i].Duration = 0
+			}
+			assert.Equal(t, tc.want, report.Checks)
+		})
+	}
+}
+
+func TestNewPlugin_HealthChecks(t *testing.T) {
+	t.Parallel()
+
+	check := HealthCheckFunc(func(context.Context) error { return nil })
+
+	_, err := NewPlugin("1.0.0",
+		WithLivestatePlugin(&ExampleLivestatePlugin{}),
+		WithHealthCheck[ExampleConfig, ExampleDeployTargetConfig, ExampleApplicationConfigSpec]("kube-apiserver", check),
+	)
+	assert.NoError(t, err)
+
+	_, err = NewPlugin("1.0.0",
+		WithLivestatePlugin(&ExampleLivestatePlugin{}),
+		WithHealthCheck[ExampleConfig, ExampleDeployTargetConfig, ExampleApplicationConfigSpec]("kube-apiserver", check),
+		WithHealthCheck[ExampleConfig, ExampleDeployTargetConfig, ExampleApplicationConfigSpec]("kube-apiserver", check),
+	)
+	assert.EqualError(t, err, "duplicated health check kube-apiserver")
+
+	_, err = NewPlugin("1.0.0",
+		WithLivestatePlugin(&ExampleLivestatePlugin{}),
+		WithHealthCheck[ExampleConfig, ExampleDeployTargetConfig, ExampleApplicationConfigSpec](pipedConnectionHealthCheck, check),
+	)
+	assert.EqualError(t, err, "duplicated health check piped-connection")
+}
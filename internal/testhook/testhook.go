@@ -0,0 +1,81 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testhook is the bridge exposing the internals of the sdk package only to the sdktest package,
+// so that they don't have to be exported as the API of the SDK.
+// The sdk package sets the hooks when it's initialized.
+//
+// The hooks can't be generic, so the values of the generic types of the sdk package are given and returned as any.
+// The generic functions are instantiated with the type of the given value, e.g. *sdk.DeploymentSource[Spec] to be filled,
+// or the nil *sdk.Plugin[Config, DeployTargetConfig, ApplicationConfigSpec] given as pluginType.
+// The hooks panic when the values are of the wrong types.
+package testhook
+
+import (
+	"context"
+	"net"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/common"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry"
+)
+
+// StageLogPersisterProvider provides the StageLogPersister for each stage.
+type StageLogPersisterProvider interface {
+	StageLogPersister(deploymentID, stageID string) logpersister.StageLogPersister
+}
+
+var (
+	// NewClient creates the *sdk.Client calling the given piped service client.
+	// The clock is used to expire the caches and to poll the stage commands, and the real clock is used when it's nil.
+	NewClient func(base pipedservice.PluginServiceClient, pluginName, applicationID, deploymentID, stageID string, slp logpersister.StageLogPersister, tr *toolregistry.ToolRegistry, clk clock.Clock) any
+
+	// NewDeploymentSource converts the deployment source given by piped into dst, which is *sdk.DeploymentSource,
+	// in the same way as the plugin server does.
+	NewDeploymentSource func(dst any, pluginName string, source *common.DeploymentSource) error
+
+	// NewExecuteStageInput converts the request from piped into dst, which is *sdk.ExecuteStageInput,
+	// in the same way as the plugin server does. The client is *sdk.Client.
+	NewExecuteStageInput func(ctx context.Context, dst any, pluginName string, client any, request *deployment.ExecuteStageRequest, logger *zap.Logger) error
+
+	// InitDeployTargetRegistry initializes dst, which is *sdk.DeployTargetRegistry, with the given map[string]*sdk.DeployTarget
+	// as the deploy targets given by the plugin config. The changes are not notified to any plugin.
+	InitDeployTargetRegistry func(dst any, deployTargets any)
+
+	// ExecuteStage executes the stage of the sdk.StagePlugin in the same way as the plugin server does for the request from piped,
+	// including the conversion of the request and the response.
+	// The config is *Config, the deploy targets are []*sdk.DeployTarget, and the client is *sdk.Client.
+	ExecuteStage func(ctx context.Context, pluginType any, pluginName string, plugin, config, deployTargets, client any, request *deployment.ExecuteStageRequest, logger *zap.Logger) (*deployment.ExecuteStageResponse, error)
+
+	// GetLivestate gets the live state from the sdk.LivestatePlugin in the same way as the plugin server does for the request from piped,
+	// including the conversion of the request and the response.
+	// The config is *Config, the deploy targets are []*sdk.DeployTarget, and the client is *sdk.Client.
+	GetLivestate func(ctx context.Context, pluginType any, pluginName string, plugin, config, deployTargets, client any, request *livestate.GetLivestateRequest, logger *zap.Logger) (*livestate.GetLivestateResponse, error)
+
+	// Serve runs the *sdk.Plugin in the same way as the start command, serving the requests accepted by the given listener.
+	// The plugin calls the given piped service, and persists the stage logs through the given persister.
+	// The config is the piped plugin config in JSON or YAML.
+	// It blocks until the context is done, and then calls the finalizers after stopping the server.
+	Serve func(ctx context.Context, plugin any, lis net.Listener, service pipedservice.PluginServiceClient, persister StageLogPersisterProvider, config string, logger *zap.Logger) error
+
+	// Initializers returns the initializers of the *sdk.Plugin as []sdk.RoleInitializer in the order the SDK calls them at start.
+	Initializers func(plugin any) any
+)
@@ -16,6 +16,7 @@ package sdk
 
 import (
 	"context"
+	"maps"
 	"time"
 
 	"go.uber.org/zap"
@@ -48,7 +49,7 @@ type LivestatePluginServer[Config, DeployTargetConfig, ApplicationConfigSpec any
 
 // Register registers the plugin to the gRPC server.
 func (s *LivestatePluginServer[Config, DeployTargetConfig, ApplicationConfigSpec]) Register(server *grpc.Server) {
-	livestate.RegisterLivestateServiceServer(server, s)
+	livestate.RegisterLivestateServiceServer(s.registrar(server), s)
 }
 
 // GetLivestate returns the live state of the resources in the given application.
@@ -56,7 +57,7 @@ func (s *LivestatePluginServer[Config, DeployTargetConfig, ApplicationConfigSpec
 	// Get the deploy targets set on the deployment from the piped plugin config.
 	deployTargets := make([]*DeployTarget[DeployTargetConfig], 0, len(request.GetDeployTargets()))
 	for _, name := range request.GetDeployTargets() {
-		dt, ok := s.deployTargets[name]
+		dt, ok := s.deployTargets.get(name)
 		if !ok {
 			return nil, status.Errorf(codes.Internal, "the deploy target %s is not found in the piped plugin config", name)
 		}
@@ -69,28 +70,66 @@ func (s *LivestatePluginServer[Config, DeployTargetConfig, ApplicationConfigSpec
 		pluginName:    s.name,
 		applicationID: request.GetApplicationId(),
 		toolRegistry:  s.toolRegistry,
+		clock:         s.clock,
 	}
 
-	deploymentSource, err := newDeploymentSource[ApplicationConfigSpec](s.name, request.GetDeploySource())
+	release, err := s.livestatePool.acquire(ctx, request.GetApplicationId())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	response, err := getLivestate(ctx, s.name, s.base, s.currentPluginConfig(), deployTargets, client, request, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	fitResponse(s.logger, "GetLivestate", response, s.maxResponseSize, fitLivestateResponse)
+	return response, nil
+}
+
+func getLivestate[Config, DeployTargetConfig, ApplicationConfigSpec any](
+	ctx context.Context,
+	pluginName string,
+	plugin LivestatePlugin[Config, DeployTargetConfig, ApplicationConfigSpec],
+	config *Config,
+	deployTargets []*DeployTarget[DeployTargetConfig],
+	client *Client,
+	request *livestate.GetLivestateRequest,
+	logger *zap.Logger,
+) (*livestate.GetLivestateResponse, error) {
+	deploymentSource, err := newDeploymentSource[ApplicationConfigSpec](pluginName, request.GetDeploySource(), placeholders{
+		appID:         request.GetApplicationId(),
+		appName:       request.GetApplicationName(),
+		deployTargets: request.GetDeployTargets(),
+	})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to parse deployment source: %v", err)
 	}
 
-	response, err := s.base.GetLivestate(ctx, s.pluginConfig, deployTargets, &GetLivestateInput[ApplicationConfigSpec]{
+	deployTargets, err = overrideDeployTargets(deploymentSource.ApplicationConfig.Spec, deployTargets)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to apply the deploy target overrides: %v", err)
+	}
+
+	start := client.clockOrReal().Now()
+	response, err := plugin.GetLivestate(ctx, config, deployTargets, &GetLivestateInput[ApplicationConfigSpec]{
 		Request: GetLivestateRequest[ApplicationConfigSpec]{
-			PipedID:          request.GetPipedId(),
-			ApplicationID:    request.GetApplicationId(),
-			ApplicationName:  request.GetApplicationName(),
-			DeploymentSource: deploymentSource,
+			PipedID:           request.GetPipedId(),
+			ApplicationID:     request.GetApplicationId(),
+			ApplicationName:   request.GetApplicationName(),
+			ApplicationLabels: deploymentSource.ApplicationConfig.Labels(),
+			DeploymentSource:  deploymentSource,
 		},
-		Client: client,
-		Logger: s.logger,
+		Client:   client,
+		Logger:   withCorrelationID(ctx, logger),
+		Deadline: deadlineOf(ctx),
 	})
+	livestateGot(err, client.clockOrReal().Since(start))
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get the live state: %v", err)
 	}
 
-	return response.toModel(s.config.Name, time.Now()), nil
+	return response.toModel(pluginName, client.clockOrReal().Now()), nil
 }
 
 // GetLivestateInput is the input for the GetLivestate method.
@@ -101,6 +140,9 @@ type GetLivestateInput[ApplicationConfigSpec any] struct {
 	Client *Client
 	// Logger is the logger for logging.
 	Logger *zap.Logger
+	// Deadline is the time when the given context is canceled, so the plugin can bound its own operations by it.
+	// It's zero when the context has no deadline.
+	Deadline time.Time
 }
 
 // GetLivestateRequest is the request for the GetLivestate method.
@@ -111,6 +153,8 @@ type GetLivestateRequest[ApplicationConfigSpec any] struct {
 	ApplicationID string
 	// ApplicationName is the name of the application.
 	ApplicationName string
+	// ApplicationLabels are the labels of the application defined in the application config.
+	ApplicationLabels map[string]string
 	// DeploymentSource is the source of the deployment.
 	DeploymentSource DeploymentSource[ApplicationConfigSpec]
 }
@@ -183,6 +227,9 @@ func (s *ApplicationLiveState) toModel(pluginName string, now time.Time) *model.
 type ResourceState struct {
 	// ID is the unique identifier of the resource.
 	ID string
+	// Key is the provider-agnostic key of the resource.
+	// It's optional, and sent to piped in the ResourceMetadata with ResourceKeyMetadataKey if set.
+	Key ResourceKey
 	// ParentIDs is the list of the parent resource's IDs.
 	ParentIDs []string
 	// Name is the name of the resource.
@@ -208,7 +255,7 @@ func (s *ResourceState) toModel(pluginName string, now time.Time) *model.Resourc
 		ParentIds:         s.ParentIDs,
 		Name:              s.Name,
 		ResourceType:      s.ResourceType,
-		ResourceMetadata:  s.ResourceMetadata,
+		ResourceMetadata:  s.resourceMetadata(),
 		HealthStatus:      s.HealthStatus.toModel(),
 		HealthDescription: s.HealthDescription,
 		DeployTarget:      s.DeployTarget,
@@ -218,6 +265,17 @@ func (s *ResourceState) toModel(pluginName string, now time.Time) *model.Resourc
 	}
 }
 
+// resourceMetadata returns the metadata of the resource with the key if set.
+func (s *ResourceState) resourceMetadata() map[string]string {
+	if s.Key.IsZero() {
+		return s.ResourceMetadata
+	}
+	metadata := make(map[string]string, len(s.ResourceMetadata)+1)
+	maps.Copy(metadata, s.ResourceMetadata)
+	metadata[ResourceKeyMetadataKey] = s.Key.String()
+	return metadata
+}
+
 // ApplicationHealthStatus represents the health status of an application.
 type ApplicationHealthStatus int
 
@@ -242,6 +300,10 @@ func (s ApplicationHealthStatus) toModel() model.ApplicationLiveState_Status {
 	}
 }
 
+func (s ApplicationHealthStatus) String() string {
+	return s.toModel().String()
+}
+
 // ResourceHealthStatus represents the health status of a resource.
 type ResourceHealthStatus int
 
@@ -266,6 +328,10 @@ func (s ResourceHealthStatus) toModel() model.ResourceState_HealthStatus {
 	}
 }
 
+func (s ResourceHealthStatus) String() string {
+	return s.toModel().String()
+}
+
 // ApplicationSyncState represents the sync state of an application.
 type ApplicationSyncState struct {
 	// Status is the sync status of the application.
@@ -315,3 +381,7 @@ func (s ApplicationSyncStatus) toModel() model.ApplicationSyncStatus {
 		return model.ApplicationSyncStatus_UNKNOWN
 	}
 }
+
+func (s ApplicationSyncStatus) String() string {
+	return s.toModel().String()
+}
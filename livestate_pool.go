@@ -0,0 +1,193 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	livestateQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "plugin_livestate_queue_depth",
+			Help: "Number of the livestate requests waiting for the worker.",
+		},
+	)
+	livestateShedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "plugin_livestate_shed_total",
+			Help: "Total number of the livestate requests rejected since the queue was full.",
+		},
+	)
+)
+
+// registerLivestatePoolMetrics registers the metrics of the livestate worker pool to the given registerer.
+func registerLivestatePoolMetrics(r prometheus.Registerer) {
+	r.MustRegister(
+		livestateQueueDepth,
+		livestateShedTotal,
+	)
+}
+
+// livestatePool bounds the number of the livestate requests handled in parallel.
+// The requests exceeding the workers wait in the queue, and the workers take them from the applications in turn,
+// so that an application with many requests doesn't delay the others.
+// The requests exceeding the queue are rejected with Unavailable, so that piped retries them at the next interval.
+// A nil livestatePool doesn't limit anything.
+type livestatePool struct {
+	workers  int
+	maxQueue int
+
+	mu      sync.Mutex
+	running int
+	queued  int
+	// queues are the waiters by the application ID in the order of arrival.
+	queues map[string][]*livestateWaiter
+	// turns are the application IDs having the waiters in the order to take the next waiter from.
+	turns []string
+}
+
+type livestateWaiter struct {
+	// ready is closed when the waiter is given the worker.
+	ready chan struct{}
+}
+
+// newLivestatePool returns a livestatePool with the given workers and the queue size, or nil if the workers are not limited.
+func newLivestatePool(workers, maxQueue int) *livestatePool {
+	if workers <= 0 {
+		return nil
+	}
+	return &livestatePool{
+		workers:  workers,
+		maxQueue: max(maxQueue, 0),
+		queues:   make(map[string][]*livestateWaiter),
+	}
+}
+
+// acquire waits for a worker to handle the livestate request of the given application.
+// It returns the function to release the worker, which must be called after the request is handled.
+func (p *livestatePool) acquire(ctx context.Context, applicationID string) (func(), error) {
+	if p == nil {
+		return func() {}, nil
+	}
+
+	p.mu.Lock()
+	if p.running < p.workers && p.queued == 0 {
+		p.running++
+		p.mu.Unlock()
+		return p.release, nil
+	}
+	if p.queued >= p.maxQueue {
+		p.mu.Unlock()
+		livestateShedTotal.Inc()
+		return nil, status.Errorf(codes.Unavailable, "too many livestate requests are queued, retry later")
+	}
+	w := &livestateWaiter{ready: make(chan struct{})}
+	if len(p.queues[applicationID]) == 0 {
+		p.turns = append(p.turns, applicationID)
+	}
+	p.queues[applicationID] = append(p.queues[applicationID], w)
+	p.queued++
+	livestateQueueDepth.Inc()
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return p.release, nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-w.ready:
+		// The worker was given while canceling, then pass it to the next waiter.
+		p.handOver()
+	default:
+		p.remove(applicationID, w)
+	}
+	return nil, status.FromContextError(ctx.Err()).Err()
+}
+
+// load returns the number of the requests handled and waiting, and the number of the workers.
+func (p *livestatePool) load() (running, queued, capacity int) {
+	if p == nil {
+		return 0, 0, 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running, p.queued, p.workers
+}
+
+// release releases the worker, passing it to the next waiter if any.
+func (p *livestatePool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handOver()
+}
+
+// handOver passes the worker to the first waiter of the application in turn, or returns it to the pool if no one waits.
+// It must be called with the lock held.
+func (p *livestatePool) handOver() {
+	if len(p.turns) == 0 {
+		p.running--
+		return
+	}
+	applicationID := p.turns[0]
+	p.turns = p.turns[1:]
+	queue := p.queues[applicationID]
+	w := queue[0]
+	if len(queue) > 1 {
+		p.queues[applicationID] = queue[1:]
+		// The application waits for its next turn after the others.
+		p.turns = append(p.turns, applicationID)
+	} else {
+		delete(p.queues, applicationID)
+	}
+	p.queued--
+	livestateQueueDepth.Dec()
+	close(w.ready)
+}
+
+// remove removes the canceled waiter from the queue.
+// It must be called with the lock held.
+func (p *livestatePool) remove(applicationID string, w *livestateWaiter) {
+	queue := p.queues[applicationID]
+	for i, q := range queue {
+		if q != w {
+			continue
+		}
+		queue = append(queue[:i:i], queue[i+1:]...)
+		break
+	}
+	if len(queue) > 0 {
+		p.queues[applicationID] = queue
+	} else {
+		delete(p.queues, applicationID)
+		for i, id := range p.turns {
+			if id == applicationID {
+				p.turns = append(p.turns[:i:i], p.turns[i+1:]...)
+				break
+			}
+		}
+	}
+	p.queued--
+	livestateQueueDepth.Dec()
+}
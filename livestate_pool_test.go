@@ -0,0 +1,130 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// acquireQueued acquires the worker in a goroutine after the queued requests,
+// and returns the channel receiving the release function when it's given the worker.
+func acquireQueued(t *testing.T, ctx context.Context, p *livestatePool, applicationID string) (<-chan func(), <-chan error) {
+	t.Helper()
+
+	p.mu.Lock()
+	queued := p.queued
+	p.mu.Unlock()
+
+	released := make(chan func(), 1)
+	errCh := make(chan error, 1)
+	go func() {
+		release, err := p.acquire(ctx, applicationID)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		released <- release
+	}()
+	require.Eventually(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.queued == queued+1
+	}, time.Second, time.Millisecond)
+	return released, errCh
+}
+
+func TestNewLivestatePool_Unlimited(t *testing.T) {
+	t.Parallel()
+
+	p := newLivestatePool(0, 10)
+	assert.Nil(t, p)
+
+	release, err := p.acquire(context.Background(), "app")
+	require.NoError(t, err)
+	release()
+}
+
+func TestLivestatePool_Fairness(t *testing.T) {
+	t.Parallel()
+
+	p := newLivestatePool(1, 10)
+	release, err := p.acquire(context.Background(), "app-a")
+	require.NoError(t, err)
+
+	a2, _ := acquireQueued(t, context.Background(), p, "app-a")
+	a3, _ := acquireQueued(t, context.Background(), p, "app-a")
+	b1, _ := acquireQueued(t, context.Background(), p, "app-b")
+
+	// The applications take the worker in turn.
+	release()
+	release = <-a2
+	release()
+	release = <-b1
+	release()
+	release = <-a3
+	release()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	assert.Equal(t, 0, p.running)
+	assert.Equal(t, 0, p.queued)
+	assert.Empty(t, p.queues)
+	assert.Empty(t, p.turns)
+}
+
+func TestLivestatePool_Shed(t *testing.T) {
+	t.Parallel()
+
+	p := newLivestatePool(1, 1)
+	release, err := p.acquire(context.Background(), "app-a")
+	require.NoError(t, err)
+	queued, _ := acquireQueued(t, context.Background(), p, "app-b")
+
+	_, err = p.acquire(context.Background(), "app-c")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	release()
+	(<-queued)()
+}
+
+func TestLivestatePool_Canceled(t *testing.T) {
+	t.Parallel()
+
+	p := newLivestatePool(1, 10)
+	release, err := p.acquire(context.Background(), "app-a")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, canceled := acquireQueued(t, ctx, p, "app-b")
+	queued, _ := acquireQueued(t, context.Background(), p, "app-c")
+	cancel()
+	assert.Equal(t, codes.Canceled, status.Code(<-canceled))
+
+	// The canceled request is removed from the queue.
+	release()
+	(<-queued)()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	assert.Equal(t, 0, p.running)
+	assert.Empty(t, p.turns)
+}
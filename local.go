@@ -0,0 +1,415 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pipe-cd/pipecd/pkg/cli"
+	config "github.com/pipe-cd/pipecd/pkg/configv1"
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/common"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/planpreview"
+	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+	"github.com/pipe-cd/pipecd/pkg/rpc"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/yaml"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry"
+)
+
+// localRunID is the ID of the application, the deployment, and the stage in the local run.
+const localRunID = "local"
+
+// localRunOptions are the options of the run-local command.
+type localRunOptions struct {
+	appDir            string
+	runningAppDir     string
+	appConfigFilename string
+	stage             string
+	stageIndex        int
+	stageConfig       string
+	planPreview       bool
+	deployTargets     []string
+	sharedToolsDirs   []string
+}
+
+// runLocalCommand returns the cobra command to execute a stage or to get the plan preview without piped.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) runLocalCommand() *cobra.Command {
+	opts := localRunOptions{
+		appConfigFilename: model.DefaultApplicationConfigFilename,
+	}
+	cmd := &cobra.Command{
+		Use:   "run-local",
+		Short: "Execute a stage or get the plan preview against a local application directory without piped.",
+		Long: "Execute a stage or get the plan preview against a local application directory without piped.\n" +
+			"The stage logs and the plan preview results are printed to stdout, and the calls to piped are served in memory.\n" +
+			"Installing tools through piped is not supported, so place the tools as <name>-<version> in --shared-tools-dir.",
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = cli.WithContext(func(ctx context.Context, input cli.Input) error {
+		return p.runLocal(ctx, opts, cmd.OutOrStdout(), input.Logger)
+	})
+
+	cmd.Flags().StringVar(&p.config, "config", p.config, "The configuration for the plugin in JSON or YAML, or its location as a file:// or https:// URL.")
+	cmd.Flags().StringVar(&p.configTokenFile, "config-token-file", p.configTokenFile, "The path to the file containing the bearer token to fetch the configuration over https.")
+	cmd.Flags().StringVar(&opts.appDir, "app-dir", opts.appDir, "The directory of the application to deploy, containing the application config file.")
+	cmd.Flags().StringVar(&opts.runningAppDir, "running-app-dir", opts.runningAppDir, "The directory of the running application. If empty, it's run as the first deployment.")
+	cmd.Flags().StringVar(&opts.appConfigFilename, "app-config-filename", opts.appConfigFilename, "The filename of the application config in the application directories.")
+	cmd.Flags().StringVar(&opts.stage, "stage", opts.stage, "The name of the stage to execute.")
+	cmd.Flags().IntVar(&opts.stageIndex, "stage-index", opts.stageIndex, "The index of the stage to execute.")
+	cmd.Flags().StringVar(&opts.stageConfig, "stage-config", opts.stageConfig, "The config of the stage in JSON or YAML.")
+	cmd.Flags().BoolVar(&opts.planPreview, "plan-preview", opts.planPreview, "Whether to get the plan preview instead of executing a stage.")
+	cmd.Flags().StringSliceVar(&opts.deployTargets, "deploy-target", opts.deployTargets, "The names of the deploy targets to run on. If empty, all deploy targets in the configuration are used.")
+	cmd.Flags().StringSliceVar(&opts.sharedToolsDirs, "shared-tools-dir", opts.sharedToolsDirs, "The directories to look up the tools used by the plugin.")
+
+	cmd.MarkFlagRequired("config")
+	cmd.MarkFlagRequired("app-dir")
+	cmd.MarkFlagsMutuallyExclusive("stage", "plan-preview")
+	cmd.MarkFlagsOneRequired("stage", "plan-preview")
+
+	return cmd
+}
+
+// runLocal executes the stage or gets the plan preview of the options in the same way as the plugin server does,
+// serving the calls to piped in memory and writing the stage logs and the results into the given writer.
+// It returns an error when the stage doesn't succeed.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) runLocal(ctx context.Context, opts localRunOptions, out io.Writer, logger *zap.Logger) error {
+	source := pluginConfigSource{
+		value:     p.config,
+		tokenFile: p.configTokenFile,
+	}
+	rawConfig, err := source.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration: %w", err)
+	}
+	cfg, err := loadPluginConfig(rawConfig)
+	if err != nil {
+		return fmt.Errorf("failed to parse the configuration: %w", err)
+	}
+
+	client := &pluginServiceClient{PluginServiceClient: newLocalPluginService()}
+	toolRegistry := toolregistry.NewToolRegistry(client, toolregistry.WithSharedToolsDirs(opts.sharedToolsDirs...), toolregistry.WithClock(p.clock))
+	services, fields, err := p.newServices(ctx, cfg, client, &localLogPersister{out: out}, toolRegistry, logger)
+	if err != nil {
+		return err
+	}
+	defer p.finalize(context.WithoutCancel(ctx), fields.logger)
+
+	deployTargets := opts.deployTargets
+	if len(deployTargets) == 0 {
+		for _, dt := range cfg.DeployTargets {
+			deployTargets = append(deployTargets, dt.Name)
+		}
+	}
+	target, err := newLocalDeploymentSource(opts.appDir, opts.appConfigFilename)
+	if err != nil {
+		return err
+	}
+	var running *common.DeploymentSource
+	if opts.runningAppDir != "" {
+		if running, err = newLocalDeploymentSource(opts.runningAppDir, opts.appConfigFilename); err != nil {
+			return err
+		}
+	}
+
+	if opts.planPreview {
+		return runLocalPlanPreview(ctx, services, cfg, deployTargets, target, running, out)
+	}
+	return runLocalStage(ctx, services, cfg, opts, deployTargets, target, running, out)
+}
+
+func runLocalStage(ctx context.Context, services []rpc.Service, cfg *config.PipedPlugin, opts localRunOptions, deployTargets []string, target, running *common.DeploymentSource, out io.Writer) error {
+	var executor interface {
+		ExecuteStage(context.Context, *deployment.ExecuteStageRequest) (*deployment.ExecuteStageResponse, error)
+	}
+	for _, s := range services {
+		if e, ok := s.(interface {
+			ExecuteStage(context.Context, *deployment.ExecuteStageRequest) (*deployment.ExecuteStageResponse, error)
+		}); ok {
+			executor = e
+		}
+	}
+	if executor == nil {
+		return fmt.Errorf("the plugin doesn't execute stages")
+	}
+
+	var stageConfig []byte
+	if opts.stageConfig != "" {
+		var err error
+		if stageConfig, err = yaml.YAMLToJSON([]byte(opts.stageConfig)); err != nil {
+			return fmt.Errorf("failed to parse the stage config: %w", err)
+		}
+	}
+	now := time.Now().Unix()
+	resp, err := executor.ExecuteStage(ctx, &deployment.ExecuteStageRequest{
+		Input: &deployment.ExecutePluginInput{
+			Deployment: &model.Deployment{
+				Id:              localRunID,
+				ApplicationId:   localRunID,
+				ApplicationName: filepath.Base(opts.appDir),
+				DeployTargetsByPlugin: map[string]*model.DeployTargets{
+					cfg.Name: {DeployTargets: deployTargets},
+				},
+				Trigger: &model.DeploymentTrigger{
+					Commit:    &model.Commit{CreatedAt: now},
+					Timestamp: now,
+				},
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			Stage: &model.PipelineStage{
+				Id:        localRunID,
+				Name:      opts.stage,
+				Index:     int32(opts.stageIndex),
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			StageConfig:             stageConfig,
+			RunningDeploymentSource: running,
+			TargetDeploymentSource:  target,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Stage %s finished with %s\n", opts.stage, resp.GetStatus())
+	if resp.GetStatus() != model.StageStatus_STAGE_SUCCESS {
+		return fmt.Errorf("the stage %s finished with %s", opts.stage, resp.GetStatus())
+	}
+	return nil
+}
+
+func runLocalPlanPreview(ctx context.Context, services []rpc.Service, cfg *config.PipedPlugin, deployTargets []string, target, running *common.DeploymentSource, out io.Writer) error {
+	var previewer interface {
+		GetPlanPreview(context.Context, *planpreview.GetPlanPreviewRequest) (*planpreview.GetPlanPreviewResponse, error)
+	}
+	for _, s := range services {
+		if p, ok := s.(interface {
+			GetPlanPreview(context.Context, *planpreview.GetPlanPreviewRequest) (*planpreview.GetPlanPreviewResponse, error)
+		}); ok {
+			previewer = p
+		}
+	}
+	if previewer == nil {
+		return fmt.Errorf("the plugin doesn't provide the plan preview")
+	}
+
+	resp, err := previewer.GetPlanPreview(ctx, &planpreview.GetPlanPreviewRequest{
+		ApplicationId:           localRunID,
+		ApplicationName:         filepath.Base(target.GetApplicationDirectory()),
+		PipedId:                 localRunID,
+		DeployTargets:           deployTargets,
+		TargetDeploymentSource:  target,
+		RunningDeploymentSource: running,
+	})
+	if err != nil {
+		return err
+	}
+	for _, r := range resp.GetResults() {
+		fmt.Fprintf(out, "=== %s ===\n%s\n", r.GetDeployTarget(), r.GetSummary())
+		if details := r.GetDetails(); len(details) > 0 {
+			fmt.Fprintf(out, "%s\n", strings.TrimSuffix(string(details), "\n"))
+		}
+	}
+	return nil
+}
+
+// newLocalDeploymentSource returns the deployment source of the application in the local directory.
+func newLocalDeploymentSource(dir, filename string) (*common.DeploymentSource, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the application directory: %w", err)
+	}
+	cfg, err := os.ReadFile(filepath.Join(dir, filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the application config: %w", err)
+	}
+	return &common.DeploymentSource{
+		ApplicationDirectory:      dir,
+		ApplicationConfig:         cfg,
+		ApplicationConfigFilename: filename,
+	}, nil
+}
+
+// localLogPersister writes the stage logs into the writer, prefixed by their severities.
+type localLogPersister struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (p *localLogPersister) StageLogPersister(string, string) logpersister.StageLogPersister {
+	return localStageLogPersister{persister: p}
+}
+
+func (p *localLogPersister) write(log string, severity model.LogSeverity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prefix := "[" + strings.TrimPrefix(severity.String(), "LogSeverity_") + "] "
+	for _, line := range strings.Split(strings.TrimSuffix(log, "\n"), "\n") {
+		fmt.Fprintln(p.out, prefix+line)
+	}
+}
+
+type localStageLogPersister struct {
+	persister *localLogPersister
+}
+
+func (p localStageLogPersister) Write(log []byte) (int, error) {
+	p.persister.write(string(log), model.LogSeverity_INFO)
+	return len(log), nil
+}
+
+func (p localStageLogPersister) Info(log string) {
+	p.persister.write(log, model.LogSeverity_INFO)
+}
+
+func (p localStageLogPersister) Infof(format string, a ...interface{}) {
+	p.persister.write(fmt.Sprintf(format, a...), model.LogSeverity_INFO)
+}
+
+func (p localStageLogPersister) Success(log string) {
+	p.persister.write(log, model.LogSeverity_SUCCESS)
+}
+
+func (p localStageLogPersister) Successf(format string, a ...interface{}) {
+	p.persister.write(fmt.Sprintf(format, a...), model.LogSeverity_SUCCESS)
+}
+
+func (p localStageLogPersister) Error(log string) {
+	p.persister.write(log, model.LogSeverity_ERROR)
+}
+
+func (p localStageLogPersister) Errorf(format string, a ...interface{}) {
+	p.persister.write(fmt.Sprintf(format, a...), model.LogSeverity_ERROR)
+}
+
+func (p localStageLogPersister) Complete(time.Duration) error {
+	return nil
+}
+
+// localPluginService serves the calls to piped in memory for the local run.
+// It stores the metadata and the application shared objects of the single deployment,
+// and doesn't install the tools nor have the stage commands.
+type localPluginService struct {
+	mu             sync.Mutex
+	stageMetadata  map[string]string
+	pluginMetadata map[string]string
+	sharedObjects  map[string][]byte
+}
+
+func newLocalPluginService() *localPluginService {
+	return &localPluginService{
+		stageMetadata:  make(map[string]string),
+		pluginMetadata: make(map[string]string),
+		sharedObjects:  make(map[string][]byte),
+	}
+}
+
+func (s *localPluginService) InstallTool(_ context.Context, in *pipedservice.InstallToolRequest, _ ...grpc.CallOption) (*pipedservice.InstallToolResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "installing the tool %s %s is not supported in the local run, place it as %s-%s in --shared-tools-dir", in.GetName(), in.GetVersion(), in.GetName(), in.GetVersion())
+}
+
+func (s *localPluginService) ReportStageLogs(context.Context, *pipedservice.ReportStageLogsRequest, ...grpc.CallOption) (*pipedservice.ReportStageLogsResponse, error) {
+	return &pipedservice.ReportStageLogsResponse{}, nil
+}
+
+func (s *localPluginService) ReportStageLogsFromLastCheckpoint(context.Context, *pipedservice.ReportStageLogsFromLastCheckpointRequest, ...grpc.CallOption) (*pipedservice.ReportStageLogsFromLastCheckpointResponse, error) {
+	return &pipedservice.ReportStageLogsFromLastCheckpointResponse{}, nil
+}
+
+func (s *localPluginService) GetStageMetadata(_ context.Context, in *pipedservice.GetStageMetadataRequest, _ ...grpc.CallOption) (*pipedservice.GetStageMetadataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.stageMetadata[in.GetKey()]
+	return &pipedservice.GetStageMetadataResponse{Value: v, Found: ok}, nil
+}
+
+func (s *localPluginService) PutStageMetadata(_ context.Context, in *pipedservice.PutStageMetadataRequest, _ ...grpc.CallOption) (*pipedservice.PutStageMetadataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stageMetadata[in.GetKey()] = in.GetValue()
+	return &pipedservice.PutStageMetadataResponse{}, nil
+}
+
+func (s *localPluginService) PutStageMetadataMulti(_ context.Context, in *pipedservice.PutStageMetadataMultiRequest, _ ...grpc.CallOption) (*pipedservice.PutStageMetadataMultiResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range in.GetMetadata() {
+		s.stageMetadata[k] = v
+	}
+	return &pipedservice.PutStageMetadataMultiResponse{}, nil
+}
+
+func (s *localPluginService) GetDeploymentPluginMetadata(_ context.Context, in *pipedservice.GetDeploymentPluginMetadataRequest, _ ...grpc.CallOption) (*pipedservice.GetDeploymentPluginMetadataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.pluginMetadata[in.GetKey()]
+	return &pipedservice.GetDeploymentPluginMetadataResponse{Value: v, Found: ok}, nil
+}
+
+func (s *localPluginService) PutDeploymentPluginMetadata(_ context.Context, in *pipedservice.PutDeploymentPluginMetadataRequest, _ ...grpc.CallOption) (*pipedservice.PutDeploymentPluginMetadataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pluginMetadata[in.GetKey()] = in.GetValue()
+	return &pipedservice.PutDeploymentPluginMetadataResponse{}, nil
+}
+
+func (s *localPluginService) PutDeploymentPluginMetadataMulti(_ context.Context, in *pipedservice.PutDeploymentPluginMetadataMultiRequest, _ ...grpc.CallOption) (*pipedservice.PutDeploymentPluginMetadataMultiResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range in.GetMetadata() {
+		s.pluginMetadata[k] = v
+	}
+	return &pipedservice.PutDeploymentPluginMetadataMultiResponse{}, nil
+}
+
+func (s *localPluginService) GetDeploymentSharedMetadata(context.Context, *pipedservice.GetDeploymentSharedMetadataRequest, ...grpc.CallOption) (*pipedservice.GetDeploymentSharedMetadataResponse, error) {
+	return &pipedservice.GetDeploymentSharedMetadataResponse{}, nil
+}
+
+func (s *localPluginService) ListStageCommands(context.Context, *pipedservice.ListStageCommandsRequest, ...grpc.CallOption) (*pipedservice.ListStageCommandsResponse, error) {
+	return &pipedservice.ListStageCommandsResponse{}, nil
+}
+
+func (s *localPluginService) GetApplicationSharedObject(_ context.Context, in *pipedservice.GetApplicationSharedObjectRequest, _ ...grpc.CallOption) (*pipedservice.GetApplicationSharedObjectResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.sharedObjects[in.GetKey()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "application shared object %s is not found", in.GetKey())
+	}
+	return &pipedservice.GetApplicationSharedObjectResponse{Object: obj}, nil
+}
+
+func (s *localPluginService) PutApplicationSharedObject(_ context.Context, in *pipedservice.PutApplicationSharedObjectRequest, _ ...grpc.CallOption) (*pipedservice.PutApplicationSharedObjectResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sharedObjects[in.GetKey()] = in.GetObject()
+	return &pipedservice.PutApplicationSharedObjectResponse{}, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+)
+
+// localRunPlugin executes the stage and returns the plan preview of the application on the deploy targets.
+type localRunPlugin struct {
+	mockStagePlugin
+}
+
+type localRunStageConfig struct {
+	Fail bool `json:"fail"`
+}
+
+func (p *localRunPlugin) ExecuteStage(ctx context.Context, _ *struct{}, _ []*DeployTarget[struct{}], input *ExecuteStageInput[struct{}]) (*ExecuteStageResponse, error) {
+	stageConfig, err := DecodeStageConfig[localRunStageConfig](input.Request.StageConfig)
+	if err != nil {
+		return nil, err
+	}
+	lp, err := input.Client.StageLogPersister()
+	if err != nil {
+		return nil, err
+	}
+	lp.Infof("deploying %s", input.Request.Deployment.ApplicationName)
+	if err := input.Client.PutStageMetadata(ctx, "key", "value"); err != nil {
+		return nil, err
+	}
+	if v, _, err := input.Client.GetStageMetadata(ctx, "key"); err != nil || v != "value" {
+		return nil, fmt.Errorf("unexpected metadata %q: %v", v, err)
+	}
+	if stageConfig.Fail {
+		lp.Error("failed\nto deploy")
+		return &ExecuteStageResponse{Status: StageStatusFailure}, nil
+	}
+	lp.Success("done")
+	return &ExecuteStageResponse{Status: StageStatusSuccess}, nil
+}
+
+func (p *localRunPlugin) GetPlanPreview(_ context.Context, _ *struct{}, targets []*DeployTarget[struct{}], input *GetPlanPreviewInput[struct{}]) (*GetPlanPreviewResponse, error) {
+	resp := &GetPlanPreviewResponse{}
+	for _, dt := range targets {
+		resp.Results = append(resp.Results, PlanPreviewResult{
+			DeployTarget: dt.Name,
+			Summary:      "1 changed",
+			Details:      []byte("~ " + input.Request.ApplicationName + "\n"),
+		})
+	}
+	return resp, nil
+}
+
+func TestPlugin_runLocal(t *testing.T) {
+	t.Parallel()
+
+	appDir := filepath.Join(t.TempDir(), "app")
+	require.NoError(t, os.MkdirAll(appDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(appDir, "app.pipecd.yaml"), []byte("apiVersion: pipecd.dev/v1beta1\nkind: Application\nspec: {}\n"), 0o644))
+	const config = `{"name": "example", "port": 7001, "url": "file:///plugin", "deployTargets": [{"name": "dt1", "config": {}}, {"name": "dt2", "config": {}}]}`
+
+	tests := []struct {
+		name           string
+		opts           localRunOptions
+		expectedOutput string
+		expectErr      bool
+	}{
+		{
+			name: "stage",
+			opts: localRunOptions{appDir: appDir, stage: "stage1"},
+			expectedOutput: `[INFO] deploying app
+[SUCCESS] done
+Stage stage1 finished with STAGE_SUCCESS
+`,
+		},
+		{
+			name: "failed stage",
+			opts: localRunOptions{appDir: appDir, runningAppDir: appDir, stage: "stage1", stageConfig: "fail: true", deployTargets: []string{"dt2"}},
+			expectedOutput: `[INFO] deploying app
+[ERROR] failed
+[ERROR] to deploy
+Stage stage1 finished with STAGE_FAILURE
+`,
+			expectErr: true,
+		},
+		{
+			name: "plan preview on all deploy targets",
+			opts: localRunOptions{appDir: appDir, planPreview: true},
+			expectedOutput: `=== dt1 ===
+1 changed
+~ app
+=== dt2 ===
+1 changed
+~ app
+`,
+		},
+		{
+			name: "plan preview on the given deploy target",
+			opts: localRunOptions{appDir: appDir, planPreview: true, deployTargets: []string{"dt2"}},
+			expectedOutput: `=== dt2 ===
+1 changed
+~ app
+`,
+		},
+		{
+			name:      "missing application config",
+			opts:      localRunOptions{appDir: t.TempDir(), stage: "stage1"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			plugin := &localRunPlugin{}
+			p, err := NewPlugin("1.0.0",
+				WithStagePlugin[struct{}, struct{}, struct{}](plugin),
+				WithPlanPreviewPlugin[struct{}, struct{}, struct{}](plugin),
+			)
+			require.NoError(t, err)
+			p.config = config
+			if tt.opts.appConfigFilename == "" {
+				tt.opts.appConfigFilename = "app.pipecd.yaml"
+			}
+
+			var out bytes.Buffer
+			err = p.runLocal(context.Background(), tt.opts, &out, zaptest.NewLogger(t))
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedOutput, out.String())
+		})
+	}
+}
+
+func TestLocalPluginService_InstallTool(t *testing.T) {
+	t.Parallel()
+
+	_, err := newLocalPluginService().InstallTool(context.Background(), &pipedservice.InstallToolRequest{Name: "kubectl", Version: "1.30.0"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.ErrorContains(t, err, "kubectl-1.30.0")
+}
@@ -0,0 +1,249 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logpersistertest
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+)
+
+// Entry is a stage log recorded by RecordingLogPersister.
+type Entry struct {
+	Severity  model.LogSeverity
+	Log       string
+	CreatedAt time.Time
+}
+
+// Lines returns the lines of the log.
+func (e Entry) Lines() []string {
+	return strings.Split(strings.TrimSuffix(e.Log, "\n"), "\n")
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s %s", e.Severity, e.Log)
+}
+
+// Matcher matches a recorded stage log.
+type Matcher struct {
+	description string
+	match       func(Entry) bool
+}
+
+func (m Matcher) String() string {
+	return m.description
+}
+
+// Line returns the Matcher matching the log having the given line.
+func Line(line string) Matcher {
+	return Matcher{
+		description: fmt.Sprintf("line %q", line),
+		match: func(e Entry) bool {
+			return slices.Contains(e.Lines(), line)
+		},
+	}
+}
+
+// Contains returns the Matcher matching the log containing the given substring.
+func Contains(substr string) Matcher {
+	return Matcher{
+		description: fmt.Sprintf("containing %q", substr),
+		match: func(e Entry) bool {
+			return strings.Contains(e.Log, substr)
+		},
+	}
+}
+
+// Regexp returns the Matcher matching the log matching the given regular expression.
+func Regexp(pattern string) Matcher {
+	re := regexp.MustCompile(pattern)
+	return Matcher{
+		description: fmt.Sprintf("matching %q", pattern),
+		match:       func(e Entry) bool { return re.MatchString(e.Log) },
+	}
+}
+
+// WithSeverity returns the Matcher matching the log of the given severity matched by m.
+func WithSeverity(severity model.LogSeverity, m Matcher) Matcher {
+	return Matcher{
+		description: fmt.Sprintf("%s %s", severity, m.description),
+		match: func(e Entry) bool {
+			return e.Severity == severity && m.match(e)
+		},
+	}
+}
+
+// Option configures the RecordingLogPersister.
+type Option func(*RecordingLogPersister)
+
+// WithClock sets the clock used to timestamp the recorded logs.
+// It's useful to assert on Entry.CreatedAt with a fake clock.
+func WithClock(c clock.Clock) Option {
+	return func(lp *RecordingLogPersister) {
+		lp.clock = clock.OrReal(c)
+	}
+}
+
+// NewRecordingLogPersister creates a new RecordingLogPersister for testing.
+func NewRecordingLogPersister(t testing.TB, opts ...Option) *RecordingLogPersister {
+	lp := &RecordingLogPersister{t: t, clock: clock.Real}
+	for _, opt := range opts {
+		opt(lp)
+	}
+	return lp
+}
+
+// RecordingLogPersister implements logpersister recording the stage logs,
+// so that the tests can verify the logs shown to the users.
+// The assertion methods report the failures to the testing.TB given to NewRecordingLogPersister,
+// and return whether the assertions succeeded.
+type RecordingLogPersister struct {
+	t     testing.TB
+	clock clock.Clock
+
+	mu        sync.Mutex
+	entries   []Entry
+	completed bool
+}
+
+func (lp *RecordingLogPersister) StageLogPersister(deploymentID, stageID string) logpersister.StageLogPersister {
+	return lp
+}
+
+func (lp *RecordingLogPersister) record(log string, severity model.LogSeverity) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	lp.entries = append(lp.entries, Entry{Severity: severity, Log: log, CreatedAt: lp.clock.Now()})
+}
+
+func (lp *RecordingLogPersister) Write(log []byte) (int, error) {
+	lp.record(string(log), model.LogSeverity_INFO)
+	return len(log), nil
+}
+func (lp *RecordingLogPersister) Info(log string) {
+	lp.record(log, model.LogSeverity_INFO)
+}
+func (lp *RecordingLogPersister) Infof(format string, a ...interface{}) {
+	lp.record(fmt.Sprintf(format, a...), model.LogSeverity_INFO)
+}
+func (lp *RecordingLogPersister) Success(log string) {
+	lp.record(log, model.LogSeverity_SUCCESS)
+}
+func (lp *RecordingLogPersister) Successf(format string, a ...interface{}) {
+	lp.record(fmt.Sprintf(format, a...), model.LogSeverity_SUCCESS)
+}
+func (lp *RecordingLogPersister) Error(log string) {
+	lp.record(log, model.LogSeverity_ERROR)
+}
+func (lp *RecordingLogPersister) Errorf(format string, a ...interface{}) {
+	lp.record(fmt.Sprintf(format, a...), model.LogSeverity_ERROR)
+}
+func (lp *RecordingLogPersister) Complete(timeout time.Duration) error {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	lp.completed = true
+	return nil
+}
+
+// Entries returns the recorded logs in order.
+func (lp *RecordingLogPersister) Entries() []Entry {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	return slices.Clone(lp.entries)
+}
+
+// Lines returns the lines of the recorded logs in order.
+func (lp *RecordingLogPersister) Lines() []string {
+	var lines []string
+	for _, e := range lp.Entries() {
+		lines = append(lines, e.Lines()...)
+	}
+	return lines
+}
+
+// Completed returns true if Complete has been called.
+func (lp *RecordingLogPersister) Completed() bool {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	return lp.completed
+}
+
+// ContainsLine asserts that the given line has been logged.
+func (lp *RecordingLogPersister) ContainsLine(line string) bool {
+	lp.t.Helper()
+	return lp.Contains(Line(line))
+}
+
+// Contains asserts that a log matched by the given Matcher has been logged.
+func (lp *RecordingLogPersister) Contains(m Matcher) bool {
+	lp.t.Helper()
+	entries := lp.Entries()
+	if slices.ContainsFunc(entries, m.match) {
+		return true
+	}
+	lp.t.Errorf("no stage log %s in:\n%s", m, formatEntries(entries))
+	return false
+}
+
+// NoErrors asserts that no log of the ERROR severity has been logged.
+func (lp *RecordingLogPersister) NoErrors() bool {
+	lp.t.Helper()
+	var errs []Entry
+	for _, e := range lp.Entries() {
+		if e.Severity == model.LogSeverity_ERROR {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) == 0 {
+		return true
+	}
+	lp.t.Errorf("unexpected error stage logs:\n%s", formatEntries(errs))
+	return false
+}
+
+// ContainsInOrder asserts that the logs matched by the given Matchers have been logged in the order.
+// The other logs may be logged between them.
+func (lp *RecordingLogPersister) ContainsInOrder(matchers ...Matcher) bool {
+	lp.t.Helper()
+	entries := lp.Entries()
+	i := 0
+	for _, e := range entries {
+		if i < len(matchers) && matchers[i].match(e) {
+			i++
+		}
+	}
+	if i == len(matchers) {
+		return true
+	}
+	lp.t.Errorf("no stage log %s after the ones matching %v in:\n%s", matchers[i], matchers[:i], formatEntries(entries))
+	return false
+}
+
+func formatEntries(entries []Entry) string {
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "  %s\n", e)
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logpersistertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+// fakeT is a testing.TB recording the failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(string, ...any) {
+	t.failed = true
+}
+
+func TestRecordingLogPersister(t *testing.T) {
+	t.Parallel()
+
+	newPersister := func(t *testing.T) (*RecordingLogPersister, *fakeT) {
+		ft := &fakeT{TB: t}
+		lp := NewRecordingLogPersister(ft)
+		slp := lp.StageLogPersister("deployment", "stage")
+		slp.Info("start")
+		slp.Write([]byte("applying\napplied\n"))
+		slp.Successf("done in %ds", 3)
+		return lp, ft
+	}
+
+	tests := []struct {
+		name     string
+		assert   func(lp *RecordingLogPersister) bool
+		expected bool
+	}{
+		{
+			name:     "contains line",
+			assert:   func(lp *RecordingLogPersister) bool { return lp.ContainsLine("applied") },
+			expected: true,
+		},
+		{
+			name:     "does not contain line",
+			assert:   func(lp *RecordingLogPersister) bool { return lp.ContainsLine("apply") },
+			expected: false,
+		},
+		{
+			name: "contains with severity",
+			assert: func(lp *RecordingLogPersister) bool {
+				return lp.Contains(WithSeverity(model.LogSeverity_SUCCESS, Regexp(`done in \d+s`)))
+			},
+			expected: true,
+		},
+		{
+			name: "does not contain with severity",
+			assert: func(lp *RecordingLogPersister) bool {
+				return lp.Contains(WithSeverity(model.LogSeverity_ERROR, Contains("done")))
+			},
+			expected: false,
+		},
+		{
+			name:     "no errors",
+			assert:   func(lp *RecordingLogPersister) bool { return lp.NoErrors() },
+			expected: true,
+		},
+		{
+			name:     "in order",
+			assert:   func(lp *RecordingLogPersister) bool { return lp.ContainsInOrder(Line("start"), Contains("done")) },
+			expected: true,
+		},
+		{
+			name:     "out of order",
+			assert:   func(lp *RecordingLogPersister) bool { return lp.ContainsInOrder(Contains("done"), Line("start")) },
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lp, ft := newPersister(t)
+			assert.Equal(t, tt.expected, tt.assert(lp))
+			assert.Equal(t, !tt.expected, ft.failed)
+		})
+	}
+}
+
+func TestRecordingLogPersister_Errors(t *testing.T) {
+	t.Parallel()
+
+	ft := &fakeT{TB: t}
+	lp := NewRecordingLogPersister(ft)
+	lp.Errorf("failed: %s", "timeout")
+	assert.NoError(t, lp.Complete(0))
+
+	assert.False(t, lp.NoErrors())
+	assert.True(t, ft.failed)
+	assert.True(t, lp.Completed())
+	assert.Equal(t, []string{"failed: timeout"}, lp.Lines())
+	assert.Equal(t, model.LogSeverity_ERROR, lp.Entries()[0].Severity)
+}
+
+func TestRecordingLogPersister_Clock(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clocktest.NewFakeClock(now)
+	lp := NewRecordingLogPersister(t, WithClock(clk))
+	lp.Info("first")
+	clk.Advance(time.Second)
+	lp.Info("second")
+
+	entries := lp.Entries()
+	assert.Equal(t, now, entries[0].CreatedAt)
+	assert.Equal(t, now.Add(time.Second), entries[1].CreatedAt)
+}
@@ -28,6 +28,8 @@ import (
 
 	"github.com/pipe-cd/pipecd/pkg/model"
 	service "github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
 )
 
 type apiClient interface {
@@ -68,31 +70,56 @@ type persister struct {
 	checkpointFlushInterval time.Duration
 	stalePeriod             time.Duration
 	gracePeriod             time.Duration
+	clock                   clock.Clock
 	logger                  *zap.Logger
 }
 
+// Option configures the persister.
+type Option func(*persister)
+
+// WithClock sets the clock used to flush the logs periodically and to timestamp the log blocks.
+// It's useful to control the time in tests.
+func WithClock(c clock.Clock) Option {
+	return func(p *persister) {
+		p.clock = clock.OrReal(c)
+	}
+}
+
+// WithFlushInterval sets the interval to flush the logs of the stages.
+// The completed stages wait for the next flush, so it's useful to shorten it in tests and benchmarks.
+func WithFlushInterval(d time.Duration) Option {
+	return func(p *persister) {
+		p.flushInterval = d
+	}
+}
+
 // NewPersister creates a new persister instance for saving the stage logs into server's storage.
 // This controls how many concurent api calls should be executed and when to flush the logs.
-func NewPersister(apiClient apiClient, logger *zap.Logger) *persister {
-	return &persister{
+func NewPersister(apiClient apiClient, logger *zap.Logger, opts ...Option) *persister {
+	p := &persister{
 		apiClient:               apiClient,
 		flushInterval:           5 * time.Second,
 		checkpointFlushInterval: 2 * time.Minute,
 		stalePeriod:             time.Minute,
 		gracePeriod:             30 * time.Second,
+		clock:                   clock.Real,
 		logger:                  logger.Named("log-persister"),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // Run starts running workers to flush logs to server.
 func (p *persister) Run(ctx context.Context) error {
 	p.logger.Info("start running log persister")
-	ticker := time.NewTicker(p.flushInterval)
+	ticker := p.clock.NewTicker(p.flushInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			p.flush(ctx)
 
 		case <-ctx.Done():
@@ -123,7 +150,7 @@ func (p *persister) StageLogPersister(deploymentID, stageID string) StageLogPers
 	)
 	sp := &stageLogPersister{
 		key:                     k,
-		curLogIndex:             time.Now().Unix(),
+		curLogIndex:             p.clock.Now().Unix(),
 		doneCh:                  make(chan struct{}),
 		checkpointFlushInterval: p.checkpointFlushInterval,
 		persister:               p,
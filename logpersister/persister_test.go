@@ -16,7 +16,10 @@ package logpersister
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -24,7 +27,10 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 
+	"github.com/pipe-cd/pipecd/pkg/model"
 	service "github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
 )
 
 type fakeAPIClient struct {
@@ -89,3 +95,154 @@ func TestPersister(t *testing.T) {
 	require.Equal(t, 0, apiClient.NumberOfReportStageLogsFromLastCheckpoint())
 	assert.Equal(t, 1, num)
 }
+
+func TestPersister_Clock(t *testing.T) {
+	t.Parallel()
+
+	apiClient := &fakeAPIClient{}
+	clk := clocktest.NewFakeClock(time.Now())
+	p := NewPersister(apiClient, zap.NewNop(), WithClock(clk))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	clk.BlockUntil(1)
+
+	sp := p.StageLogPersister("deployment-1", "stage-1")
+	sp.Info("log")
+
+	// The logs are flushed from the last checkpoint first, and only the new logs after that.
+	clk.Advance(5 * time.Second)
+	assert.Eventually(t, func() bool { return apiClient.NumberOfReportStageLogsFromLastCheckpoint() == 1 }, time.Second, time.Millisecond)
+	sp.Info("log")
+	clk.Advance(5 * time.Second)
+	assert.Eventually(t, func() bool { return apiClient.NumberOfReportStageLogs() == 1 }, time.Second, time.Millisecond)
+
+	// Complete returns when the logs are flushed on the next tick.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sp.Complete(time.Hour)
+	}()
+	clk.BlockUntil(2)
+	clk.Advance(5 * time.Second)
+	require.NoError(t, <-errCh)
+
+	cancel()
+	<-done
+}
+
+func TestPersister_FlushInterval(t *testing.T) {
+	t.Parallel()
+
+	apiClient := &fakeAPIClient{}
+	clk := clocktest.NewFakeClock(time.Now())
+	p := NewPersister(apiClient, zap.NewNop(), WithClock(clk), WithFlushInterval(time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	clk.BlockUntil(1)
+
+	p.StageLogPersister("deployment-1", "stage-1").Info("log")
+	clk.Advance(time.Second)
+	assert.Eventually(t, func() bool { return apiClient.NumberOfReportStageLogsFromLastCheckpoint() == 1 }, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestStageLogPersister_CompleteTimeout(t *testing.T) {
+	t.Parallel()
+
+	clk := clocktest.NewFakeClock(time.Now())
+	p := NewPersister(&fakeAPIClient{}, zap.NewNop(), WithClock(clk))
+	sp := p.StageLogPersister("deployment-1", "stage-1")
+
+	// Complete times out on the clock since the persister is not running.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- sp.Complete(time.Minute)
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	assert.EqualError(t, <-errCh, "timed out")
+}
+
+func TestStageLogPersister_Write(t *testing.T) {
+	t.Parallel()
+
+	p := NewPersister(&fakeAPIClient{}, zap.NewNop(), WithClock(clocktest.NewFakeClock(time.Now())))
+	sp := p.StageLogPersister("deployment-1", "stage-1").(*stageLogPersister)
+
+	// The written bytes are copied, so the caller can reuse them.
+	buf := []byte("first")
+	n, err := sp.Write(buf)
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	copy(buf, "xxxxx")
+	sp.Write(buf)
+
+	// The logs larger than the chunk are stored as well.
+	large := strings.Repeat("y", logTextChunkSize)
+	sp.Write([]byte(large))
+	sp.Errorf("error %d", 1)
+
+	// The blocks are allocated in chunks without sharing the fields.
+	for i := 0; i < logBlockChunkSize; i++ {
+		sp.Successf("line %d", i)
+	}
+
+	require.Len(t, sp.blocks, 4+logBlockChunkSize)
+	assert.Equal(t, "first", sp.blocks[0].Log)
+	assert.Equal(t, "xxxxx", sp.blocks[1].Log)
+	assert.Equal(t, large, sp.blocks[2].Log)
+	assert.Equal(t, "error 1", sp.blocks[3].Log)
+	assert.Equal(t, model.LogSeverity_ERROR, sp.blocks[3].Severity)
+	for i, b := range sp.blocks {
+		assert.Equal(t, sp.blocks[0].Index+int64(i), b.Index)
+	}
+	assert.Equal(t, fmt.Sprintf("line %d", logBlockChunkSize-1), sp.blocks[len(sp.blocks)-1].Log)
+	assert.Equal(t, model.LogSeverity_SUCCESS, sp.blocks[len(sp.blocks)-1].Severity)
+}
+
+func BenchmarkStageLogPersister(b *testing.B) {
+	line := []byte(strings.Repeat("x", 100))
+	benchmarks := []struct {
+		name string
+		log  func(sp StageLogPersister)
+	}{
+		{
+			name: "Write",
+			log:  func(sp StageLogPersister) { sp.Write(line) },
+		},
+		{
+			name: "Info",
+			log:  func(sp StageLogPersister) { sp.Info("line") },
+		},
+		{
+			name: "Infof",
+			log:  func(sp StageLogPersister) { sp.Infof("line %d of %s", 1, "stage") },
+		},
+		{
+			name: "Errorf",
+			log:  func(sp StageLogPersister) { sp.Errorf("line %d of %s", 1, "stage") },
+		},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			p := NewPersister(&fakeAPIClient{}, zap.NewNop())
+			sp := p.StageLogPersister("deployment-1", "stage-1")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bm.log(sp)
+			}
+		})
+	}
+}
@@ -15,10 +15,12 @@
 package logpersister
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"sync"
 	"time"
+	"unsafe"
 
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -26,10 +28,58 @@ import (
 	"github.com/pipe-cd/pipecd/pkg/model"
 )
 
+const (
+	// logBlockChunkSize is the number of the log blocks allocated at once.
+	logBlockChunkSize = 64
+	// logTextChunkSize is the size of the buffer the log texts given as bytes are copied into.
+	// The texts larger than a quarter of it are allocated on their own to not waste the rest of the chunk.
+	logTextChunkSize = 16 << 10
+)
+
+// bufferPool holds the buffers used to format the log texts.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// logArena packs the log blocks and the log texts into larger allocations,
+// so that appending a log line doesn't allocate until the current chunk is used up.
+// The chunks are never modified once handed out, and are collected when all blocks referencing them are sent.
+type logArena struct {
+	blocks []model.LogBlock
+	text   []byte
+}
+
+// block returns a new zeroed log block.
+func (a *logArena) block() *model.LogBlock {
+	if len(a.blocks) == 0 {
+		a.blocks = make([]model.LogBlock, logBlockChunkSize)
+	}
+	b := &a.blocks[0]
+	a.blocks = a.blocks[1:]
+	return b
+}
+
+// string returns a string with the same content as the given bytes, which may be reused by the caller.
+func (a *logArena) string(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	if len(b) > logTextChunkSize/4 {
+		return string(b)
+	}
+	if cap(a.text)-len(a.text) < len(b) {
+		a.text = make([]byte, 0, logTextChunkSize)
+	}
+	start := len(a.text)
+	a.text = append(a.text, b...)
+	return unsafe.String(&a.text[start], len(b))
+}
+
 // stageLogPersister represents a log persister for a specific stage.
 type stageLogPersister struct {
 	key         key
 	blocks      []*model.LogBlock
+	arena       logArena
 	curLogIndex int64
 	completed   bool
 	completedAt time.Time
@@ -48,28 +98,61 @@ type stageLogPersister struct {
 
 // append appends a new log block.
 func (sp *stageLogPersister) append(log string, s model.LogSeverity) {
-	now := time.Now()
+	now := sp.persister.clock.Now()
 
 	// We also send the error logs to the local logger.
 	if s == model.LogSeverity_ERROR {
-		sp.logger.Warn(fmt.Sprintf("STAGE ERROR LOG: %s", log))
+		sp.logger.Warn("STAGE ERROR LOG", zap.String("log", log))
 	}
 
 	sp.mu.Lock()
 	defer sp.mu.Unlock()
 
+	sp.appendLocked(log, s, now)
+}
+
+// appendBytes appends a new log block with a copy of the given bytes.
+// The bytes are packed into the arena, so the caller can reuse them after return.
+func (sp *stageLogPersister) appendBytes(log []byte, s model.LogSeverity) {
+	now := sp.persister.clock.Now()
+
+	sp.mu.Lock()
+	text := sp.arena.string(log)
+	sp.appendLocked(text, s, now)
+	sp.mu.Unlock()
+
+	if s == model.LogSeverity_ERROR {
+		sp.logger.Warn("STAGE ERROR LOG", zap.String("log", text))
+	}
+}
+
+// appendLocked appends a new log block. The caller must hold mu.
+func (sp *stageLogPersister) appendLocked(log string, s model.LogSeverity, now time.Time) {
 	sp.curLogIndex++
-	sp.blocks = append(sp.blocks, &model.LogBlock{
-		Index:     sp.curLogIndex,
-		Log:       log,
-		Severity:  s,
-		CreatedAt: now.Unix(),
-	})
+	b := sp.arena.block()
+	b.Index = sp.curLogIndex
+	b.Log = log
+	b.Severity = s
+	b.CreatedAt = now.Unix()
+	sp.blocks = append(sp.blocks, b)
+}
+
+// appendf formats and appends a new log block using a pooled buffer.
+func (sp *stageLogPersister) appendf(s model.LogSeverity, format string, a ...interface{}) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	fmt.Fprintf(buf, format, a...)
+	sp.appendBytes(buf.Bytes(), s)
+	// Don't keep the buffers grown by the huge logs.
+	if buf.Cap() <= logTextChunkSize {
+		bufferPool.Put(buf)
+	}
 }
 
 // Write appends a new INFO log block.
+// The given bytes are copied, so the caller can reuse them.
 func (sp *stageLogPersister) Write(log []byte) (int, error) {
-	sp.Info(string(log))
+	sp.appendBytes(log, model.LogSeverity_INFO)
 	return len(log), nil
 }
 
@@ -80,7 +163,7 @@ func (sp *stageLogPersister) Info(log string) {
 
 // Infof formats and appends a new INFO log block.
 func (sp *stageLogPersister) Infof(format string, a ...interface{}) {
-	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_INFO)
+	sp.appendf(model.LogSeverity_INFO, format, a...)
 }
 
 // Success appends a new SUCCESS log block.
@@ -90,7 +173,7 @@ func (sp *stageLogPersister) Success(log string) {
 
 // Successf formats and appends a new SUCCESS log block.
 func (sp *stageLogPersister) Successf(format string, a ...interface{}) {
-	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_SUCCESS)
+	sp.appendf(model.LogSeverity_SUCCESS, format, a...)
 }
 
 // Error appends a new ERROR log block.
@@ -100,7 +183,7 @@ func (sp *stageLogPersister) Error(log string) {
 
 // Errorf formats and appends a new ERROR log block.
 func (sp *stageLogPersister) Errorf(format string, a ...interface{}) {
-	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_ERROR)
+	sp.appendf(model.LogSeverity_ERROR, format, a...)
 }
 
 // Complete marks the completion of logging for this stage.
@@ -108,14 +191,14 @@ func (sp *stageLogPersister) Errorf(format string, a ...interface{}) {
 func (sp *stageLogPersister) Complete(timeout time.Duration) error {
 	sp.mu.Lock()
 	sp.completed = true
-	sp.completedAt = time.Now()
+	sp.completedAt = sp.persister.clock.Now()
 	sp.mu.Unlock()
 
-	timer := time.NewTimer(timeout)
+	timer := sp.persister.clock.NewTimer(timeout)
 	defer timer.Stop()
 
 	select {
-	case <-timer.C:
+	case <-timer.C():
 		return fmt.Errorf("timed out")
 
 	case <-sp.doneCh:
@@ -131,7 +214,7 @@ func (sp *stageLogPersister) isStale(period time.Duration) bool {
 	sp.mu.RLock()
 	defer sp.mu.RUnlock()
 
-	if sp.completed && time.Since(sp.completedAt) > period {
+	if sp.completed && sp.persister.clock.Since(sp.completedAt) > period {
 		return true
 	}
 	return false
@@ -150,8 +233,8 @@ func (sp *stageLogPersister) flush(ctx context.Context) error {
 	completed := sp.completed
 	sp.mu.RUnlock()
 
-	if completed || time.Since(sp.checkpointSentTimestamp) > sp.checkpointFlushInterval {
-		sp.checkpointSentTimestamp = time.Now()
+	if completed || sp.persister.clock.Since(sp.checkpointSentTimestamp) > sp.checkpointFlushInterval {
+		sp.checkpointSentTimestamp = sp.persister.clock.Now()
 		return sp.flushFromLastCheckpoint(ctx)
 	}
 
@@ -0,0 +1,166 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package manifest provides the helpers for the multi-document YAML files, e.g. the rendered templates of the applications.
+// The documents are kept in the order of the source, and re-serialized deterministically with their key order and comments,
+// so that the plugins can treat them as the deployment unit and compare them by the stable content hashes.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/digest"
+)
+
+// Manifest is a document in a multi-document YAML.
+type Manifest struct {
+	// Index is the position of the document in the source, counting the non-empty documents from zero.
+	Index int
+	node  *yaml.Node
+}
+
+// Parse parses the multi-document YAML into the manifests in the order of the source.
+// The empty documents, e.g. the ones only with comments, are skipped.
+func Parse(data []byte) ([]Manifest, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var manifests []Manifest
+	for {
+		var node yaml.Node
+		if err := dec.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				return manifests, nil
+			}
+			return nil, fmt.Errorf("failed to parse the document %d: %w", len(manifests), err)
+		}
+		if isEmpty(&node) {
+			continue
+		}
+		manifests = append(manifests, Manifest{Index: len(manifests), node: &node})
+	}
+}
+
+// isEmpty reports whether the document has no content.
+func isEmpty(node *yaml.Node) bool {
+	if node.Kind != yaml.DocumentNode || len(node.Content) == 0 {
+		return true
+	}
+	content := node.Content[0]
+	return content.Kind == yaml.ScalarNode && content.Tag == "!!null"
+}
+
+// Decode decodes the manifest into the given value in the same way as yaml.Unmarshal.
+func (m Manifest) Decode(v any) error {
+	return m.node.Decode(v)
+}
+
+// Bytes returns the manifest serialized in YAML, keeping the key order and the comments of the source.
+// The same manifest is always serialized into the same bytes.
+func (m Manifest) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m.node); err != nil {
+		return nil, fmt.Errorf("failed to serialize the document %d: %w", m.Index, err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to serialize the document %d: %w", m.Index, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Hash returns the hex-encoded SHA-256 hash of the content of the manifest.
+// It depends only on the values, so the manifests differing only in the key order, the formatting,
+// the comments or the anchors have the same hash.
+func (m Manifest) Hash() (string, error) {
+	data, err := m.canonicalJSON()
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(data).Hex(), nil
+}
+
+// canonicalJSON returns the content of the manifest in JSON with the keys sorted.
+func (m Manifest) canonicalJSON() ([]byte, error) {
+	var v any
+	if err := m.node.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode the document %d: %w", m.Index, err)
+	}
+	data, err := json.Marshal(normalize(v))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the document %d: %w", m.Index, err)
+	}
+	return data, nil
+}
+
+// normalize converts the maps with the non-string keys into the ones with the string keys, so that they can be encoded in JSON.
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+		return v
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[fmt.Sprint(k)] = normalize(e)
+		}
+		return out
+	case []any:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Marshal serializes the manifests into a multi-document YAML in the given order.
+func Marshal(manifests []Manifest) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, m := range manifests {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		data, err := m.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// Hash returns the hex-encoded SHA-256 hash of the contents of the manifests.
+// It changes when the manifests are reordered, since the order may matter for applying them.
+func Hash(manifests []Manifest) (string, error) {
+	w := digest.NewWriter()
+	for _, m := range manifests {
+		data, err := m.canonicalJSON()
+		if err != nil {
+			return "", err
+		}
+		// Separate the documents by the newline, which never appears in the compact JSON.
+		w.Write(data)
+		w.Write([]byte{'\n'})
+	}
+	return w.Digest().Hex(), nil
+}
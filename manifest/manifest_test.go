@@ -0,0 +1,131 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`# leading comment
+---
+kind: Service
+name: first
+---
+# only comment
+---
+kind: Deployment
+name: second
+...
+---
+`)
+	manifests, err := Parse(data)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	var v struct {
+		Kind string `yaml:"kind"`
+		Name string `yaml:"name"`
+	}
+	require.NoError(t, manifests[0].Decode(&v))
+	assert.Equal(t, "first", v.Name)
+	assert.Equal(t, 0, manifests[0].Index)
+	require.NoError(t, manifests[1].Decode(&v))
+	assert.Equal(t, "second", v.Name)
+	assert.Equal(t, 1, manifests[1].Index)
+
+	_, err = Parse([]byte("a: [\n"))
+	assert.Error(t, err)
+
+	manifests, err = Parse(nil)
+	require.NoError(t, err)
+	assert.Empty(t, manifests)
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`z: 1
+a:
+    # keep this comment
+    list: [1, 2]
+---
+b: x
+`)
+	manifests, err := Parse(data)
+	require.NoError(t, err)
+
+	out, err := Marshal(manifests)
+	require.NoError(t, err)
+	expected := `z: 1
+a:
+  # keep this comment
+  list: [1, 2]
+---
+b: x
+`
+	assert.Equal(t, expected, string(out))
+
+	// The serialized manifests are parsed into the same ones.
+	reparsed, err := Parse(out)
+	require.NoError(t, err)
+	again, err := Marshal(reparsed)
+	require.NoError(t, err)
+	assert.Equal(t, out, again)
+}
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+
+	parse := func(s string) []Manifest {
+		t.Helper()
+		manifests, err := Parse([]byte(s))
+		require.NoError(t, err)
+		return manifests
+	}
+	hashOf := func(s string) string {
+		t.Helper()
+		h, err := parse(s)[0].Hash()
+		require.NoError(t, err)
+		return h
+	}
+
+	base := hashOf("a: 1\nb: [x, y]\n")
+	assert.Len(t, base, 64)
+	// The key order, the formatting, the comments and the anchors don't change the hash.
+	assert.Equal(t, base, hashOf("# comment\nb:\n  - x\n  - y\na: 1\n"))
+	assert.Equal(t, base, hashOf("a: &one 1\nb: [x, y]\n"))
+	assert.Equal(t, hashOf("a: x\nb: [x, y]\n"), hashOf("a: &x x\nb: [*x, y]\n"))
+	// The values change the hash.
+	assert.NotEqual(t, base, hashOf("a: 2\nb: [x, y]\n"))
+	assert.NotEqual(t, base, hashOf("a: 1\nb: [y, x]\n"))
+	// The maps with the non-string keys are hashed as well.
+	assert.NotEmpty(t, hashOf("1: a\ntrue: b\n"))
+
+	// The hash of the manifests depends on their order.
+	h1, err := Hash(parse("a: 1\n---\nb: 2\n"))
+	require.NoError(t, err)
+	h2, err := Hash(parse("b: 2\n---\na: 1\n"))
+	require.NoError(t, err)
+	h3, err := Hash(parse("# comment\na: 1\n---\nb: 2\n"))
+	require.NoError(t, err)
+	assert.NotEqual(t, h1, h2)
+	assert.Equal(t, h1, h3)
+}
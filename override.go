@@ -0,0 +1,151 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DeployTargetOverrider is an optional interface for the ApplicationConfigSpec
+// to override the config of the deploy targets for the application, e.g. the namespace or the profile.
+// When it's implemented, the deploy targets given to ExecuteStage, GetLivestate, and GetPlanPreview
+// have the effective config, that is, the override is deep-merged onto the config in the piped config.
+type DeployTargetOverrider interface {
+	// DeployTargetOverride returns the JSON object to be merged onto the config of the deploy target of the given name.
+	// The objects are merged recursively, and the other values, including the arrays, are replaced.
+	// It returns nil when the config is not overridden.
+	DeployTargetOverride(deployTarget string) json.RawMessage
+}
+
+// overrideDeployTargets returns the deploy targets with the overrides declared in the given application spec.
+// The given deploy targets are not modified.
+func overrideDeployTargets[Spec, DeployTargetConfig any](spec *Spec, deployTargets []*DeployTarget[DeployTargetConfig]) ([]*DeployTarget[DeployTargetConfig], error) {
+	overrider, ok := any(spec).(DeployTargetOverrider)
+	if spec == nil || !ok {
+		return deployTargets, nil
+	}
+
+	overridden := make([]*DeployTarget[DeployTargetConfig], 0, len(deployTargets))
+	for _, dt := range deployTargets {
+		override := overrider.DeployTargetOverride(dt.Name)
+		if len(override) == 0 {
+			overridden = append(overridden, dt)
+			continue
+		}
+		c, raw, err := mergeDeployTargetConfig(dt, override)
+		if err != nil {
+			return nil, err
+		}
+		copied := *dt
+		copied.Config = *c
+		copied.RawConfig = raw
+		overridden = append(overridden, &copied)
+	}
+	return overridden, nil
+}
+
+// mergeDeployTargetConfig returns the config of the deploy target with the override merged, and its JSON.
+// The override is merged onto the RawConfig as it is given in the piped config, since encoding the decoded
+// config again loses the values hidden by MarshalJSON, e.g. the value of Credential is encoded as the mask.
+// The decoded config is encoded only for the deploy target without RawConfig, e.g. the one built in tests.
+func mergeDeployTargetConfig[DeployTargetConfig any](dt *DeployTarget[DeployTargetConfig], override json.RawMessage) (*DeployTargetConfig, json.RawMessage, error) {
+	root := fmt.Sprintf("deployTargets[%s].config", dt.Name)
+
+	data := dt.RawConfig
+	if len(data) == 0 {
+		var err error
+		if data, err = json.Marshal(dt.Config); err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal the config of the deploy target %s: %w", dt.Name, err)
+		}
+	}
+	var base, patch any
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal the config of the deploy target %s: %w", dt.Name, err)
+	}
+	if err := json.Unmarshal(override, &patch); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal the override of the deploy target %s: %w", dt.Name, err)
+	}
+
+	merged, err := mergeJSON(base, patch, root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to override the config of the deploy target %s: %w", dt.Name, err)
+	}
+	data, err = json.Marshal(merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal the overridden config of the deploy target %s: %w", dt.Name, err)
+	}
+
+	var c DeployTargetConfig
+	if err := decodeConfig(data, &c, root); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal the overridden config of the deploy target %s: %w", dt.Name, err)
+	}
+	// Apply the defaults again, since the override may clear the fields or change the ones the defaults derive from.
+	if err := setDefaults(&c); err != nil {
+		return nil, nil, fmt.Errorf("failed to set defaults of the overridden config of the deploy target %s: %w", dt.Name, err)
+	}
+	if err := validate(&c); err != nil {
+		return nil, nil, fmt.Errorf("invalid overridden config of the deploy target %s: %s: %w", dt.Name, root, err)
+	}
+	return &c, data, nil
+}
+
+// mergeJSON deep-merges the patch onto the base.
+// It reports a conflict when an object and a non-object value are merged, since it's likely a mistake.
+// The null in the patch keeps the value in the base.
+func mergeJSON(base, patch any, path string) (any, error) {
+	if patch == nil {
+		return base, nil
+	}
+	if base == nil {
+		return patch, nil
+	}
+
+	baseObj, baseIsObj := base.(map[string]any)
+	patchObj, patchIsObj := patch.(map[string]any)
+	switch {
+	case baseIsObj && patchIsObj:
+		for k, v := range patchObj {
+			merged, err := mergeJSON(baseObj[k], v, path+"."+k)
+			if err != nil {
+				return nil, err
+			}
+			baseObj[k] = merged
+		}
+		return baseObj, nil
+	case baseIsObj != patchIsObj:
+		return nil, fmt.Errorf("conflict at %s: cannot override %s with %s", path, jsonKind(base), jsonKind(patch))
+	default:
+		return patch, nil
+	}
+}
+
+// jsonKind returns the kind of the decoded JSON value for the error messages.
+func jsonKind(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return "null"
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config "github.com/pipe-cd/pipecd/pkg/configv1"
+)
+
+type overridableDeployTargetConfig struct {
+	Namespace  string            `json:"namespace"`
+	Profile    string            `json:"profile,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Args       []string          `json:"args,omitempty"`
+}
+
+func (c *overridableDeployTargetConfig) Validate() error {
+	if c.Namespace == "" {
+		return errors.New("namespace is required")
+	}
+	return nil
+}
+
+type overridingSpec struct {
+	Overrides map[string]json.RawMessage `json:"overrides"`
+}
+
+func (s *overridingSpec) DeployTargetOverride(deployTarget string) json.RawMessage {
+	return s.Overrides[deployTarget]
+}
+
+func TestOverrideDeployTargets(t *testing.T) {
+	t.Parallel()
+
+	base := &DeployTarget[overridableDeployTargetConfig]{
+		Name:   "dt1",
+		Labels: map[string]string{"env": "prod"},
+		Config: overridableDeployTargetConfig{
+			Namespace:  "default",
+			Parameters: map[string]string{"a": "1", "b": "2"},
+			Args:       []string{"--x"},
+		},
+	}
+
+	testcases := []struct {
+		name        string
+		override    string
+		expected    overridableDeployTargetConfig
+		expectedErr string
+	}{
+		{
+			name:     "no override",
+			override: "",
+			expected: base.Config,
+		},
+		{
+			name:     "deep merge",
+			override: `{"namespace":"app","profile":"p","parameters":{"b":"3","c":"4"},"args":["--y"]}`,
+			expected: overridableDeployTargetConfig{
+				Namespace:  "app",
+				Profile:    "p",
+				Parameters: map[string]string{"a": "1", "b": "3", "c": "4"},
+				Args:       []string{"--y"},
+			},
+		},
+		{
+			name:        "conflict",
+			override:    `{"namespace":{"name":"app"}}`,
+			expectedErr: "conflict at deployTargets[dt1].config.namespace: cannot override a string with an object",
+		},
+		{
+			name:        "invalid result",
+			override:    `{"namespace":""}`,
+			expectedErr: "namespace is required",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			spec := &overridingSpec{}
+			if tc.override != "" {
+				spec.Overrides = map[string]json.RawMessage{"dt1": json.RawMessage(tc.override)}
+			}
+			got, err := overrideDeployTargets(spec, []*DeployTarget[overridableDeployTargetConfig]{base})
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, got, 1)
+			assert.Equal(t, "dt1", got[0].Name)
+			assert.Equal(t, base.Labels, got[0].Labels)
+			assert.Equal(t, tc.expected, got[0].Config)
+			if tc.override != "" {
+				expected, err := json.Marshal(tc.expected)
+				require.NoError(t, err)
+				assert.JSONEq(t, string(expected), string(got[0].RawConfig))
+			}
+		})
+	}
+
+	// The original deploy target is not modified.
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, base.Config.Parameters)
+	assert.Equal(t, "default", base.Config.Namespace)
+}
+
+func TestOverrideDeployTargets_NotImplemented(t *testing.T) {
+	t.Parallel()
+
+	dts := []*DeployTarget[overridableDeployTargetConfig]{{Name: "dt1"}}
+	got, err := overrideDeployTargets(&struct{}{}, dts)
+	require.NoError(t, err)
+	assert.Equal(t, dts, got)
+}
+
+type credentialDeployTargetConfig struct {
+	Namespace string     `json:"namespace"`
+	Token     Credential `json:"token"`
+}
+
+func TestOverrideDeployTargets_Credential(t *testing.T) {
+	t.Parallel()
+
+	dts, err := parseDeployTargets[credentialDeployTargetConfig]([]config.PipedDeployTarget{
+		{Name: "dt1", Config: json.RawMessage(`{"namespace":"default","token":"secret"}`)},
+	})
+	require.NoError(t, err)
+
+	spec := &overridingSpec{Overrides: map[string]json.RawMessage{"dt1": json.RawMessage(`{"namespace":"app"}`)}}
+	got, err := overrideDeployTargets(spec, []*DeployTarget[credentialDeployTargetConfig]{dts["dt1"]})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "app", got[0].Config.Namespace)
+	// The credential is kept, not replaced by its mask.
+	assert.Equal(t, "secret", got[0].Config.Token.Value())
+}
@@ -0,0 +1,166 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+)
+
+// PlaceholderSubstituter is an optional interface for the ApplicationConfigSpec to opt out of the placeholder substitution.
+// The well-known placeholders in the string values of the plugin spec in the application config are substituted
+// before it's decoded unless SubstitutePlaceholders returns false:
+//
+//	${commitHash}      the commit hash of the deployment source
+//	${commitHashShort} the first 7 characters of the commit hash
+//	${appID}           the ID of the application
+//	${appName}         the name of the application
+//	${deployTarget}    the name of the deploy target, available only when the application has exactly one
+//	${env.NAME}        the environment variable NAME of the plugin process
+//
+// The other placeholders such as "${HOME}" in scripts are left as is, and "$${...}" is replaced with the literal "${...}".
+// It's an error if a known placeholder is used but its value is not available, e.g. the environment variable is not set.
+type PlaceholderSubstituter interface {
+	SubstitutePlaceholders() bool
+}
+
+// placeholderPattern matches the placeholders and the escaped ones prefixed with "$$".
+var placeholderPattern = regexp.MustCompile(`\$?\$\{([A-Za-z][A-Za-z0-9_.]*)\}`)
+
+// placeholders holds the values of the well-known placeholders.
+type placeholders struct {
+	commitHash    string
+	appID         string
+	appName       string
+	deployTargets []string
+}
+
+// lookup returns the value of the placeholder with the given name.
+// It returns false if the name is not a known placeholder, and an error if its value is not available.
+func (p *placeholders) lookup(name string) (string, bool, error) {
+	var v string
+	switch {
+	case name == "commitHash":
+		v = p.commitHash
+	case name == "commitHashShort":
+		v = p.commitHash[:min(7, len(p.commitHash))]
+	case name == "appID":
+		v = p.appID
+	case name == "appName":
+		v = p.appName
+	case name == "deployTarget":
+		if len(p.deployTargets) > 1 {
+			return "", true, fmt.Errorf("${deployTarget} is ambiguous since the application has multiple deploy targets %v", p.deployTargets)
+		}
+		if len(p.deployTargets) == 1 {
+			v = p.deployTargets[0]
+		}
+	case strings.HasPrefix(name, "env."):
+		env, ok := os.LookupEnv(strings.TrimPrefix(name, "env."))
+		if !ok {
+			return "", true, fmt.Errorf("environment variable for ${%s} is not set", name)
+		}
+		return env, true, nil
+	default:
+		return "", false, nil
+	}
+	if v == "" {
+		return "", true, fmt.Errorf("the value of ${%s} is not available", name)
+	}
+	return v, true, nil
+}
+
+// substitute substitutes the placeholders in the string values of the given JSON.
+// The keys of the objects are not substituted.
+func (p *placeholders) substitute(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte("${")) {
+		return data, nil
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	// Keep the numbers as is to avoid losing the precision.
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	v, err := p.substituteValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func (p *placeholders) substituteValue(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return p.substituteString(v)
+	case map[string]any:
+		for k, e := range v {
+			s, err := p.substituteValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = s
+		}
+	case []any:
+		for i, e := range v {
+			s, err := p.substituteValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = s
+		}
+	}
+	return v, nil
+}
+
+func (p *placeholders) substituteString(s string) (string, error) {
+	var errs []error
+	s = placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasPrefix(m, "$$") {
+			name := m[3 : len(m)-1]
+			if _, ok, _ := p.lookup(name); ok {
+				return m[1:]
+			}
+			// Leave the unknown ones as is, e.g. "$${HOME}" in Makefiles.
+			return m
+		}
+		v, ok, err := p.lookup(m[2 : len(m)-1])
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if !ok || err != nil {
+			return m
+		}
+		return v
+	})
+	return s, errors.Join(errs...)
+}
+
+// deploymentPlaceholders returns the placeholders of the given deployment.
+func deploymentPlaceholders(pluginName string, d *model.Deployment) placeholders {
+	return placeholders{
+		appID:         d.GetApplicationId(),
+		appName:       d.GetApplicationName(),
+		deployTargets: d.GetDeployTargets(pluginName),
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/common"
+)
+
+func TestPlaceholders_substitute(t *testing.T) {
+	t.Setenv("SDK_PLACEHOLDER_TEST_REGION", "us-east-1")
+
+	p := &placeholders{
+		commitHash:    "0123456789abcdef",
+		appID:         "app-id",
+		appName:       "app-name",
+		deployTargets: []string{"dt1"},
+	}
+
+	tests := []struct {
+		name    string
+		p       *placeholders
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "no placeholder",
+			p:     p,
+			input: `{"replicas":1.0000000000000001,"name":"app"}`,
+			want:  `{"replicas":1.0000000000000001,"name":"app"}`,
+		},
+		{
+			name:  "well-known placeholders",
+			p:     p,
+			input: `{"image":"app:${commitHashShort}","labels":{"app":"${appName}","id":"${appID}"},"args":["${commitHash}","${deployTarget}","${env.SDK_PLACEHOLDER_TEST_REGION}"]}`,
+			want:  `{"args":["0123456789abcdef","dt1","us-east-1"],"image":"app:0123456","labels":{"app":"app-name","id":"app-id"}}`,
+		},
+		{
+			name:  "keys and numbers are not substituted",
+			p:     p,
+			input: `{"${appName}":"x","n":12345678901234567890}`,
+			want:  `{"${appName}":"x","n":12345678901234567890}`,
+		},
+		{
+			name:  "unknown and escaped placeholders",
+			p:     p,
+			input: `{"script":"echo ${HOME} $${appName} $${HOME} ${appName}"}`,
+			want:  `{"script":"echo ${HOME} ${appName} $${HOME} app-name"}`,
+		},
+		{
+			name:  "value containing JSON special characters",
+			p:     &placeholders{appName: `a"b\c`},
+			input: `{"name":"${appName}"}`,
+			want:  `{"name":"a\"b\\c"}`,
+		},
+		{
+			name:    "ambiguous deploy target",
+			p:       &placeholders{deployTargets: []string{"dt1", "dt2"}},
+			input:   `{"name":"${deployTarget}"}`,
+			wantErr: "${deployTarget} is ambiguous",
+		},
+		{
+			name:    "unavailable value",
+			p:       &placeholders{},
+			input:   `{"name":"${appID}"}`,
+			wantErr: "the value of ${appID} is not available",
+		},
+		{
+			name:    "missing environment variable",
+			p:       p,
+			input:   `{"name":"${env.SDK_PLACEHOLDER_TEST_MISSING}"}`,
+			wantErr: "environment variable for ${env.SDK_PLACEHOLDER_TEST_MISSING} is not set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.p.substitute([]byte(tt.input))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+type placeholderSpec struct {
+	Image string `json:"image"`
+}
+
+type rawPlaceholderSpec struct {
+	Image string `json:"image"`
+}
+
+func (rawPlaceholderSpec) SubstitutePlaceholders() bool {
+	return false
+}
+
+func TestNewDeploymentSource_Placeholders(t *testing.T) {
+	t.Parallel()
+
+	config := strings.TrimSpace(`
+apiVersion: pipecd.dev/v1beta1
+kind: Application
+spec:
+  name: app-from-config
+  plugins:
+    test:
+      image: ${appName}:${commitHash}
+`)
+	source := &common.DeploymentSource{
+		CommitHash:        "abc",
+		ApplicationConfig: []byte(config),
+	}
+
+	ds, err := newDeploymentSource[placeholderSpec]("test", source, deploymentPlaceholders("test", &model.Deployment{ApplicationName: "app"}))
+	require.NoError(t, err)
+	assert.Equal(t, "app:abc", ds.ApplicationConfig.Spec.Image)
+
+	// The name in the application config is used when the request doesn't have it.
+	ds, err = newDeploymentSource[placeholderSpec]("test", source, placeholders{})
+	require.NoError(t, err)
+	assert.Equal(t, "app-from-config:abc", ds.ApplicationConfig.Spec.Image)
+
+	// The spec can opt out of the substitution.
+	raw, err := newDeploymentSource[rawPlaceholderSpec]("test", source, placeholders{})
+	require.NoError(t, err)
+	assert.Equal(t, "${appName}:${commitHash}", raw.ApplicationConfig.Spec.Image)
+}
@@ -16,6 +16,7 @@ package sdk
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -44,7 +45,7 @@ type PlanPreviewPluginServer[Config, DeployTargetConfig, ApplicationConfigSpec a
 
 // Register registers the plugin to the gRPC server.
 func (s *PlanPreviewPluginServer[Config, DeployTargetConfig, ApplicationConfigSpec]) Register(server *grpc.Server) {
-	planpreview.RegisterPlanPreviewServiceServer(server, s)
+	planpreview.RegisterPlanPreviewServiceServer(s.registrar(server), s)
 }
 
 // GetPlanPreview returns the plan preview of the resources in the given application.
@@ -52,7 +53,7 @@ func (s *PlanPreviewPluginServer[Config, DeployTargetConfig, ApplicationConfigSp
 	// Get the deploy targets set on the deployment from the piped plugin config.
 	deployTargets := make([]*DeployTarget[DeployTargetConfig], 0, len(request.GetDeployTargets()))
 	for _, name := range request.GetDeployTargets() {
-		dt, ok := s.deployTargets[name]
+		dt, ok := s.deployTargets.get(name)
 		if !ok {
 			return nil, status.Errorf(codes.Internal, "the deploy target %s is not found in the piped plugin config", name)
 		}
@@ -65,38 +66,55 @@ func (s *PlanPreviewPluginServer[Config, DeployTargetConfig, ApplicationConfigSp
 		pluginName:    s.name,
 		applicationID: request.GetApplicationId(),
 		toolRegistry:  s.toolRegistry,
+		clock:         s.clock,
 	}
 
-	targetDS, err := newDeploymentSource[ApplicationConfigSpec](s.name, request.GetTargetDeploymentSource())
+	p := placeholders{
+		appID:         request.GetApplicationId(),
+		appName:       request.GetApplicationName(),
+		deployTargets: request.GetDeployTargets(),
+	}
+	targetDS, err := newDeploymentSource[ApplicationConfigSpec](s.name, request.GetTargetDeploymentSource(), p)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to parse target deployment source: %v", err)
 	}
 
 	runningDS := DeploymentSource[ApplicationConfigSpec]{}
 	if request.GetRunningDeploymentSource() != nil {
-		runningDS, err = newDeploymentSource[ApplicationConfigSpec](s.name, request.GetRunningDeploymentSource())
+		runningDS, err = newDeploymentSource[ApplicationConfigSpec](s.name, request.GetRunningDeploymentSource(), p)
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to parse running deployment source: %v", err)
 		}
 	}
 
-	response, err := s.base.GetPlanPreview(ctx, s.pluginConfig, deployTargets, &GetPlanPreviewInput[ApplicationConfigSpec]{
+	deployTargets, err = overrideDeployTargets(targetDS.ApplicationConfig.Spec, deployTargets)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to apply the deploy target overrides: %v", err)
+	}
+
+	start := client.clockOrReal().Now()
+	response, err := s.base.GetPlanPreview(ctx, s.currentPluginConfig(), deployTargets, &GetPlanPreviewInput[ApplicationConfigSpec]{
 		Request: GetPlanPreviewRequest[ApplicationConfigSpec]{
 			ApplicationID:           request.GetApplicationId(),
 			ApplicationName:         request.GetApplicationName(),
+			ApplicationLabels:       targetDS.ApplicationConfig.Labels(),
 			PipedID:                 request.GetPipedId(),
 			DeployTargets:           request.GetDeployTargets(),
 			TargetDeploymentSource:  targetDS,
 			RunningDeploymentSource: runningDS,
 		},
-		Client: client,
-		Logger: s.logger,
+		Client:   client,
+		Logger:   withCorrelationID(ctx, s.logger),
+		Deadline: deadlineOf(ctx),
 	})
+	planPreviewGot(err, client.clockOrReal().Since(start))
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to get the plan preview: %v", err)
 	}
 
-	return response.toProto(), nil
+	resp := response.toProto()
+	fitResponse(s.logger, "GetPlanPreview", resp, s.maxResponseSize, fitPlanPreviewResponse)
+	return resp, nil
 }
 
 // GetPlanPreviewInput is the input for the GetPlanPreview method.
@@ -107,6 +125,9 @@ type GetPlanPreviewInput[ApplicationConfigSpec any] struct {
 	Client *Client
 	// Logger is the logger for logging.
 	Logger *zap.Logger
+	// Deadline is the time when the given context is canceled, so the plugin can bound its own operations by it.
+	// It's zero when the context has no deadline.
+	Deadline time.Time
 }
 
 // GetPlanPreviewRequest is the request for the GetPlanPreview method.
@@ -115,6 +136,8 @@ type GetPlanPreviewRequest[ApplicationConfigSpec any] struct {
 	ApplicationID string
 	// ApplicationName is the name of the application.
 	ApplicationName string
+	// ApplicationLabels are the labels of the application defined in the target application config.
+	ApplicationLabels map[string]string
 	// PipedID is the ID of the piped.
 	PipedID string
 	// DeployTargets is the names of the deploy targets.
@@ -49,14 +49,14 @@ func newTestPlanPreviewPluginServer(t *testing.T, plugin *mockPlanPreviewPlugin)
 			config: &config.PipedPlugin{
 				Name: "mockPlanPreviewPlugin",
 			},
-			deployTargets: map[string]*DeployTarget[struct{}]{
+			deployTargets: newDeployTargetStore(map[string]*DeployTarget[struct{}]{
 				"target1": {
 					Name: "target1",
 					Labels: map[string]string{
 						"key1": "value1",
 					},
 				},
-			},
+			}),
 		},
 	}
 }
@@ -238,3 +238,46 @@ func TestGetPlanPreviewResponse_toProto(t *testing.T) {
 		})
 	}
 }
+
+type overridingPlanPreviewPlugin struct{}
+
+func (overridingPlanPreviewPlugin) GetPlanPreview(context.Context, *struct{}, []*DeployTarget[overridableDeployTargetConfig], *GetPlanPreviewInput[overridingSpec]) (*GetPlanPreviewResponse, error) {
+	return &GetPlanPreviewResponse{}, nil
+}
+
+func TestPlanPreviewPluginServer_GetPlanPreview_InvalidOverride(t *testing.T) {
+	t.Parallel()
+
+	server := &PlanPreviewPluginServer[struct{}, overridableDeployTargetConfig, overridingSpec]{
+		base: overridingPlanPreviewPlugin{},
+		commonFields: commonFields[struct{}, overridableDeployTargetConfig]{
+			name:   "overridingPlanPreviewPlugin",
+			logger: zaptest.NewLogger(t),
+			config: &config.PipedPlugin{Name: "overridingPlanPreviewPlugin"},
+			deployTargets: newDeployTargetStore(map[string]*DeployTarget[overridableDeployTargetConfig]{
+				"target1": {Name: "target1", Config: overridableDeployTargetConfig{Namespace: "default"}},
+			}),
+		},
+	}
+
+	// The override conflicting with the config is the mistake of the application config.
+	_, err := server.GetPlanPreview(context.Background(), &planpreview.GetPlanPreviewRequest{
+		ApplicationId: "app1",
+		DeployTargets: []string{"target1"},
+		TargetDeploymentSource: &common.DeploymentSource{
+			ApplicationConfig: []byte(`
+apiVersion: pipecd.dev/v1beta1
+kind: Application
+spec:
+  plugins:
+    overridingPlanPreviewPlugin:
+      overrides:
+        target1:
+          namespace:
+            name: app
+`),
+			ApplicationConfigFilename: "app.pipecd.yaml",
+		},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -18,21 +18,41 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"net"
 	"net/http"
 	"net/http/pprof"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	// Register the gzip compressor to accept the compressed requests from piped
+	// and compress the responses to them.
+	"google.golang.org/grpc/encoding/gzip"
 
 	"github.com/pipe-cd/pipecd/pkg/admin"
 	"github.com/pipe-cd/pipecd/pkg/cli"
 	config "github.com/pipe-cd/pipecd/pkg/configv1"
+	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
 	"github.com/pipe-cd/pipecd/pkg/rpc"
 
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/filelock/filelockmetrics"
 	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+	"github.com/pipe-cd/piped-plugin-sdk-go/profiler"
 	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrymetrics"
 )
 
 // DeployTargetsNone is a type alias for a slice of pointers to DeployTarget
@@ -53,25 +73,91 @@ type DeployTarget[Config any] struct {
 	Labels map[string]string `json:"labels,omitempty"`
 	// The configuration of the deploy target.
 	Config Config `json:"config"`
+
+	// The configuration of the deploy target in JSON before decoding it into Config.
+	// It's useful to decode the polymorphic sections of the configuration by the plugin.
+	RawConfig json.RawMessage `json:"-"`
+
+	// The rollout group of the deploy target, e.g. the region. It's taken from the DeployTargetLabelGroup label.
+	Group string `json:"group,omitempty"`
+	// The rollout order of the deploy target. The smaller one is deployed earlier.
+	// It's taken from the DeployTargetLabelOrder label, and zero if not set.
+	Order int `json:"order,omitempty"`
+	// The relative weight of the deploy target, e.g. to split the traffic.
+	// It's taken from the DeployTargetLabelWeight label, and zero if not set.
+	Weight int `json:"weight,omitempty"`
+}
+
+// parseDeployTargets parses the deploy targets in the piped plugin config.
+// The defaults of the config of each deploy target are applied, and then it's validated if it implements the Validate method,
+// so that an invalid deploy target prevents the plugin from starting instead of failing the deployments later.
+func parseDeployTargets[Config any](dts []config.PipedDeployTarget) (map[string]*DeployTarget[Config], error) {
+	deployTargets := make(map[string]*DeployTarget[Config], len(dts))
+	for _, dt := range dts {
+		var c Config
+		if err := decodeConfig(dt.Config, &c, fmt.Sprintf("deployTargets[%s].config", dt.Name)); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal the config of the deploy target %s: %w", dt.Name, err)
+		}
+		if err := setDefaults(&c); err != nil {
+			return nil, fmt.Errorf("failed to set defaults of the config of the deploy target %s: %w", dt.Name, err)
+		}
+		if err := validate(&c); err != nil {
+			return nil, fmt.Errorf("invalid config of the deploy target %s: deployTargets[%s].config: %w", dt.Name, dt.Name, err)
+		}
+		deployTarget := &DeployTarget[Config]{
+			Name:      dt.Name,
+			Labels:    dt.Labels,
+			Config:    c,
+			RawConfig: dt.Config,
+		}
+		if err := deployTarget.parseRolloutLabels(); err != nil {
+			return nil, fmt.Errorf("invalid labels of the deploy target %s: %w", dt.Name, err)
+		}
+		deployTargets[dt.Name] = deployTarget
+	}
+	return deployTargets, nil
 }
 
 // InitializeInput is the input for the Initializer interface.
 type InitializeInput[Config, DeployTargetConfig any] struct {
 	// Config is the configuration of the plugin.
 	Config *Config
+	// RawConfig is the configuration of the plugin in JSON as it is given, before decoding it into Config.
+	// It's useful to decode the polymorphic sections of the configuration by the plugin.
+	RawConfig json.RawMessage
 	// DeployTargets is the deploy targets of the plugin.
 	DeployTargets map[string]*DeployTarget[DeployTargetConfig]
+	// DeployTargetRegistry is the registry to add and remove the deploy targets while the plugin is running.
+	DeployTargetRegistry *DeployTargetRegistry[DeployTargetConfig]
 	// Client is the client to interact with the piped.
 	Client *Client
 	// Logger is the logger for the plugin.
 	Logger *zap.Logger
+	// Role is the role which the plugin is initialized for, one of the InitializeRole constants.
+	Role string
 }
 
+// The roles which the initializers are called for, given as InitializeInput.Role and RoleInitializer.Role.
+const (
+	// InitializeRolePlugin is the role of the initializers added by WithInitializer.
+	InitializeRolePlugin = "plugin"
+	// InitializeRoleStage is the role of the plugin registered by WithStagePlugin.
+	InitializeRoleStage = "stage plugin"
+	// InitializeRoleDeployment is the role of the plugin registered by WithDeploymentPlugin.
+	InitializeRoleDeployment = "deployment plugin"
+	// InitializeRoleLivestate is the role of the plugin registered by WithLivestatePlugin.
+	InitializeRoleLivestate = "livestate plugin"
+	// InitializeRolePlanPreview is the role of the plugin registered by WithPlanPreviewPlugin.
+	InitializeRolePlanPreview = "plan-preview plugin"
+)
+
 // Initializer is an interface that defines the Initialize method.
 type Initializer[Config, DeployTargetConfig any] interface {
 	// Initialize initializes the plugin with the given context and input.
-	// It is called multiple times when the plugin is registered multiple times, such as deployment, livestate, and plan-preview plugins.
-	// It is recommended to use sync.Once to ensure that the plugin is initialized only once.
+	// It is called once per role when the plugin is registered for multiple roles, such as deployment, livestate, and plan-preview plugins,
+	// with the input whose Role tells the role being initialized.
+	// Set up the resources specific to each role by switching on the Role, and the shared ones for only one of the roles,
+	// instead of guarding the initialization with sync.Once.
 	Initialize(context.Context, *InitializeInput[Config, DeployTargetConfig]) error
 }
 
@@ -83,14 +169,69 @@ type commonFields[Config, DeployTargetConfig any] struct {
 	logPersister  logPersister
 	client        *pluginServiceClient
 	toolRegistry  *toolregistry.ToolRegistry
-	pluginConfig  *Config
-	deployTargets map[string]*DeployTarget[DeployTargetConfig]
+	pluginConfig  *atomic.Pointer[Config]
+	deployTargets *deployTargetStore[DeployTargetConfig]
+	redactor      *redactor
+	clock         clock.Clock
+	// auditor records the handled RPCs. It's nil when no audit sink is configured.
+	auditor *auditor
+	// stageLimiter limits the stages executed concurrently. It's nil when not limited.
+	stageLimiter *stageLimiter
+	// maxResponseSize is the size to fit the livestate and plan preview responses to. It's zero when not limited.
+	maxResponseSize int
+	// responseCompressor is the name of the compressor for the responses. It's empty when following piped.
+	responseCompressor string
+	// slowRPCDetector warns about the slow RPCs. It's nil when the detection is disabled.
+	slowRPCDetector *slowRPCDetector
+	// livestatePool bounds the livestate requests handled in parallel. It's nil when not limited.
+	livestatePool *livestatePool
+	// responseCache caches the responses of the idempotent methods. It's nil when not enabled.
+	responseCache *responseCache
+	// deadlinePolicy sets the default deadlines to the RPCs from piped. It's nil when not configured, e.g. in tests.
+	deadlinePolicy *deadlinePolicy
 }
 
 type logPersister interface {
 	StageLogPersister(deploymentID, stageID string) logpersister.StageLogPersister
 }
 
+// registrar returns the registrar for the services, which masks the sensitive values in the errors returned to piped,
+// records the handled RPCs to the audit sinks if configured, warns about the slow RPCs, returns the cached responses,
+// reports the load of the plugin to piped, sets the default deadlines, and records the payload sizes.
+func (c commonFields[Config, DeployTargetConfig]) registrar(server *grpc.Server) grpc.ServiceRegistrar {
+	var registrar grpc.ServiceRegistrar = payloadRegistrar{ServiceRegistrar: server, compressor: c.responseCompressor, logger: c.logger}
+	registrar = backpressureRegistrar{
+		ServiceRegistrar: registrar,
+		reporter:         loadReporter{stages: c.stageLimiter, livestate: c.livestatePool, logger: c.logger},
+	}
+	if c.deadlinePolicy != nil {
+		registrar = deadlineRegistrar{ServiceRegistrar: registrar, policy: c.deadlinePolicy}
+	}
+	if c.responseCache != nil {
+		registrar = cachingRegistrar{ServiceRegistrar: registrar, cache: c.responseCache}
+	}
+	if c.slowRPCDetector != nil {
+		registrar = slowRPCRegistrar{ServiceRegistrar: registrar, detector: c.slowRPCDetector}
+	}
+	if c.auditor != nil {
+		registrar = auditingRegistrar{ServiceRegistrar: registrar, auditor: c.auditor}
+	}
+	return redactingRegistrar{ServiceRegistrar: registrar, redactor: c.redactor}
+}
+
+// now returns the current time on the clock of the plugin.
+func (c commonFields[Config, DeployTargetConfig]) now() time.Time {
+	return clock.OrReal(c.clock).Now()
+}
+
+// currentPluginConfig returns the plugin config currently applied.
+func (c commonFields[Config, DeployTargetConfig]) currentPluginConfig() *Config {
+	if c.pluginConfig == nil {
+		return nil
+	}
+	return c.pluginConfig.Load()
+}
+
 // withLogger copies the commonFields and sets the logger to the given one.
 func (c commonFields[Config, DeployTargetConfig]) withLogger(logger *zap.Logger) commonFields[Config, DeployTargetConfig] {
 	c.logger = logger
@@ -138,6 +279,34 @@ func WithPlanPreviewPlugin[Config, DeployTargetConfig, ApplicationConfigSpec any
 	}
 }
 
+// WithClientInterceptors is a function that appends the interceptors for all outgoing calls to piped.
+// They are useful to enforce consistent logging, metrics, and headers across plugins.
+// The interceptors are called in the order in which they are added for each attempt of the call,
+// after the retry and the timeout handled by the SDK.
+// The type parameters can't be inferred, so they have to be given explicitly.
+func WithClientInterceptors[Config, DeployTargetConfig, ApplicationConfigSpec any](interceptors ...grpc.UnaryClientInterceptor) PluginOption[Config, DeployTargetConfig, ApplicationConfigSpec] {
+	return func(plugin *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) {
+		plugin.clientInterceptors = append(plugin.clientInterceptors, interceptors...)
+	}
+}
+
+// WithAuditSink is a function that appends the sink to write the audit records of the RPCs handled by the plugin.
+// The records are sampled by the --audit-sample-rate flag, and the failed RPCs are always recorded.
+func WithAuditSink[Config, DeployTargetConfig, ApplicationConfigSpec any](sink AuditSink) PluginOption[Config, DeployTargetConfig, ApplicationConfigSpec] {
+	return func(plugin *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) {
+		plugin.auditSinks = append(plugin.auditSinks, sink)
+	}
+}
+
+// WithClock is a function that sets the clock used by the SDK, e.g. to flush the stage logs, to retry the calls to piped, and to expire the caches.
+// It's useful to test the time-dependent behavior with clocktest.FakeClock.
+// The type parameters can't be inferred, so they have to be given explicitly.
+func WithClock[Config, DeployTargetConfig, ApplicationConfigSpec any](c clock.Clock) PluginOption[Config, DeployTargetConfig, ApplicationConfigSpec] {
+	return func(plugin *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) {
+		plugin.clock = clock.OrReal(c)
+	}
+}
+
 // Plugin is a wrapper for the plugin.
 // It provides a way to run the plugin with the given config and deploy target config.
 type Plugin[Config, DeployTargetConfig, ApplicationConfigSpec any] struct {
@@ -147,8 +316,24 @@ type Plugin[Config, DeployTargetConfig, ApplicationConfigSpec any] struct {
 	// name is the name of the plugin defined in the piped plugin config.
 	name string
 
-	// initializers
+	// initializers and finalizers
 	initializers []Initializer[Config, DeployTargetConfig]
+	finalizers   []Finalizer
+
+	// clientInterceptors are the user-defined interceptors for the calls to piped.
+	clientInterceptors []grpc.UnaryClientInterceptor
+
+	// auditSinks are the destinations of the audit records of the RPCs handled by the plugin.
+	auditSinks []AuditSink
+
+	// responseCache is the options of the cache of the responses to piped. It's nil when not enabled.
+	responseCache *responseCacheOptions
+
+	// healthChecks are the health checks served on the /healthz endpoint in addition to the connection to piped.
+	healthChecks []namedHealthCheck
+
+	// clock is used for all time-dependent behavior of the SDK.
+	clock clock.Clock
 
 	// plugin implementations
 	stagePlugin       StagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec]
@@ -163,16 +348,94 @@ type Plugin[Config, DeployTargetConfig, ApplicationConfigSpec any] struct {
 	certFile             string
 	keyFile              string
 	config               string
+	configTokenFile      string
+	configChecksum       string
+	configWatchInterval  time.Duration
 	enableGRPCReflection bool
+	toolsDir             string
+	toolsDirPerPlugin    bool
+	sharedToolsDirs      []string
+	toolInstallAttempts  int
+	toolDownloadRate     float64
+	toolDownloadBurst    int
+
+	pipedClientRetryAttempts    int
+	pipedClientBreakerThreshold int
+	pipedClientBreakerCooldown  time.Duration
+	pipedClientTimeout          time.Duration
+	pipedClientMethodTimeouts   map[string]string
+	pipedClientCompression      string
+
+	profilerEndpoint  string
+	profilerInterval  time.Duration
+	profilerTokenFile string
+
+	auditLogFile    string
+	auditSampleRate float64
+
+	maxConcurrentStages                int
+	maxConcurrentStagesPerDeployTarget int
+	stageQueueTimeout                  time.Duration
+
+	maxResponseSize    int
+	responseCompressor string
+
+	watchdogInterval   time.Duration
+	watchdogWindow     int
+	watchdogThresholds resourceThresholds
+
+	slowRPCThreshold        time.Duration
+	slowRPCMethodThresholds map[string]string
+
+	rpcDeadline        time.Duration
+	rpcMethodDeadlines map[string]string
+	nearDeadlineRatio  float64
+
+	livestateWorkers   int
+	livestateQueueSize int
+
+	healthCheckTimeout time.Duration
 }
 
 // NewPlugin creates a new plugin.
 func NewPlugin[Config, DeployTargetConfig, ApplicationConfigSpec any](version string, options ...PluginOption[Config, DeployTargetConfig, ApplicationConfigSpec]) (*Plugin[Config, DeployTargetConfig, ApplicationConfigSpec], error) {
 	plugin := &Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]{
 		version: version,
+		clock:   clock.Real,
 
 		// Default values of command line options
-		gracePeriod: 30 * time.Second,
+		gracePeriod:         30 * time.Second,
+		toolInstallAttempts: 3,
+		toolDownloadBurst:   1,
+
+		pipedClientRetryAttempts:    3,
+		pipedClientBreakerThreshold: 5,
+		pipedClientBreakerCooldown:  30 * time.Second,
+		pipedClientTimeout:          30 * time.Second,
+
+		profilerInterval: 10 * time.Second,
+
+		auditSampleRate: 1,
+
+		stageQueueTimeout: 10 * time.Minute,
+
+		slowRPCThreshold: 10 * time.Second,
+
+		rpcDeadline:       5 * time.Minute,
+		nearDeadlineRatio: 0.9,
+
+		livestateWorkers:   16,
+		livestateQueueSize: 1024,
+
+		watchdogInterval: time.Minute,
+		watchdogWindow:   60,
+		watchdogThresholds: resourceThresholds{
+			Goroutines: 10000,
+			OpenFDs:    4096,
+			HeapGrowth: 1,
+		},
+
+		healthCheckTimeout: 5 * time.Second,
 	}
 
 	for _, option := range options {
@@ -191,6 +454,10 @@ func NewPlugin[Config, DeployTargetConfig, ApplicationConfigSpec any](version st
 		return nil, fmt.Errorf("stage plugin and deployment plugin cannot be registered at the same time")
 	}
 
+	if err := validateHealthChecks(plugin.healthChecks); err != nil {
+		return nil, err
+	}
+
 	return plugin, nil
 }
 
@@ -203,6 +470,8 @@ func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) Run() error
 
 	app.AddCommands(
 		p.command(),
+		p.schemaCommand(),
+		p.runLocalCommand(),
 	)
 
 	if err := app.Run(); err != nil {
@@ -221,13 +490,60 @@ func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) command() *c
 	}
 
 	cmd.Flags().StringVar(&p.pipedPluginService, "piped-plugin-service", p.pipedPluginService, "The address used to connect to the piped plugin service.")
-	cmd.Flags().StringVar(&p.config, "config", p.config, "The configuration for the plugin.")
+	cmd.Flags().IntVar(&p.pipedClientRetryAttempts, "piped-client-retry-attempts", p.pipedClientRetryAttempts, "The maximum number of attempts for the calls to the piped plugin service failed with transient errors. ReportStageLogs and InstallTool are not retried.")
+	cmd.Flags().IntVar(&p.pipedClientBreakerThreshold, "piped-client-circuit-breaker-threshold", p.pipedClientBreakerThreshold, "The number of consecutive failures to stop calling the piped plugin service for a while. If zero, the circuit breaker is disabled.")
+	cmd.Flags().DurationVar(&p.pipedClientBreakerCooldown, "piped-client-circuit-breaker-cooldown", p.pipedClientBreakerCooldown, "How long to stop calling the piped plugin service after the circuit breaker is opened.")
+	cmd.Flags().DurationVar(&p.pipedClientTimeout, "piped-client-timeout", p.pipedClientTimeout, "The default timeout of each call to the piped plugin service. If zero, the calls have no timeout.")
+	cmd.Flags().StringToStringVar(&p.pipedClientMethodTimeouts, "piped-client-method-timeout", p.pipedClientMethodTimeouts, "The timeouts of the calls to the piped plugin service by the method name, e.g. PutStageMetadata=5s. InstallTool defaults to 10m.")
+	cmd.Flags().StringVar(&p.pipedClientCompression, "piped-client-compression", p.pipedClientCompression, "The compression of the requests to the piped plugin service. Supported values are \"gzip\" and empty (no compression).")
+	cmd.Flags().StringVar(&p.config, "config", p.config, "The configuration for the plugin in JSON or YAML, or its location as a file:// or https:// URL.")
+	cmd.Flags().StringVar(&p.configTokenFile, "config-token-file", p.configTokenFile, "The path to the file containing the bearer token to fetch the configuration over https.")
+	cmd.Flags().StringVar(&p.configChecksum, "config-checksum", p.configChecksum, "The expected checksum of the configuration in the form of sha256:<hex>.")
+	cmd.Flags().DurationVar(&p.configWatchInterval, "config-watch-interval", p.configWatchInterval, "The interval to check the changes of the configuration given as a file:// or https:// URL to reload it. If zero, it's reloaded only on SIGHUP.")
 	cmd.Flags().DurationVar(&p.gracePeriod, "grace-period", p.gracePeriod, "How long to wait for graceful shutdown.")
 
 	cmd.Flags().BoolVar(&p.tls, "tls", p.tls, "Whether running the gRPC server with TLS or not.")
 	cmd.Flags().StringVar(&p.certFile, "cert-file", p.certFile, "The path to the TLS certificate file.")
 	cmd.Flags().StringVar(&p.keyFile, "key-file", p.keyFile, "The path to the TLS key file.")
 
+	cmd.Flags().StringVar(&p.toolsDir, "tools-dir", p.toolsDir, "The directory to place the tools installed by the plugin. If empty, the tools installed by piped are used as is.")
+	cmd.Flags().BoolVar(&p.toolsDirPerPlugin, "tools-dir-per-plugin", p.toolsDirPerPlugin, "Whether to use a sub directory of the tools directory dedicated to the plugin.")
+	cmd.Flags().StringSliceVar(&p.sharedToolsDirs, "shared-tools-dir", p.sharedToolsDirs, "The read-only directories shared among plugins to look up the tools before installing them.")
+	cmd.Flags().IntVar(&p.toolInstallAttempts, "tool-install-attempts", p.toolInstallAttempts, "The maximum number of attempts to install a tool.")
+	cmd.Flags().Float64Var(&p.toolDownloadRate, "tool-download-rate", p.toolDownloadRate, "The maximum number of tool downloads per second for each host. If zero, the downloads are not limited.")
+	cmd.Flags().IntVar(&p.toolDownloadBurst, "tool-download-burst", p.toolDownloadBurst, "The maximum number of tool downloads at once for each host.")
+
+	cmd.Flags().StringVar(&p.profilerEndpoint, "profiler-endpoint", p.profilerEndpoint, "The address of the continuous profiling backend compatible with the Pyroscope ingest API to push the profiles to. If empty, the profiles are not pushed.")
+	cmd.Flags().DurationVar(&p.profilerInterval, "profiler-interval", p.profilerInterval, "The interval to push the profiles to the continuous profiling backend.")
+	cmd.Flags().StringVar(&p.profilerTokenFile, "profiler-token-file", p.profilerTokenFile, "The path to the file containing the bearer token to push the profiles.")
+
+	cmd.Flags().StringVar(&p.auditLogFile, "audit-log-file", p.auditLogFile, "The path to the file to append the audit records of the handled RPCs to as JSON lines.")
+	cmd.Flags().Float64Var(&p.auditSampleRate, "audit-sample-rate", p.auditSampleRate, "The ratio of the succeeded RPCs to record in the audit log, in the range of [0, 1]. The failed RPCs are always recorded.")
+
+	cmd.Flags().IntVar(&p.maxConcurrentStages, "max-concurrent-stages", p.maxConcurrentStages, "The maximum number of the stages executed concurrently. If zero, the stages are not limited.")
+	cmd.Flags().IntVar(&p.maxConcurrentStagesPerDeployTarget, "max-concurrent-stages-per-deploy-target", p.maxConcurrentStagesPerDeployTarget, "The maximum number of the stages executed concurrently on each deploy target. If zero, the stages are not limited.")
+	cmd.Flags().DurationVar(&p.stageQueueTimeout, "stage-queue-timeout", p.stageQueueTimeout, "How long a stage exceeding the concurrency limits waits to be executed before failing. If zero, it waits until the request is canceled.")
+
+	cmd.Flags().IntVar(&p.maxResponseSize, "max-response-size", p.maxResponseSize, "The maximum size in bytes of the livestate and plan preview responses. The larger responses are truncated to fit, since the unary RPCs of piped can't receive a response in chunks: the sync reason, the resource metadata values except the resource key, the health descriptions and the plan preview details are shortened and end with a marker telling how many bytes are dropped. If zero, the responses are not truncated.")
+	cmd.Flags().StringVar(&p.responseCompressor, "response-compression", p.responseCompressor, "The compression of the responses to piped. Supported values are \"gzip\" and empty (the same compression as the requests).")
+
+	cmd.Flags().DurationVar(&p.slowRPCThreshold, "slow-rpc-threshold", p.slowRPCThreshold, "The duration of the RPCs from piped to warn about as slow. If zero, the RPCs are not checked except the ones given by --slow-rpc-method-threshold.")
+	cmd.Flags().StringToStringVar(&p.slowRPCMethodThresholds, "slow-rpc-method-threshold", p.slowRPCMethodThresholds, "The slow RPC thresholds by the method name, e.g. GetLivestate=30s. ExecuteStage defaults to 30m. Zero disables the warnings for the method.")
+	cmd.Flags().DurationVar(&p.rpcDeadline, "rpc-deadline", p.rpcDeadline, "The fallback deadline of the RPCs from piped which don't set one; the deadline set by piped is always kept. If zero, no deadline is set.")
+	cmd.Flags().StringToStringVar(&p.rpcMethodDeadlines, "rpc-method-deadline", p.rpcMethodDeadlines, "The fallback deadlines of the RPCs from piped which don't set one by the method name, e.g. GetLivestate=1m; the deadline set by piped is always kept. Zero means no deadline is set for the method. ExecuteStage defaults to zero.")
+	cmd.Flags().Float64Var(&p.nearDeadlineRatio, "near-deadline-ratio", p.nearDeadlineRatio, "The ratio of the time until the deadline to count the RPCs from piped as completed near the deadline, in the range of (0, 1].")
+
+	cmd.Flags().IntVar(&p.livestateWorkers, "livestate-workers", p.livestateWorkers, "The maximum number of the livestate requests handled in parallel. If zero, the requests are not limited.")
+	cmd.Flags().IntVar(&p.livestateQueueSize, "livestate-queue-size", p.livestateQueueSize, "The maximum number of the livestate requests waiting for the workers. The excess requests are rejected to be retried by piped.")
+
+	cmd.Flags().DurationVar(&p.watchdogInterval, "watchdog-interval", p.watchdogInterval, "The interval to sample the goroutines, the open file descriptors, and the heap to warn about the leaks. If zero, the resource watchdog is disabled.")
+	cmd.Flags().IntVar(&p.watchdogWindow, "watchdog-window", p.watchdogWindow, "The number of the samples kept by the resource watchdog to detect the heap growth.")
+	cmd.Flags().IntVar(&p.watchdogThresholds.Goroutines, "watchdog-max-goroutines", p.watchdogThresholds.Goroutines, "The number of goroutines to warn about. If zero, the goroutines are not checked.")
+	cmd.Flags().IntVar(&p.watchdogThresholds.OpenFDs, "watchdog-max-open-fds", p.watchdogThresholds.OpenFDs, "The number of open file descriptors to warn about. If zero, the file descriptors are not checked.")
+	cmd.Flags().Float64Var(&p.watchdogThresholds.HeapGrowth, "watchdog-max-heap-growth", p.watchdogThresholds.HeapGrowth, "The ratio of the heap growth from the smallest heap in the window to warn about, e.g. 1 for doubling. If zero, the heap is not checked.")
+
+	cmd.Flags().DurationVar(&p.healthCheckTimeout, "health-check-timeout", p.healthCheckTimeout, "The timeout of the health checks served on the /healthz endpoint. If zero, the checks have no timeout.")
+
 	// For debugging early in development
 	cmd.Flags().BoolVar(&p.enableGRPCReflection, "enable-grpc-reflection", p.enableGRPCReflection, "Whether to enable the reflection service or not.")
 
@@ -257,14 +573,48 @@ func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) run(ctx cont
 
 	group, ctx := errgroup.WithContext(ctx)
 
-	pipedPluginServiceClient, err := newPluginServiceClient(ctx, p.pipedPluginService)
+	interceptors, err := p.pipedClientInterceptors()
+	if err != nil {
+		input.Logger.Error("invalid options for piped plugin service client", zap.Error(err))
+		return err
+	}
+
+	if p.auditSampleRate < 0 || p.auditSampleRate > 1 {
+		input.Logger.Error("invalid audit sample rate", zap.Float64("audit-sample-rate", p.auditSampleRate))
+		return fmt.Errorf("audit sample rate must be in the range of [0, 1]: %v", p.auditSampleRate)
+	}
+	if p.responseCompressor != "" && p.responseCompressor != gzip.Name {
+		input.Logger.Error("unsupported response compression", zap.String("response-compression", p.responseCompressor))
+		return fmt.Errorf("unsupported compression %q for responses", p.responseCompressor)
+	}
+	if p.auditLogFile != "" {
+		f, err := os.OpenFile(p.auditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			input.Logger.Error("failed to open the audit log file", zap.Error(err))
+			return err
+		}
+		defer f.Close()
+		p.auditSinks = append(p.auditSinks, NewAuditLogSink(f))
+	}
+
+	pipedPluginServiceClient, err := newPluginServiceClient(ctx, p.pipedPluginService, interceptors)
 	if err != nil {
 		input.Logger.Error("failed to create piped plugin service client", zap.Error(err))
 		return err
 	}
 
 	// Load the configuration.
-	cfg, err := config.ParsePluginConfig(p.config)
+	source := pluginConfigSource{
+		value:     p.config,
+		tokenFile: p.configTokenFile,
+		checksum:  p.configChecksum,
+	}
+	rawConfig, err := source.read(ctx)
+	if err != nil {
+		input.Logger.Error("failed to read the configuration", zap.Error(err))
+		return err
+	}
+	cfg, err := loadPluginConfig(rawConfig)
 	if err != nil {
 		input.Logger.Error("failed to parse the configuration", zap.Error(err))
 		return err
@@ -275,6 +625,24 @@ func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) run(ctx cont
 		zap.String("plugin-version", p.version),
 	)
 
+	// Register all metrics.
+	registry := registerMetrics(cfg.Name, p.version)
+
+	toolRegistry := p.newToolRegistry(pipedPluginServiceClient, cfg.Name)
+
+	// The connection to piped is always checked first.
+	health := newHealthHandler(append([]namedHealthCheck{{
+		name: pipedConnectionHealthCheck,
+		checker: HealthCheckFunc(func(context.Context) error {
+			if state := pipedPluginServiceClient.ConnectionState(); !state.Healthy() {
+				return fmt.Errorf("connection to piped is %s", state)
+			}
+			return nil
+		}),
+	}}, p.healthChecks...), p.healthCheckTimeout, p.clock)
+
+	ready := &readiness{}
+
 	// Start running admin server.
 	{
 		var (
@@ -285,9 +653,20 @@ func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) run(ctx cont
 		admin.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
 			w.Write(ver)
 		})
-		admin.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-			w.Write([]byte("ok"))
+		admin.Handle("/healthz", health)
+		admin.Handle("/readyz", ready)
+		admin.Handle("/metrics", input.PrometheusMetricsHandlerFor(registry))
+		admin.HandleFunc("/tools", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toolRegistry.List())
 		})
+		if p.watchdogInterval > 0 {
+			watchdog := newResourceWatchdog(p.watchdogInterval, p.watchdogWindow, p.watchdogThresholds, p.clock, logger)
+			admin.Handle("/debug/watchdog", watchdog)
+			group.Go(func() error {
+				return watchdog.Run(ctx)
+			})
+		}
 		admin.HandleFunc("/debug/pprof/", pprof.Index)
 		admin.HandleFunc("/debug/pprof/profile", pprof.Profile)
 		admin.HandleFunc("/debug/pprof/trace", pprof.Trace)
@@ -297,141 +676,51 @@ func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) run(ctx cont
 		})
 	}
 
+	// Start pushing the profiles to the continuous profiling backend.
+	if p.profilerEndpoint != "" {
+		prof, err := p.newProfiler(cfg.Name, logger)
+		if err != nil {
+			logger.Error("failed to create profiler", zap.Error(err))
+			return err
+		}
+		group.Go(func() error {
+			return prof.Run(ctx)
+		})
+	}
+
+	// Start watching the connection to piped.
+	connectionLogger := logger.Named("piped-connection")
+	group.Go(func() error {
+		return pipedPluginServiceClient.watchConnection(ctx, connectionLogger, p.connectionStateObservers()...)
+	})
+
 	// Start log persister
-	persister := logpersister.NewPersister(pipedPluginServiceClient, logger)
+	persister := logpersister.NewPersister(pipedPluginServiceClient, logger, logpersister.WithClock(p.clock))
 	group.Go(func() error {
 		return persister.Run(ctx)
 	})
 
 	// Start a gRPC server for handling external API requests.
 	{
-		commonFields := commonFields[Config, DeployTargetConfig]{
-			name:         cfg.Name,
-			version:      p.version,
-			config:       cfg,
-			logPersister: persister,
-			client:       pipedPluginServiceClient,
-			pluginConfig: new(Config),
-			toolRegistry: toolregistry.NewToolRegistry(pipedPluginServiceClient),
-		}
-
-		if len(cfg.Config) == 0 {
-			// It is necessary to prepare config with default value when users don't set any config,
-			// or when plugin developers implement custom unmarshalling logic.
-			cfg.Config = []byte("{}")
-		}
-
-		if err := json.Unmarshal(cfg.Config, commonFields.pluginConfig); err != nil {
-			logger.Fatal("failed to unmarshal the plugin config", zap.Error(err))
+		services, commonFields, err := p.newServices(ctx, cfg, pipedPluginServiceClient, persister, toolRegistry, logger)
+		if err != nil {
+			logger.Error("failed to set up the plugin", zap.Error(err))
 			return err
 		}
-
-		commonFields.deployTargets = make(map[string]*DeployTarget[DeployTargetConfig], len(cfg.DeployTargets))
-		for _, dt := range cfg.DeployTargets {
-			var sdkDt DeployTargetConfig
-			if err := json.Unmarshal(dt.Config, &sdkDt); err != nil {
-				logger.Fatal("failed to unmarshal deploy target config", zap.Error(err))
-				return err
-			}
-			commonFields.deployTargets[dt.Name] = &DeployTarget[DeployTargetConfig]{
-				Name:   dt.Name,
-				Labels: dt.Labels,
-				Config: sdkDt,
-			}
-		}
-
-		client := &Client{
-			base:         commonFields.client,
-			pluginName:   commonFields.name,
-			toolRegistry: commonFields.toolRegistry,
-			// These fields are not available at initializing state.
-			applicationID:     "",
-			deploymentID:      "",
-			stageID:           "",
-			stageLogPersister: nil,
-		}
-
-		initializeInput := &InitializeInput[Config, DeployTargetConfig]{
-			Config:        commonFields.pluginConfig,
-			DeployTargets: commonFields.deployTargets,
-			Client:        client,
-			Logger:        logger.Named("plugin-initializer"),
-		}
-
-		for _, initializer := range p.initializers {
-			if err := initializer.Initialize(ctx, initializeInput); err != nil {
-				logger.Error("failed to initialize plugin", zap.Error(err))
-				return err
-			}
-		}
-
-		var services []rpc.Service
-
-		if p.stagePlugin != nil {
-			if initializer, ok := p.stagePlugin.(Initializer[Config, DeployTargetConfig]); ok {
-				if err := initializer.Initialize(ctx, initializeInput); err != nil {
-					logger.Error("failed to initialize stage plugin", zap.Error(err))
-					return err
-				}
-			}
-			stagePluginServiceServer := &StagePluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]{
-				base:         p.stagePlugin,
-				commonFields: commonFields.withLogger(logger.Named("stage-service")),
-			}
-			services = append(services, stagePluginServiceServer)
-		}
-
-		if p.deploymentPlugin != nil {
-			if initializer, ok := p.deploymentPlugin.(Initializer[Config, DeployTargetConfig]); ok {
-				if err := initializer.Initialize(ctx, initializeInput); err != nil {
-					logger.Error("failed to initialize deployment plugin", zap.Error(err))
-					return err
-				}
-			}
-			deploymentPluginServiceServer := &DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]{
-				base:         p.deploymentPlugin,
-				commonFields: commonFields.withLogger(logger.Named("deployment-service")),
-			}
-			services = append(services, deploymentPluginServiceServer)
-		}
-
-		if p.livestatePlugin != nil {
-			if initializer, ok := p.livestatePlugin.(Initializer[Config, DeployTargetConfig]); ok {
-				if err := initializer.Initialize(ctx, initializeInput); err != nil {
-					logger.Error("failed to initialize livestate plugin", zap.Error(err))
-					return err
-				}
-			}
-			livestatePluginServiceServer := &LivestatePluginServer[Config, DeployTargetConfig, ApplicationConfigSpec]{
-				base:         p.livestatePlugin,
-				commonFields: commonFields.withLogger(logger.Named("livestate-service")),
-			}
-			services = append(services, livestatePluginServiceServer)
-		}
-
-		if p.planPreviewPlugin != nil {
-			if initializer, ok := p.planPreviewPlugin.(Initializer[Config, DeployTargetConfig]); ok {
-				if err := initializer.Initialize(ctx, initializeInput); err != nil {
-					logger.Error("failed to initialize plan-preview plugin", zap.Error(err))
-					return err
-				}
-			}
-			planPreviewPluginServiceServer := &PlanPreviewPluginServer[Config, DeployTargetConfig, ApplicationConfigSpec]{
-				base:         p.planPreviewPlugin,
-				commonFields: commonFields.withLogger(logger.Named("plan-preview-service")),
-			}
-			services = append(services, planPreviewPluginServiceServer)
-		}
-
-		if len(services) == 0 {
-			// This is promised in the NewPlugin function.
-			// When this happens, it means that *Plugin was initialized without using NewPlugin.
-			logger.Error(
-				"no plugin is registered, plugin implementation must use NewPlugin to initialize the plugin",
-				zap.String("name", p.name),
-				zap.String("version", p.version),
-			)
-			return fmt.Errorf("no plugin is registered, plugin implementation must use NewPlugin to initialize the plugin")
+		logger = commonFields.logger
+		health.redactor.Store(commonFields.redactor)
+		ready.initialized.Store(true)
+
+		// Reload the configuration given by its location while running.
+		if source.reloadable() {
+			reloader := newConfigReloader(source, rawConfig, p.configWatchInterval, func(ctx context.Context, rawConfig string) error {
+				return p.reloadConfig(ctx, commonFields, rawConfig, logger.Named("reload"))
+			}, p.clock, logger)
+			group.Go(func() error {
+				return reloader.Run(ctx)
+			})
+		} else if p.configWatchInterval > 0 {
+			logger.Warn("the configuration given as is is not watched, give it as a file:// or https:// URL to reload it")
 		}
 
 		var (
@@ -460,9 +749,19 @@ func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) run(ctx cont
 		}
 
 		server := rpc.NewServer(services[0], opts...)
+		group.Go(func() error {
+			ready.waitListening(ctx, cfg.Port, p.clock)
+			return nil
+		})
 
+		shutdownCtx, cancelShutdown := p.shutdownContext(ctx)
+		defer cancelShutdown()
 		group.Go(func() error {
-			return server.Run(ctx)
+			err := server.Run(ctx)
+			// The server has stopped accepting requests, so release the resources of the plugins within the grace period.
+			// The failures are logged but don't fail the shutdown.
+			p.finalize(shutdownCtx, logger)
+			return err
 		})
 	}
 
@@ -472,3 +771,410 @@ func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) run(ctx cont
 	}
 	return nil
 }
+
+// serveForTest runs the plugin in the same way as the start command for testhook.Serve.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) serveForTest(
+	ctx context.Context,
+	lis net.Listener,
+	service pipedservice.PluginServiceClient,
+	persister logPersister,
+	config string,
+	logger *zap.Logger,
+) error {
+	cfg, err := loadPluginConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse the configuration: %w", err)
+	}
+	interceptors, err := p.pipedClientInterceptors()
+	if err != nil {
+		return fmt.Errorf("invalid options for piped plugin service client: %w", err)
+	}
+	// Call the given service through the same interceptors as the client of piped used by the start command.
+	client := &pluginServiceClient{PluginServiceClient: pipedservice.NewPluginServiceClient(&inProcessConn{service: service, interceptors: interceptors})}
+
+	services, commonFields, err := p.newServices(ctx, cfg, client, persister, toolregistry.NewToolRegistry(client, toolregistry.WithClock(p.clock)), logger)
+	if err != nil {
+		return err
+	}
+	logger = commonFields.logger
+
+	// Use the same interceptors as the rpc.Server used by the start command,
+	// which can't serve on the given listener since it listens on the port by itself.
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		rpc.LogUnaryServerInterceptor(logger),
+		rpc.RequestValidationUnaryServerInterceptor(),
+		rpc.SignalHandlingInterceptor,
+	))
+	for _, s := range services {
+		s.Register(server)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Serve(lis)
+	}()
+
+	select {
+	case <-ctx.Done():
+	case err := <-errCh:
+		return err
+	}
+
+	// Stop the server and release the resources of the plugins within the grace period as the start command does.
+	shutdownCtx, cancelShutdown := p.shutdownContext(ctx)
+	defer cancelShutdown()
+	stop := context.AfterFunc(shutdownCtx, server.Stop)
+	defer stop()
+	server.GracefulStop()
+	return p.finalize(shutdownCtx, logger)
+}
+
+// newServices parses the plugin config, initializes the registered plugins, and returns their services.
+// The returned commonFields has the logger masking the sensitive values in the configs.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) newServices(ctx context.Context, cfg *config.PipedPlugin, client *pluginServiceClient, persister logPersister, toolRegistry *toolregistry.ToolRegistry, logger *zap.Logger) ([]rpc.Service, commonFields[Config, DeployTargetConfig], error) {
+	commonFields := commonFields[Config, DeployTargetConfig]{
+		name:         cfg.Name,
+		version:      p.version,
+		config:       cfg,
+		logPersister: persister,
+		client:       client,
+		pluginConfig: new(atomic.Pointer[Config]),
+		toolRegistry: toolRegistry,
+		clock:        p.clock,
+		stageLimiter: newStageLimiter(p.maxConcurrentStages, p.maxConcurrentStagesPerDeployTarget, p.stageQueueTimeout, p.clock),
+
+		maxResponseSize:    p.maxResponseSize,
+		responseCompressor: p.responseCompressor,
+		livestatePool:      newLivestatePool(p.livestateWorkers, p.livestateQueueSize),
+		responseCache:      newResponseCache(p.responseCache, p.clock),
+	}
+
+	pluginConfig, deployTargets, err := parseConfigs[Config, DeployTargetConfig](cfg)
+	if err != nil {
+		return nil, commonFields, err
+	}
+	commonFields.pluginConfig.Store(pluginConfig)
+	commonFields.deployTargets = newDeployTargetStore(deployTargets)
+
+	// Mask the sensitive values in the configs from here.
+	commonFields.redactor = &redactor{}
+	commonFields.redactor.set(append(sensitiveValues(pluginConfig), sensitiveValues(deployTargets)...))
+	commonFields.logPersister = redactingLogPersister{logPersister: persister, redactor: commonFields.redactor}
+	logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newRedactingCore(core, commonFields.redactor)
+	}))
+	commonFields.logger = logger
+	if commonFields.slowRPCDetector, err = newSlowRPCDetector(p.slowRPCThreshold, p.slowRPCMethodThresholds, p.clock, logger); err != nil {
+		return nil, commonFields, fmt.Errorf("invalid slow RPC thresholds: %w", err)
+	}
+	if commonFields.deadlinePolicy, err = newDeadlinePolicy(p.rpcDeadline, p.rpcMethodDeadlines, p.nearDeadlineRatio); err != nil {
+		return nil, commonFields, fmt.Errorf("invalid RPC deadlines: %w", err)
+	}
+	if len(p.auditSinks) > 0 {
+		commonFields.auditor = newAuditor(p.auditSinks, p.auditSampleRate, p.clock, logger)
+	}
+	// Mask the sensitive fields structurally, since the redacting logger doesn't mask the short values.
+	if data, err := maskSensitiveJSON(pluginConfig); err == nil {
+		logger.Info("loaded the plugin config",
+			zap.String("config", string(data)),
+			zap.Strings("deploy-targets", slices.Sorted(maps.Keys(deployTargets))),
+		)
+	}
+
+	initializeInput := &InitializeInput[Config, DeployTargetConfig]{
+		Config:        pluginConfig,
+		RawConfig:     cfg.Config,
+		DeployTargets: commonFields.deployTargets.snapshot(),
+		// The registry notifies the plugins of the changes after they are initialized.
+		DeployTargetRegistry: p.newDeployTargetRegistry(commonFields, logger.Named("deploy-target-registry")),
+		Client: &Client{
+			base:         commonFields.client,
+			pluginName:   commonFields.name,
+			toolRegistry: commonFields.toolRegistry,
+			clock:        commonFields.clock,
+			// These fields are not available at initializing state.
+			applicationID:     "",
+			deploymentID:      "",
+			stageID:           "",
+			stageLogPersister: nil,
+		},
+		Logger: logger.Named("plugin-initializer"),
+	}
+
+	for _, initializer := range p.roleInitializers() {
+		if err := initializer.Initialize(ctx, initializeInput); err != nil {
+			return nil, commonFields, fmt.Errorf("failed to initialize %s: %w", initializer.Role, err)
+		}
+	}
+
+	var services []rpc.Service
+
+	if p.stagePlugin != nil {
+		stagePluginServiceServer := &StagePluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]{
+			base:         p.stagePlugin,
+			commonFields: commonFields.withLogger(logger.Named("stage-service")),
+		}
+		services = append(services, stagePluginServiceServer)
+	}
+
+	if p.deploymentPlugin != nil {
+		deploymentPluginServiceServer := &DeploymentPluginServiceServer[Config, DeployTargetConfig, ApplicationConfigSpec]{
+			base:         p.deploymentPlugin,
+			commonFields: commonFields.withLogger(logger.Named("deployment-service")),
+		}
+		services = append(services, deploymentPluginServiceServer)
+	}
+
+	if p.livestatePlugin != nil {
+		livestatePluginServiceServer := &LivestatePluginServer[Config, DeployTargetConfig, ApplicationConfigSpec]{
+			base:         p.livestatePlugin,
+			commonFields: commonFields.withLogger(logger.Named("livestate-service")),
+		}
+		services = append(services, livestatePluginServiceServer)
+	}
+
+	if p.planPreviewPlugin != nil {
+		planPreviewPluginServiceServer := &PlanPreviewPluginServer[Config, DeployTargetConfig, ApplicationConfigSpec]{
+			base:         p.planPreviewPlugin,
+			commonFields: commonFields.withLogger(logger.Named("plan-preview-service")),
+		}
+		services = append(services, planPreviewPluginServiceServer)
+	}
+
+	if len(services) == 0 {
+		// This is promised in the NewPlugin function.
+		// When this happens, it means that *Plugin was initialized without using NewPlugin.
+		return nil, commonFields, fmt.Errorf("no plugin is registered, plugin implementation must use NewPlugin to initialize the plugin")
+	}
+	return services, commonFields, nil
+}
+
+// parseConfigs decodes the plugin config and the deploy target configs in the piped plugin config,
+// applying the defaults and validating them.
+func parseConfigs[Config, DeployTargetConfig any](cfg *config.PipedPlugin) (*Config, map[string]*DeployTarget[DeployTargetConfig], error) {
+	if len(cfg.Config) == 0 {
+		// It is necessary to prepare config with default value when users don't set any config,
+		// or when plugin developers implement custom unmarshalling logic.
+		cfg.Config = []byte("{}")
+	}
+
+	pluginConfig := new(Config)
+	if err := decodeConfig(cfg.Config, pluginConfig, "config"); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal the plugin config: %w", err)
+	}
+
+	if err := setDefaults(pluginConfig); err != nil {
+		return nil, nil, fmt.Errorf("failed to set defaults of the plugin config: %w", err)
+	}
+
+	if err := validate(pluginConfig); err != nil {
+		return nil, nil, fmt.Errorf("invalid plugin config: config: %w", err)
+	}
+
+	deployTargets, err := parseDeployTargets[DeployTargetConfig](cfg.DeployTargets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse deploy target config: %w", err)
+	}
+	return pluginConfig, deployTargets, nil
+}
+
+// RoleInitializer is the Initializer registered for a role of the plugin.
+type RoleInitializer[Config, DeployTargetConfig any] struct {
+	Initializer[Config, DeployTargetConfig]
+	// Role is the role which the initializer is registered for, one of the InitializeRole constants.
+	// It's InitializeRolePlugin for the initializers added by WithInitializer.
+	Role string
+}
+
+// Initialize calls the initializer with the copy of the input whose Role is set to the role of the initializer.
+func (r RoleInitializer[Config, DeployTargetConfig]) Initialize(ctx context.Context, input *InitializeInput[Config, DeployTargetConfig]) error {
+	in := *input
+	in.Role = r.Role
+	return r.Initializer.Initialize(ctx, &in)
+}
+
+// roleInitializers returns the initializers in the order they are called at start:
+// the ones added by WithInitializer, then the registered plugins implementing Initializer.
+// A plugin registered for multiple roles appears once per role, since it's initialized for each of them.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) roleInitializers() []RoleInitializer[Config, DeployTargetConfig] {
+	var initializers []RoleInitializer[Config, DeployTargetConfig]
+	for _, initializer := range p.initializers {
+		initializers = append(initializers, RoleInitializer[Config, DeployTargetConfig]{Initializer: initializer, Role: InitializeRolePlugin})
+	}
+	roles := []struct {
+		name   string
+		plugin any
+	}{
+		{name: InitializeRoleStage, plugin: p.stagePlugin},
+		{name: InitializeRoleDeployment, plugin: p.deploymentPlugin},
+		{name: InitializeRoleLivestate, plugin: p.livestatePlugin},
+		{name: InitializeRolePlanPreview, plugin: p.planPreviewPlugin},
+	}
+	for _, r := range roles {
+		if initializer, ok := r.plugin.(Initializer[Config, DeployTargetConfig]); ok {
+			initializers = append(initializers, RoleInitializer[Config, DeployTargetConfig]{Initializer: initializer, Role: r.name})
+		}
+	}
+	return initializers
+}
+
+// connectionStateObservers returns the registered plugins which want to be notified of the connection state changes.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) connectionStateObservers() []ConnectionStateObserver {
+	var observers []ConnectionStateObserver
+	for _, plugin := range p.plugins() {
+		if o, ok := plugin.(ConnectionStateObserver); ok {
+			observers = append(observers, o)
+		}
+	}
+	return observers
+}
+
+// plugins returns the registered plugins.
+// The plugin registered for multiple roles is returned only once. The non-comparable ones can't be the same.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) plugins() []any {
+	var plugins []any
+	for _, plugin := range []any{p.stagePlugin, p.deploymentPlugin, p.livestatePlugin, p.planPreviewPlugin} {
+		if plugin == nil {
+			continue
+		}
+		if reflect.TypeOf(plugin).Comparable() && slices.ContainsFunc(plugins, func(e any) bool {
+			return reflect.TypeOf(e).Comparable() && e == plugin
+		}) {
+			continue
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins
+}
+
+// updateDeployTargets replaces the deploy targets in the store and notifies the registered plugins implementing DeployTargetObserver of the changes.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) updateDeployTargets(ctx context.Context, store *deployTargetStore[DeployTargetConfig], targets map[string]*DeployTarget[DeployTargetConfig], logger *zap.Logger) DeployTargetChanges[DeployTargetConfig] {
+	old := store.snapshot()
+	changes := store.replace(targets)
+	p.notifyDeployTargetChanges(ctx, old, changes, logger)
+	return changes
+}
+
+// newDeployTargetRegistry returns the registry of the deploy targets in the store of the given commonFields,
+// which notifies the registered plugins implementing DeployTargetObserver of the changes.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) newDeployTargetRegistry(fields commonFields[Config, DeployTargetConfig], logger *zap.Logger) *DeployTargetRegistry[DeployTargetConfig] {
+	return &DeployTargetRegistry[DeployTargetConfig]{
+		store:    fields.deployTargets,
+		redactor: fields.redactor,
+		notify: func(ctx context.Context, old map[string]*DeployTarget[DeployTargetConfig], changes DeployTargetChanges[DeployTargetConfig]) {
+			p.notifyDeployTargetChanges(ctx, old, changes, logger)
+		},
+	}
+}
+
+// notifyDeployTargetChanges logs the changes of the deploy targets and notifies the registered plugins implementing DeployTargetObserver of them.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) notifyDeployTargetChanges(ctx context.Context, old map[string]*DeployTarget[DeployTargetConfig], changes DeployTargetChanges[DeployTargetConfig], logger *zap.Logger) {
+	if changes.Empty() {
+		return
+	}
+
+	logger.Info("deploy targets have been changed",
+		zap.Int("added", len(changes.Added)),
+		zap.Int("updated", len(changes.Updated)),
+		zap.Int("removed", len(changes.Removed)),
+	)
+	logDeployTargetChanges(logger, old, changes)
+	for _, plugin := range p.plugins() {
+		if o, ok := plugin.(DeployTargetObserver[DeployTargetConfig]); ok {
+			if err := o.OnDeployTargetsChanged(ctx, changes); err != nil {
+				logger.Error("failed to handle the changes of the deploy targets", zap.Error(err))
+			}
+		}
+	}
+}
+
+// pipedClientInterceptors returns the interceptors for the piped plugin service client configured by the command line options.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) pipedClientInterceptors() ([]grpc.UnaryClientInterceptor, error) {
+	methodTimeouts, err := parseMethodTimeouts(p.pipedClientMethodTimeouts)
+	if err != nil {
+		return nil, err
+	}
+	interceptors := []grpc.UnaryClientInterceptor{
+		newCircuitBreaker(p.pipedClientBreakerThreshold, p.pipedClientBreakerCooldown, p.clock).unaryClientInterceptor(),
+		retryUnaryClientInterceptor(p.pipedClientRetryAttempts, 100*time.Millisecond, 5*time.Second, p.clock),
+		// The timeout is applied to each attempt.
+		timeoutUnaryClientInterceptor(p.pipedClientTimeout, methodTimeouts),
+		correlationUnaryClientInterceptor(),
+	}
+
+	switch p.pipedClientCompression {
+	case "":
+	case gzip.Name:
+		interceptors = append(interceptors, compressionUnaryClientInterceptor(gzip.Name))
+	default:
+		return nil, fmt.Errorf("unsupported compression %q for piped plugin service client", p.pipedClientCompression)
+	}
+
+	return append(interceptors, p.clientInterceptors...), nil
+}
+
+// newToolRegistry creates a new tool registry configured by the command line options.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) newToolRegistry(client *pluginServiceClient, pluginName string) *toolregistry.ToolRegistry {
+	opts := []toolregistry.Option{
+		toolregistry.WithToolsDir(p.toolsDir),
+		toolregistry.WithSharedToolsDirs(p.sharedToolsDirs...),
+		toolregistry.WithInstallRetry(p.toolInstallAttempts, time.Second, 30*time.Second),
+		toolregistry.WithDownloadRateLimit(rate.Limit(p.toolDownloadRate), p.toolDownloadBurst),
+		toolregistry.WithClock(p.clock),
+	}
+	if p.toolsDirPerPlugin {
+		opts = append(opts, toolregistry.WithPluginIsolation(pluginName))
+	}
+	return toolregistry.NewToolRegistry(client, opts...)
+}
+
+// newProfiler creates a new profiler configured by the command line options.
+// The profiles are labeled with the plugin name and version.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) newProfiler(pluginName string, logger *zap.Logger) (*profiler.Profiler, error) {
+	opts := []profiler.Option{
+		profiler.WithLabels(map[string]string{
+			"plugin":         pluginName,
+			"plugin_version": p.version,
+		}),
+		profiler.WithInterval(p.profilerInterval),
+		profiler.WithClock(p.clock),
+	}
+	if p.profilerTokenFile != "" {
+		token, err := os.ReadFile(p.profilerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token to push the profiles: %w", err)
+		}
+		opts = append(opts, profiler.WithToken(strings.TrimSpace(string(token))))
+	}
+	return profiler.NewProfiler(p.profilerEndpoint, pluginName, logger, opts...), nil
+}
+
+func registerMetrics(pluginName, pluginVersion string) *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	wrapped := prometheus.WrapRegistererWith(
+		map[string]string{
+			"pipecd_component": "plugin",
+			"plugin":           pluginName,
+			"plugin_version":   pluginVersion,
+		},
+		r,
+	)
+	wrapped.Register(collectors.NewGoCollector())
+	wrapped.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	toolregistrymetrics.Register(wrapped)
+	filelockmetrics.Register(wrapped)
+	registerClientMetrics(wrapped)
+	registerServerMetrics(wrapped)
+	registerPayloadMetrics(wrapped)
+	registerWatchdogMetrics(wrapped)
+	registerSlowRPCMetrics(wrapped)
+	registerLivestatePoolMetrics(wrapped)
+	registerResponseCacheMetrics(wrapped)
+	registerBackpressureMetrics(wrapped)
+	registerDeadlineMetrics(wrapped)
+	registerReloadMetrics(wrapped)
+	registerHealthMetrics(wrapped)
+
+	return r
+}
@@ -16,7 +16,17 @@ package sdk
 
 import (
 	"context"
+	"errors"
 	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	config "github.com/pipe-cd/pipecd/pkg/configv1"
 )
 
 var (
@@ -158,3 +168,100 @@ func ExampleWithPlanPreviewPlugin() {
 	// plugin.Run()
 	_ = plugin
 }
+
+func ExampleWithClientInterceptors() {
+	// Add a header to all calls to piped.
+	withTeamHeader := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-team", "platform")
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	plugin, err := NewPlugin("1.0.0",
+		WithDeploymentPlugin(ExampleDeploymentPlugin{}),
+		WithClientInterceptors[ExampleConfig, ExampleDeployTargetConfig, ExampleApplicationConfigSpec](withTeamHeader),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// plugin.Run()
+	_ = plugin
+}
+
+type validatedDeployTargetConfig struct {
+	Region string `json:"region"`
+}
+
+func (c validatedDeployTargetConfig) Validate() error {
+	if c.Region == "" {
+		return errors.New("region must be set")
+	}
+	return nil
+}
+
+func TestParseDeployTargets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		deployTargets []config.PipedDeployTarget
+		expected      map[string]*DeployTarget[validatedDeployTargetConfig]
+		expectedErr   string
+	}{
+		{
+			name: "valid deploy targets",
+			deployTargets: []config.PipedDeployTarget{
+				{Name: "dt1", Labels: map[string]string{"env": "prod"}, Config: []byte(`{"region":"us"}`)},
+			},
+			expected: map[string]*DeployTarget[validatedDeployTargetConfig]{
+				"dt1": {Name: "dt1", Labels: map[string]string{"env": "prod"}, Config: validatedDeployTargetConfig{Region: "us"}, RawConfig: []byte(`{"region":"us"}`)},
+			},
+		},
+		{
+			name: "invalid deploy target",
+			deployTargets: []config.PipedDeployTarget{
+				{Name: "dt1", Config: []byte(`{"region":"us"}`)},
+				{Name: "dt2", Config: []byte(`{}`)},
+			},
+			expectedErr: "invalid config of the deploy target dt2: deployTargets[dt2].config: region must be set",
+		},
+		{
+			name: "malformed deploy target",
+			deployTargets: []config.PipedDeployTarget{
+				{Name: "dt1", Config: []byte(`{"region":1}`)},
+			},
+			expectedErr: "failed to unmarshal the config of the deploy target dt1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseDeployTargets[validatedDeployTargetConfig](tt.deployTargets)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestPlugin_newServices_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	// The invalid config fails the plugin at start before initializing the plugins.
+	p := &Plugin[validatedDeployTargetConfig, validatedDeployTargetConfig, struct{}]{}
+	_, _, err := p.newServices(context.Background(), &config.PipedPlugin{Name: "plugin", Config: []byte(`{}`)}, nil, nil, nil, zap.NewNop())
+	assert.EqualError(t, err, "invalid plugin config: config: region must be set")
+
+	_, _, err = p.newServices(context.Background(), &config.PipedPlugin{
+		Name:          "plugin",
+		Config:        []byte(`{"region":"us"}`),
+		DeployTargets: []config.PipedDeployTarget{{Name: "dt1", Config: []byte(`{}`)}},
+	}, nil, nil, nil, zap.NewNop())
+	assert.ErrorContains(t, err, "invalid config of the deploy target dt1")
+}
@@ -0,0 +1,302 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	config "github.com/pipe-cd/pipecd/pkg/configv1"
+	yamlv3 "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/digest"
+)
+
+// pluginConfigFetchTimeout is the timeout to fetch the plugin config from the remote source.
+const pluginConfigFetchTimeout = 30 * time.Second
+
+// pluginConfigSource is where the plugin config given by the --config flag comes from.
+type pluginConfigSource struct {
+	// value is the value of the --config flag.
+	// It's the config itself, or its location as a file:// or https:// URL.
+	value string
+	// tokenFile is the path to the file containing the bearer token to fetch the config over https.
+	tokenFile string
+	// checksum is the expected checksum of the config in the form of "sha256:<hex>".
+	checksum string
+
+	httpClient *http.Client
+}
+
+// reloadable returns true if the config is given by its location, so that it can be read again to reload.
+func (s pluginConfigSource) reloadable() bool {
+	return strings.HasPrefix(s.value, "file://") || strings.HasPrefix(s.value, "https://")
+}
+
+// read returns the content of the plugin config, verifying its checksum if it's given.
+func (s pluginConfigSource) read(ctx context.Context) (string, error) {
+	data, err := s.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyChecksum(data, s.checksum); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (s pluginConfigSource) fetch(ctx context.Context) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(s.value, "file://"):
+		u, err := url.Parse(s.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config location %s: %w", s.value, err)
+		}
+		data, err := os.ReadFile(u.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the config from %s: %w", s.value, err)
+		}
+		return data, nil
+	case strings.HasPrefix(s.value, "https://"):
+		return s.fetchHTTPS(ctx)
+	case strings.HasPrefix(s.value, "http://"):
+		return nil, fmt.Errorf("invalid config location %s: only https is supported to fetch the config", s.value)
+	default:
+		return []byte(s.value), nil
+	}
+}
+
+func (s pluginConfigSource) fetchHTTPS(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginConfigFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.value, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config location %s: %w", s.value, err)
+	}
+	if s.tokenFile != "" {
+		token, err := os.ReadFile(s.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the token to fetch the config: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	client := s.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the config from %s: %w", s.value, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the config from %s: unexpected status %s", s.value, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the config from %s: %w", s.value, err)
+	}
+	return data, nil
+}
+
+// verifyChecksum verifies the data matches the checksum in the form of "sha256:<hex>".
+// The empty checksum is always satisfied.
+func verifyChecksum(data []byte, checksum string) error {
+	if checksum == "" {
+		return nil
+	}
+	algo, expected, ok := strings.Cut(checksum, ":")
+	if !ok || algo != "sha256" {
+		return fmt.Errorf("invalid config checksum %q: it must be in the form of sha256:<hex>", checksum)
+	}
+	if actual := digest.FromBytes(data).Hex(); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("config checksum mismatch: expected sha256:%s, got sha256:%s", expected, actual)
+	}
+	return nil
+}
+
+// loadPluginConfig parses the piped plugin config given as JSON or YAML.
+//
+// The YAML config may use anchors and aliases, and may consist of multiple documents.
+// The documents are merged in order: the objects are merged recursively, the deployTargets are concatenated,
+// and the other values are replaced by the later documents. So the large list of the deploy targets can be split, e.g.
+//
+//	name: example
+//	url: https://example.com/plugin
+//	deployTargets:
+//	  - name: dt1
+//	    config: &base
+//	      region: us-east-1
+//	---
+//	deployTargets:
+//	  - name: dt2
+//	    config:
+//	      <<: *base
+//	      region: us-west-1
+//
+// The defaults block is merged into the config of every deploy target, and the deploy targets can override its fields, e.g.
+//
+//	defaults:
+//	  endpoint: https://api.example.com
+//	  timeout: 30s
+//	deployTargets:
+//	  - name: dt1
+//	    config:
+//	      region: us-east-1
+//	  - name: dt2
+//	    config:
+//	      region: us-west-1
+//	      timeout: 1m
+func loadPluginConfig(s string) (*config.PipedPlugin, error) {
+	data := []byte(s)
+	// Keep the JSON config as it is, since it's what the piped gives.
+	if !strings.HasPrefix(strings.TrimSpace(s), "{") {
+		var err error
+		if data, err = pluginConfigYAMLToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := config.ParsePluginConfig(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]struct{}, len(cfg.DeployTargets))
+	for _, dt := range cfg.DeployTargets {
+		if _, ok := names[dt.Name]; ok {
+			return nil, fmt.Errorf("duplicated deploy target %s", dt.Name)
+		}
+		names[dt.Name] = struct{}{}
+	}
+
+	if err := applyDeployTargetDefaults(cfg, data); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyDeployTargetDefaults merges the defaults block in the plugin config into the config of every deploy target.
+func applyDeployTargetDefaults(cfg *config.PipedPlugin, data []byte) error {
+	var raw struct {
+		Defaults json.RawMessage `json:"defaults"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse the defaults of the deploy targets: %w", err)
+	}
+	if len(raw.Defaults) == 0 || string(raw.Defaults) == "null" {
+		return nil
+	}
+
+	for i, dt := range cfg.DeployTargets {
+		// Decode the defaults every time since they are modified by merging.
+		var defaults, c any
+		if err := json.Unmarshal(raw.Defaults, &defaults); err != nil {
+			return fmt.Errorf("failed to parse the defaults of the deploy targets: %w", err)
+		}
+		if _, ok := defaults.(map[string]any); !ok {
+			return errors.New("invalid defaults of the deploy targets: it must be an object")
+		}
+		if len(dt.Config) > 0 {
+			if err := json.Unmarshal(dt.Config, &c); err != nil {
+				return fmt.Errorf("failed to parse the config of the deploy target %s: %w", dt.Name, err)
+			}
+		}
+
+		merged, err := mergeJSON(defaults, c, fmt.Sprintf("deployTargets[%s].config", dt.Name))
+		if err != nil {
+			return fmt.Errorf("failed to apply the defaults to the deploy target %s: %w", dt.Name, err)
+		}
+		if cfg.DeployTargets[i].Config, err = json.Marshal(merged); err != nil {
+			return fmt.Errorf("failed to apply the defaults to the deploy target %s: %w", dt.Name, err)
+		}
+	}
+	return nil
+}
+
+// pluginConfigYAMLToJSON converts the YAML documents into a JSON object by merging them.
+func pluginConfigYAMLToJSON(data []byte) ([]byte, error) {
+	var merged map[string]any
+	dec := yamlv3.NewDecoder(bytes.NewReader(data))
+	for i := 0; ; i++ {
+		var node yamlv3.Node
+		if err := dec.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse the plugin config: document %d: %w", i, err)
+		}
+		if node.Kind == 0 {
+			// Skip the empty document.
+			continue
+		}
+
+		// Re-encode the document to resolve the anchors and aliases while converting it to JSON.
+		doc, err := yamlv3.Marshal(&node)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the plugin config: document %d: %w", i, err)
+		}
+		js, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the plugin config: document %d: %w", i, err)
+		}
+
+		var obj map[string]any
+		if err := json.Unmarshal(js, &obj); err != nil {
+			return nil, fmt.Errorf("failed to parse the plugin config: document %d must be an object: %w", i, err)
+		}
+		merged = mergePluginConfigDocument(merged, obj)
+	}
+	if merged == nil {
+		return nil, errors.New("failed to parse the plugin config: no document")
+	}
+	return json.Marshal(merged)
+}
+
+// mergePluginConfigDocument merges the document onto the base.
+func mergePluginConfigDocument(base, doc map[string]any) map[string]any {
+	if base == nil {
+		return doc
+	}
+	for k, v := range doc {
+		switch bv := base[k].(type) {
+		case map[string]any:
+			if dv, ok := v.(map[string]any); ok {
+				base[k] = mergePluginConfigDocument(bv, dv)
+				continue
+			}
+		case []any:
+			if dv, ok := v.([]any); ok && k == "deployTargets" {
+				base[k] = append(bv, dv...)
+				continue
+			}
+		}
+		base[k] = v
+	}
+	return base
+}
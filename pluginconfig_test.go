@@ -0,0 +1,266 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPluginConfig(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name                  string
+		input                 string
+		expectedDeployTargets map[string]string
+		expectedConfig        string
+		expectedErr           bool
+	}{
+		{
+			name:                  "json",
+			input:                 `{"name":"example","url":"https://example.com","config":{"a":1},"deployTargets":[{"name":"dt1","config":{"region":"us"}}]}`,
+			expectedDeployTargets: map[string]string{"dt1": `{"region":"us"}`},
+			expectedConfig:        `{"a":1}`,
+		},
+		{
+			name: "yaml with anchors",
+			input: `
+name: example
+url: https://example.com
+deployTargets:
+  - name: dt1
+    config: &base
+      region: us
+      replicas: 2
+  - name: dt2
+    config:
+      <<: *base
+      region: eu
+`,
+			expectedDeployTargets: map[string]string{
+				"dt1": `{"region":"us","replicas":2}`,
+				"dt2": `{"region":"eu","replicas":2}`,
+			},
+		},
+		{
+			name: "yaml with multiple documents",
+			input: `
+name: example
+url: https://example.com
+config:
+  a: 1
+  b: 2
+deployTargets:
+  - name: dt1
+    config:
+      region: us
+---
+config:
+  b: 3
+deployTargets:
+  - name: dt2
+    config:
+      region: eu
+---
+`,
+			expectedDeployTargets: map[string]string{
+				"dt1": `{"region":"us"}`,
+				"dt2": `{"region":"eu"}`,
+			},
+			expectedConfig: `{"a":1,"b":3}`,
+		},
+		{
+			name: "defaults merged into deploy targets",
+			input: `
+name: example
+url: https://example.com
+defaults:
+  endpoint: https://api.example.com
+  options:
+    timeout: 30s
+    retries: 3
+deployTargets:
+  - name: dt1
+    config:
+      region: us
+  - name: dt2
+    config:
+      region: eu
+      options:
+        timeout: 1m
+  - name: dt3
+`,
+			expectedDeployTargets: map[string]string{
+				"dt1": `{"endpoint":"https://api.example.com","options":{"timeout":"30s","retries":3},"region":"us"}`,
+				"dt2": `{"endpoint":"https://api.example.com","options":{"timeout":"1m","retries":3},"region":"eu"}`,
+				"dt3": `{"endpoint":"https://api.example.com","options":{"timeout":"30s","retries":3}}`,
+			},
+		},
+		{
+			name:                  "defaults in json",
+			input:                 `{"name":"example","url":"https://example.com","defaults":{"a":1},"deployTargets":[{"name":"dt1","config":{"b":2}}]}`,
+			expectedDeployTargets: map[string]string{"dt1": `{"a":1,"b":2}`},
+		},
+		{
+			name:        "defaults conflicting with deploy target",
+			input:       `{"name":"example","url":"https://example.com","defaults":{"a":{"b":1}},"deployTargets":[{"name":"dt1","config":{"a":2}}]}`,
+			expectedErr: true,
+		},
+		{
+			name:        "defaults is not an object",
+			input:       `{"name":"example","url":"https://example.com","defaults":[1],"deployTargets":[{"name":"dt1","config":{}}]}`,
+			expectedErr: true,
+		},
+		{
+			name: "duplicated deploy targets",
+			input: `
+name: example
+url: https://example.com
+deployTargets:
+  - name: dt1
+---
+deployTargets:
+  - name: dt1
+`,
+			expectedErr: true,
+		},
+		{
+			name:        "document is not an object",
+			input:       "- a\n- b\n",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid yaml",
+			input:       "name: [",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := loadPluginConfig(tc.input)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "example", cfg.Name)
+
+			deployTargets := make(map[string]string, len(cfg.DeployTargets))
+			for _, dt := range cfg.DeployTargets {
+				deployTargets[dt.Name] = string(dt.Config)
+			}
+			assert.Len(t, deployTargets, len(tc.expectedDeployTargets))
+			for name, expected := range tc.expectedDeployTargets {
+				assert.JSONEq(t, expected, deployTargets[name])
+			}
+			if tc.expectedConfig != "" {
+				assert.JSONEq(t, tc.expectedConfig, string(cfg.Config))
+			}
+		})
+	}
+}
+
+func TestPluginConfigSource_Read(t *testing.T) {
+	t.Parallel()
+
+	const content = "name: example\nurl: https://example.com\n"
+	sum := sha256.Sum256([]byte(content))
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0o600))
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+
+	testcases := []struct {
+		name        string
+		source      pluginConfigSource
+		expectedErr bool
+	}{
+		{
+			name:   "inline",
+			source: pluginConfigSource{value: content},
+		},
+		{
+			name:   "file with checksum",
+			source: pluginConfigSource{value: "file://" + file, checksum: checksum},
+		},
+		{
+			name:   "https with token and checksum",
+			source: pluginConfigSource{value: server.URL, tokenFile: tokenFile, checksum: checksum},
+		},
+		{
+			name:        "https without token",
+			source:      pluginConfigSource{value: server.URL},
+			expectedErr: true,
+		},
+		{
+			name:        "http",
+			source:      pluginConfigSource{value: "http://example.com/config.yaml"},
+			expectedErr: true,
+		},
+		{
+			name:        "missing file",
+			source:      pluginConfigSource{value: "file://" + filepath.Join(dir, "missing")},
+			expectedErr: true,
+		},
+		{
+			name:        "checksum mismatch",
+			source:      pluginConfigSource{value: content, checksum: "sha256:0000"},
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported checksum algorithm",
+			source:      pluginConfigSource{value: content, checksum: "md5:0000"},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.source.httpClient = server.Client()
+			got, err := tc.source.read(context.Background())
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, content, got)
+		})
+	}
+}
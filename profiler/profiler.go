@@ -0,0 +1,221 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package profiler pushes the pprof profiles of the plugin to a continuous profiling backend periodically,
+// so that the slow stages in production can be diagnosed without capturing the profiles manually.
+//
+// The profiles are sent to the ingest API of Pyroscope, which is accepted by the compatible backends as well:
+//
+//	POST <endpoint>/ingest?name=<app>.<type>{<labels>}&from=<unix>&until=<unix>&format=pprof&spyName=gospy
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	spyName         = "gospy"
+)
+
+// Profiler pushes the CPU and heap profiles of the process on an interval.
+type Profiler struct {
+	endpoint string
+	appName  string
+	labels   map[string]string
+	interval time.Duration
+	token    string
+	client   *http.Client
+	clock    clock.Clock
+	logger   *zap.Logger
+}
+
+// Option configures the profiler.
+type Option func(*Profiler)
+
+// WithLabels adds the labels attached to the profiles, e.g. the plugin name and version.
+func WithLabels(labels map[string]string) Option {
+	return func(p *Profiler) {
+		maps.Copy(p.labels, labels)
+	}
+}
+
+// WithInterval sets the interval to push the profiles.
+// The CPU profile covers the whole interval. The default is 10 seconds.
+func WithInterval(d time.Duration) Option {
+	return func(p *Profiler) {
+		if d > 0 {
+			p.interval = d
+		}
+	}
+}
+
+// WithToken sets the bearer token to authenticate to the backend.
+func WithToken(token string) Option {
+	return func(p *Profiler) {
+		p.token = token
+	}
+}
+
+// WithHTTPClient sets the HTTP client to push the profiles.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Profiler) {
+		p.client = c
+	}
+}
+
+// WithClock sets the clock used to push the profiles periodically.
+// It's useful to control the time in tests.
+func WithClock(c clock.Clock) Option {
+	return func(p *Profiler) {
+		p.clock = clock.OrReal(c)
+	}
+}
+
+// NewProfiler creates a new profiler pushing the profiles of the application to the endpoint of the backend.
+func NewProfiler(endpoint, appName string, logger *zap.Logger, opts ...Option) *Profiler {
+	p := &Profiler{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		appName:  appName,
+		labels:   make(map[string]string),
+		interval: defaultInterval,
+		client:   http.DefaultClient,
+		clock:    clock.Real,
+		logger:   logger.Named("profiler"),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Run pushes the profiles on every interval until the context is done.
+// The failures to collect or push the profiles are logged, and don't stop the profiler.
+func (p *Profiler) Run(ctx context.Context) error {
+	p.logger.Info("start running profiler", zap.String("endpoint", p.endpoint), zap.Duration("interval", p.interval))
+	ticker := p.clock.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	var cpu bytes.Buffer
+	from := p.clock.Now()
+	cpuStarted := p.startCPUProfile(&cpu)
+	for {
+		select {
+		case <-ctx.Done():
+			if cpuStarted {
+				pprof.StopCPUProfile()
+			}
+			p.logger.Info("profiler has been stopped")
+			return nil
+
+		case <-ticker.C():
+			until := p.clock.Now()
+			if cpuStarted {
+				pprof.StopCPUProfile()
+				p.push(ctx, "cpu", from, until, cpu.Bytes())
+			}
+			var heap bytes.Buffer
+			if err := pprof.Lookup("heap").WriteTo(&heap, 0); err != nil {
+				p.logger.Error("failed to collect the heap profile", zap.Error(err))
+			} else {
+				p.push(ctx, "heap", from, until, heap.Bytes())
+			}
+
+			cpu.Reset()
+			from = until
+			cpuStarted = p.startCPUProfile(&cpu)
+		}
+	}
+}
+
+// startCPUProfile starts the CPU profiling, which fails while the profile is captured by the other, e.g. /debug/pprof/profile.
+func (p *Profiler) startCPUProfile(w io.Writer) bool {
+	if err := pprof.StartCPUProfile(w); err != nil {
+		p.logger.Warn("failed to start the CPU profile, skip it in this interval", zap.Error(err))
+		return false
+	}
+	return true
+}
+
+func (p *Profiler) push(ctx context.Context, profileType string, from, until time.Time, profile []byte) {
+	if err := p.upload(ctx, profileType, from, until, profile); err != nil {
+		p.logger.Error("failed to push the profile", zap.String("type", profileType), zap.Error(err))
+	}
+}
+
+func (p *Profiler) upload(ctx context.Context, profileType string, from, until time.Time, profile []byte) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	fw, err := w.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return fmt.Errorf("failed to create the form of the profile: %w", err)
+	}
+	if _, err := fw.Write(profile); err != nil {
+		return fmt.Errorf("failed to write the profile: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write the profile: %w", err)
+	}
+
+	q := url.Values{}
+	q.Set("name", p.name(profileType))
+	q.Set("from", fmt.Sprint(from.Unix()))
+	q.Set("until", fmt.Sprint(until.Unix()))
+	q.Set("format", "pprof")
+	q.Set("spyName", spyName)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/ingest?"+q.Encode(), &body)
+	if err != nil {
+		return fmt.Errorf("failed to create the request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the profile: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+// name returns the name of the profile in the form of <app>.<type>{<key>=<value>,...}.
+func (p *Profiler) name(profileType string) string {
+	labels := make([]string, 0, len(p.labels))
+	for _, k := range slices.Sorted(maps.Keys(p.labels)) {
+		labels = append(labels, k+"="+p.labels[k])
+	}
+	return fmt.Sprintf("%s.%s{%s}", p.appName, profileType, strings.Join(labels, ","))
+}
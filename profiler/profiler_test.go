@@ -0,0 +1,117 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+type ingestRequest struct {
+	query   map[string]string
+	auth    string
+	profile []byte
+}
+
+func TestProfiler_Run(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		requests []ingestRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ingest", r.URL.Path)
+		f, _, err := r.FormFile("profile")
+		if !assert.NoError(t, err) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		profile, err := io.ReadAll(f)
+		require.NoError(t, err)
+
+		query := make(map[string]string)
+		for k := range r.URL.Query() {
+			query[k] = r.URL.Query().Get(k)
+		}
+		mu.Lock()
+		requests = append(requests, ingestRequest{query: query, auth: r.Header.Get("Authorization"), profile: profile})
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clocktest.NewFakeClock(start)
+	p := NewProfiler(server.URL+"/", "example", zap.NewNop(),
+		WithLabels(map[string]string{"plugin_version": "v0.0.1", "plugin": "example"}),
+		WithInterval(time.Minute),
+		WithToken("token"),
+		WithClock(clk),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(requests) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	names := []string{requests[0].query["name"], requests[1].query["name"]}
+	assert.ElementsMatch(t, []string{"example.cpu{plugin=example,plugin_version=v0.0.1}", "example.heap{plugin=example,plugin_version=v0.0.1}"}, names)
+	for _, r := range requests {
+		assert.Equal(t, "pprof", r.query["format"])
+		assert.Equal(t, "gospy", r.query["spyName"])
+		assert.Equal(t, "1735689600", r.query["from"])
+		assert.Equal(t, "1735689660", r.query["until"])
+		assert.Equal(t, "Bearer token", r.auth)
+		assert.NotEmpty(t, r.profile)
+	}
+}
+
+func TestProfiler_UploadError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid profile", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	p := NewProfiler(server.URL, "example", zap.NewNop())
+	err := p.upload(context.Background(), "heap", time.Now(), time.Now(), []byte("profile"))
+	assert.ErrorContains(t, err, "invalid profile")
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+// readinessPollInterval is the interval to check whether the gRPC server is listening.
+const readinessPollInterval = 100 * time.Millisecond
+
+// readiness tells whether the plugin is ready to serve the requests from piped,
+// that is, all the initializers have succeeded and the gRPC server is listening, and it's not shutting down.
+type readiness struct {
+	initialized atomic.Bool
+	listening   atomic.Bool
+	stopping    atomic.Bool
+}
+
+// reason returns why the plugin is not ready, or the empty string if it's ready.
+func (r *readiness) reason() string {
+	switch {
+	case r.stopping.Load():
+		return "shutting down"
+	case !r.initialized.Load():
+		return "initializing the plugin"
+	case !r.listening.Load():
+		return "waiting for the gRPC server to listen"
+	default:
+		return ""
+	}
+}
+
+// ServeHTTP responds "ok" if the plugin is ready, otherwise 503 with the reason.
+func (r *readiness) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	if reason := r.reason(); reason != "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "not ready: %s", reason)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// waitListening marks the gRPC server as listening once it accepts the connections on the given port,
+// and marks the plugin as stopping when the context is done.
+func (r *readiness) waitListening(ctx context.Context, port int, clk clock.Clock) {
+	defer r.stopping.Store(true)
+
+	addr := net.JoinHostPort("localhost", strconv.Itoa(port))
+	ticker := clock.OrReal(clk).NewTicker(readinessPollInterval)
+	defer ticker.Stop()
+	for {
+		var d net.Dialer
+		if conn, err := d.DialContext(ctx, "tcp", addr); err == nil {
+			conn.Close()
+			r.listening.Store(true)
+			<-ctx.Done()
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
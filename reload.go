@@ -0,0 +1,211 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/digest"
+)
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	configReloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_config_reloads_total",
+			Help: "Total number of the reloads of the plugin config grouped by the result.",
+		},
+		[]string{resultKey},
+	)
+)
+
+// registerReloadMetrics registers the metrics of the config reloads to the given registerer.
+func registerReloadMetrics(r prometheus.Registerer) {
+	r.MustRegister(configReloadsTotal)
+}
+
+// ReconfigureInput is the input for the Reconfigurer interface.
+type ReconfigureInput[Config, DeployTargetConfig any] struct {
+	// Config is the reloaded configuration of the plugin.
+	Config *Config
+	// RawConfig is the reloaded configuration of the plugin in JSON as it is given, before decoding it into Config.
+	RawConfig json.RawMessage
+	// DeployTargets is the deploy targets of the plugin after the reload.
+	DeployTargets map[string]*DeployTarget[DeployTargetConfig]
+	// DeployTargetChanges is the changes of the deploy targets by the reload.
+	DeployTargetChanges DeployTargetChanges[DeployTargetConfig]
+	// DeployTargetRegistry is the registry to add and remove the deploy targets while the plugin is running.
+	DeployTargetRegistry *DeployTargetRegistry[DeployTargetConfig]
+	// Logger is the logger for the plugin.
+	Logger *zap.Logger
+}
+
+// Reconfigurer is an optional interface for the registered plugins to be notified
+// when the plugin config is reloaded while the plugin is running.
+// The config is reloaded on SIGHUP, and when it changes if --config-watch-interval is set,
+// given that --config is a file:// or https:// URL.
+//
+// The Config given to Initialize is not updated in place, so the plugins keeping it should replace it with the new one.
+// The name and the port of the plugin are not changed by the reload.
+type Reconfigurer[Config, DeployTargetConfig any] interface {
+	// Reconfigure is called after the new config is applied, and after the DeployTargetObservers are notified.
+	// The returned error is logged, but it does not revert the new config.
+	Reconfigure(ctx context.Context, input *ReconfigureInput[Config, DeployTargetConfig]) error
+}
+
+// reloadConfig applies the given piped plugin config to the running plugin.
+// The new config is applied only when the plugin config and all deploy target configs are valid,
+// and then the cached responses are cleared and the registered plugins are notified.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) reloadConfig(ctx context.Context, fields commonFields[Config, DeployTargetConfig], rawConfig string, logger *zap.Logger) error {
+	cfg, err := loadPluginConfig(rawConfig)
+	if err != nil {
+		return fmt.Errorf("failed to parse the configuration: %w", err)
+	}
+	if cfg.Name != fields.name {
+		return fmt.Errorf("the plugin name cannot be changed by reloading the config: %s to %s", fields.name, cfg.Name)
+	}
+	if cfg.Port != fields.config.Port {
+		logger.Warn("the port of the plugin is not changed until the plugin is restarted", zap.Int("port", fields.config.Port), zap.Int("new-port", cfg.Port))
+	}
+
+	pluginConfig, deployTargets, err := parseConfigs[Config, DeployTargetConfig](cfg)
+	if err != nil {
+		return err
+	}
+
+	// Keep masking the old sensitive values, since they may remain in the outputs of the running stages.
+	fields.redactor.add(append(sensitiveValues(pluginConfig), sensitiveValues(deployTargets)...))
+
+	fields.pluginConfig.Store(pluginConfig)
+	changes := p.updateDeployTargets(ctx, fields.deployTargets, deployTargets, logger)
+	// The cached responses may depend on the old config.
+	fields.responseCache.clear()
+
+	input := &ReconfigureInput[Config, DeployTargetConfig]{
+		Config:               pluginConfig,
+		RawConfig:            cfg.Config,
+		DeployTargets:        fields.deployTargets.snapshot(),
+		DeployTargetChanges:  changes,
+		DeployTargetRegistry: p.newDeployTargetRegistry(fields, logger),
+		Logger:               logger,
+	}
+	for _, plugin := range p.plugins() {
+		if r, ok := plugin.(Reconfigurer[Config, DeployTargetConfig]); ok {
+			if err := r.Reconfigure(ctx, input); err != nil {
+				logger.Error("failed to reconfigure the plugin", zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// configReloader reloads the plugin config on SIGHUP, and when it changes if the interval is set.
+type configReloader struct {
+	source pluginConfigSource
+	// interval is the interval to check the changes of the config. It's zero when not watched.
+	interval time.Duration
+	// reload applies the given config to the running plugin.
+	reload func(ctx context.Context, rawConfig string) error
+	clock  clock.Clock
+	logger *zap.Logger
+	// signals receives the signals to reload the config. It's nil when not listening to the signals.
+	signals <-chan os.Signal
+
+	// applied is the digest of the config currently applied.
+	applied digest.Digest
+	// rejected is the digest of the config failed to be applied last, not to retry it until it changes.
+	rejected digest.Digest
+}
+
+// newConfigReloader returns the reloader of the config which has been applied as the given one.
+func newConfigReloader(source pluginConfigSource, rawConfig string, interval time.Duration, reload func(context.Context, string) error, clk clock.Clock, logger *zap.Logger) *configReloader {
+	return &configReloader{
+		source:   source,
+		interval: interval,
+		reload:   reload,
+		clock:    clock.OrReal(clk),
+		logger:   logger.Named("config-reloader"),
+		applied:  digest.FromString(rawConfig),
+	}
+}
+
+// Run reloads the config until the context is done.
+func (r *configReloader) Run(ctx context.Context) error {
+	signals := r.signals
+	if signals == nil {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGHUP)
+		defer signal.Stop(ch)
+		signals = ch
+	}
+
+	var tick <-chan time.Time
+	if r.interval > 0 {
+		ticker := r.clock.NewTicker(r.interval)
+		defer ticker.Stop()
+		tick = ticker.C()
+	}
+
+	r.logger.Info("start watching the plugin config to reload", zap.Duration("interval", r.interval))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-signals:
+			r.logger.Info("reloading the plugin config on the signal")
+			r.check(ctx, true)
+		case <-tick:
+			r.check(ctx, false)
+		}
+	}
+}
+
+// check reads the config and applies it if it's changed or forced.
+// The failures are logged, and the plugin keeps running with the config currently applied.
+func (r *configReloader) check(ctx context.Context, force bool) {
+	rawConfig, err := r.source.read(ctx)
+	if err != nil {
+		configReloadsTotal.With(prometheus.Labels{resultKey: resultFailure}).Inc()
+		r.logger.Error("failed to read the plugin config to reload", zap.Error(err))
+		return
+	}
+	d := digest.FromString(rawConfig)
+	if (d == r.applied || d == r.rejected) && !force {
+		return
+	}
+	if err := r.reload(ctx, rawConfig); err != nil {
+		r.rejected = d
+		configReloadsTotal.With(prometheus.Labels{resultKey: resultFailure}).Inc()
+		r.logger.Error("failed to reload the plugin config, keep running with the current one", zap.Error(err))
+		return
+	}
+	r.applied, r.rejected = d, digest.Digest{}
+	configReloadsTotal.With(prometheus.Labels{resultKey: resultSuccess}).Inc()
+	r.logger.Info("reloaded the plugin config", zap.String("digest", d.Short()))
+}
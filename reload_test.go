@@ -0,0 +1,171 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	config "github.com/pipe-cd/pipecd/pkg/configv1"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+type reconfiguringLivestatePlugin struct {
+	observingLivestatePlugin
+	inputs []*ReconfigureInput[ExampleConfig, ExampleDeployTargetConfig]
+}
+
+func (p *reconfiguringLivestatePlugin) Reconfigure(_ context.Context, input *ReconfigureInput[ExampleConfig, ExampleDeployTargetConfig]) error {
+	p.inputs = append(p.inputs, input)
+	return nil
+}
+
+func TestPlugin_reloadConfig(t *testing.T) {
+	t.Parallel()
+
+	plugin := &reconfiguringLivestatePlugin{}
+	p, err := NewPlugin("1.0.0", WithLivestatePlugin(plugin))
+	require.NoError(t, err)
+
+	fields := commonFields[ExampleConfig, ExampleDeployTargetConfig]{
+		name:          "plugin",
+		config:        &config.PipedPlugin{Name: "plugin", Port: 7001},
+		pluginConfig:  new(atomic.Pointer[ExampleConfig]),
+		deployTargets: newDeployTargetStore(map[string]*DeployTarget[ExampleDeployTargetConfig]{"dt1": {Name: "dt1"}}),
+		redactor:      &redactor{},
+		responseCache: newResponseCache(&responseCacheOptions{size: 10}, nil),
+	}
+	fields.pluginConfig.Store(&ExampleConfig{})
+	fields.responseCache.put("key", &emptypb.Empty{})
+	logger := zaptest.NewLogger(t)
+
+	// The invalid configs are not applied.
+	err = p.reloadConfig(context.Background(), fields, `{"name": "renamed", "port": 7001, "url": "file:///plugin"}`, logger)
+	assert.ErrorContains(t, err, "the plugin name cannot be changed")
+	err = p.reloadConfig(context.Background(), fields, `{"name": "plugin", "port": 7001, "url": "file:///plugin", "deployTargets": [{"name": "dt2", "config": "invalid"}]}`, logger)
+	assert.ErrorContains(t, err, "failed to unmarshal the config of the deploy target dt2")
+	assert.Equal(t, []string{"dt1"}, deployTargetNames(fields.deployTargets.snapshot()))
+	assert.Empty(t, plugin.inputs)
+	_, ok := fields.responseCache.get("key")
+	assert.True(t, ok)
+
+	err = p.reloadConfig(context.Background(), fields, `{"name": "plugin", "port": 7001, "url": "file:///plugin", "deployTargets": [{"name": "dt2", "config": {}}]}`, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dt2"}, deployTargetNames(fields.deployTargets.snapshot()))
+	_, ok = fields.responseCache.get("key")
+	assert.False(t, ok)
+
+	// The observers are notified of the changes, and then the plugin is reconfigured.
+	require.Len(t, plugin.changes, 1)
+	require.Len(t, plugin.inputs, 1)
+	assert.Same(t, fields.currentPluginConfig(), plugin.inputs[0].Config)
+	assert.Equal(t, plugin.changes[0], plugin.inputs[0].DeployTargetChanges)
+	assert.Equal(t, []string{"dt2"}, deployTargetNames(plugin.inputs[0].DeployTargets))
+}
+
+func deployTargetNames[DeployTargetConfig any](targets map[string]*DeployTarget[DeployTargetConfig]) []string {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestConfigReloader_check(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("v1"), 0o600))
+
+	var (
+		reloaded  []string
+		reloadErr error
+	)
+	r := newConfigReloader(pluginConfigSource{value: "file://" + path}, "v1", 0, func(_ context.Context, rawConfig string) error {
+		reloaded = append(reloaded, rawConfig)
+		return reloadErr
+	}, nil, zaptest.NewLogger(t))
+
+	// The unchanged config is not reloaded unless forced.
+	r.check(context.Background(), false)
+	assert.Empty(t, reloaded)
+	r.check(context.Background(), true)
+	assert.Equal(t, []string{"v1"}, reloaded)
+
+	// The rejected config is not retried until it changes.
+	reloadErr = errors.New("invalid")
+	require.NoError(t, os.WriteFile(path, []byte("v2"), 0o600))
+	r.check(context.Background(), false)
+	r.check(context.Background(), false)
+	assert.Equal(t, []string{"v1", "v2"}, reloaded)
+
+	reloadErr = nil
+	require.NoError(t, os.WriteFile(path, []byte("v3"), 0o600))
+	r.check(context.Background(), false)
+	r.check(context.Background(), false)
+	assert.Equal(t, []string{"v1", "v2", "v3"}, reloaded)
+
+	// The config failed to be read is not reloaded.
+	require.NoError(t, os.Remove(path))
+	r.check(context.Background(), true)
+	assert.Equal(t, []string{"v1", "v2", "v3"}, reloaded)
+}
+
+func TestConfigReloader_Run(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("v1"), 0o600))
+
+	clk := clocktest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	reloaded := make(chan string, 1)
+	signals := make(chan os.Signal, 1)
+	r := newConfigReloader(pluginConfigSource{value: "file://" + path}, "v1", time.Minute, func(_ context.Context, rawConfig string) error {
+		reloaded <- rawConfig
+		return nil
+	}, clk, zaptest.NewLogger(t))
+	r.signals = signals
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+
+	// The config is reloaded on the signal even if it's unchanged.
+	signals <- syscall.SIGHUP
+	assert.Equal(t, "v1", <-reloaded)
+
+	// The changed config is reloaded on the next tick.
+	clk.BlockUntil(1)
+	require.NoError(t, os.WriteFile(path, []byte("v2"), 0o600))
+	clk.Advance(time.Minute)
+	assert.Equal(t, "v2", <-reloaded)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// ResourceKeyMetadataKey is the key of ResourceState.ResourceMetadata holding the ResourceKey of the resource,
+	// so that the tools built on top of the plugins can correlate the live resources with the other results.
+	ResourceKeyMetadataKey = "pipecd.dev/resource-key"
+	// ResourceKeysStageMetadataKey is the key of the stage metadata holding the ResourceKeys reported by
+	// ExecuteStageResponse.Resources, encoded as a JSON array of the strings of the keys.
+	ResourceKeysStageMetadataKey = "pipecd.dev/resource-keys"
+)
+
+// ResourceKey identifies a resource managed by a plugin regardless of the provider.
+// It's formatted as "<provider>:<kind>:<scope>:<name>" followed by "#<id>" if the ID is set,
+// e.g. "kubernetes:apps/Deployment:default:nginx", where ":", "#" and "%" in each part are percent-encoded.
+type ResourceKey struct {
+	// Provider is the provider of the resource, e.g. "kubernetes", "ecs" or "cloudrun".
+	Provider string
+	// Kind is the kind of the resource, e.g. "apps/Deployment" or "Service".
+	Kind string
+	// Scope is the scope where the name is unique, e.g. the namespace, the cluster or the region.
+	// It's empty for the resources unique in the provider.
+	Scope string
+	// Name is the name of the resource.
+	Name string
+	// ID is the identifier assigned to the resource by the provider, e.g. the UID or the ARN.
+	// It's optional and ignored by Equal, since it changes when the resource is recreated.
+	ID string
+}
+
+// ParseResourceKey parses the string formatted by ResourceKey.String.
+func ParseResourceKey(s string) (ResourceKey, error) {
+	body, id, hasID := strings.Cut(s, "#")
+	parts := strings.Split(body, ":")
+	if len(parts) != 4 {
+		return ResourceKey{}, fmt.Errorf("invalid resource key %q: it must be in the form of <provider>:<kind>:<scope>:<name>[#<id>]", s)
+	}
+	if hasID {
+		parts = append(parts, id)
+	}
+	for i, p := range parts {
+		u, err := url.PathUnescape(p)
+		if err != nil {
+			return ResourceKey{}, fmt.Errorf("invalid resource key %q: %w", s, err)
+		}
+		parts[i] = u
+	}
+	k := ResourceKey{Provider: parts[0], Kind: parts[1], Scope: parts[2], Name: parts[3]}
+	if hasID {
+		k.ID = parts[4]
+	}
+	if k.Provider == "" || k.Kind == "" || k.Name == "" {
+		return ResourceKey{}, fmt.Errorf("invalid resource key %q: the provider, the kind and the name must not be empty", s)
+	}
+	return k, nil
+}
+
+// String returns the key in the form of "<provider>:<kind>:<scope>:<name>[#<id>]".
+func (k ResourceKey) String() string {
+	s := escapeResourceKeyPart(k.Provider) + ":" + escapeResourceKeyPart(k.Kind) + ":" + escapeResourceKeyPart(k.Scope) + ":" + escapeResourceKeyPart(k.Name)
+	if k.ID != "" {
+		s += "#" + escapeResourceKeyPart(k.ID)
+	}
+	return s
+}
+
+// IsZero reports whether the key is the zero value.
+func (k ResourceKey) IsZero() bool {
+	return k == ResourceKey{}
+}
+
+// Equal reports whether k and o identify the same resource by the provider, the kind, the scope and the name.
+// The IDs are ignored, so the recreated resource is still equal.
+func (k ResourceKey) Equal(o ResourceKey) bool {
+	return k.WithoutID() == o.WithoutID()
+}
+
+// WithoutID returns the key without the ID, e.g. to use it as the key of a map.
+func (k ResourceKey) WithoutID() ResourceKey {
+	k.ID = ""
+	return k
+}
+
+// Compare returns -1, 0 or +1 comparing the provider, the kind, the scope, the name and the ID in this order,
+// e.g. to sort the keys with slices.SortFunc.
+func (k ResourceKey) Compare(o ResourceKey) int {
+	return cmp.Or(
+		cmp.Compare(k.Provider, o.Provider),
+		cmp.Compare(k.Kind, o.Kind),
+		cmp.Compare(k.Scope, o.Scope),
+		cmp.Compare(k.Name, o.Name),
+		cmp.Compare(k.ID, o.ID),
+	)
+}
+
+// MarshalText encodes the key in the form of String.
+func (k ResourceKey) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes the key in the form of String.
+func (k *ResourceKey) UnmarshalText(b []byte) error {
+	v, err := ParseResourceKey(string(b))
+	if err != nil {
+		return err
+	}
+	*k = v
+	return nil
+}
+
+// ResourceKeysToPrune returns the keys of the live resources which are not in the desired ones,
+// in the order of the live ones. The keys are compared by Equal.
+func ResourceKeysToPrune(live, desired []ResourceKey) []ResourceKey {
+	want := make(map[ResourceKey]struct{}, len(desired))
+	for _, k := range desired {
+		want[k.WithoutID()] = struct{}{}
+	}
+	var prune []ResourceKey
+	for _, k := range live {
+		if _, ok := want[k.WithoutID()]; !ok {
+			prune = append(prune, k)
+		}
+	}
+	return prune
+}
+
+var resourceKeyEscaper = strings.NewReplacer("%", "%25", ":", "%3A", "#", "%23")
+
+func escapeResourceKeyPart(s string) string {
+	return resourceKeyEscaper.Replace(s)
+}
+
+// putStageResourceKeys stores the keys in the stage metadata with ResourceKeysStageMetadataKey.
+func putStageResourceKeys(ctx context.Context, client *Client, keys []ResourceKey) error {
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return client.PutStageMetadata(ctx, ResourceKeysStageMetadataKey, string(data))
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/common"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+)
+
+func TestResourceKey_String(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		key  ResourceKey
+		want string
+	}{
+		{
+			name: "kubernetes",
+			key:  ResourceKey{Provider: "kubernetes", Kind: "apps/Deployment", Scope: "default", Name: "nginx"},
+			want: "kubernetes:apps/Deployment:default:nginx",
+		},
+		{
+			name: "without scope",
+			key:  ResourceKey{Provider: "kubernetes", Kind: "Namespace", Name: "default"},
+			want: "kubernetes:Namespace::default",
+		},
+		{
+			name: "with escaped id",
+			key:  ResourceKey{Provider: "ecs", Kind: "Service", Scope: "cluster#1", Name: "web%", ID: "arn:aws:ecs:us-east-1:123:service/web"},
+			want: "ecs:Service:cluster%231:web%25#arn%3Aaws%3Aecs%3Aus-east-1%3A123%3Aservice/web",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, tt.key.String())
+			got, err := ParseResourceKey(tt.want)
+			require.NoError(t, err)
+			assert.Equal(t, tt.key, got)
+		})
+	}
+}
+
+func TestParseResourceKey_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{
+		"",
+		"kubernetes:Deployment:nginx",
+		"kubernetes:Deployment:default:nginx:extra",
+		":Deployment:default:nginx",
+		"kubernetes:Deployment:default:",
+		"kubernetes:Deployment:default:nginx%zz",
+	} {
+		_, err := ParseResourceKey(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestResourceKey_Equal(t *testing.T) {
+	t.Parallel()
+
+	a := ResourceKey{Provider: "kubernetes", Kind: "Service", Scope: "default", Name: "web", ID: "uid-1"}
+	recreated := ResourceKey{Provider: "kubernetes", Kind: "Service", Scope: "default", Name: "web", ID: "uid-2"}
+	other := ResourceKey{Provider: "kubernetes", Kind: "Service", Scope: "staging", Name: "web"}
+
+	assert.True(t, a.Equal(recreated))
+	assert.False(t, a.Equal(other))
+	assert.Equal(t, -1, a.Compare(recreated))
+	assert.Equal(t, -1, a.Compare(other))
+	assert.True(t, ResourceKey{}.IsZero())
+	assert.False(t, a.IsZero())
+
+	keys := []ResourceKey{other, recreated, a}
+	slices.SortFunc(keys, ResourceKey.Compare)
+	assert.Equal(t, []ResourceKey{a, recreated, other}, keys)
+}
+
+func TestResourceKey_JSON(t *testing.T) {
+	t.Parallel()
+
+	key := ResourceKey{Provider: "kubernetes", Kind: "Service", Scope: "default", Name: "web"}
+	data, err := json.Marshal(map[ResourceKey][]ResourceKey{key: {key}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"kubernetes:Service:default:web":["kubernetes:Service:default:web"]}`, string(data))
+
+	var got map[ResourceKey][]ResourceKey
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, []ResourceKey{key}, got[key])
+}
+
+func TestResourceKeysToPrune(t *testing.T) {
+	t.Parallel()
+
+	deploy := ResourceKey{Provider: "kubernetes", Kind: "apps/Deployment", Scope: "default", Name: "web", ID: "uid-1"}
+	svc := ResourceKey{Provider: "kubernetes", Kind: "Service", Scope: "default", Name: "web", ID: "uid-2"}
+	old := ResourceKey{Provider: "kubernetes", Kind: "ConfigMap", Scope: "default", Name: "web-v1", ID: "uid-3"}
+
+	desired := []ResourceKey{deploy.WithoutID(), svc.WithoutID()}
+	assert.Equal(t, []ResourceKey{old}, ResourceKeysToPrune([]ResourceKey{deploy, old, svc}, desired))
+	assert.Empty(t, ResourceKeysToPrune([]ResourceKey{deploy, svc}, desired))
+}
+
+// resourceStagePlugin reports the resources applied by the stage.
+type resourceStagePlugin struct {
+	mockStagePlugin
+	resources []ResourceKey
+}
+
+func (p *resourceStagePlugin) ExecuteStage(context.Context, *struct{}, []*DeployTarget[struct{}], *ExecuteStageInput[struct{}]) (*ExecuteStageResponse, error) {
+	return &ExecuteStageResponse{Status: StageStatusSuccess, Resources: p.resources}, nil
+}
+
+func TestExecuteStage_Resources(t *testing.T) {
+	t.Parallel()
+
+	config := strings.TrimSpace(`
+apiVersion: pipecd.dev/v1beta1
+kind: Application
+spec: {}
+`)
+	request := &deployment.ExecuteStageRequest{
+		Input: &deployment.ExecutePluginInput{
+			Stage:                  &model.PipelineStage{Name: "stage1"},
+			Deployment:             &model.Deployment{Trigger: &model.DeploymentTrigger{Commit: &model.Commit{}}},
+			TargetDeploymentSource: &common.DeploymentSource{ApplicationConfig: []byte(config)},
+		},
+	}
+	keys := []ResourceKey{
+		{Provider: "kubernetes", Kind: "Service", Scope: "default", Name: "web"},
+		{Provider: "kubernetes", Kind: "apps/Deployment", Scope: "default", Name: "web"},
+	}
+
+	fake := newFakePluginServiceClient()
+	_, err := executeStage[struct{}, struct{}, struct{}](context.Background(), "test", &resourceStagePlugin{resources: keys}, nil, nil, &Client{base: &pluginServiceClient{PluginServiceClient: fake}}, request, zap.NewNop())
+	require.NoError(t, err)
+
+	var got []ResourceKey
+	require.NoError(t, json.Unmarshal([]byte(fake.stageMetadata[ResourceKeysStageMetadataKey]), &got))
+	assert.Equal(t, keys, got)
+
+	// Nothing is stored when the stage doesn't report the resources.
+	fake = newFakePluginServiceClient()
+	_, err = executeStage[struct{}, struct{}, struct{}](context.Background(), "test", &resourceStagePlugin{}, nil, nil, &Client{base: &pluginServiceClient{PluginServiceClient: fake}}, request, zap.NewNop())
+	require.NoError(t, err)
+	assert.NotContains(t, fake.stageMetadata, ResourceKeysStageMetadataKey)
+}
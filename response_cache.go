@@ -0,0 +1,234 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/digest"
+)
+
+const (
+	resultKey = "result"
+
+	resultHit  = "hit"
+	resultMiss = "miss"
+)
+
+var (
+	responseCacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_response_cache_requests_total",
+			Help: "Total number of the requests looked up in the response cache grouped by the result.",
+		},
+		[]string{methodKey, resultKey},
+	)
+)
+
+// registerResponseCacheMetrics registers the metrics of the response cache to the given registerer.
+func registerResponseCacheMetrics(r prometheus.Registerer) {
+	r.MustRegister(responseCacheRequestsTotal)
+}
+
+// defaultCachedMethods are the methods cached when WithResponseCache is given no method.
+var defaultCachedMethods = []string{
+	// The defined stages never change while the plugin is running.
+	"FetchDefinedStages",
+}
+
+// WithResponseCache is a function that enables the cache of the responses to piped for the given methods,
+// e.g. "FetchDefinedStages" and "BuildQuickSyncStages". If no method is given, only FetchDefinedStages is cached.
+// The responses are cached by the method and the request in memory, up to the given number of the entries for the given TTL.
+// The TTL of zero means the entries don't expire until evicted.
+// The cached responses are returned as they are, so the methods must return the same response for the same request,
+// not depending on the time or the state outside the plugin.
+// The cache is cleared when the plugin config is loaded again.
+func WithResponseCache[Config, DeployTargetConfig, ApplicationConfigSpec any](size int, ttl time.Duration, methods ...string) PluginOption[Config, DeployTargetConfig, ApplicationConfigSpec] {
+	return func(plugin *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) {
+		if len(methods) == 0 {
+			methods = defaultCachedMethods
+		}
+		plugin.responseCache = &responseCacheOptions{size: size, ttl: ttl, methods: methods}
+	}
+}
+
+// responseCacheOptions are the options given by WithResponseCache.
+type responseCacheOptions struct {
+	size    int
+	ttl     time.Duration
+	methods []string
+}
+
+// responseCache is the LRU cache of the responses keyed by the method and the request.
+type responseCache struct {
+	size    int
+	ttl     time.Duration
+	methods map[string]bool
+	clock   clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru has the entries from the most recently used to the least.
+	lru *list.List
+}
+
+type responseCacheEntry struct {
+	key       string
+	response  proto.Message
+	expiresAt time.Time
+}
+
+// newResponseCache returns the cache with the given options, or nil if the options are nil.
+func newResponseCache(opts *responseCacheOptions, clk clock.Clock) *responseCache {
+	if opts == nil || opts.size <= 0 {
+		return nil
+	}
+	methods := make(map[string]bool, len(opts.methods))
+	for _, m := range opts.methods {
+		methods[m] = true
+	}
+	return &responseCache{
+		size:    opts.size,
+		ttl:     opts.ttl,
+		methods: methods,
+		clock:   clock.OrReal(clk),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// responseCacheKey returns the key of the request for the method.
+// It returns false if the request can't be marshaled.
+func responseCacheKey(method string, request any) (string, bool) {
+	msg, ok := request.(proto.Message)
+	if !ok {
+		return "", false
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", false
+	}
+	return method + "/" + digest.FromBytes(data).Hex(), true
+}
+
+// get returns the copy of the cached response, or false if it's not cached or has expired.
+func (c *responseCache) get(key string) (proto.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*responseCacheEntry)
+	if !entry.expiresAt.IsZero() && !c.clock.Now().Before(entry.expiresAt) {
+		c.lru.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return proto.Clone(entry.response), true
+}
+
+// put caches the copy of the response, evicting the least recently used entry if the cache is full.
+func (c *responseCache) put(key string, response proto.Message) {
+	entry := &responseCacheEntry{key: key, response: proto.Clone(response)}
+	if c.ttl > 0 {
+		entry.expiresAt = c.clock.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseCacheEntry).key)
+	}
+}
+
+// clear removes all the cached responses. It does nothing on the nil cache.
+func (c *responseCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	c.lru.Init()
+}
+
+// cachingRegistrar registers the services returning the cached responses for the cached methods.
+type cachingRegistrar struct {
+	grpc.ServiceRegistrar
+	cache *responseCache
+}
+
+func (r cachingRegistrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	wrapped := *desc
+	wrapped.Methods = make([]grpc.MethodDesc, 0, len(desc.Methods))
+	for _, m := range desc.Methods {
+		if !r.cache.methods[m.MethodName] {
+			wrapped.Methods = append(wrapped.Methods, m)
+			continue
+		}
+
+		handler := m.Handler
+		method := m.MethodName
+		m.Handler = func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			// Look up the cache in the innermost handler, so that the server interceptors see the cached calls as well.
+			caching := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+				cached := func(ctx context.Context, req any) (any, error) {
+					key, ok := responseCacheKey(method, req)
+					if !ok {
+						return h(ctx, req)
+					}
+					if resp, ok := r.cache.get(key); ok {
+						responseCacheRequestsTotal.With(prometheus.Labels{methodKey: method, resultKey: resultHit}).Inc()
+						return resp, nil
+					}
+					responseCacheRequestsTotal.With(prometheus.Labels{methodKey: method, resultKey: resultMiss}).Inc()
+					resp, err := h(ctx, req)
+					if msg, ok := resp.(proto.Message); ok && err == nil {
+						r.cache.put(key, msg)
+					}
+					return resp, err
+				}
+				if interceptor == nil {
+					return cached(ctx, req)
+				}
+				return interceptor(ctx, req, info, cached)
+			}
+			return handler(srv, ctx, dec, caching)
+		}
+		wrapped.Methods = append(wrapped.Methods, m)
+	}
+	r.ServiceRegistrar.RegisterService(&wrapped, impl)
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+func TestNewResponseCache(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, newResponseCache(nil, nil))
+	assert.Nil(t, newResponseCache(&responseCacheOptions{size: 0, methods: defaultCachedMethods}, nil))
+
+	plugin := &Plugin[struct{}, struct{}, struct{}]{}
+	WithResponseCache[struct{}, struct{}, struct{}](10, time.Minute)(plugin)
+	c := newResponseCache(plugin.responseCache, nil)
+	require.NotNil(t, c)
+	assert.Equal(t, map[string]bool{"FetchDefinedStages": true}, c.methods)
+}
+
+func TestResponseCache(t *testing.T) {
+	t.Parallel()
+
+	clk := clocktest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := newResponseCache(&responseCacheOptions{size: 2, ttl: time.Minute, methods: defaultCachedMethods}, clk)
+
+	c.put("a", &deployment.FetchDefinedStagesResponse{Stages: []string{"A"}})
+	c.put("b", &deployment.FetchDefinedStagesResponse{Stages: []string{"B"}})
+
+	// The cached response is a copy.
+	resp, ok := c.get("a")
+	require.True(t, ok)
+	resp.(*deployment.FetchDefinedStagesResponse).Stages[0] = "modified"
+	resp, ok = c.get("a")
+	require.True(t, ok)
+	assert.Equal(t, []string{"A"}, resp.(*deployment.FetchDefinedStagesResponse).Stages)
+
+	// The least recently used entry is evicted.
+	c.put("c", &deployment.FetchDefinedStagesResponse{Stages: []string{"C"}})
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	_, ok = c.get("a")
+	assert.True(t, ok)
+
+	// The entries expire after the TTL.
+	clk.Advance(time.Minute)
+	_, ok = c.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.lru.Len())
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	t.Parallel()
+
+	key1, ok := responseCacheKey("BuildQuickSyncStages", &deployment.BuildQuickSyncStagesRequest{Rollback: true})
+	require.True(t, ok)
+	key2, ok := responseCacheKey("BuildQuickSyncStages", &deployment.BuildQuickSyncStagesRequest{Rollback: true})
+	require.True(t, ok)
+	key3, ok := responseCacheKey("BuildQuickSyncStages", &deployment.BuildQuickSyncStagesRequest{Rollback: false})
+	require.True(t, ok)
+	key4, ok := responseCacheKey("Other", &deployment.BuildQuickSyncStagesRequest{Rollback: true})
+	require.True(t, ok)
+	assert.Equal(t, key1, key2)
+	assert.NotEqual(t, key1, key3)
+	assert.NotEqual(t, key1, key4)
+
+	_, ok = responseCacheKey("BuildQuickSyncStages", "not a proto message")
+	assert.False(t, ok)
+}
+
+func TestCachingRegistrar(t *testing.T) {
+	t.Parallel()
+
+	c := newResponseCache(&responseCacheOptions{size: 10, methods: []string{"BuildQuickSyncStages"}}, nil)
+
+	var calls int
+	handler := func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := &deployment.BuildQuickSyncStagesRequest{}
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		h := func(context.Context, any) (any, error) {
+			calls++
+			return &deployment.BuildQuickSyncStagesResponse{Stages: []*model.PipelineStage{{Name: "SYNC"}}}, nil
+		}
+		if interceptor == nil {
+			return h(ctx, in)
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{}, h)
+	}
+	desc := &grpc.ServiceDesc{
+		ServiceName: "test.Service",
+		Methods: []grpc.MethodDesc{
+			{MethodName: "BuildQuickSyncStages", Handler: handler},
+			{MethodName: "NotCached", Handler: handler},
+		},
+	}
+	fake := &fakeServiceRegistrar{}
+	cachingRegistrar{ServiceRegistrar: fake, cache: c}.RegisterService(desc, nil)
+	require.Len(t, fake.desc.Methods, 2)
+
+	decode := func(rollback bool) func(any) error {
+		return func(v any) error {
+			v.(*deployment.BuildQuickSyncStagesRequest).Rollback = rollback
+			return nil
+		}
+	}
+	var intercepted int
+	interceptor := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		intercepted++
+		return h(ctx, req)
+	}
+
+	resp1, err := fake.desc.Methods[0].Handler(nil, context.Background(), decode(true), interceptor)
+	require.NoError(t, err)
+	resp2, err := fake.desc.Methods[0].Handler(nil, context.Background(), decode(true), nil)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(resp1.(proto.Message), resp2.(proto.Message)))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, intercepted)
+
+	// The different request is not served from the cache.
+	_, err = fake.desc.Methods[0].Handler(nil, context.Background(), decode(false), interceptor)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 2, intercepted)
+
+	// The methods not configured are not cached.
+	for range 2 {
+		_, err = fake.desc.Methods[1].Handler(nil, context.Background(), decode(true), nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,263 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/planpreview"
+)
+
+const (
+	directionKey = "direction"
+
+	directionRequest  = "request"
+	directionResponse = "response"
+
+	// truncatedMarker is appended to the truncated texts to tell the users that they are incomplete.
+	truncatedMarker = "\n... (truncated %d bytes to fit the response size limit)"
+)
+
+var (
+	payloadBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_payload_bytes",
+			Help:    "Histogram of the sizes of the requests from piped and the responses to it.",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 10),
+		},
+		[]string{methodKey, directionKey},
+	)
+	responsesTruncatedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_responses_truncated_total",
+			Help: "Total number of the responses truncated to fit the response size limit.",
+		},
+		[]string{methodKey},
+	)
+)
+
+// registerPayloadMetrics registers the metrics of the payload sizes to the given registerer.
+func registerPayloadMetrics(r prometheus.Registerer) {
+	r.MustRegister(
+		payloadBytes,
+		responsesTruncatedTotal,
+	)
+}
+
+// payloadRegistrar registers the services recording the sizes of the requests and the responses,
+// and compressing the responses with the given compressor.
+type payloadRegistrar struct {
+	grpc.ServiceRegistrar
+	// compressor is the name of the compressor for the responses, e.g. "gzip".
+	// If empty, the responses are compressed only when piped compresses the requests.
+	compressor string
+	logger     *zap.Logger
+}
+
+func (r payloadRegistrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	wrapped := *desc
+	wrapped.Methods = make([]grpc.MethodDesc, 0, len(desc.Methods))
+	for _, m := range desc.Methods {
+		handler := m.Handler
+		method := m.MethodName
+		m.Handler = func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			decode := func(v any) error {
+				if err := dec(v); err != nil {
+					return err
+				}
+				if msg, ok := v.(proto.Message); ok {
+					payloadBytes.With(prometheus.Labels{methodKey: method, directionKey: directionRequest}).Observe(float64(proto.Size(msg)))
+				}
+				return nil
+			}
+
+			if r.compressor != "" {
+				// This fails when piped doesn't accept the compressor, then the response is sent as is.
+				if err := grpc.SetSendCompressor(ctx, r.compressor); err != nil {
+					r.logger.Debug("failed to set the compressor for the response", zap.String("method", method), zap.Error(err))
+				}
+			}
+
+			resp, err := handler(srv, ctx, decode, interceptor)
+			if msg, ok := resp.(proto.Message); ok && err == nil {
+				payloadBytes.With(prometheus.Labels{methodKey: method, directionKey: directionResponse}).Observe(float64(proto.Size(msg)))
+			}
+			return resp, err
+		}
+		wrapped.Methods = append(wrapped.Methods, m)
+	}
+	r.ServiceRegistrar.RegisterService(&wrapped, impl)
+}
+
+// fitResponse reduces the response exceeding the given size with fit, and records the truncation.
+func fitResponse[M proto.Message](logger *zap.Logger, method string, resp M, maxSize int, fit func(M, int) bool) {
+	if maxSize <= 0 {
+		return
+	}
+	size := proto.Size(resp)
+	if size <= maxSize {
+		return
+	}
+	responsesTruncatedTotal.With(prometheus.Labels{methodKey: method}).Inc()
+	if !fit(resp, maxSize) {
+		logger.Warn("the response still exceeds the size limit after truncation",
+			zap.String("method", method),
+			zap.Int("size", proto.Size(resp)),
+			zap.Int("max-size", maxSize),
+		)
+		return
+	}
+	logger.Info("truncated the response to fit the size limit",
+		zap.String("method", method),
+		zap.Int("original-size", size),
+		zap.Int("max-size", maxSize),
+	)
+}
+
+// fitLivestateResponse reduces the response to fit the given size, and reports whether it fits.
+// Since piped receives the live state in a single message, it truncates the auxiliary information in the order of
+// the reason of the sync state, the metadata values of the resources, and the health descriptions of the resources,
+// keeping all resources, their health statuses and their resource keys.
+// The response may still exceed the size when the resources themselves exceed it.
+func fitLivestateResponse(resp *livestate.GetLivestateResponse, maxSize int) bool {
+	if maxSize <= 0 || proto.Size(resp) <= maxSize {
+		return true
+	}
+
+	if s := resp.GetSyncState(); s != nil {
+		if truncateTexts([]*string{&s.Reason}, proto.Size(resp)-maxSize) {
+			return true
+		}
+	}
+
+	resources := resp.GetApplicationLiveState().GetResources()
+	if truncateResourceMetadata(resources, proto.Size(resp)-maxSize) {
+		return true
+	}
+
+	descriptions := make([]*string, 0, len(resources))
+	for _, r := range resources {
+		descriptions = append(descriptions, &r.HealthDescription)
+	}
+	return truncateTexts(descriptions, proto.Size(resp)-maxSize)
+}
+
+// truncateResourceMetadata truncates the metadata values of the resources in the same way as truncateTexts,
+// except the resource key which piped needs to correlate the resources.
+func truncateResourceMetadata(resources []*model.ResourceState, n int) bool {
+	type entry struct {
+		metadata map[string]string
+		key      string
+		value    string
+	}
+	var entries []*entry
+	for _, r := range resources {
+		for k, v := range r.GetResourceMetadata() {
+			if k == ResourceKeyMetadataKey {
+				continue
+			}
+			entries = append(entries, &entry{metadata: r.ResourceMetadata, key: k, value: v})
+		}
+	}
+
+	values := make([]*string, 0, len(entries))
+	for _, e := range entries {
+		values = append(values, &e.value)
+	}
+	fits := truncateTexts(values, n)
+	for _, e := range entries {
+		e.metadata[e.key] = e.value
+	}
+	return fits
+}
+
+// fitPlanPreviewResponse reduces the response to fit the given size, and reports whether it fits.
+// Since piped receives the results for all deploy targets in a single message,
+// it truncates the longest details first, keeping the summaries of all results.
+func fitPlanPreviewResponse(resp *planpreview.GetPlanPreviewResponse, maxSize int) bool {
+	if maxSize <= 0 || proto.Size(resp) <= maxSize {
+		return true
+	}
+
+	details := make([]*[]byte, 0, len(resp.GetResults()))
+	for _, r := range resp.GetResults() {
+		details = append(details, &r.Details)
+	}
+	return truncateTexts(details, proto.Size(resp)-maxSize)
+}
+
+// truncateTexts truncates the longest texts first, so that their total length is reduced by at least n bytes.
+// Each truncated text ends with the marker telling how many bytes are dropped.
+// It reports whether the texts are reduced enough, otherwise they are truncated as much as possible.
+func truncateTexts[T string | []byte](texts []*T, n int) bool {
+	if n <= 0 {
+		return true
+	}
+
+	// Find the largest length to truncate the texts to, which reduces them enough.
+	reduction := func(limit int) int {
+		var total int
+		for _, t := range texts {
+			total += max(len(*t)-limit-maxTruncatedMarkerSize, 0)
+		}
+		return total
+	}
+	var longest int
+	for _, t := range texts {
+		longest = max(longest, len(*t))
+	}
+	// The reduction decreases as the limit increases.
+	limit := sort.Search(longest+1, func(limit int) bool {
+		return reduction(limit) < n
+	}) - 1
+	if limit < 0 {
+		for _, t := range texts {
+			*t = truncateText(*t, 0)
+		}
+		return false
+	}
+	for _, t := range texts {
+		*t = truncateText(*t, limit)
+	}
+	return true
+}
+
+// maxTruncatedMarkerSize is the maximum size of the marker appended to the truncated texts.
+var maxTruncatedMarkerSize = len(fmt.Sprintf(truncatedMarker, math.MaxInt))
+
+// truncateText truncates the text longer than the given limit and the marker,
+// keeping the first bytes up to the limit at the boundary of UTF-8 characters.
+func truncateText[T string | []byte](text T, limit int) T {
+	if len(text) <= limit+maxTruncatedMarkerSize {
+		return text
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	truncated := append([]byte(text[:cut]), fmt.Sprintf(truncatedMarker, len(text)-cut)...)
+	return T(truncated)
+}
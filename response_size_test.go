@@ -0,0 +1,265 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/planpreview"
+)
+
+func TestTruncateText(t *testing.T) {
+	t.Parallel()
+
+	short := strings.Repeat("a", 10)
+	assert.Equal(t, short, truncateText(short, 0))
+
+	long := strings.Repeat("あ", 100)
+	truncated := truncateText(long, 10)
+	assert.True(t, utf8.ValidString(truncated))
+	// "あ" is 3 bytes, so the first 3 characters are kept.
+	assert.True(t, strings.HasPrefix(truncated, "あああ\n... (truncated 291 bytes"), truncated)
+
+	assert.Equal(t, []byte(truncated), truncateText([]byte(long), 10))
+}
+
+func TestTruncateTexts(t *testing.T) {
+	t.Parallel()
+
+	small := strings.Repeat("a", 10)
+	medium := strings.Repeat("b", 1000)
+	large := strings.Repeat("c", 5000)
+	texts := []*string{&small, &medium, &large}
+
+	total := func() int {
+		return len(small) + len(medium) + len(large)
+	}
+	before := total()
+	require.True(t, truncateTexts(texts, 3000))
+	assert.GreaterOrEqual(t, before-total(), 3000)
+	// The longest text is truncated first.
+	assert.Equal(t, strings.Repeat("a", 10), small)
+	assert.Equal(t, strings.Repeat("b", 1000), medium)
+	assert.Contains(t, large, "truncated")
+
+	// The texts can't be reduced by more than their total length.
+	assert.False(t, truncateTexts(texts, total()))
+}
+
+func TestFitPlanPreviewResponse(t *testing.T) {
+	t.Parallel()
+
+	resp := &planpreview.GetPlanPreviewResponse{
+		Results: []*planpreview.PlanPreviewResult{
+			{DeployTarget: "dt-1", Summary: "1 change", Details: []byte(strings.Repeat("a", 100))},
+			{DeployTarget: "dt-2", Summary: "100 changes", Details: []byte(strings.Repeat("b", 10000))},
+		},
+	}
+	require.True(t, fitPlanPreviewResponse(resp, 2000))
+	assert.LessOrEqual(t, proto.Size(resp), 2000)
+	assert.Equal(t, "100 changes", resp.Results[1].Summary)
+	assert.Equal(t, strings.Repeat("a", 100), string(resp.Results[0].Details))
+	assert.Contains(t, string(resp.Results[1].Details), "truncated")
+
+	// The response within the size is not changed.
+	fitted := proto.Clone(resp)
+	require.True(t, fitPlanPreviewResponse(resp, 2000))
+	assert.True(t, proto.Equal(fitted, resp))
+}
+
+func TestFitLivestateResponse(t *testing.T) {
+	t.Parallel()
+
+	newResponse := func() *livestate.GetLivestateResponse {
+		resources := make([]*model.ResourceState, 0, 10)
+		for range 10 {
+			resources = append(resources, &model.ResourceState{
+				Id:                strings.Repeat("i", 10),
+				HealthStatus:      model.ResourceState_HEALTHY,
+				HealthDescription: strings.Repeat("d", 500),
+				ResourceMetadata:  map[string]string{"key": strings.Repeat("m", 500)},
+			})
+		}
+		return &livestate.GetLivestateResponse{
+			ApplicationLiveState: &model.ApplicationLiveState{Resources: resources},
+			SyncState:            &model.ApplicationSyncState{Reason: strings.Repeat("r", 5000)},
+		}
+	}
+
+	testcases := []struct {
+		name    string
+		maxSize int
+		fits    bool
+		check   func(t *testing.T, resp *livestate.GetLivestateResponse)
+	}{
+		{
+			name:    "truncate the reason",
+			maxSize: 11000,
+			fits:    true,
+			check: func(t *testing.T, resp *livestate.GetLivestateResponse) {
+				assert.Contains(t, resp.SyncState.Reason, "truncated")
+				assert.NotEmpty(t, resp.ApplicationLiveState.Resources[0].ResourceMetadata)
+			},
+		},
+		{
+			name:    "truncate the metadata",
+			maxSize: 8000,
+			fits:    true,
+			check: func(t *testing.T, resp *livestate.GetLivestateResponse) {
+				assert.Contains(t, resp.ApplicationLiveState.Resources[0].ResourceMetadata["key"], "truncated")
+				assert.Equal(t, strings.Repeat("d", 500), resp.ApplicationLiveState.Resources[0].HealthDescription)
+			},
+		},
+		{
+			name:    "truncate the descriptions",
+			maxSize: 3000,
+			fits:    true,
+			check: func(t *testing.T, resp *livestate.GetLivestateResponse) {
+				assert.Contains(t, resp.ApplicationLiveState.Resources[0].HealthDescription, "truncated")
+				assert.Contains(t, resp.ApplicationLiveState.Resources[0].ResourceMetadata, "key")
+			},
+		},
+		{
+			name:    "keep all resources",
+			maxSize: 100,
+			fits:    false,
+			check: func(t *testing.T, resp *livestate.GetLivestateResponse) {
+				assert.Len(t, resp.ApplicationLiveState.Resources, 10)
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			resp := newResponse()
+			assert.Equal(t, tc.fits, fitLivestateResponse(resp, tc.maxSize))
+			if tc.fits {
+				assert.LessOrEqual(t, proto.Size(resp), tc.maxSize)
+			}
+			tc.check(t, resp)
+		})
+	}
+}
+
+func TestFitLivestateResponse_ResourceKey(t *testing.T) {
+	t.Parallel()
+
+	key := ResourceKey{Provider: "kubernetes", Kind: "apps/Deployment", Scope: "default", Name: "nginx"}.String()
+	resp := &livestate.GetLivestateResponse{
+		ApplicationLiveState: &model.ApplicationLiveState{
+			Resources: []*model.ResourceState{
+				{
+					Id:           "id",
+					HealthStatus: model.ResourceState_HEALTHY,
+					ResourceMetadata: map[string]string{
+						ResourceKeyMetadataKey: key,
+						"manifest":             strings.Repeat("m", 5000),
+					},
+				},
+			},
+		},
+	}
+	require.True(t, fitLivestateResponse(resp, 1000))
+	assert.LessOrEqual(t, proto.Size(resp), 1000)
+	metadata := resp.ApplicationLiveState.Resources[0].ResourceMetadata
+	assert.Equal(t, key, metadata[ResourceKeyMetadataKey])
+	assert.Contains(t, metadata["manifest"], "truncated")
+}
+
+func TestFitResponse(t *testing.T) {
+	t.Parallel()
+
+	// Use the method name unique to the test since the metrics are global.
+	method := "TestFitResponse"
+	resp := &planpreview.GetPlanPreviewResponse{
+		Results: []*planpreview.PlanPreviewResult{{Details: []byte(strings.Repeat("a", 1000))}},
+	}
+	fitResponse(zap.NewNop(), method, resp, 2000, fitPlanPreviewResponse)
+	assert.Equal(t, 0.0, testutil.ToFloat64(responsesTruncatedTotal.WithLabelValues(method)))
+
+	fitResponse(zap.NewNop(), method, resp, 500, fitPlanPreviewResponse)
+	assert.Equal(t, 1.0, testutil.ToFloat64(responsesTruncatedTotal.WithLabelValues(method)))
+	assert.LessOrEqual(t, proto.Size(resp), 500)
+}
+
+func TestPayloadRegistrar(t *testing.T) {
+	t.Parallel()
+
+	// Use the method name unique to the test since the metrics are global.
+	desc := &grpc.ServiceDesc{
+		ServiceName: "test.Service",
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "TestPayloadRegistrar",
+				Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+					req := &livestate.GetLivestateRequest{}
+					if err := dec(req); err != nil {
+						return nil, err
+					}
+					return &livestate.GetLivestateResponse{SyncState: &model.ApplicationSyncState{Reason: req.ApplicationId + req.ApplicationId}}, nil
+				},
+			},
+		},
+	}
+	fake := &fakeServiceRegistrar{}
+	// The compressor is not set without the server stream, but the call succeeds.
+	payloadRegistrar{ServiceRegistrar: fake, compressor: "gzip", logger: zap.NewNop()}.RegisterService(desc, nil)
+	require.Len(t, fake.desc.Methods, 1)
+
+	dec := func(v any) error {
+		v.(*livestate.GetLivestateRequest).ApplicationId = strings.Repeat("a", 100)
+		return nil
+	}
+	resp, err := fake.desc.Methods[0].Handler(nil, context.Background(), dec, nil)
+	require.NoError(t, err)
+
+	registry := prometheus.NewRegistry()
+	registerPayloadMetrics(registry)
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	sizes := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != "plugin_payload_bytes" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels[methodKey] == "TestPayloadRegistrar" {
+				sizes[labels[directionKey]] = m.GetHistogram().GetSampleSum()
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		directionRequest:  float64(proto.Size(&livestate.GetLivestateRequest{ApplicationId: strings.Repeat("a", 100)})),
+		directionResponse: float64(proto.Size(resp.(proto.Message))),
+	}, sizes)
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package retry provides the retries with exponential backoff and jitter used by the SDK,
+// so that the plugins calling the flaky APIs of the providers retry in the same way.
+//
+// For example:
+//
+//	err := retry.Do(ctx, retry.DefaultPolicy(), func(ctx context.Context) error {
+//		return callProviderAPI(ctx)
+//	})
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+// Policy configures how the operation is retried.
+type Policy struct {
+	// MaxAttempts is the number of the attempts including the first one. Zero means no limit.
+	MaxAttempts int
+	// InitialInterval is the interval before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval is the upper bound of the intervals. Zero means no limit.
+	MaxInterval time.Duration
+	// Multiplier is the factor the interval grows by after each retry. It defaults to 2.
+	Multiplier float64
+	// Jitter is the ratio of the interval randomized in the range of [0, 1], to spread the retries of the callers.
+	// The interval is chosen from [(1-Jitter)*interval, interval], so 1 is the full jitter.
+	Jitter float64
+	// MaxElapsed is the time after which no more retries are made, counted from the first attempt. Zero means no limit.
+	MaxElapsed time.Duration
+	// Retryable reports whether the error is worth retrying. All errors except the ones wrapped by Permanent are retried if nil.
+	Retryable func(error) bool
+	// OnRetry is called before waiting for each retry with the number of the failed attempts, e.g. to log or count the retries.
+	OnRetry func(attempt int, err error, wait time.Duration)
+	// Clock is the clock to wait for the intervals on. It defaults to the real clock.
+	Clock clock.Clock
+}
+
+// DefaultPolicy returns the policy retrying 5 times at most with the full jitter,
+// starting from 100ms up to 10s between the attempts.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:     5,
+		InitialInterval: 100 * time.Millisecond,
+		MaxInterval:     10 * time.Second,
+		Jitter:          1,
+	}
+}
+
+// Backoff returns the interval to wait after the given number of the failed attempts, starting from 1.
+func (p Policy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	d := float64(p.InitialInterval) * math.Pow(multiplier, float64(max(attempt-1, 0)))
+	if p.MaxInterval > 0 {
+		d = math.Min(d, float64(p.MaxInterval))
+	}
+	if jitter := math.Min(math.Max(p.Jitter, 0), 1); jitter > 0 {
+		d *= 1 - jitter*rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// Do calls the operation until it succeeds, it returns a non-retryable error, or the retries are exhausted.
+// It returns the error of the last attempt, or the error of the context if the context is done before the first attempt.
+func Do(ctx context.Context, p Policy, op func(ctx context.Context) error) error {
+	_, err := DoValue(ctx, p, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, op(ctx)
+	})
+	return err
+}
+
+// DoValue is the same as Do, but returns the value of the successful attempt.
+func DoValue[T any](ctx context.Context, p Policy, op func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	clk := clock.OrReal(p.Clock)
+	start := clk.Now()
+	for attempt := 1; ; attempt++ {
+		v, err := op(ctx)
+		if err == nil {
+			return v, nil
+		}
+		if !p.retryable(err) {
+			return zero, unwrapPermanent(err)
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return zero, err
+		}
+		wait := p.Backoff(attempt)
+		if p.MaxElapsed > 0 && clk.Since(start)+wait > p.MaxElapsed {
+			return zero, err
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		if !sleep(ctx, clk, wait) {
+			return zero, err
+		}
+	}
+}
+
+func (p Policy) retryable(err error) bool {
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// sleep waits for the duration on the clock. It returns false if the context is done before that.
+func sleep(ctx context.Context, clk clock.Clock, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := clk.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C():
+		return true
+	}
+}
+
+// permanentError is the error which is never retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps the error so that it's returned without being retried regardless of the policy.
+// Do and DoValue return the error given to Permanent, even if the returned error wraps it further.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func unwrapPermanent(err error) error {
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return perm.err
+	}
+	return err
+}
+
+// Any returns the predicate reporting whether the error matches any of the given ones.
+func Any(predicates ...func(error) bool) func(error) bool {
+	return func(err error) bool {
+		for _, p := range predicates {
+			if p(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsTransientGRPC reports whether the error is a gRPC status error with the code worth retrying,
+// i.e. Unavailable, ResourceExhausted, or Aborted.
+func IsTransientGRPC(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTransientNetwork reports whether the error is a network error worth retrying,
+// e.g. a timeout, a reset or refused connection, or an unexpectedly closed connection.
+func IsTransientNetwork(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// IsRetryableHTTPStatus reports whether the HTTP status code is worth retrying,
+// i.e. 429 Too Many Requests, 502 Bad Gateway, 503 Service Unavailable, or 504 Gateway Timeout.
+func IsRetryableHTTPStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+func TestPolicy_Backoff(t *testing.T) {
+	t.Parallel()
+
+	p := Policy{InitialInterval: time.Second, MaxInterval: 5 * time.Second}
+	assert.Equal(t, time.Second, p.Backoff(1))
+	assert.Equal(t, 2*time.Second, p.Backoff(2))
+	assert.Equal(t, 4*time.Second, p.Backoff(3))
+	assert.Equal(t, 5*time.Second, p.Backoff(4))
+
+	p.Multiplier = 3
+	assert.Equal(t, 3*time.Second, p.Backoff(2))
+
+	p = Policy{InitialInterval: time.Second, Jitter: 0.5}
+	for range 100 {
+		d := p.Backoff(2)
+		assert.GreaterOrEqual(t, d, time.Second)
+		assert.LessOrEqual(t, d, 2*time.Second)
+	}
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	errTransient := errors.New("transient")
+	errFatal := errors.New("fatal")
+
+	tests := []struct {
+		name      string
+		policy    Policy
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "success after retries",
+			policy:    Policy{MaxAttempts: 3},
+			errs:      []error{errTransient, errTransient, nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "exhausted",
+			policy:    Policy{MaxAttempts: 2},
+			errs:      []error{errTransient, errTransient, nil},
+			wantCalls: 2,
+			wantErr:   errTransient,
+		},
+		{
+			name:      "not retryable",
+			policy:    Policy{MaxAttempts: 3, Retryable: func(err error) bool { return err == errTransient }},
+			errs:      []error{errTransient, errFatal, nil},
+			wantCalls: 2,
+			wantErr:   errFatal,
+		},
+		{
+			name:      "permanent",
+			policy:    Policy{MaxAttempts: 3},
+			errs:      []error{Permanent(errFatal), nil},
+			wantCalls: 1,
+			wantErr:   errFatal,
+		},
+		{
+			name:      "wrapped permanent",
+			policy:    Policy{MaxAttempts: 3},
+			errs:      []error{fmt.Errorf("wrapped: %w", Permanent(errFatal)), nil},
+			wantCalls: 1,
+			wantErr:   errFatal,
+		},
+		{
+			name:      "max elapsed",
+			policy:    Policy{InitialInterval: time.Hour, MaxElapsed: time.Minute},
+			errs:      []error{errTransient, nil},
+			wantCalls: 1,
+			wantErr:   errTransient,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var calls, retries int
+			tt.policy.OnRetry = func(attempt int, err error, _ time.Duration) {
+				retries++
+				assert.Equal(t, calls, attempt)
+			}
+			err := Do(context.Background(), tt.policy, func(context.Context) error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantCalls, calls)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.wantCalls-1, retries)
+			}
+		})
+	}
+}
+
+func TestDoValue_Clock(t *testing.T) {
+	t.Parallel()
+
+	clk := clocktest.NewFakeClock(time.Now())
+	p := Policy{MaxAttempts: 3, InitialInterval: time.Minute, Clock: clk}
+
+	var calls int
+	type result struct {
+		v   string
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		v, err := DoValue(context.Background(), p, func(context.Context) (string, error) {
+			calls++
+			if calls < 3 {
+				return "", errors.New("transient")
+			}
+			return "done", nil
+		})
+		ch <- result{v, err}
+	}()
+
+	// The retries wait for the intervals on the clock.
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	clk.BlockUntil(1)
+	clk.Advance(2 * time.Minute)
+	r := <-ch
+	require.NoError(t, r.err)
+	assert.Equal(t, "done", r.v)
+}
+
+func TestDo_Context(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var calls int
+	err := Do(ctx, DefaultPolicy(), func(context.Context) error {
+		calls++
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, calls)
+
+	// The error of the last attempt is returned when the context is done while waiting.
+	clk := clocktest.NewFakeClock(time.Now())
+	ctx, cancel = context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Do(ctx, Policy{InitialInterval: time.Minute, Clock: clk}, func(context.Context) error {
+			return errors.New("transient")
+		})
+	}()
+	clk.BlockUntil(1)
+	cancel()
+	assert.EqualError(t, <-errCh, "transient")
+}
+
+func TestPredicates(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsTransientGRPC(status.Error(codes.Unavailable, "")))
+	assert.True(t, IsTransientGRPC(fmt.Errorf("wrapped: %w", status.Error(codes.ResourceExhausted, ""))))
+	assert.False(t, IsTransientGRPC(status.Error(codes.InvalidArgument, "")))
+	assert.False(t, IsTransientGRPC(errors.New("not a status")))
+
+	assert.True(t, IsTransientNetwork(fmt.Errorf("dial: %w", syscall.ECONNREFUSED)))
+	assert.True(t, IsTransientNetwork(io.ErrUnexpectedEOF))
+	assert.True(t, IsTransientNetwork(context.DeadlineExceeded))
+	assert.False(t, IsTransientNetwork(errors.New("other")))
+
+	assert.True(t, IsRetryableHTTPStatus(http.StatusTooManyRequests))
+	assert.True(t, IsRetryableHTTPStatus(http.StatusServiceUnavailable))
+	assert.False(t, IsRetryableHTTPStatus(http.StatusNotFound))
+
+	any := Any(IsTransientGRPC, IsTransientNetwork)
+	assert.True(t, any(io.ErrUnexpectedEOF))
+	assert.True(t, any(status.Error(codes.Aborted, "")))
+	assert.False(t, any(errors.New("other")))
+}
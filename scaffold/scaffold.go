@@ -0,0 +1,222 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scaffold generates the skeleton of a new plugin built on the SDK,
+// with the example implementation of the chosen roles and the tests against sdktest.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Role is the role of the plugin.
+type Role string
+
+const (
+	// RoleStage is the plugin providing the stages, registered by sdk.WithStagePlugin.
+	RoleStage Role = "stage"
+	// RoleDeployment is the plugin deploying the applications, registered by sdk.WithDeploymentPlugin.
+	RoleDeployment Role = "deployment"
+	// RoleLivestate is the plugin providing the live state, registered by sdk.WithLivestatePlugin.
+	RoleLivestate Role = "livestate"
+	// RolePlanPreview is the plugin providing the plan preview, registered by sdk.WithPlanPreviewPlugin.
+	RolePlanPreview Role = "plan-preview"
+)
+
+// Roles are all the roles in the order they are registered.
+var Roles = []Role{RoleStage, RoleDeployment, RoleLivestate, RolePlanPreview}
+
+// ParseRoles parses the comma-separated roles, e.g. "deployment,livestate".
+func ParseRoles(s string) ([]Role, error) {
+	var roles []Role
+	for _, r := range strings.Split(s, ",") {
+		role := Role(strings.TrimSpace(r))
+		if !slices.Contains(Roles, role) {
+			return nil, fmt.Errorf("unknown role %q, it must be one of %v", r, Roles)
+		}
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+const sdkModule = "github.com/pipe-cd/piped-plugin-sdk-go"
+
+// Options is the options to generate the plugin.
+type Options struct {
+	// Module is the module path of the plugin, e.g. github.com/example/plugin.
+	Module string
+	// Name is the name of the plugin in the piped config and the application config.
+	Name string
+	// Roles are the roles of the plugin.
+	// At least one of stage, deployment, and livestate is required, and stage and deployment are exclusive
+	// as sdk.NewPlugin requires.
+	Roles []Role
+	// SDKVersion is the version of the SDK required in go.mod, e.g. v0.1.0.
+	// The requirement is left to `make tidy` when it's empty.
+	SDKVersion string
+}
+
+func (o Options) validate() error {
+	if o.Module == "" {
+		return errors.New("module is required")
+	}
+	if o.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(o.Roles) == 0 {
+		return errors.New("at least one role is required")
+	}
+	for _, r := range o.Roles {
+		if !slices.Contains(Roles, r) {
+			return fmt.Errorf("unknown role %q, it must be one of %v", r, Roles)
+		}
+	}
+	if !o.has(RoleStage) && !o.has(RoleDeployment) && !o.has(RoleLivestate) {
+		return errors.New("at least one of stage, deployment, and livestate role is required")
+	}
+	if o.has(RoleStage) && o.has(RoleDeployment) {
+		return errors.New("stage and deployment roles cannot be chosen at the same time, the deployment plugin provides the stages as well")
+	}
+	return nil
+}
+
+func (o Options) has(r Role) bool {
+	return slices.Contains(o.Roles, r)
+}
+
+//go:embed templates
+var templates embed.FS
+
+// file is the file generated from the template of the same name with the .tmpl suffix.
+type file struct {
+	path string
+	// roles are the roles requiring the file. It's always generated when it's empty.
+	roles []Role
+}
+
+var files = []file{
+	{path: "go.mod"},
+	{path: "Makefile"},
+	{path: "config.yaml"},
+	{path: "main.go"},
+	{path: "config.go"},
+	{path: "stage.go", roles: []Role{RoleStage, RoleDeployment}},
+	{path: "deployment.go", roles: []Role{RoleDeployment}},
+	{path: "livestate.go", roles: []Role{RoleLivestate}},
+	{path: "planpreview.go", roles: []Role{RolePlanPreview}},
+	{path: "plugin_test.go"},
+	{path: "testdata/app/app.pipecd.yaml"},
+}
+
+// data is the data given to the templates.
+type data struct {
+	Options
+	SDKModule   string
+	StagePrefix string
+	Stage       bool
+	Deployment  bool
+	Livestate   bool
+	PlanPreview bool
+}
+
+// Generate generates the plugin into the directory, creating it if it doesn't exist.
+// It fails without writing anything if any of the files already exists.
+func Generate(dir string, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+
+	d := data{
+		Options:     opts,
+		SDKModule:   sdkModule,
+		StagePrefix: stagePrefix(opts.Name),
+		Stage:       opts.has(RoleStage) || opts.has(RoleDeployment),
+		Deployment:  opts.has(RoleDeployment),
+		Livestate:   opts.has(RoleLivestate),
+		PlanPreview: opts.has(RolePlanPreview),
+	}
+
+	contents := make(map[string][]byte, len(files))
+	for _, f := range files {
+		if len(f.roles) > 0 && !slices.ContainsFunc(f.roles, opts.has) {
+			continue
+		}
+		content, err := render(f.path, d)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, filepath.FromSlash(f.path))
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("the file %s already exists", path)
+		}
+		contents[path] = content
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.path))
+		content, ok := contents[path]
+		if !ok {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create the directory of %s: %w", path, err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// render executes the template of the file, and formats it if it's a Go file.
+func render(path string, d data) ([]byte, error) {
+	tmpl, err := template.ParseFS(templates, "templates/"+path+".tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the template of %s: %w", path, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d); err != nil {
+		return nil, fmt.Errorf("failed to execute the template of %s: %w", path, err)
+	}
+	if filepath.Ext(path) != ".go" {
+		return buf.Bytes(), nil
+	}
+	content, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", path, err)
+	}
+	return content, nil
+}
+
+// stagePrefix returns the prefix of the stage names from the plugin name, e.g. EXAMPLE for example.
+func stagePrefix(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffold
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestParseRoles(t *testing.T) {
+	t.Parallel()
+
+	roles, err := ParseRoles("deployment, livestate,deployment")
+	require.NoError(t, err)
+	assert.Equal(t, []Role{RoleDeployment, RoleLivestate}, roles)
+
+	_, err = ParseRoles("deployment,unknown")
+	assert.Error(t, err)
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		roles    []Role
+		expected []string
+		missing  []string
+	}{
+		{
+			name:     "deployment with all the roles",
+			roles:    []Role{RoleDeployment, RoleLivestate, RolePlanPreview},
+			expected: []string{"stage.go", "deployment.go", "livestate.go", "planpreview.go"},
+		},
+		{
+			name:     "stage",
+			roles:    []Role{RoleStage},
+			expected: []string{"stage.go"},
+			missing:  []string{"deployment.go", "livestate.go", "planpreview.go"},
+		},
+		{
+			name:     "livestate and plan preview",
+			roles:    []Role{RoleLivestate, RolePlanPreview},
+			expected: []string{"livestate.go", "planpreview.go"},
+			missing:  []string{"stage.go", "deployment.go"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			require.NoError(t, Generate(dir, Options{Module: "example.com/plugin", Name: "my-plugin", Roles: tc.roles, SDKVersion: "v0.1.0"}))
+
+			for _, f := range append([]string{"go.mod", "Makefile", "config.yaml", "main.go", "config.go", "plugin_test.go", "testdata/app/app.pipecd.yaml"}, tc.expected...) {
+				assert.FileExists(t, filepath.Join(dir, f))
+			}
+			for _, f := range tc.missing {
+				assert.NoFileExists(t, filepath.Join(dir, f))
+			}
+
+			// The Go files are a valid package.
+			pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, parser.AllErrors)
+			require.NoError(t, err)
+			assert.Contains(t, pkgs, "main")
+
+			gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+			require.NoError(t, err)
+			assert.Contains(t, string(gomod), "module example.com/plugin")
+			assert.Contains(t, string(gomod), "require github.com/pipe-cd/piped-plugin-sdk-go v0.1.0")
+
+			app, err := os.ReadFile(filepath.Join(dir, "testdata/app/app.pipecd.yaml"))
+			require.NoError(t, err)
+			assert.Contains(t, string(app), "my-plugin:")
+		})
+	}
+}
+
+func TestGenerate_StageNames(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, Generate(dir, Options{Module: "example.com/plugin", Name: "my-plugin", Roles: []Role{RoleStage}}))
+
+	stage, err := os.ReadFile(filepath.Join(dir, "stage.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(stage), `"MY_PLUGIN_SYNC"`)
+
+	gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	require.NoError(t, err)
+	assert.False(t, strings.Contains(string(gomod), "require"))
+}
+
+func TestGenerate_Makefile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, Generate(dir, Options{Module: "example.com/plugin", Name: "my-plugin", Roles: []Role{RoleStage}}))
+
+	// The run target starts the plugin with the sample config.
+	makefile, err := os.ReadFile(filepath.Join(dir, "Makefile"))
+	require.NoError(t, err)
+	var invoked [][]string
+	for _, line := range strings.Split(string(makefile), "\n") {
+		if args := strings.Fields(line); len(args) > 0 && args[0] == ".artifacts/my-plugin" {
+			invoked = append(invoked, args[1:])
+		}
+	}
+	assert.Equal(t, [][]string{
+		{"start", "--piped-plugin-service=$(PIPED_PLUGIN_SERVICE)", "--config=file://$(CURDIR)/config.yaml"},
+	}, invoked)
+
+	// The sample config is valid YAML with the plugin name and a deploy target.
+	data, err := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	require.NoError(t, err)
+	var cfg struct {
+		Name          string `json:"name"`
+		DeployTargets []struct {
+			Name   string `json:"name"`
+			Config struct {
+				Endpoint string `json:"endpoint"`
+			} `json:"config"`
+		} `json:"deployTargets"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &cfg))
+	assert.Equal(t, "my-plugin", cfg.Name)
+	require.Len(t, cfg.DeployTargets, 1)
+	assert.Equal(t, "local", cfg.DeployTargets[0].Name)
+	assert.Equal(t, "http://localhost:8080", cfg.DeployTargets[0].Config.Endpoint)
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name string
+		opts Options
+	}{
+		{
+			name: "no module",
+			opts: Options{Name: "example", Roles: []Role{RoleStage}},
+		},
+		{
+			name: "no name",
+			opts: Options{Module: "example.com/plugin", Roles: []Role{RoleStage}},
+		},
+		{
+			name: "no role",
+			opts: Options{Module: "example.com/plugin", Name: "example"},
+		},
+		{
+			name: "unknown role",
+			opts: Options{Module: "example.com/plugin", Name: "example", Roles: []Role{"unknown"}},
+		},
+		{
+			name: "only plan preview",
+			opts: Options{Module: "example.com/plugin", Name: "example", Roles: []Role{RolePlanPreview}},
+		},
+		{
+			name: "stage and deployment",
+			opts: Options{Module: "example.com/plugin", Name: "example", Roles: []Role{RoleStage, RoleDeployment}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			assert.Error(t, Generate(dir, tc.opts))
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+
+	// The existing files are not overwritten.
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644))
+	err := Generate(dir, Options{Module: "example.com/plugin", Name: "example", Roles: []Role{RoleStage}})
+	assert.ErrorContains(t, err, "already exists")
+	assert.NoFileExists(t, filepath.Join(dir, "go.mod"))
+}
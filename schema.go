@@ -0,0 +1,329 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// jsonSchemaDialect is the JSON Schema dialect of the generated schemas.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// SchemaProvider is an optional interface for the types used in the Config, the DeployTargetConfig,
+// and the ApplicationConfigSpec to provide their own JSON Schema instead of the one reflected from the type.
+// It's useful for the types with the custom JSON decoding, e.g. the ones accepting either a string or a number.
+// JSONSchema is called on the zero value of the type.
+type SchemaProvider interface {
+	JSONSchema() map[string]any
+}
+
+var (
+	schemaProviderType  = reflect.TypeFor[SchemaProvider]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// schemaKinds are the kinds of the configs accepted by the schema command.
+var schemaKinds = []string{"config", "deploy-target", "application"}
+
+// schemaTitles are the titles of the schemas by the kind.
+var schemaTitles = map[string]string{
+	"config":        "Config",
+	"deploy-target": "DeployTargetConfig",
+	"application":   "ApplicationConfigSpec",
+}
+
+// JSONSchema returns the JSON Schema document of the given type reflected from its fields and struct tags.
+// The fields are named by the `json` struct tags, the `default` struct tags are set as the default values,
+// and the `validate` struct tags are converted to the corresponding keywords, e.g. required, enum, and minimum.
+// The types implementing json.Unmarshaler without SchemaProvider accept any value.
+func JSONSchema[T any]() (map[string]any, error) {
+	s, err := (&schemaGenerator{visiting: make(map[reflect.Type]bool)}).schemaOf(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	s["$schema"] = jsonSchemaDialect
+	return s, nil
+}
+
+// schemas returns the JSON Schema documents of the configs of the plugin by the kind.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) schemas() (map[string]map[string]any, error) {
+	generators := map[string]func() (map[string]any, error){
+		"config":        JSONSchema[Config],
+		"deploy-target": JSONSchema[DeployTargetConfig],
+		"application":   JSONSchema[ApplicationConfigSpec],
+	}
+	schemas := make(map[string]map[string]any, len(generators))
+	for kind, generate := range generators {
+		s, err := generate()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate the schema of %s: %w", schemaTitles[kind], err)
+		}
+		s["title"] = schemaTitles[kind]
+		schemas[kind] = s
+	}
+	return schemas, nil
+}
+
+// schemaCommand returns the cobra command to print the JSON Schema of the configs of the plugin.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) schemaCommand() *cobra.Command {
+	var kind string
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the plugin configuration.",
+		Long:  "Print the JSON Schema documents of the plugin config, the deploy target config, and the application config spec to validate them before deployment.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if kind != "" && !slices.Contains(schemaKinds, kind) {
+				return fmt.Errorf("unknown kind %q, it must be one of %v", kind, schemaKinds)
+			}
+			schemas, err := p.schemas()
+			if err != nil {
+				return err
+			}
+			var out any = schemas
+			if kind != "" {
+				out = schemas[kind]
+			}
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(out)
+		},
+	}
+
+	cmd.Flags().StringVar(&kind, "kind", kind, "The kind of the config to print the schema of. Supported values are \"config\", \"deploy-target\", \"application\", and empty (all of them keyed by the kind).")
+
+	return cmd
+}
+
+// schemaGenerator generates the JSON Schema by reflection.
+type schemaGenerator struct {
+	// visiting is the set of the structs being generated to stop at the recursive types.
+	visiting map[reflect.Type]bool
+}
+
+func (g *schemaGenerator) schemaOf(t reflect.Type) (map[string]any, error) {
+	if reflect.PointerTo(t).Implements(schemaProviderType) {
+		return maps.Clone(reflect.New(t).Interface().(SchemaProvider).JSONSchema()), nil
+	}
+	if t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return map[string]any{}, nil
+	}
+	if t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return map[string]any{"type": "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaOf(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := g.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s := map[string]any{"type": "array", "items": items}
+		if t.Kind() == reflect.Array {
+			s["minItems"], s["maxItems"] = t.Len(), t.Len()
+		}
+		return s, nil
+	case reflect.Map:
+		values, err := g.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return g.structSchema(t)
+	default:
+		// The interfaces and the others accept any value.
+		return map[string]any{}, nil
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) (map[string]any, error) {
+	if g.visiting[t] {
+		// The recursive reference accepts any object not to expand infinitely.
+		return map[string]any{"type": "object"}, nil
+	}
+	g.visiting[t] = true
+	defer delete(g.visiting, t)
+
+	properties := make(map[string]any)
+	var required []string
+	if err := g.addFields(t, properties, &required); err != nil {
+		return nil, err
+	}
+	s := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s, nil
+}
+
+// addFields adds the schemas of the fields of the struct to the properties.
+// The embedded structs without the name in the `json` struct tag are flattened as encoding/json does.
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]any, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := g.addFields(ft, properties, required); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s, err := g.schemaOf(f.Type)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if tag, ok := f.Tag.Lookup(defaultTag); ok {
+			v, err := schemaDefault(tag, f.Type)
+			if err != nil {
+				return fmt.Errorf("invalid default value %q of the field %s: %w", tag, f.Name, err)
+			}
+			s["default"] = v
+		}
+		isRequired, err := applyValidateRules(s, f.Type, f.Tag.Get(validateTag))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if isRequired {
+			*required = append(*required, name)
+		}
+		properties[name] = s
+	}
+	return nil
+}
+
+// schemaDefault returns the default value of the field in the form of JSON.
+func schemaDefault(tag string, t reflect.Type) (any, error) {
+	v := reflect.New(t).Elem()
+	if err := decodeDefault(tag, v); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// applyValidateRules converts the rules of the `validate` struct tag to the keywords of the schema.
+// It returns whether the field is required.
+func applyValidateRules(s map[string]any, t reflect.Type, tag string) (bool, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var required bool
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+			continue
+		case "required":
+			required = true
+		case "oneof":
+			values := strings.Fields(param)
+			enum := make([]any, 0, len(values))
+			for _, v := range values {
+				if t.Kind() == reflect.String {
+					enum = append(enum, v)
+					continue
+				}
+				n, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return false, fmt.Errorf("invalid value %q of the oneof rule for %s", v, t)
+				}
+				enum = append(enum, n)
+			}
+			s["enum"] = enum
+		case "min", "max":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return false, fmt.Errorf("invalid parameter %q of the validation rule: %w", param, err)
+			}
+			keyword, ok := rangeKeyword(t.Kind(), name)
+			if !ok {
+				return false, fmt.Errorf("the validation rule is not supported for %s", t)
+			}
+			s[keyword] = limit
+		case "url":
+			s["format"] = "uri"
+		default:
+			return false, fmt.Errorf("unknown validation rule %q", name)
+		}
+	}
+	return required, nil
+}
+
+// rangeKeyword returns the keyword of the schema for the min and max rules of the given kind.
+func rangeKeyword(kind reflect.Kind, rule string) (string, bool) {
+	prefix := rule
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return prefix + "imum", true
+	case reflect.String:
+		return prefix + "Length", true
+	case reflect.Slice, reflect.Array:
+		return prefix + "Items", true
+	case reflect.Map:
+		return prefix + "Properties", true
+	default:
+		return "", false
+	}
+}
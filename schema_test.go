@@ -0,0 +1,161 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/unit"
+)
+
+type schemaEmbedded struct {
+	Labels map[string]string `json:"labels"`
+}
+
+type schemaNode struct {
+	Name     string        `json:"name"`
+	Children []*schemaNode `json:"children"`
+}
+
+type schemaConfig struct {
+	schemaEmbedded
+	Endpoint string        `json:"endpoint" validate:"required,url"`
+	Mode     string        `json:"mode" validate:"oneof=fast safe" default:"safe"`
+	Replicas int           `json:"replicas" validate:"min=1,max=10" default:"3"`
+	Regions  []string      `json:"regions" validate:"min=1"`
+	Timeout  unit.Duration `json:"timeout" default:"5m"`
+	Token    Credential    `json:"token"`
+	Prune    *bool         `json:"prune"`
+	Data     []byte        `json:"data"`
+	Extra    any           `json:"extra"`
+	Tree     schemaNode    `json:"tree"`
+	Ignored  string        `json:"-"`
+	internal string
+}
+
+func TestJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	got, err := JSONSchema[schemaConfig]()
+	require.NoError(t, err)
+
+	// Compare in the form of JSON to ignore the differences of the Go types.
+	b, err := json.Marshal(got)
+	require.NoError(t, err)
+	want := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["endpoint"],
+		"properties": {
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+			"endpoint": {"type": "string", "format": "uri"},
+			"mode": {"type": "string", "enum": ["fast", "safe"], "default": "safe"},
+			"replicas": {"type": "integer", "minimum": 1, "maximum": 10, "default": 3},
+			"regions": {"type": "array", "items": {"type": "string"}, "minItems": 1},
+			"timeout": {"type": ["string", "number"], "default": "5m0s"},
+			"token": {"oneOf": [
+				{"type": "string"},
+				{"type": "object", "properties": {"value": {"type": "string"}, "file": {"type": "string"}, "env": {"type": "string"}}, "minProperties": 1, "maxProperties": 1, "additionalProperties": false}
+			]},
+			"prune": {"type": "boolean"},
+			"data": {"type": "string", "contentEncoding": "base64"},
+			"extra": {},
+			"tree": {"type": "object", "properties": {
+				"name": {"type": "string"},
+				"children": {"type": "array", "items": {"type": "object"}}
+			}}
+		}
+	}`
+	assert.JSONEq(t, want, string(b))
+}
+
+func TestJSONSchema_InvalidTags(t *testing.T) {
+	t.Parallel()
+
+	type invalidDefault struct {
+		Replicas int `json:"replicas" default:"many"`
+	}
+	_, err := JSONSchema[invalidDefault]()
+	assert.ErrorContains(t, err, "invalid default value")
+
+	type invalidRule struct {
+		Enabled bool `json:"enabled" validate:"min=1"`
+	}
+	_, err = JSONSchema[invalidRule]()
+	assert.ErrorContains(t, err, "enabled: the validation rule is not supported for bool")
+}
+
+func TestPlugin_schemaCommand(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "all kinds",
+			want: []string{"config", "deploy-target", "application"},
+		},
+		{
+			name: "config only",
+			args: []string{"--kind", "config"},
+		},
+		{
+			name:    "unknown kind",
+			args:    []string{"--kind", "stage"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &Plugin[schemaConfig, struct{}, schemaNode]{}
+			cmd := p.schemaCommand()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetArgs(tc.args)
+
+			err := cmd.Execute()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var got map[string]any
+			require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+			if tc.want == nil {
+				assert.Equal(t, "Config", got["title"])
+				assert.Equal(t, []any{"endpoint"}, got["required"])
+				return
+			}
+			assert.Len(t, got, len(tc.want))
+			for _, kind := range tc.want {
+				assert.Contains(t, got, kind)
+			}
+			assert.Equal(t, "ApplicationConfigSpec", got["application"].(map[string]any)["title"])
+		})
+	}
+}
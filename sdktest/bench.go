@@ -0,0 +1,200 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+)
+
+// StageBenchmark is the stage executed by BenchmarkStage.
+type StageBenchmark struct {
+	// StageName is the name of the stage to execute.
+	StageName string
+	// StageConfig is the config of the stage.
+	// The []byte and json.RawMessage are passed as they are, and the other values are marshaled to JSON.
+	StageConfig any
+	// TargetApplicationDirectory is the directory of the application to deploy.
+	// It must contain the application config file.
+	TargetApplicationDirectory string
+	// ApplicationConfigFilename is the filename of the application config in the directory.
+	// The default is app.pipecd.yaml.
+	ApplicationConfigFilename string
+	// Concurrency is the number of the stages executed at the same time. The default is 1.
+	Concurrency int
+	// FlushInterval is the interval of the log persister to flush the stage logs. The default is DefaultBenchmarkFlushInterval.
+	// Each execution waits for the flush after the stage completes, as the plugin server does.
+	FlushInterval time.Duration
+}
+
+// DefaultBenchmarkFlushInterval is the interval to flush the stage logs used when StageBenchmark.FlushInterval is not given.
+// It's shorter than the one of the start command, so that the executions don't spend most of the time waiting for the flush.
+const DefaultBenchmarkFlushInterval = 10 * time.Millisecond
+
+// BenchmarkStage executes the stage b.N times through the plugin server and the log persister used by the start command,
+// with the given piped plugin config in JSON or YAML.
+// Each execution is of a different deployment, so that the stage logs are persisted separately as on piped.
+// It reports the stage logs persisted per execution as "logs/op" and "logbytes/op" in addition to the time.
+func BenchmarkStage[Config, DeployTargetConfig, ApplicationConfigSpec any](b *testing.B, plugin *sdk.Plugin[Config, DeployTargetConfig, ApplicationConfigSpec], config string, bench StageBenchmark) {
+	b.Helper()
+
+	filename := bench.ApplicationConfigFilename
+	if filename == "" {
+		filename = model.DefaultApplicationConfigFilename
+	}
+	source, err := newDeploymentSource(bench.TargetApplicationDirectory, filename)
+	if err != nil {
+		b.Fatalf("failed to prepare the deployment source: %s", err)
+	}
+	stageConfig, err := marshalStageConfig(bench.StageConfig)
+	if err != nil {
+		b.Fatalf("failed to prepare the stage config: %s", err)
+	}
+
+	service := NewPluginService()
+	persister := logpersister.NewPersister(service, zap.NewNop(), logpersister.WithFlushInterval(cmp.Or(bench.FlushInterval, DefaultBenchmarkFlushInterval)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	persisterDone := make(chan error, 1)
+	go func() {
+		persisterDone <- persister.Run(ctx)
+	}()
+	// Discard the logs of the plugin not to measure writing them into the benchmark output.
+	conn, stop := startPlugin(b, plugin, config, service, persister, zap.NewNop())
+
+	concurrency := max(bench.Concurrency, 1)
+	var (
+		next atomic.Int64
+		wg   sync.WaitGroup
+	)
+	b.ResetTimer()
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := next.Add(1) - 1
+				if i >= int64(b.N) {
+					return
+				}
+				d := NewDeployment()
+				d.Id = benchmarkDeploymentID(i)
+				resp, err := conn.Deployment.ExecuteStage(context.Background(), &deployment.ExecuteStageRequest{
+					Input: &deployment.ExecutePluginInput{
+						Deployment:             d,
+						Stage:                  NewPipelineStage(bench.StageName, 0),
+						StageConfig:            stageConfig,
+						TargetDeploymentSource: source,
+					},
+				})
+				if err != nil {
+					b.Errorf("failed to execute the stage: %s", err)
+					return
+				}
+				if resp.GetStatus() != model.StageStatus_STAGE_SUCCESS {
+					b.Errorf("the stage finished with %s", resp.GetStatus())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	b.StopTimer()
+
+	// Stop the plugin, then the persister to flush all the stage logs.
+	if err := stop(); err != nil {
+		b.Errorf("failed to run the plugin: %s", err)
+	}
+	cancel()
+	if err := <-persisterDone; err != nil {
+		b.Errorf("failed to run the log persister: %s", err)
+	}
+
+	var logs, bytes int
+	for i := range int64(b.N) {
+		for _, block := range service.StageLogs(benchmarkDeploymentID(i), DefaultStageID) {
+			logs++
+			bytes += len(block.Log)
+		}
+	}
+	b.ReportMetric(float64(logs)/float64(b.N), "logs/op")
+	b.ReportMetric(float64(bytes)/float64(b.N), "logbytes/op")
+}
+
+func benchmarkDeploymentID(i int64) string {
+	return fmt.Sprintf("deployment-%d", i)
+}
+
+// LogStageName is the name of the stage of LogStagePlugin.
+const LogStageName = "SDKTEST_LOG"
+
+// LogStagePlugin is the stage plugin writing the stage logs at the given rate,
+// to measure the overhead of the SDK with BenchmarkStage regardless of the work of the stages.
+type LogStagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec any] struct {
+	// Lines is the number of the log lines written by the stage.
+	Lines int
+	// LineSize is the size of each log line in bytes.
+	LineSize int
+	// Interval is the interval between the log lines. The lines are written at once when it's zero.
+	Interval time.Duration
+}
+
+// FetchDefinedStages implements sdk.StagePlugin.
+func (p *LogStagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec]) FetchDefinedStages() []string {
+	return []string{LogStageName}
+}
+
+// BuildPipelineSyncStages implements sdk.StagePlugin.
+func (p *LogStagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec]) BuildPipelineSyncStages(_ context.Context, _ *Config, input *sdk.BuildPipelineSyncStagesInput) (*sdk.BuildPipelineSyncStagesResponse, error) {
+	stages := make([]sdk.PipelineStage, 0, len(input.Request.Stages))
+	for _, s := range input.Request.Stages {
+		stages = append(stages, sdk.PipelineStage{Index: s.Index, Name: s.Name})
+	}
+	return &sdk.BuildPipelineSyncStagesResponse{Stages: stages}, nil
+}
+
+// ExecuteStage implements sdk.StagePlugin.
+func (p *LogStagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec]) ExecuteStage(ctx context.Context, _ *Config, _ []*sdk.DeployTarget[DeployTargetConfig], input *sdk.ExecuteStageInput[ApplicationConfigSpec]) (*sdk.ExecuteStageResponse, error) {
+	lp, err := input.Client.StageLogPersister()
+	if err != nil {
+		return nil, err
+	}
+	line := strings.Repeat("x", p.LineSize)
+	for i := range p.Lines {
+		if i > 0 && p.Interval > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(p.Interval):
+			}
+		}
+		lp.Info(line)
+	}
+	return &sdk.ExecuteStageResponse{Status: sdk.StageStatusSuccess}, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+func TestLogStagePlugin(t *testing.T) {
+	t.Parallel()
+
+	plugin := &LogStagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec]{Lines: 3, LineSize: 8}
+	h := NewStageHarness(t, "example", sdk.StagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](plugin), nil)
+	result, err := h.ExecuteStage(context.Background(), ExecuteStageCase[testDeployTargetConfig]{
+		StageName:                  LogStageName,
+		TargetApplicationDirectory: "testdata/app",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.StageStatus_STAGE_SUCCESS, result.Status)
+	require.Len(t, result.Logs, 3)
+	assert.Equal(t, strings.Repeat("x", 8), result.Logs[0].Log)
+}
+
+func BenchmarkStage_Logs(b *testing.B) {
+	for _, lines := range []int{0, 100, 1000} {
+		for _, concurrency := range []int{1, 16} {
+			b.Run(fmt.Sprintf("lines=%d/concurrency=%d", lines, concurrency), func(b *testing.B) {
+				plugin, err := sdk.NewPlugin("v0.0.1", sdk.WithStagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](
+					&LogStagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec]{Lines: lines, LineSize: 100},
+				))
+				require.NoError(b, err)
+
+				BenchmarkStage(b, plugin, testPluginConfigYAML, StageBenchmark{
+					StageName:                  LogStageName,
+					TargetApplicationDirectory: "testdata/app",
+					Concurrency:                concurrency,
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,299 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+// ChaosService wraps the piped service to inject the latency and the failures into the calls from the plugin,
+// so that the plugins can be tested under the connectivity problems to piped, e.g.
+//
+//	chaos := sdktest.NewChaosService(service)
+//	chaos.FailFirst(2, codes.Unavailable, "PutStageMetadata")
+//	client := service.NewClient(sdktest.ClientConfig{Service: chaos, ...})
+//
+// The faults are applied to the methods of the given names, e.g. "PutStageMetadata", or all methods when no name is given.
+// The calls are sent to the wrapped service unless they fail.
+type ChaosService struct {
+	pipedservice.PluginServiceClient
+
+	clock clock.Clock
+
+	mu          sync.Mutex
+	rand        *rand.Rand
+	unavailable bool
+	faults      []*chaosFault
+	calls       map[string]int
+	failures    map[string]int
+}
+
+// ChaosOption configures the ChaosService.
+type ChaosOption func(*ChaosService)
+
+// WithChaosClock sets the clock to wait for the injected latency.
+// The real clock is used by default.
+func WithChaosClock(c clock.Clock) ChaosOption {
+	return func(s *ChaosService) {
+		s.clock = clock.OrReal(c)
+	}
+}
+
+// WithChaosSeed sets the seed of the random failures injected by FailRandomly to make them reproducible.
+func WithChaosSeed(seed int64) ChaosOption {
+	return func(s *ChaosService) {
+		s.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// NewChaosService creates a new ChaosService wrapping the given service.
+func NewChaosService(base pipedservice.PluginServiceClient, opts ...ChaosOption) *ChaosService {
+	s := &ChaosService{
+		PluginServiceClient: base,
+		clock:               clock.Real,
+		rand:                rand.New(rand.NewSource(time.Now().UnixNano())),
+		calls:               make(map[string]int),
+		failures:            make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+type chaosFault struct {
+	methods []string
+	latency time.Duration
+	code    codes.Code
+	// The failure is injected to the first remaining calls, every n-th call, or the calls at the rate.
+	remaining int
+	every     int
+	rate      float64
+	// lost means the call reaches the wrapped service but its response is lost.
+	lost  bool
+	calls int
+}
+
+func (f *chaosFault) matches(method string) bool {
+	return len(f.methods) == 0 || slices.Contains(f.methods, method)
+}
+
+// fails decides whether the call fails. It must be called while holding the lock of the service.
+func (f *chaosFault) fails(r *rand.Rand) bool {
+	f.calls++
+	switch {
+	case f.remaining > 0:
+		f.remaining--
+		return true
+	case f.every > 0:
+		return f.calls%f.every == 0
+	case f.rate > 0:
+		return r.Float64() < f.rate
+	default:
+		return false
+	}
+}
+
+func (s *ChaosService) addFault(f *chaosFault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, f)
+}
+
+// AddLatency delays the calls by the given duration.
+// The delayed calls fail with DeadlineExceeded or Canceled when the context is done while waiting.
+func (s *ChaosService) AddLatency(d time.Duration, methods ...string) {
+	s.addFault(&chaosFault{methods: methods, latency: d})
+}
+
+// FailFirst fails the first n calls with the given code.
+func (s *ChaosService) FailFirst(n int, code codes.Code, methods ...string) {
+	s.addFault(&chaosFault{methods: methods, code: code, remaining: n})
+}
+
+// FailEvery fails every n-th call with the given code.
+func (s *ChaosService) FailEvery(n int, code codes.Code, methods ...string) {
+	s.addFault(&chaosFault{methods: methods, code: code, every: n})
+}
+
+// FailRandomly fails the calls at the given rate between 0 and 1 with the given code.
+// Use WithChaosSeed to make the failures reproducible.
+func (s *ChaosService) FailRandomly(rate float64, code codes.Code, methods ...string) {
+	s.addFault(&chaosFault{methods: methods, code: code, rate: rate})
+}
+
+// LoseResponses makes the first n calls reach the wrapped service but fail with Unavailable,
+// as if the connection is lost before the response arrives.
+// It's useful to verify the plugin handles the retry of the call already applied by piped.
+func (s *ChaosService) LoseResponses(n int, methods ...string) {
+	s.addFault(&chaosFault{methods: methods, code: codes.Unavailable, remaining: n, lost: true})
+}
+
+// SetUnavailable makes all calls fail with Unavailable until it's set to false, as if piped is down.
+func (s *ChaosService) SetUnavailable(unavailable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unavailable = unavailable
+}
+
+// Reset removes all faults and makes the service available.
+// The numbers of the calls and the failures are kept.
+func (s *ChaosService) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+	s.unavailable = false
+}
+
+// Calls returns the number of the calls of the given method, including the failed ones.
+func (s *ChaosService) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// Failures returns the number of the calls of the given method failed by the injected faults.
+func (s *ChaosService) Failures(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures[method]
+}
+
+// inject decides the latency and the failure of the call.
+func (s *ChaosService) inject(method string) (latency time.Duration, code codes.Code, lost bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[method]++
+	code = codes.OK
+	if s.unavailable {
+		code = codes.Unavailable
+	}
+	for _, f := range s.faults {
+		if !f.matches(method) {
+			continue
+		}
+		latency += f.latency
+		if f.code == codes.OK || code != codes.OK {
+			continue
+		}
+		if f.fails(s.rand) {
+			code, lost = f.code, f.lost
+		}
+	}
+	if code != codes.OK {
+		s.failures[method]++
+	}
+	return latency, code, lost
+}
+
+// wait waits for the latency on the clock, and returns the error when the context is done while waiting.
+func (s *ChaosService) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := s.clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case <-t.C():
+		return nil
+	}
+}
+
+// chaosCall calls the method of the wrapped service with the faults injected.
+func chaosCall[Req, Resp any](ctx context.Context, s *ChaosService, method string, call func(context.Context, Req, ...grpc.CallOption) (Resp, error), in Req, opts []grpc.CallOption) (Resp, error) {
+	var zero Resp
+	latency, code, lost := s.inject(method)
+	if err := s.wait(ctx, latency); err != nil {
+		return zero, err
+	}
+	switch {
+	case code == codes.OK:
+		return call(ctx, in, opts...)
+	case lost:
+		if _, err := call(ctx, in, opts...); err != nil {
+			return zero, err
+		}
+		return zero, status.Errorf(code, "sdktest: the response of %s is lost by the chaos service", method)
+	default:
+		return zero, status.Errorf(code, "sdktest: %s is failed by the chaos service", method)
+	}
+}
+
+func (s *ChaosService) InstallTool(ctx context.Context, in *pipedservice.InstallToolRequest, opts ...grpc.CallOption) (*pipedservice.InstallToolResponse, error) {
+	return chaosCall(ctx, s, "InstallTool", s.PluginServiceClient.InstallTool, in, opts)
+}
+
+func (s *ChaosService) ReportStageLogs(ctx context.Context, in *pipedservice.ReportStageLogsRequest, opts ...grpc.CallOption) (*pipedservice.ReportStageLogsResponse, error) {
+	return chaosCall(ctx, s, "ReportStageLogs", s.PluginServiceClient.ReportStageLogs, in, opts)
+}
+
+func (s *ChaosService) ReportStageLogsFromLastCheckpoint(ctx context.Context, in *pipedservice.ReportStageLogsFromLastCheckpointRequest, opts ...grpc.CallOption) (*pipedservice.ReportStageLogsFromLastCheckpointResponse, error) {
+	return chaosCall(ctx, s, "ReportStageLogsFromLastCheckpoint", s.PluginServiceClient.ReportStageLogsFromLastCheckpoint, in, opts)
+}
+
+func (s *ChaosService) GetStageMetadata(ctx context.Context, in *pipedservice.GetStageMetadataRequest, opts ...grpc.CallOption) (*pipedservice.GetStageMetadataResponse, error) {
+	return chaosCall(ctx, s, "GetStageMetadata", s.PluginServiceClient.GetStageMetadata, in, opts)
+}
+
+func (s *ChaosService) PutStageMetadata(ctx context.Context, in *pipedservice.PutStageMetadataRequest, opts ...grpc.CallOption) (*pipedservice.PutStageMetadataResponse, error) {
+	return chaosCall(ctx, s, "PutStageMetadata", s.PluginServiceClient.PutStageMetadata, in, opts)
+}
+
+func (s *ChaosService) PutStageMetadataMulti(ctx context.Context, in *pipedservice.PutStageMetadataMultiRequest, opts ...grpc.CallOption) (*pipedservice.PutStageMetadataMultiResponse, error) {
+	return chaosCall(ctx, s, "PutStageMetadataMulti", s.PluginServiceClient.PutStageMetadataMulti, in, opts)
+}
+
+func (s *ChaosService) GetDeploymentPluginMetadata(ctx context.Context, in *pipedservice.GetDeploymentPluginMetadataRequest, opts ...grpc.CallOption) (*pipedservice.GetDeploymentPluginMetadataResponse, error) {
+	return chaosCall(ctx, s, "GetDeploymentPluginMetadata", s.PluginServiceClient.GetDeploymentPluginMetadata, in, opts)
+}
+
+func (s *ChaosService) PutDeploymentPluginMetadata(ctx context.Context, in *pipedservice.PutDeploymentPluginMetadataRequest, opts ...grpc.CallOption) (*pipedservice.PutDeploymentPluginMetadataResponse, error) {
+	return chaosCall(ctx, s, "PutDeploymentPluginMetadata", s.PluginServiceClient.PutDeploymentPluginMetadata, in, opts)
+}
+
+func (s *ChaosService) PutDeploymentPluginMetadataMulti(ctx context.Context, in *pipedservice.PutDeploymentPluginMetadataMultiRequest, opts ...grpc.CallOption) (*pipedservice.PutDeploymentPluginMetadataMultiResponse, error) {
+	return chaosCall(ctx, s, "PutDeploymentPluginMetadataMulti", s.PluginServiceClient.PutDeploymentPluginMetadataMulti, in, opts)
+}
+
+func (s *ChaosService) GetDeploymentSharedMetadata(ctx context.Context, in *pipedservice.GetDeploymentSharedMetadataRequest, opts ...grpc.CallOption) (*pipedservice.GetDeploymentSharedMetadataResponse, error) {
+	return chaosCall(ctx, s, "GetDeploymentSharedMetadata", s.PluginServiceClient.GetDeploymentSharedMetadata, in, opts)
+}
+
+func (s *ChaosService) ListStageCommands(ctx context.Context, in *pipedservice.ListStageCommandsRequest, opts ...grpc.CallOption) (*pipedservice.ListStageCommandsResponse, error) {
+	return chaosCall(ctx, s, "ListStageCommands", s.PluginServiceClient.ListStageCommands, in, opts)
+}
+
+func (s *ChaosService) GetApplicationSharedObject(ctx context.Context, in *pipedservice.GetApplicationSharedObjectRequest, opts ...grpc.CallOption) (*pipedservice.GetApplicationSharedObjectResponse, error) {
+	return chaosCall(ctx, s, "GetApplicationSharedObject", s.PluginServiceClient.GetApplicationSharedObject, in, opts)
+}
+
+func (s *ChaosService) PutApplicationSharedObject(ctx context.Context, in *pipedservice.PutApplicationSharedObjectRequest, opts ...grpc.CallOption) (*pipedservice.PutApplicationSharedObjectResponse, error) {
+	return chaosCall(ctx, s, "PutApplicationSharedObject", s.PluginServiceClient.PutApplicationSharedObject, in, opts)
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+func TestChaosService_Failures(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service := NewPluginService()
+	chaos := NewChaosService(service)
+	c := service.NewClient(ClientConfig{DeploymentID: "deployment", StageID: "stage", Service: chaos})
+
+	// The first calls fail, and only the given methods are affected.
+	chaos.FailFirst(2, codes.Unavailable, "PutStageMetadata")
+	for range 2 {
+		err := c.PutStageMetadata(ctx, "key", "value")
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	require.NoError(t, c.PutStageMetadata(ctx, "key", "value"))
+	_, _, err := c.GetStageMetadata(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, 3, chaos.Calls("PutStageMetadata"))
+	assert.Equal(t, 2, chaos.Failures("PutStageMetadata"))
+
+	// Every n-th call fails.
+	chaos.Reset()
+	chaos.FailEvery(2, codes.ResourceExhausted)
+	var failures int
+	for range 4 {
+		if _, _, err := c.GetStageMetadata(ctx, "key"); err != nil {
+			assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+			failures++
+		}
+	}
+	assert.Equal(t, 2, failures)
+
+	// All calls fail while piped is unavailable.
+	chaos.Reset()
+	chaos.SetUnavailable(true)
+	_, _, err = c.GetStageMetadata(ctx, "key")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	chaos.SetUnavailable(false)
+	_, _, err = c.GetStageMetadata(ctx, "key")
+	assert.NoError(t, err)
+}
+
+func TestChaosService_FailRandomly(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	failures := func(seed int64) []bool {
+		service := NewPluginService()
+		chaos := NewChaosService(service, WithChaosSeed(seed))
+		chaos.FailRandomly(0.5, codes.Unavailable)
+		c := service.NewClient(ClientConfig{DeploymentID: "deployment", StageID: "stage", Service: chaos})
+
+		var results []bool
+		for range 20 {
+			_, _, err := c.GetStageMetadata(ctx, "key")
+			results = append(results, err != nil)
+		}
+		return results
+	}
+
+	// The failures are reproducible with the same seed.
+	results := failures(1)
+	assert.Equal(t, results, failures(1))
+	assert.Contains(t, results, true)
+	assert.Contains(t, results, false)
+}
+
+func TestChaosService_LoseResponses(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service := NewPluginService()
+	chaos := NewChaosService(service)
+	chaos.LoseResponses(1, "PutStageMetadata")
+	c := service.NewClient(ClientConfig{DeploymentID: "deployment", StageID: "stage", Service: chaos})
+
+	// The call fails, but piped has applied it.
+	err := c.PutStageMetadata(ctx, "key", "value")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, map[string]string{"key": "value"}, service.StageMetadata("deployment", "stage"))
+}
+
+func TestChaosService_Latency(t *testing.T) {
+	t.Parallel()
+
+	service := NewPluginService()
+	clk := clocktest.NewFakeClock(time.Now())
+	chaos := NewChaosService(service, WithChaosClock(clk))
+	chaos.AddLatency(time.Minute, "GetStageMetadata")
+	c := service.NewClient(ClientConfig{DeploymentID: "deployment", StageID: "stage", Service: chaos})
+
+	// The call returns after the latency.
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := c.GetStageMetadata(context.Background(), "key")
+		errCh <- err
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	require.NoError(t, <-errCh)
+
+	// The call fails when the context is done while waiting.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, _, err := c.GetStageMetadata(ctx, "key")
+		errCh <- err
+	}()
+	clk.BlockUntil(1)
+	cancel()
+	assert.Equal(t, codes.Canceled, status.Code(<-errCh))
+}
+
+func TestStageHarness_WithChaos(t *testing.T) {
+	t.Parallel()
+
+	h := NewStageHarness(t, "example", sdk.StagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](testStagePlugin{}), nil)
+	chaos := h.WithChaos()
+	chaos.SetUnavailable(true)
+
+	_, err := h.ExecuteStage(context.Background(), ExecuteStageCase[testDeployTargetConfig]{
+		StageName:                  "TEST_STAGE",
+		TargetApplicationDirectory: "testdata/app",
+	})
+	require.Error(t, err)
+	assert.Equal(t, 1, chaos.Failures("PutStageMetadata"))
+}
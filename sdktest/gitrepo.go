@@ -0,0 +1,165 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/common"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/internal/testhook"
+)
+
+// GitRepo is a temporary git repository to build the deployment sources from its commits.
+// The methods call t.Fatal on failure.
+type GitRepo struct {
+	t   testing.TB
+	dir string
+}
+
+// NewGitRepo creates an empty git repository in a temp dir.
+// It requires the git command.
+func NewGitRepo(t testing.TB) *GitRepo {
+	t.Helper()
+
+	r := &GitRepo{t: t, dir: t.TempDir()}
+	r.git("init", "--quiet", "--initial-branch=main")
+	r.git("config", "user.name", "sdktest")
+	r.git("config", "user.email", "sdktest@example.com")
+	r.git("config", "commit.gpgsign", "false")
+	return r
+}
+
+// Dir returns the directory of the working tree.
+func (r *GitRepo) Dir() string {
+	return r.dir
+}
+
+// WriteFile writes the file at the given path relative to the root of the repository.
+// The parent directories are created as needed.
+func (r *GitRepo) WriteFile(path, content string) {
+	r.t.Helper()
+
+	p := filepath.Join(r.dir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		r.t.Fatalf("failed to create the directory of %s: %s", path, err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		r.t.Fatalf("failed to write %s: %s", path, err)
+	}
+}
+
+// WriteFiles writes the files by their paths relative to the root of the repository.
+func (r *GitRepo) WriteFiles(files map[string]string) {
+	r.t.Helper()
+
+	for path, content := range files {
+		r.WriteFile(path, content)
+	}
+}
+
+// RemoveFile removes the file or the directory at the given path relative to the root of the repository.
+func (r *GitRepo) RemoveFile(path string) {
+	r.t.Helper()
+
+	if err := os.RemoveAll(filepath.Join(r.dir, filepath.FromSlash(path))); err != nil {
+		r.t.Fatalf("failed to remove %s: %s", path, err)
+	}
+}
+
+// Commit commits all the changes in the working tree, and returns the commit hash.
+// The empty commit is allowed.
+func (r *GitRepo) Commit(message string) string {
+	r.t.Helper()
+
+	r.git("add", "--all")
+	r.git("commit", "--quiet", "--allow-empty", "--message", message)
+	return r.git("rev-parse", "HEAD")
+}
+
+// Checkout checks out the given commit into a new temp dir, and returns the directory.
+// The working tree of the repository is not changed, so the multiple commits can be checked out at once.
+func (r *GitRepo) Checkout(commit string) string {
+	r.t.Helper()
+
+	dir := filepath.Join(r.t.TempDir(), "worktree")
+	r.git("worktree", "add", "--quiet", "--detach", dir, commit)
+	return dir
+}
+
+// DeploymentSource returns the deployment source of the application in the given directory at the given commit
+// as piped gives to the plugin.
+// The application directory is relative to the root of the repository,
+// and it must contain the application config file named app.pipecd.yaml.
+func (r *GitRepo) DeploymentSource(commit, appDir string) *common.DeploymentSource {
+	r.t.Helper()
+
+	root := r.Checkout(commit)
+	dir := filepath.Join(root, filepath.FromSlash(appDir))
+	cfg, err := os.ReadFile(filepath.Join(dir, model.DefaultApplicationConfigFilename))
+	if err != nil {
+		r.t.Fatalf("failed to read the application config at %s: %s", commit, err)
+	}
+	return &common.DeploymentSource{
+		ApplicationDirectory:      dir,
+		CommitHash:                commit,
+		ApplicationConfig:         cfg,
+		ApplicationConfigFilename: model.DefaultApplicationConfigFilename,
+	}
+}
+
+// DeploymentSources returns the running and the target deployment sources of the application
+// in the given directory for the given plugin.
+// The running commit can be empty to build the sources for the first deployment.
+func DeploymentSources[Spec any](r *GitRepo, pluginName, appDir, runningCommit, targetCommit string) (running, target sdk.DeploymentSource[Spec]) {
+	r.t.Helper()
+
+	if runningCommit != "" {
+		running = deploymentSource[Spec](r, pluginName, runningCommit, appDir)
+	}
+	target = deploymentSource[Spec](r, pluginName, targetCommit, appDir)
+	return running, target
+}
+
+func deploymentSource[Spec any](r *GitRepo, pluginName, commit, appDir string) sdk.DeploymentSource[Spec] {
+	r.t.Helper()
+
+	var ds sdk.DeploymentSource[Spec]
+	if err := testhook.NewDeploymentSource(&ds, pluginName, r.DeploymentSource(commit, appDir)); err != nil {
+		r.t.Fatalf("failed to build the deployment source at %s: %s", commit, err)
+	}
+	return ds
+}
+
+func (r *GitRepo) git(args ...string) string {
+	r.t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		r.t.Fatalf("failed to run git %s: %s: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitRepo_DeploymentSources(t *testing.T) {
+	t.Parallel()
+
+	r := NewGitRepo(t)
+	r.WriteFiles(map[string]string{
+		"app/app.pipecd.yaml": "apiVersion: pipecd.dev/v1beta1\nkind: Application\nspec:\n  plugins:\n    example:\n      replicas: 1\n",
+		"app/manifest.yaml":   "v1",
+	})
+	first := r.Commit("first")
+
+	r.WriteFile("app/app.pipecd.yaml", "apiVersion: pipecd.dev/v1beta1\nkind: Application\nspec:\n  plugins:\n    example:\n      replicas: 3\n")
+	r.RemoveFile("app/manifest.yaml")
+	r.WriteFile("app/new.yaml", "v2")
+	second := r.Commit("second")
+	assert.NotEqual(t, first, second)
+
+	running, target := DeploymentSources[testApplicationSpec](r, "example", "app", first, second)
+	assert.Equal(t, first, running.CommitHash)
+	assert.Equal(t, 1, running.ApplicationConfig.Spec.Replicas)
+	assert.FileExists(t, filepath.Join(running.ApplicationDirectory, "manifest.yaml"))
+	assert.NoFileExists(t, filepath.Join(running.ApplicationDirectory, "new.yaml"))
+
+	assert.Equal(t, second, target.CommitHash)
+	assert.Equal(t, 3, target.ApplicationConfig.Spec.Replicas)
+	assert.Equal(t, "app.pipecd.yaml", target.ApplicationConfigFilename)
+	data, err := os.ReadFile(filepath.Join(target.ApplicationDirectory, "new.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+	assert.NoFileExists(t, filepath.Join(target.ApplicationDirectory, "manifest.yaml"))
+
+	// The first deployment has no running deployment source.
+	running, target = DeploymentSources[testApplicationSpec](r, "example", "app", "", second)
+	assert.Empty(t, running.CommitHash)
+	assert.Nil(t, running.ApplicationConfig)
+	assert.Equal(t, second, target.CommitHash)
+}
@@ -0,0 +1,229 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+// UpdateGoldenEnv is the environment variable to update the golden files instead of comparing with them, e.g.
+//
+//	SDKTEST_UPDATE_GOLDEN=true go test ./...
+//
+// It's an environment variable rather than a flag, so that it doesn't conflict with the flags defined by the tests.
+const UpdateGoldenEnv = "SDKTEST_UPDATE_GOLDEN"
+
+// updateGolden returns true if the golden files should be updated.
+func updateGolden() bool {
+	update, _ := strconv.ParseBool(os.Getenv(UpdateGoldenEnv))
+	return update
+}
+
+// AssertGolden compares the actual output with the content of the golden file.
+// When UpdateGoldenEnv is set to true, the golden file is overwritten by the actual output instead.
+// The difference is reported line by line on failure.
+func AssertGolden(t testing.TB, filename string, actual []byte) {
+	t.Helper()
+
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatalf("failed to create the directory of the golden file: %s", err)
+		}
+		if err := os.WriteFile(filename, actual, 0o644); err != nil {
+			t.Fatalf("failed to update the golden file: %s", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("golden file %s does not exist, run the test with %s=true to create it", filename, UpdateGoldenEnv)
+	}
+	if err != nil {
+		t.Fatalf("failed to read the golden file: %s", err)
+	}
+	assert.Equal(t, string(expected), string(actual), "the output differs from the golden file %s, run the test with %s=true to update it", filename, UpdateGoldenEnv)
+}
+
+// GoldenNormalizer rewrites the text written into the golden files,
+// e.g. to replace the values changing in every run with the placeholders.
+type GoldenNormalizer func(string) string
+
+// timestampRegexp matches the RFC 3339 timestamps and the ones separated by a space instead of "T".
+var timestampRegexp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?`)
+
+// NormalizeTimestamps replaces the timestamps in the text with "<timestamp>".
+func NormalizeTimestamps(s string) string {
+	return timestampRegexp.ReplaceAllString(s, "<timestamp>")
+}
+
+// NormalizeRegexp returns the normalizer replacing the matches of the pattern with the replacement.
+// The replacement can refer to the submatches in the same way as regexp.Regexp.ReplaceAllString.
+func NormalizeRegexp(pattern *regexp.Regexp, replacement string) GoldenNormalizer {
+	return func(s string) string {
+		return pattern.ReplaceAllString(s, replacement)
+	}
+}
+
+// AssertPlanPreviewGolden compares the plan preview response with the golden file in the form of MarshalPlanPreview.
+func AssertPlanPreviewGolden(t testing.TB, filename string, resp *sdk.GetPlanPreviewResponse, normalizers ...GoldenNormalizer) {
+	t.Helper()
+
+	data, err := MarshalPlanPreview(resp, normalizers...)
+	if err != nil {
+		t.Fatalf("failed to marshal the plan preview: %s", err)
+	}
+	AssertGolden(t, filename, data)
+}
+
+// AssertLivestateGolden compares the livestate response with the golden file in the form of MarshalLivestate.
+func AssertLivestateGolden(t testing.TB, filename string, resp *sdk.GetLivestateResponse) {
+	t.Helper()
+
+	data, err := MarshalLivestate(resp)
+	if err != nil {
+		t.Fatalf("failed to marshal the livestate: %s", err)
+	}
+	AssertGolden(t, filename, data)
+}
+
+type goldenPlanPreviewResult struct {
+	DeployTarget string `yaml:"deployTarget"`
+	Summary      string `yaml:"summary"`
+	NoChange     bool   `yaml:"noChange"`
+	DiffLanguage string `yaml:"diffLanguage,omitempty"`
+	Details      string `yaml:"details,omitempty"`
+}
+
+// MarshalPlanPreview serializes the plan preview response into YAML deterministically.
+// The results are sorted by the deploy target, and the details are written as the multi-line text to be readable.
+// The summaries and the details are rewritten by the normalizers in order, e.g. NormalizeTimestamps.
+// The results of the same deploy target are sorted by the normalized summary and details,
+// so that the order of the results doesn't change the output.
+func MarshalPlanPreview(resp *sdk.GetPlanPreviewResponse, normalizers ...GoldenNormalizer) ([]byte, error) {
+	normalize := func(s string) string {
+		for _, n := range normalizers {
+			s = n(s)
+		}
+		return s
+	}
+	results := make([]goldenPlanPreviewResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		results = append(results, goldenPlanPreviewResult{
+			DeployTarget: r.DeployTarget,
+			Summary:      normalize(r.Summary),
+			NoChange:     r.NoChange,
+			DiffLanguage: r.DiffLanguage,
+			Details:      normalize(string(r.Details)),
+		})
+	}
+	slices.SortStableFunc(results, func(a, b goldenPlanPreviewResult) int {
+		return cmp.Or(
+			cmp.Compare(a.DeployTarget, b.DeployTarget),
+			cmp.Compare(a.Summary, b.Summary),
+			cmp.Compare(a.Details, b.Details),
+		)
+	})
+	return marshalGolden(map[string]any{"results": results})
+}
+
+type goldenLivestate struct {
+	SyncState goldenSyncState  `yaml:"syncState"`
+	Resources []goldenResource `yaml:"resources"`
+}
+
+type goldenSyncState struct {
+	Status      string `yaml:"status"`
+	ShortReason string `yaml:"shortReason,omitempty"`
+	Reason      string `yaml:"reason,omitempty"`
+}
+
+type goldenResource struct {
+	ID                string            `yaml:"id"`
+	ParentIDs         []string          `yaml:"parentIDs,omitempty"`
+	Name              string            `yaml:"name"`
+	ResourceType      string            `yaml:"resourceType"`
+	ResourceMetadata  map[string]string `yaml:"resourceMetadata,omitempty"`
+	HealthStatus      string            `yaml:"healthStatus"`
+	HealthDescription string            `yaml:"healthDescription,omitempty"`
+	DeployTarget      string            `yaml:"deployTarget"`
+	CreatedAt         string            `yaml:"createdAt,omitempty"`
+}
+
+// MarshalLivestate serializes the livestate response into YAML deterministically.
+// The resources are sorted by the deploy target and the ID, the parent IDs are sorted,
+// and the creation time is written in RFC 3339 in UTC.
+func MarshalLivestate(resp *sdk.GetLivestateResponse) ([]byte, error) {
+	resources := make([]goldenResource, 0, len(resp.LiveState.Resources))
+	for _, r := range resp.LiveState.Resources {
+		var createdAt string
+		if !r.CreatedAt.IsZero() {
+			createdAt = r.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		parentIDs := slices.Clone(r.ParentIDs)
+		slices.Sort(parentIDs)
+		resources = append(resources, goldenResource{
+			ID:                r.ID,
+			ParentIDs:         parentIDs,
+			Name:              r.Name,
+			ResourceType:      r.ResourceType,
+			ResourceMetadata:  r.ResourceMetadata,
+			HealthStatus:      r.HealthStatus.String(),
+			HealthDescription: r.HealthDescription,
+			DeployTarget:      r.DeployTarget,
+			CreatedAt:         createdAt,
+		})
+	}
+	slices.SortStableFunc(resources, func(a, b goldenResource) int {
+		return cmp.Or(cmp.Compare(a.DeployTarget, b.DeployTarget), cmp.Compare(a.ID, b.ID))
+	})
+
+	return marshalGolden(goldenLivestate{
+		SyncState: goldenSyncState{
+			Status:      resp.SyncState.Status.String(),
+			ShortReason: resp.SyncState.ShortReason,
+			Reason:      resp.SyncState.Reason,
+		},
+		Resources: resources,
+	})
+}
+
+func marshalGolden(v any) ([]byte, error) {
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to marshal into YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal into YAML: %w", err)
+	}
+	return []byte(b.String()), nil
+}
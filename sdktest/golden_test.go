@@ -0,0 +1,160 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+// fakeT is a testing.TB recording the failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(string, ...any) {
+	t.failed = true
+}
+
+func TestAssertPlanPreviewGolden(t *testing.T) {
+	t.Parallel()
+
+	resp := &sdk.GetPlanPreviewResponse{
+		Results: []sdk.PlanPreviewResult{
+			{
+				DeployTarget: "dt2",
+				Summary:      "No changes were detected",
+				NoChange:     true,
+			},
+			{
+				DeployTarget: "dt1",
+				Summary:      "1 added, 1 changed",
+				Details:      []byte("+ added\n- removed\n+ changed\n"),
+				DiffLanguage: "diff",
+			},
+		},
+	}
+	AssertPlanPreviewGolden(t, "testdata/golden/planpreview.yaml", resp)
+}
+
+func TestMarshalPlanPreview_Normalizers(t *testing.T) {
+	t.Parallel()
+
+	newResponse := func(at time.Time, id string, reversed bool) *sdk.GetPlanPreviewResponse {
+		results := []sdk.PlanPreviewResult{
+			{DeployTarget: "dt1", Summary: "1 changed", Details: []byte("~ configmap " + id + "\n")},
+			{DeployTarget: "dt1", Summary: "1 added at " + at.Format(time.RFC3339), Details: []byte("+ updatedAt: " + at.Format(time.RFC3339Nano) + "\n")},
+		}
+		if reversed {
+			results[0], results[1] = results[1], results[0]
+		}
+		return &sdk.GetPlanPreviewResponse{Results: results}
+	}
+	normalizers := []GoldenNormalizer{
+		NormalizeTimestamps,
+		NormalizeRegexp(regexp.MustCompile(`configmap-[0-9a-f]+`), "configmap-<hash>"),
+	}
+
+	data1, err := MarshalPlanPreview(newResponse(time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC), "configmap-abc123", false), normalizers...)
+	require.NoError(t, err)
+	data2, err := MarshalPlanPreview(newResponse(time.Date(2026, 7, 8, 9, 10, 11, 0, time.FixedZone("JST", 9*60*60)), "configmap-def456", true), normalizers...)
+	require.NoError(t, err)
+	assert.Equal(t, string(data1), string(data2))
+	assert.Equal(t, `results:
+  - deployTarget: dt1
+    summary: 1 added at <timestamp>
+    noChange: false
+    details: |
+      + updatedAt: <timestamp>
+  - deployTarget: dt1
+    summary: 1 changed
+    noChange: false
+    details: |
+      ~ configmap configmap-<hash>
+`, string(data1))
+}
+
+func TestAssertLivestateGolden(t *testing.T) {
+	t.Parallel()
+
+	resp := &sdk.GetLivestateResponse{
+		LiveState: sdk.ApplicationLiveState{
+			Resources: []sdk.ResourceState{
+				{
+					ID:           "b",
+					ParentIDs:    []string{"y", "x"},
+					Name:         "child",
+					ResourceType: "Pod",
+					HealthStatus: sdk.ResourceHealthStateUnhealthy,
+					DeployTarget: "dt1",
+					CreatedAt:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("JST", 9*60*60)),
+				},
+				{
+					ID:               "a",
+					Name:             "parent",
+					ResourceType:     "Deployment",
+					ResourceMetadata: map[string]string{"namespace": "default", "app": "example"},
+					HealthStatus:     sdk.ResourceHealthStateHealthy,
+					DeployTarget:     "dt1",
+				},
+			},
+		},
+		SyncState: sdk.ApplicationSyncState{
+			Status:      sdk.ApplicationSyncStateOutOfSync,
+			ShortReason: "1 resource is out of sync",
+		},
+	}
+	AssertLivestateGolden(t, "testdata/golden/livestate.yaml", resp)
+}
+
+func TestAssertGolden_Mismatch(t *testing.T) {
+	t.Parallel()
+
+	if updateGolden() {
+		t.Skipf("the golden file is always updated with %s", UpdateGoldenEnv)
+	}
+
+	filename := filepath.Join(t.TempDir(), "golden.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte("results: []\n"), 0o644))
+
+	ft := &fakeT{TB: t}
+	AssertGolden(ft, filename, []byte("results: []\n"))
+	assert.False(t, ft.failed)
+
+	AssertGolden(ft, filename, []byte("results:\n  - deployTarget: dt1\n"))
+	assert.True(t, ft.failed)
+}
+
+func TestAssertGolden_Update(t *testing.T) {
+	t.Setenv(UpdateGoldenEnv, "true")
+
+	filename := filepath.Join(t.TempDir(), "golden", "golden.yaml")
+	AssertGolden(t, filename, []byte("results: []\n"))
+
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "results: []\n", string(data))
+}
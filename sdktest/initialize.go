@@ -0,0 +1,101 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap/zaptest"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/internal/testhook"
+)
+
+// NewInitializeInput creates the input of Initializer as the SDK does at start.
+// The client calls the given service without the application, the deployment, and the stage,
+// RawConfig is the given config encoded in JSON, and DeployTargetRegistry notifies no plugin of the changes.
+func NewInitializeInput[Config, DeployTargetConfig any](t testing.TB, service *PluginService, pluginName string, config *Config, deployTargets ...*sdk.DeployTarget[DeployTargetConfig]) *sdk.InitializeInput[Config, DeployTargetConfig] {
+	t.Helper()
+
+	rawConfig, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("failed to marshal the plugin config: %s", err)
+	}
+	dts := make(map[string]*sdk.DeployTarget[DeployTargetConfig], len(deployTargets))
+	for _, dt := range deployTargets {
+		dts[dt.Name] = dt
+	}
+	registry := &sdk.DeployTargetRegistry[DeployTargetConfig]{}
+	testhook.InitDeployTargetRegistry(registry, dts)
+	return &sdk.InitializeInput[Config, DeployTargetConfig]{
+		Config:               config,
+		RawConfig:            rawConfig,
+		DeployTargets:        dts,
+		DeployTargetRegistry: registry,
+		Client:               service.NewClient(ClientConfig{PluginName: pluginName}),
+		Logger:               zaptest.NewLogger(t).Named("plugin-initializer"),
+	}
+}
+
+// InitializePlugin calls the initializers of the plugin in the same order as the SDK does at start,
+// the ones added by sdk.WithInitializer first, then every registered plugin implementing sdk.Initializer once per its role.
+// Each initializer is given the copy of the input whose Role is set to its role.
+// It stops at the first error, which is wrapped with the role as the start command does.
+func InitializePlugin[Config, DeployTargetConfig, ApplicationConfigSpec any](ctx context.Context, plugin *sdk.Plugin[Config, DeployTargetConfig, ApplicationConfigSpec], input *sdk.InitializeInput[Config, DeployTargetConfig]) error {
+	for _, initializer := range testhook.Initializers(plugin).([]sdk.RoleInitializer[Config, DeployTargetConfig]) {
+		if err := initializer.Initialize(ctx, input); err != nil {
+			return fmt.Errorf("failed to initialize %s: %w", initializer.Role, err)
+		}
+	}
+	return nil
+}
+
+// InitializeConcurrently calls the initializers of the plugin from n goroutines per role at the same time with the same input.
+// The SDK calls them one by one, but a plugin registered for multiple roles, e.g. stage, livestate, and plan-preview,
+// is initialized once per role, so the initialization shared among the roles must be done once, e.g. for one of the roles.
+// Run the test with -race to detect the data races in Initialize, and check that the shared resources are set up only once.
+// It returns all the errors joined, each wrapped with the role.
+func InitializeConcurrently[Config, DeployTargetConfig, ApplicationConfigSpec any](ctx context.Context, plugin *sdk.Plugin[Config, DeployTargetConfig, ApplicationConfigSpec], input *sdk.InitializeInput[Config, DeployTargetConfig], n int) error {
+	var (
+		initializers = testhook.Initializers(plugin).([]sdk.RoleInitializer[Config, DeployTargetConfig])
+		start        = make(chan struct{})
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		errs         []error
+	)
+	for _, initializer := range initializers {
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Wait for all the goroutines to start to make the calls overlap as much as possible.
+				<-start
+				if err := initializer.Initialize(ctx, input); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("failed to initialize %s: %w", initializer.Role, err))
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	close(start)
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+// testMultiRolePlugin is registered as the stage, livestate, and plan-preview plugin.
+type testMultiRolePlugin struct {
+	testStagePlugin
+
+	once   sync.Once
+	calls  atomic.Int32
+	inits  atomic.Int32
+	err    error
+	prefix string
+
+	mu    sync.Mutex
+	roles []string
+}
+
+func (p *testMultiRolePlugin) Initialize(_ context.Context, input *sdk.InitializeInput[testPluginConfig, testDeployTargetConfig]) error {
+	p.calls.Add(1)
+	p.mu.Lock()
+	p.roles = append(p.roles, input.Role)
+	p.mu.Unlock()
+	p.once.Do(func() {
+		p.inits.Add(1)
+		p.prefix = input.Config.Prefix
+	})
+	return p.err
+}
+
+func (p *testMultiRolePlugin) GetLivestate(context.Context, *testPluginConfig, []*sdk.DeployTarget[testDeployTargetConfig], *sdk.GetLivestateInput[testApplicationSpec]) (*sdk.GetLivestateResponse, error) {
+	return &sdk.GetLivestateResponse{}, nil
+}
+
+func (p *testMultiRolePlugin) GetPlanPreview(context.Context, *testPluginConfig, []*sdk.DeployTarget[testDeployTargetConfig], *sdk.GetPlanPreviewInput[testApplicationSpec]) (*sdk.GetPlanPreviewResponse, error) {
+	return &sdk.GetPlanPreviewResponse{}, nil
+}
+
+func newTestMultiRolePlugin(t *testing.T, p *testMultiRolePlugin) *sdk.Plugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec] {
+	t.Helper()
+
+	plugin, err := sdk.NewPlugin("v0.0.1",
+		sdk.WithStagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](p),
+		sdk.WithLivestatePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](p),
+		sdk.WithPlanPreviewPlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](p),
+	)
+	require.NoError(t, err)
+	return plugin
+}
+
+func TestInitializePlugin(t *testing.T) {
+	t.Parallel()
+
+	p := &testMultiRolePlugin{}
+	plugin := newTestMultiRolePlugin(t, p)
+	input := NewInitializeInput(t, NewPluginService(), "example", &testPluginConfig{Prefix: "[test]"},
+		&sdk.DeployTarget[testDeployTargetConfig]{Name: "dt1", Config: testDeployTargetConfig{Region: "us-east-1"}},
+	)
+	assert.JSONEq(t, `{"prefix":"[test]"}`, string(input.RawConfig))
+	assert.Contains(t, input.DeployTargets, "dt1")
+
+	require.NoError(t, InitializePlugin(context.Background(), plugin, input))
+	assert.Equal(t, int32(3), p.calls.Load())
+	assert.Equal(t, int32(1), p.inits.Load())
+	assert.Equal(t, "[test]", p.prefix)
+	// Each role is given the copy of the input with the role.
+	assert.Equal(t, []string{sdk.InitializeRoleStage, sdk.InitializeRoleLivestate, sdk.InitializeRolePlanPreview}, p.roles)
+	assert.Empty(t, input.Role)
+
+	// It stops at the first error with the role.
+	p = &testMultiRolePlugin{err: errors.New("boom")}
+	err := InitializePlugin(context.Background(), newTestMultiRolePlugin(t, p), input)
+	assert.EqualError(t, err, "failed to initialize stage plugin: boom")
+	assert.Equal(t, int32(1), p.calls.Load())
+}
+
+func TestInitializeConcurrently(t *testing.T) {
+	t.Parallel()
+
+	p := &testMultiRolePlugin{}
+	plugin := newTestMultiRolePlugin(t, p)
+	input := NewInitializeInput[testPluginConfig, testDeployTargetConfig](t, NewPluginService(), "example", &testPluginConfig{Prefix: "[test]"})
+
+	require.NoError(t, InitializeConcurrently(context.Background(), plugin, input, 10))
+	assert.Equal(t, int32(30), p.calls.Load())
+	assert.Equal(t, int32(1), p.inits.Load())
+	assert.Equal(t, "[test]", p.prefix)
+
+	// All the errors are returned with the roles.
+	p = &testMultiRolePlugin{err: errors.New("boom")}
+	err := InitializeConcurrently(context.Background(), newTestMultiRolePlugin(t, p), input, 2)
+	require.Error(t, err)
+	for _, role := range []string{"stage plugin", "livestate plugin", "plan-preview plugin"} {
+		assert.Contains(t, err.Error(), "failed to initialize "+role+": boom")
+	}
+	assert.Equal(t, int32(6), p.calls.Load())
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/internal/testhook"
+)
+
+const (
+	// DefaultLivestateTimeout is the timeout of the call to get the live state used when LivestateCase.Timeout is not given.
+	DefaultLivestateTimeout = 30 * time.Second
+	// DefaultApplicationName is the name of the application used when LivestateCase.ApplicationName is not given.
+	DefaultApplicationName = "application-name"
+	// DefaultPipedID is the ID of the piped calling the plugin used when LivestateCase.PipedID is not given.
+	DefaultPipedID = "piped-id"
+)
+
+// LivestateDriver gets the live state from the livestate plugin in the same way as piped does,
+// so that the plugin can be tested end-to-end with the fake deploy targets without running piped.
+type LivestateDriver[Config, DeployTargetConfig, ApplicationConfigSpec any] struct {
+	// Service is the piped service used by the plugin.
+	// Use it to prepare the metadata, the application shared objects, and the tools.
+	Service *PluginService
+
+	t             testing.TB
+	pluginName    string
+	plugin        sdk.LivestatePlugin[Config, DeployTargetConfig, ApplicationConfigSpec]
+	config        *Config
+	deployTargets []*sdk.DeployTarget[DeployTargetConfig]
+}
+
+// NewLivestateDriver creates a new LivestateDriver for the plugin of the given name and config.
+// The deploy targets are the ones in the piped plugin config, and the ones named in LivestateCase.DeployTargets are given to the plugin.
+func NewLivestateDriver[Config, DeployTargetConfig, ApplicationConfigSpec any](t testing.TB, pluginName string, plugin sdk.LivestatePlugin[Config, DeployTargetConfig, ApplicationConfigSpec], config *Config, deployTargets ...*sdk.DeployTarget[DeployTargetConfig]) *LivestateDriver[Config, DeployTargetConfig, ApplicationConfigSpec] {
+	if config == nil {
+		config = new(Config)
+	}
+	return &LivestateDriver[Config, DeployTargetConfig, ApplicationConfigSpec]{
+		Service:       NewPluginService(),
+		t:             t,
+		pluginName:    pluginName,
+		plugin:        plugin,
+		config:        config,
+		deployTargets: deployTargets,
+	}
+}
+
+// LivestateCase is the application to get the live state by LivestateDriver.
+type LivestateCase struct {
+	// ApplicationDirectory is the directory of the deployed application.
+	// It must contain the application config file.
+	ApplicationDirectory string
+	// ApplicationConfigFilename is the filename of the application config in the directory.
+	// The default is app.pipecd.yaml.
+	ApplicationConfigFilename string
+	// ApplicationID is the ID of the application. The default is DefaultApplicationID.
+	ApplicationID string
+	// ApplicationName is the name of the application. The default is DefaultApplicationName.
+	ApplicationName string
+	// PipedID is the ID of the piped. The default is DefaultPipedID.
+	PipedID string
+	// DeployTargets are the names of the deploy targets of the application.
+	// piped sends only the deploy targets of the application for the plugin,
+	// and the ones not given to NewLivestateDriver fail as the plugin server does.
+	DeployTargets []string
+	// Timeout is the timeout of the call. The default is DefaultLivestateTimeout.
+	Timeout time.Duration
+}
+
+// LivestateResult is the live state got by LivestateDriver.
+type LivestateResult struct {
+	// Request is the request sent to the plugin.
+	Request *livestate.GetLivestateRequest
+	// Proto is the response as piped receives.
+	Proto *livestate.GetLivestateResponse
+	// Response is the response decoded back from Proto.
+	Response *sdk.GetLivestateResponse
+	// HealthStatus is the health status of the application rolled up from the resources.
+	HealthStatus model.ApplicationLiveState_Status
+}
+
+// GetLivestate gets the live state of the application of the given case.
+// The call is canceled after the timeout, and the error is a gRPC status error as piped receives.
+func (d *LivestateDriver[Config, DeployTargetConfig, ApplicationConfigSpec]) GetLivestate(ctx context.Context, c LivestateCase) (*LivestateResult, error) {
+	d.t.Helper()
+
+	request, err := d.newGetLivestateRequest(c)
+	if err != nil {
+		d.t.Fatalf("failed to prepare the request to get the live state: %s", err)
+	}
+
+	// Filter the deploy targets of the piped plugin config as the plugin server does.
+	deployTargets := make([]*sdk.DeployTarget[DeployTargetConfig], 0, len(c.DeployTargets))
+	for _, name := range c.DeployTargets {
+		i := slices.IndexFunc(d.deployTargets, func(dt *sdk.DeployTarget[DeployTargetConfig]) bool { return dt.Name == name })
+		if i < 0 {
+			return nil, status.Errorf(codes.Internal, "the deploy target %s is not found in the piped plugin config", name)
+		}
+		deployTargets = append(deployTargets, d.deployTargets[i])
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultLivestateTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	client := d.Service.NewClient(ClientConfig{PluginName: d.pluginName, ApplicationID: request.GetApplicationId()})
+	pluginType := (*sdk.Plugin[Config, DeployTargetConfig, ApplicationConfigSpec])(nil)
+	resp, err := testhook.GetLivestate(ctx, pluginType, d.pluginName, d.plugin, d.config, deployTargets, client, request, zaptest.NewLogger(d.t))
+	if ctx.Err() != nil {
+		// The gRPC server returns the error of the context when the deadline of piped exceeded.
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &LivestateResult{
+		Request:      request,
+		Proto:        resp,
+		Response:     sdk.LivestateResponseFromProto(resp),
+		HealthStatus: resp.GetApplicationLiveState().GetHealthStatus(),
+	}, nil
+}
+
+// GetLivestates gets the live state of the applications of the given cases, chunkSize of them at the same time,
+// as piped checks the applications of the plugin concurrently.
+// The results and the errors are in the order of the cases.
+func (d *LivestateDriver[Config, DeployTargetConfig, ApplicationConfigSpec]) GetLivestates(ctx context.Context, cases []LivestateCase, chunkSize int) ([]*LivestateResult, []error) {
+	d.t.Helper()
+
+	if chunkSize <= 0 {
+		chunkSize = 1
+	}
+	results := make([]*LivestateResult, len(cases))
+	errs := make([]error, len(cases))
+	for start := 0; start < len(cases); start += chunkSize {
+		var wg sync.WaitGroup
+		for i := start; i < min(start+chunkSize, len(cases)); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], errs[i] = d.GetLivestate(ctx, cases[i])
+			}()
+		}
+		wg.Wait()
+	}
+	return results, errs
+}
+
+func (d *LivestateDriver[Config, DeployTargetConfig, ApplicationConfigSpec]) newGetLivestateRequest(c LivestateCase) (*livestate.GetLivestateRequest, error) {
+	filename := c.ApplicationConfigFilename
+	if filename == "" {
+		filename = model.DefaultApplicationConfigFilename
+	}
+	source, err := newDeploymentSource(c.ApplicationDirectory, filename)
+	if err != nil {
+		return nil, err
+	}
+	return &livestate.GetLivestateRequest{
+		PipedId:         cmp.Or(c.PipedID, DefaultPipedID),
+		ApplicationId:   cmp.Or(c.ApplicationID, DefaultApplicationID),
+		ApplicationName: cmp.Or(c.ApplicationName, DefaultApplicationName),
+		DeploySource:    source,
+		DeployTargets:   c.DeployTargets,
+	}, nil
+}
+
+// AssertLivestate asserts the shape of the live state as piped expects:
+//   - every resource has the unique ID, and its parents are in the live state
+//   - every resource is on one of the requested deploy targets
+//   - the health status of the application is rolled up from the resources:
+//     UNKNOWN if any resource is unknown, OTHER if any resource is unhealthy, otherwise HEALTHY
+func AssertLivestate(t testing.TB, result *LivestateResult) bool {
+	t.Helper()
+
+	if result == nil {
+		t.Errorf("the result is nil")
+		return false
+	}
+
+	ok := true
+	resources := result.Proto.GetApplicationLiveState().GetResources()
+	ids := make(map[string]bool, len(resources))
+	for _, rs := range resources {
+		if rs.GetId() == "" {
+			t.Errorf("the resource %q has no ID", rs.GetName())
+			ok = false
+			continue
+		}
+		if ids[rs.GetId()] {
+			t.Errorf("the resource ID %q is duplicated", rs.GetId())
+			ok = false
+		}
+		ids[rs.GetId()] = true
+	}
+
+	var unknown, unhealthy bool
+	for _, rs := range resources {
+		for _, parent := range rs.GetParentIds() {
+			if !ids[parent] {
+				t.Errorf("the parent %q of the resource %q is not in the live state", parent, rs.GetId())
+				ok = false
+			}
+		}
+		if !slices.Contains(result.Request.GetDeployTargets(), rs.GetDeployTarget()) {
+			t.Errorf("the resource %q is on the deploy target %q not in the request %v", rs.GetId(), rs.GetDeployTarget(), result.Request.GetDeployTargets())
+			ok = false
+		}
+		switch rs.GetHealthStatus() {
+		case model.ResourceState_UNKNOWN:
+			unknown = true
+		case model.ResourceState_UNHEALTHY:
+			unhealthy = true
+		}
+	}
+
+	expected := model.ApplicationLiveState_HEALTHY
+	switch {
+	case unknown:
+		expected = model.ApplicationLiveState_UNKNOWN
+	case unhealthy:
+		expected = model.ApplicationLiveState_OTHER
+	}
+	if result.HealthStatus != expected {
+		t.Errorf("the health status of the application is %s, but %s is expected from the resources", result.HealthStatus, expected)
+		ok = false
+	}
+	return ok
+}
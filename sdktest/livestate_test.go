@@ -0,0 +1,195 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+// testLivestatePlugin returns a deployment and its pods on each deploy target.
+type testLivestatePlugin struct {
+	health sdk.ResourceHealthStatus
+	delay  time.Duration
+}
+
+func (p testLivestatePlugin) GetLivestate(ctx context.Context, _ *testPluginConfig, dts []*sdk.DeployTarget[testDeployTargetConfig], input *sdk.GetLivestateInput[testApplicationSpec]) (*sdk.GetLivestateResponse, error) {
+	if p.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(p.delay):
+		}
+	}
+	var resources []sdk.ResourceState
+	for _, dt := range dts {
+		id := dt.Name + "/deployment"
+		resources = append(resources, sdk.ResourceState{ID: id, Name: "deployment", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: dt.Name})
+		for i := range input.Request.DeploymentSource.ApplicationConfig.Spec.Replicas {
+			resources = append(resources, sdk.ResourceState{
+				ID:           fmt.Sprintf("%s/pod-%d", id, i),
+				ParentIDs:    []string{id},
+				Name:         "pod",
+				HealthStatus: p.health,
+				DeployTarget: dt.Name,
+			})
+		}
+	}
+	return &sdk.GetLivestateResponse{LiveState: sdk.ApplicationLiveState{Resources: resources}}, nil
+}
+
+var testLivestateDeployTargets = []*sdk.DeployTarget[testDeployTargetConfig]{
+	{Name: "dt1", Config: testDeployTargetConfig{Region: "us-east-1"}},
+	{Name: "dt2", Config: testDeployTargetConfig{Region: "us-west-2"}},
+}
+
+func TestLivestateDriver_GetLivestate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		health   sdk.ResourceHealthStatus
+		expected model.ApplicationLiveState_Status
+	}{
+		{name: "healthy", health: sdk.ResourceHealthStateHealthy, expected: model.ApplicationLiveState_HEALTHY},
+		{name: "unhealthy", health: sdk.ResourceHealthStateUnhealthy, expected: model.ApplicationLiveState_OTHER},
+		{name: "unknown", health: sdk.ResourceHealthStateUnknown, expected: model.ApplicationLiveState_UNKNOWN},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := NewLivestateDriver(t, "example", sdk.LivestatePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](testLivestatePlugin{health: tc.health}), nil, testLivestateDeployTargets...)
+			result, err := d.GetLivestate(context.Background(), LivestateCase{
+				ApplicationDirectory: "testdata/app",
+				DeployTargets:        []string{"dt2"},
+			})
+			require.NoError(t, err)
+			assert.True(t, AssertLivestate(t, result))
+			assert.Equal(t, tc.expected, result.HealthStatus)
+
+			// Only the requested deploy target is given to the plugin.
+			require.Len(t, result.Response.LiveState.Resources, 3)
+			for _, rs := range result.Response.LiveState.Resources {
+				assert.Equal(t, "dt2", rs.DeployTarget)
+			}
+		})
+	}
+}
+
+func TestLivestateDriver_GetLivestateErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	d := NewLivestateDriver(t, "example", sdk.LivestatePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](testLivestatePlugin{}), nil, testLivestateDeployTargets...)
+	_, err := d.GetLivestate(ctx, LivestateCase{ApplicationDirectory: "testdata/app", DeployTargets: []string{"unknown"}})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	d = NewLivestateDriver(t, "example", sdk.LivestatePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](testLivestatePlugin{delay: time.Minute}), nil, testLivestateDeployTargets...)
+	_, err = d.GetLivestate(ctx, LivestateCase{ApplicationDirectory: "testdata/app", DeployTargets: []string{"dt1"}, Timeout: 10 * time.Millisecond})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestLivestateDriver_GetLivestates(t *testing.T) {
+	t.Parallel()
+
+	d := NewLivestateDriver(t, "example", sdk.LivestatePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](testLivestatePlugin{health: sdk.ResourceHealthStateHealthy}), nil, testLivestateDeployTargets...)
+	cases := []LivestateCase{
+		{ApplicationID: "app-1", ApplicationDirectory: "testdata/app", DeployTargets: []string{"dt1"}},
+		{ApplicationID: "app-2", ApplicationDirectory: "testdata/app", DeployTargets: []string{"dt1", "dt2"}},
+		{ApplicationID: "app-3", ApplicationDirectory: "testdata/app", DeployTargets: []string{"unknown"}},
+	}
+	results, errs := d.GetLivestates(context.Background(), cases, 2)
+	require.Len(t, results, 3)
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	assert.Error(t, errs[2])
+	assert.Equal(t, "app-1", results[0].Request.GetApplicationId())
+	assert.Len(t, results[1].Response.LiveState.Resources, 6)
+	assert.Nil(t, results[2])
+}
+
+func TestAssertLivestate(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name      string
+		resources []sdk.ResourceState
+		expected  bool
+	}{
+		{
+			name: "valid",
+			resources: []sdk.ResourceState{
+				{ID: "a", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "dt1"},
+				{ID: "b", ParentIDs: []string{"a"}, HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "dt1"},
+			},
+			expected: true,
+		},
+		{
+			name:      "no ID",
+			resources: []sdk.ResourceState{{Name: "a", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "dt1"}},
+		},
+		{
+			name: "duplicated ID",
+			resources: []sdk.ResourceState{
+				{ID: "a", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "dt1"},
+				{ID: "a", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "dt1"},
+			},
+		},
+		{
+			name:      "missing parent",
+			resources: []sdk.ResourceState{{ID: "b", ParentIDs: []string{"a"}, HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "dt1"}},
+		},
+		{
+			name:      "unknown deploy target",
+			resources: []sdk.ResourceState{{ID: "a", HealthStatus: sdk.ResourceHealthStateHealthy, DeployTarget: "dt2"}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp := &sdk.GetLivestateResponse{LiveState: sdk.ApplicationLiveState{Resources: tc.resources}}
+			msg := sdk.LivestateResponseToProto("example", time.Unix(fixtureTime, 0), resp)
+			result := &LivestateResult{
+				Request:      &livestate.GetLivestateRequest{DeployTargets: []string{"dt1"}},
+				Proto:        msg,
+				Response:     resp,
+				HealthStatus: msg.GetApplicationLiveState().GetHealthStatus(),
+			}
+			ft := &fakeT{TB: t}
+			assert.Equal(t, tc.expected, AssertLivestate(ft, result))
+			assert.Equal(t, !tc.expected, ft.failed)
+		})
+	}
+
+	// The health status not rolled up from the resources fails.
+	ft := &fakeT{TB: t}
+	AssertLivestate(ft, &LivestateResult{Request: &livestate.GetLivestateRequest{}, Proto: &livestate.GetLivestateResponse{}, HealthStatus: model.ApplicationLiveState_OTHER})
+	assert.True(t, ft.failed)
+}
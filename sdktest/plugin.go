@@ -0,0 +1,116 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/planpreview"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/internal/testhook"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+)
+
+const bufconnSize = 1024 * 1024
+
+// PluginConn is the connection to the plugin started by StartPlugin.
+type PluginConn struct {
+	// Service is the piped service called by the plugin.
+	// Use it to prepare the metadata and the tools, and to check the stage logs.
+	Service *PluginService
+
+	// Deployment calls the stage plugin or the deployment plugin.
+	Deployment deployment.DeploymentServiceClient
+	// Livestate calls the livestate plugin.
+	Livestate livestate.LivestateServiceClient
+	// PlanPreview calls the plan preview plugin.
+	PlanPreview planpreview.PlanPreviewServiceClient
+}
+
+// StartPlugin starts the plugin with the given piped plugin config in JSON or YAML, and returns the clients calling it.
+// The plugin is served on an in-process listener with the same services and interceptors as the start command,
+// so the tests can exercise the real wire path without ports or TLS.
+// The plugin is stopped when the test finishes, and the error while running it fails the test.
+func StartPlugin[Config, DeployTargetConfig, ApplicationConfigSpec any](t testing.TB, plugin *sdk.Plugin[Config, DeployTargetConfig, ApplicationConfigSpec], config string) *PluginConn {
+	t.Helper()
+
+	service := NewPluginService()
+	conn, stop := startPlugin(t, plugin, config, service, service, zaptest.NewLogger(t))
+	t.Cleanup(func() {
+		if err := stop(); err != nil {
+			t.Errorf("failed to run the plugin: %s", err)
+		}
+	})
+	return conn
+}
+
+// startPlugin starts the plugin calling the service and persisting the stage logs through the persister.
+// The plugin writes its logs into the given logger.
+// It returns the function to stop the plugin, which returns the error while running it.
+func startPlugin[Config, DeployTargetConfig, ApplicationConfigSpec any](t testing.TB, plugin *sdk.Plugin[Config, DeployTargetConfig, ApplicationConfigSpec], config string, service *PluginService, persister stageLogPersisterProvider, logger *zap.Logger) (*PluginConn, func() error) {
+	t.Helper()
+
+	lis := bufconn.Listen(bufconnSize)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errCh := make(chan error, 1)
+	go func() {
+		err := testhook.Serve(ctx, plugin, lis, service, persister, config, logger)
+		if err != nil {
+			// Close the listener to fail the calls immediately.
+			lis.Close()
+		}
+		errCh <- err
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		cancel()
+		t.Fatalf("failed to connect to the plugin: %s", err)
+	}
+
+	stop := func() error {
+		conn.Close()
+		cancel()
+		return <-errCh
+	}
+	return &PluginConn{
+		Service:     service,
+		Deployment:  deployment.NewDeploymentServiceClient(conn),
+		Livestate:   livestate.NewLivestateServiceClient(conn),
+		PlanPreview: planpreview.NewPlanPreviewServiceClient(conn),
+	}, stop
+}
+
+// stageLogPersisterProvider provides the persisters of the stage logs, e.g. PluginService and the persister of logpersister.
+type stageLogPersisterProvider interface {
+	StageLogPersister(deploymentID, stageID string) logpersister.StageLogPersister
+}
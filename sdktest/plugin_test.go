@@ -0,0 +1,84 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/common"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+const testPluginConfigYAML = `
+name: example
+url: https://example.com/plugin
+port: 7001
+config:
+  prefix: "[test]"
+deployTargets:
+  - name: dt1
+    config:
+      region: us-east-1
+`
+
+func TestStartPlugin(t *testing.T) {
+	t.Parallel()
+
+	plugin, err := sdk.NewPlugin("v0.0.1", sdk.WithStagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](testStagePlugin{}))
+	require.NoError(t, err)
+	conn := StartPlugin(t, plugin, testPluginConfigYAML)
+
+	ctx := context.Background()
+	var header metadata.MD
+	stages, err := conn.Deployment.FetchDefinedStages(ctx, &deployment.FetchDefinedStagesRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"TEST_STAGE"}, stages.GetStages())
+	// The load of the plugin is reported to piped.
+	assert.Equal(t, []string{"0"}, header.Get(sdk.SaturationHeader))
+	assert.Equal(t, []string{"0"}, header.Get(sdk.QueuedStagesHeader))
+
+	appConfig, err := os.ReadFile("testdata/app/app.pipecd.yaml")
+	require.NoError(t, err)
+	resp, err := conn.Deployment.ExecuteStage(ctx, &deployment.ExecuteStageRequest{
+		Input: &deployment.ExecutePluginInput{
+			Deployment: NewDeployment(),
+			Stage:      NewPipelineStage("TEST_STAGE", 0),
+			TargetDeploymentSource: &common.DeploymentSource{
+				ApplicationDirectory: "testdata/app",
+				ApplicationConfig:    appConfig,
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.StageStatus_STAGE_SUCCESS, resp.GetStatus())
+	assert.Equal(t, map[string]string{"key": "value"}, conn.Service.StageMetadata(DefaultDeploymentID, DefaultStageID))
+
+	// The request is validated by the interceptor.
+	_, err = conn.Deployment.ExecuteStage(ctx, &deployment.ExecuteStageRequest{})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,394 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sdktest provides the utilities for the unit tests of the plugins,
+// so that they can be tested without running piped.
+package sdktest
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/internal/testhook"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry"
+)
+
+// PluginService is an in-memory implementation of the piped service used by the plugins.
+// It stores the metadata, the application shared objects, and the stage logs in memory,
+// and returns the registered tools and stage commands.
+// It's safe for concurrent use.
+type PluginService struct {
+	// Embed the interface to panic on the methods not implemented yet.
+	pipedservice.PluginServiceClient
+
+	mu             sync.Mutex
+	stageMetadata  map[stageKey]map[string]string
+	pluginMetadata map[pluginKey]map[string]string
+	sharedMetadata map[string]map[string]string
+	sharedObjects  map[objectKey][]byte
+	commands       []*model.Command
+	tools          map[toolKey]string
+	stageLogs      map[stageKey]map[int64]*model.LogBlock
+}
+
+type stageKey struct {
+	deploymentID string
+	stageID      string
+}
+
+type pluginKey struct {
+	deploymentID string
+	pluginName   string
+}
+
+type objectKey struct {
+	applicationID string
+	pluginName    string
+	key           string
+}
+
+type toolKey struct {
+	name    string
+	version string
+}
+
+// NewPluginService creates a new empty PluginService.
+func NewPluginService() *PluginService {
+	return &PluginService{
+		stageMetadata:  make(map[stageKey]map[string]string),
+		pluginMetadata: make(map[pluginKey]map[string]string),
+		sharedMetadata: make(map[string]map[string]string),
+		sharedObjects:  make(map[objectKey][]byte),
+		tools:          make(map[toolKey]string),
+		stageLogs:      make(map[stageKey]map[int64]*model.LogBlock),
+	}
+}
+
+// ClientConfig is the context of the client created by PluginService.NewClient.
+type ClientConfig struct {
+	PluginName    string
+	ApplicationID string
+	DeploymentID  string
+	StageID       string
+	// Clock is used by the client to expire the caches and to poll the stage commands.
+	// The real clock is used when it's nil.
+	Clock clock.Clock
+	// Service is the piped service called by the client instead of the PluginService, e.g. the ChaosService wrapping it.
+	// The stage logs are written into the PluginService directly regardless of it.
+	Service pipedservice.PluginServiceClient
+	// StageLogPersister records the stage logs written through the client instead of the PluginService,
+	// e.g. the logpersistertest.RecordingLogPersister to assert on the log output.
+	StageLogPersister logpersister.StageLogPersister
+}
+
+// NewClient creates a new client calling the service.
+// The stage logs written through the client are stored in the service unless ClientConfig.StageLogPersister is set, and the tools are installed by the service.
+func (s *PluginService) NewClient(cfg ClientConfig) *sdk.Client {
+	var service pipedservice.PluginServiceClient = s
+	if cfg.Service != nil {
+		service = cfg.Service
+	}
+	slp := cfg.StageLogPersister
+	if slp == nil {
+		slp = s.StageLogPersister(cfg.DeploymentID, cfg.StageID)
+	}
+	return testhook.NewClient(
+		service,
+		cfg.PluginName,
+		cfg.ApplicationID,
+		cfg.DeploymentID,
+		cfg.StageID,
+		slp,
+		toolregistry.NewToolRegistry(service, toolregistry.WithClock(cfg.Clock)),
+		cfg.Clock,
+	).(*sdk.Client)
+}
+
+// SetTool registers the path of the tool returned when it's installed.
+// Installing the tools not registered fails.
+func (s *PluginService) SetTool(name, version, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tools[toolKey{name: name, version: version}] = path
+}
+
+// SetDeploymentSharedMetadata sets the metadata of the deployment shared among piped and plugins.
+func (s *PluginService) SetDeploymentSharedMetadata(deploymentID, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	putMetadata(s.sharedMetadata, deploymentID, map[string]string{key: value})
+}
+
+// SetStageMetadata sets the metadata of the stage, e.g. the one written by the previous run of the stage.
+func (s *PluginService) SetStageMetadata(deploymentID, stageID, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	putMetadata(s.stageMetadata, stageKey{deploymentID: deploymentID, stageID: stageID}, map[string]string{key: value})
+}
+
+// SetDeploymentPluginMetadata sets the metadata of the deployment for the plugin, e.g. the one written by the previous stages.
+func (s *PluginService) SetDeploymentPluginMetadata(deploymentID, pluginName, key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	putMetadata(s.pluginMetadata, pluginKey{deploymentID: deploymentID, pluginName: pluginName}, map[string]string{key: value})
+}
+
+// SetApplicationSharedObject sets the application shared object of the given key.
+func (s *PluginService) SetApplicationSharedObject(applicationID, pluginName, key string, obj []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sharedObjects[objectKey{applicationID: applicationID, pluginName: pluginName, key: key}] = slices.Clone(obj)
+}
+
+// AddStageCommand adds the command returned to the stage of its deployment ID and stage ID.
+func (s *PluginService) AddStageCommand(cmd *model.Command) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, cmd)
+}
+
+// StageMetadata returns a copy of the metadata of the given stage.
+func (s *PluginService) StageMetadata(deploymentID, stageID string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.stageMetadata[stageKey{deploymentID: deploymentID, stageID: stageID}])
+}
+
+// DeploymentPluginMetadata returns a copy of the metadata of the given deployment and plugin.
+func (s *PluginService) DeploymentPluginMetadata(deploymentID, pluginName string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.pluginMetadata[pluginKey{deploymentID: deploymentID, pluginName: pluginName}])
+}
+
+// DeploymentSharedMetadata returns a copy of the metadata of the given deployment shared among piped and plugins.
+func (s *PluginService) DeploymentSharedMetadata(deploymentID string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.sharedMetadata[deploymentID])
+}
+
+// ApplicationSharedObject returns the application shared object of the given key.
+func (s *PluginService) ApplicationSharedObject(applicationID, pluginName, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.sharedObjects[objectKey{applicationID: applicationID, pluginName: pluginName, key: key}]
+	return slices.Clone(obj), ok
+}
+
+// StageLogs returns the log blocks of the given stage in the order of their indexes.
+func (s *PluginService) StageLogs(deploymentID, stageID string) []*model.LogBlock {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blocks := s.stageLogs[stageKey{deploymentID: deploymentID, stageID: stageID}]
+	return slices.SortedFunc(maps.Values(blocks), func(a, b *model.LogBlock) int {
+		return cmp.Compare(a.Index, b.Index)
+	})
+}
+
+// StageLogPersister returns the persister writing the logs of the given stage into the service.
+func (s *PluginService) StageLogPersister(deploymentID, stageID string) logpersister.StageLogPersister {
+	return &stageLogPersister{service: s, key: stageKey{deploymentID: deploymentID, stageID: stageID}}
+}
+
+// appendStageLogs stores the log blocks of the stage.
+// The blocks of the same index are replaced, since the piped service receives the logs from the last checkpoint again.
+func (s *PluginService) appendStageLogs(key stageKey, blocks ...*model.LogBlock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.stageLogs[key]
+	if !ok {
+		stored = make(map[int64]*model.LogBlock)
+		s.stageLogs[key] = stored
+	}
+	for _, b := range blocks {
+		stored[b.Index] = b
+	}
+}
+
+func putMetadata[K comparable](store map[K]map[string]string, key K, metadata map[string]string) {
+	m, ok := store[key]
+	if !ok {
+		m = make(map[string]string, len(metadata))
+		store[key] = m
+	}
+	maps.Copy(m, metadata)
+}
+
+func (s *PluginService) InstallTool(_ context.Context, in *pipedservice.InstallToolRequest, _ ...grpc.CallOption) (*pipedservice.InstallToolResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path, ok := s.tools[toolKey{name: in.GetName(), version: in.GetVersion()}]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "tool %s %s is not registered", in.GetName(), in.GetVersion())
+	}
+	return &pipedservice.InstallToolResponse{InstalledPath: path}, nil
+}
+
+func (s *PluginService) ReportStageLogs(_ context.Context, in *pipedservice.ReportStageLogsRequest, _ ...grpc.CallOption) (*pipedservice.ReportStageLogsResponse, error) {
+	s.appendStageLogs(stageKey{deploymentID: in.GetDeploymentId(), stageID: in.GetStageId()}, in.GetBlocks()...)
+	return &pipedservice.ReportStageLogsResponse{}, nil
+}
+
+func (s *PluginService) ReportStageLogsFromLastCheckpoint(_ context.Context, in *pipedservice.ReportStageLogsFromLastCheckpointRequest, _ ...grpc.CallOption) (*pipedservice.ReportStageLogsFromLastCheckpointResponse, error) {
+	s.appendStageLogs(stageKey{deploymentID: in.GetDeploymentId(), stageID: in.GetStageId()}, in.GetBlocks()...)
+	return &pipedservice.ReportStageLogsFromLastCheckpointResponse{}, nil
+}
+
+func (s *PluginService) GetStageMetadata(_ context.Context, in *pipedservice.GetStageMetadataRequest, _ ...grpc.CallOption) (*pipedservice.GetStageMetadataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.stageMetadata[stageKey{deploymentID: in.GetDeploymentId(), stageID: in.GetStageId()}][in.GetKey()]
+	return &pipedservice.GetStageMetadataResponse{Value: v, Found: ok}, nil
+}
+
+func (s *PluginService) PutStageMetadata(_ context.Context, in *pipedservice.PutStageMetadataRequest, _ ...grpc.CallOption) (*pipedservice.PutStageMetadataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	putMetadata(s.stageMetadata, stageKey{deploymentID: in.GetDeploymentId(), stageID: in.GetStageId()}, map[string]string{in.GetKey(): in.GetValue()})
+	return &pipedservice.PutStageMetadataResponse{}, nil
+}
+
+func (s *PluginService) PutStageMetadataMulti(_ context.Context, in *pipedservice.PutStageMetadataMultiRequest, _ ...grpc.CallOption) (*pipedservice.PutStageMetadataMultiResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	putMetadata(s.stageMetadata, stageKey{deploymentID: in.GetDeploymentId(), stageID: in.GetStageId()}, in.GetMetadata())
+	return &pipedservice.PutStageMetadataMultiResponse{}, nil
+}
+
+func (s *PluginService) GetDeploymentPluginMetadata(_ context.Context, in *pipedservice.GetDeploymentPluginMetadataRequest, _ ...grpc.CallOption) (*pipedservice.GetDeploymentPluginMetadataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.pluginMetadata[pluginKey{deploymentID: in.GetDeploymentId(), pluginName: in.GetPluginName()}][in.GetKey()]
+	return &pipedservice.GetDeploymentPluginMetadataResponse{Value: v, Found: ok}, nil
+}
+
+func (s *PluginService) PutDeploymentPluginMetadata(_ context.Context, in *pipedservice.PutDeploymentPluginMetadataRequest, _ ...grpc.CallOption) (*pipedservice.PutDeploymentPluginMetadataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	putMetadata(s.pluginMetadata, pluginKey{deploymentID: in.GetDeploymentId(), pluginName: in.GetPluginName()}, map[string]string{in.GetKey(): in.GetValue()})
+	return &pipedservice.PutDeploymentPluginMetadataResponse{}, nil
+}
+
+func (s *PluginService) PutDeploymentPluginMetadataMulti(_ context.Context, in *pipedservice.PutDeploymentPluginMetadataMultiRequest, _ ...grpc.CallOption) (*pipedservice.PutDeploymentPluginMetadataMultiResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	putMetadata(s.pluginMetadata, pluginKey{deploymentID: in.GetDeploymentId(), pluginName: in.GetPluginName()}, in.GetMetadata())
+	return &pipedservice.PutDeploymentPluginMetadataMultiResponse{}, nil
+}
+
+func (s *PluginService) GetDeploymentSharedMetadata(_ context.Context, in *pipedservice.GetDeploymentSharedMetadataRequest, _ ...grpc.CallOption) (*pipedservice.GetDeploymentSharedMetadataResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.sharedMetadata[in.GetDeploymentId()][in.GetKey()]
+	return &pipedservice.GetDeploymentSharedMetadataResponse{Value: v, Found: ok}, nil
+}
+
+func (s *PluginService) ListStageCommands(_ context.Context, in *pipedservice.ListStageCommandsRequest, _ ...grpc.CallOption) (*pipedservice.ListStageCommandsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var commands []*model.Command
+	for _, cmd := range s.commands {
+		if cmd.GetDeploymentId() == in.GetDeploymentId() && cmd.GetStageId() == in.GetStageId() {
+			commands = append(commands, cmd)
+		}
+	}
+	return &pipedservice.ListStageCommandsResponse{Commands: commands}, nil
+}
+
+func (s *PluginService) GetApplicationSharedObject(_ context.Context, in *pipedservice.GetApplicationSharedObjectRequest, _ ...grpc.CallOption) (*pipedservice.GetApplicationSharedObjectResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.sharedObjects[objectKey{applicationID: in.GetApplicationId(), pluginName: in.GetPluginName(), key: in.GetKey()}]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "object %s is not found", in.GetKey())
+	}
+	return &pipedservice.GetApplicationSharedObjectResponse{Object: obj}, nil
+}
+
+func (s *PluginService) PutApplicationSharedObject(_ context.Context, in *pipedservice.PutApplicationSharedObjectRequest, _ ...grpc.CallOption) (*pipedservice.PutApplicationSharedObjectResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sharedObjects[objectKey{applicationID: in.GetApplicationId(), pluginName: in.GetPluginName(), key: in.GetKey()}] = slices.Clone(in.GetObject())
+	return &pipedservice.PutApplicationSharedObjectResponse{}, nil
+}
+
+// stageLogPersister writes the stage logs into the PluginService directly.
+type stageLogPersister struct {
+	service *PluginService
+	key     stageKey
+
+	mu    sync.Mutex
+	index int64
+}
+
+func (p *stageLogPersister) append(log string, severity model.LogSeverity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.service.appendStageLogs(p.key, &model.LogBlock{
+		Index:     p.index,
+		Log:       log,
+		Severity:  severity,
+		CreatedAt: time.Now().Unix(),
+	})
+	p.index++
+}
+
+func (p *stageLogPersister) Write(log []byte) (int, error) {
+	p.append(string(log), model.LogSeverity_INFO)
+	return len(log), nil
+}
+
+func (p *stageLogPersister) Info(log string) {
+	p.append(log, model.LogSeverity_INFO)
+}
+
+func (p *stageLogPersister) Infof(format string, a ...interface{}) {
+	p.append(fmt.Sprintf(format, a...), model.LogSeverity_INFO)
+}
+
+func (p *stageLogPersister) Success(log string) {
+	p.append(log, model.LogSeverity_SUCCESS)
+}
+
+func (p *stageLogPersister) Successf(format string, a ...interface{}) {
+	p.append(fmt.Sprintf(format, a...), model.LogSeverity_SUCCESS)
+}
+
+func (p *stageLogPersister) Error(log string) {
+	p.append(log, model.LogSeverity_ERROR)
+}
+
+func (p *stageLogPersister) Errorf(format string, a ...interface{}) {
+	p.append(fmt.Sprintf(format, a...), model.LogSeverity_ERROR)
+}
+
+func (p *stageLogPersister) Complete(time.Duration) error {
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister/logpersistertest"
+)
+
+func TestPluginService_Metadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewPluginService()
+	c := s.NewClient(ClientConfig{PluginName: "plugin", ApplicationID: "app", DeploymentID: "deployment", StageID: "stage"})
+
+	require.NoError(t, c.PutStageMetadata(ctx, "k1", "v1"))
+	require.NoError(t, c.PutStageMetadataMulti(ctx, map[string]string{"k2": "v2"}))
+	v, found, err := c.GetStageMetadata(ctx, "k1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v1", v)
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, s.StageMetadata("deployment", "stage"))
+	assert.Empty(t, s.StageMetadata("deployment", "other"))
+
+	require.NoError(t, c.PutDeploymentPluginMetadata(ctx, "k1", "v1"))
+	require.NoError(t, c.PutDeploymentPluginMetadataMulti(ctx, map[string]string{"k2": "v2"}))
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, s.DeploymentPluginMetadata("deployment", "plugin"))
+	_, found, err = s.NewClient(ClientConfig{PluginName: "other", DeploymentID: "deployment"}).GetDeploymentPluginMetadata(ctx, "k1")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	s.SetDeploymentSharedMetadata("deployment", "shared", "value")
+	v, found, err = c.GetDeploymentSharedMetadata(ctx, "shared")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, map[string]string{"shared": "value"}, s.DeploymentSharedMetadata("deployment"))
+}
+
+func TestPluginService_SeedMetadata(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewPluginService()
+	c := s.NewClient(ClientConfig{PluginName: "plugin", ApplicationID: "app", DeploymentID: "deployment", StageID: "stage"})
+
+	s.SetStageMetadata("deployment", "stage", "k1", "v1")
+	v, found, err := c.GetStageMetadata(ctx, "k1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v1", v)
+
+	s.SetDeploymentPluginMetadata("deployment", "plugin", "k1", "v1")
+	v, found, err = c.GetDeploymentPluginMetadata(ctx, "k1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "v1", v)
+
+	// The seeded metadata is merged with the one written by the plugin.
+	require.NoError(t, c.PutDeploymentPluginMetadata(ctx, "k2", "v2"))
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, s.DeploymentPluginMetadata("deployment", "plugin"))
+
+	s.SetApplicationSharedObject("app", "plugin", "key", []byte("object"))
+	obj, found, err := c.GetApplicationSharedObject(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("object"), obj)
+}
+
+func TestPluginService_ApplicationSharedObject(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewPluginService()
+	c := s.NewClient(ClientConfig{PluginName: "plugin", ApplicationID: "app"})
+
+	_, found, err := c.GetApplicationSharedObject(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, c.PutApplicationSharedObject(ctx, "key", []byte("object")))
+	obj, found, err := c.GetApplicationSharedObject(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("object"), obj)
+
+	obj, found = s.ApplicationSharedObject("app", "plugin", "key")
+	assert.True(t, found)
+	assert.Equal(t, []byte("object"), obj)
+	_, found = s.ApplicationSharedObject("other", "plugin", "key")
+	assert.False(t, found)
+}
+
+func TestPluginService_StageLogs(t *testing.T) {
+	t.Parallel()
+
+	s := NewPluginService()
+	c := s.NewClient(ClientConfig{DeploymentID: "deployment", StageID: "stage"})
+
+	lp, err := c.StageLogPersister()
+	require.NoError(t, err)
+	lp.Info("info")
+	lp.Successf("success %d", 1)
+	lp.Error("error")
+
+	blocks := s.StageLogs("deployment", "stage")
+	require.Len(t, blocks, 3)
+	assert.Equal(t, "info", blocks[0].Log)
+	assert.Equal(t, model.LogSeverity_INFO, blocks[0].Severity)
+	assert.Equal(t, "success 1", blocks[1].Log)
+	assert.Equal(t, model.LogSeverity_SUCCESS, blocks[1].Severity)
+	assert.Equal(t, "error", blocks[2].Log)
+	assert.Equal(t, model.LogSeverity_ERROR, blocks[2].Severity)
+	assert.Empty(t, s.StageLogs("deployment", "other"))
+}
+
+func TestPluginService_StageLogPersister(t *testing.T) {
+	t.Parallel()
+
+	s := NewPluginService()
+	rlp := logpersistertest.NewRecordingLogPersister(t)
+	c := s.NewClient(ClientConfig{DeploymentID: "deployment", StageID: "stage", StageLogPersister: rlp})
+
+	lp, err := c.StageLogPersister()
+	require.NoError(t, err)
+	lp.Info("info")
+	lp.Error("error")
+
+	rlp.ContainsInOrder(logpersistertest.Line("info"), logpersistertest.WithSeverity(model.LogSeverity_ERROR, logpersistertest.Line("error")))
+	// The logs are recorded by the persister instead of the service.
+	assert.Empty(t, s.StageLogs("deployment", "stage"))
+}
+
+func TestPluginService_InstallTool(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := NewPluginService()
+	s.SetTool("tool", "1.0.0", "/path/to/tool")
+	c := s.NewClient(ClientConfig{})
+
+	path, err := c.ToolRegistry().InstallTool(ctx, "tool", "1.0.0", "")
+	require.NoError(t, err)
+	assert.Equal(t, "/path/to/tool", path)
+
+	_, err = c.ToolRegistry().InstallTool(ctx, "tool", "2.0.0", "")
+	require.Error(t, err)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestPluginService_StageCommands(t *testing.T) {
+	t.Parallel()
+
+	s := NewPluginService()
+	s.AddStageCommand(&model.Command{Id: "other", DeploymentId: "deployment", StageId: "other", Type: model.Command_APPROVE_STAGE})
+	s.AddStageCommand(&model.Command{Id: "command", DeploymentId: "deployment", StageId: "stage", Type: model.Command_APPROVE_STAGE, Commander: "user"})
+	c := s.NewClient(ClientConfig{DeploymentID: "deployment", StageID: "stage"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd, err := c.WaitStageCommand(ctx, sdk.CommandTypeApproveStage)
+	require.NoError(t, err)
+	assert.Equal(t, "user", cmd.Commander)
+}
+
+func TestPluginService_StageCommandsWithClock(t *testing.T) {
+	t.Parallel()
+
+	s := NewPluginService()
+	clk := clocktest.NewFakeClock(time.Now())
+	c := s.NewClient(ClientConfig{DeploymentID: "deployment", StageID: "stage", Clock: clk})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmdCh := make(chan *sdk.StageCommand, 1)
+	go func() {
+		cmd, err := c.WaitStageCommand(ctx, sdk.CommandTypeApproveStage)
+		assert.NoError(t, err)
+		cmdCh <- cmd
+	}()
+
+	// The command is found on the next poll after the clock advances.
+	clk.BlockUntil(1)
+	s.AddStageCommand(&model.Command{Id: "command", DeploymentId: "deployment", StageId: "stage", Type: model.Command_APPROVE_STAGE, Commander: "user"})
+	clk.Advance(5 * time.Second)
+
+	cmd := <-cmdCh
+	require.NotNil(t, cmd)
+	assert.Equal(t, "user", cmd.Commander)
+}
@@ -0,0 +1,273 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/common"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+	"github.com/pipe-cd/piped-plugin-sdk-go/internal/testhook"
+)
+
+const (
+	// DefaultDeploymentID is the ID of the deployment used when ExecuteStageCase.Deployment is not given.
+	DefaultDeploymentID = "deployment-id"
+	// DefaultApplicationID is the ID of the application used when ExecuteStageCase.Deployment is not given.
+	DefaultApplicationID = "application-id"
+	// DefaultStageID is the ID of the stage to execute.
+	DefaultStageID = "stage-id"
+)
+
+// StageHarness executes the stages of the plugin in the same way as the plugin server does,
+// so that the stages can be tested by the table-driven tests without running piped.
+type StageHarness[Config, DeployTargetConfig, ApplicationConfigSpec any] struct {
+	// Service is the piped service used by the plugin.
+	// Use it to prepare the metadata and the tools, and to check the stage logs.
+	Service *PluginService
+
+	chaos      *ChaosService
+	t          testing.TB
+	pluginName string
+	plugin     sdk.StagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec]
+	config     *Config
+}
+
+// NewStageHarness creates a new StageHarness for the plugin of the given name and config.
+// The DeploymentPlugin can be given as well since it's a StagePlugin.
+func NewStageHarness[Config, DeployTargetConfig, ApplicationConfigSpec any](t testing.TB, pluginName string, plugin sdk.StagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec], config *Config) *StageHarness[Config, DeployTargetConfig, ApplicationConfigSpec] {
+	if config == nil {
+		config = new(Config)
+	}
+	return &StageHarness[Config, DeployTargetConfig, ApplicationConfigSpec]{
+		Service:    NewPluginService(),
+		t:          t,
+		pluginName: pluginName,
+		plugin:     plugin,
+		config:     config,
+	}
+}
+
+// WithChaos makes the plugin call the Service through a ChaosService, and returns it to inject the faults.
+func (h *StageHarness[Config, DeployTargetConfig, ApplicationConfigSpec]) WithChaos(opts ...ChaosOption) *ChaosService {
+	h.chaos = NewChaosService(h.Service, opts...)
+	return h.chaos
+}
+
+// ExecuteStageCase is the stage to execute by StageHarness.
+type ExecuteStageCase[DeployTargetConfig any] struct {
+	// StageName is the name of the stage to execute.
+	StageName string
+	// StageIndex is the index of the stage to execute.
+	StageIndex int
+	// StageConfig is the config of the stage.
+	// The []byte and json.RawMessage are passed as they are, and the other values are marshaled to JSON.
+	StageConfig any
+	// TargetApplicationDirectory is the directory of the application to deploy.
+	// It must contain the application config file.
+	TargetApplicationDirectory string
+	// RunningApplicationDirectory is the directory of the running application.
+	// Leave it empty to execute the stage as the first deployment.
+	RunningApplicationDirectory string
+	// ApplicationConfigFilename is the filename of the application config in the directories.
+	// The default is app.pipecd.yaml.
+	ApplicationConfigFilename string
+	// Deployment is the deployment running the stage.
+	// The deployment of DefaultDeploymentID and DefaultApplicationID is used when it's nil.
+	Deployment *model.Deployment
+	// DeployTargets are the deploy targets to execute the stage on.
+	DeployTargets []*sdk.DeployTarget[DeployTargetConfig]
+}
+
+// ExecuteStageResult is the result of the stage executed by StageHarness.
+type ExecuteStageResult struct {
+	// Status is the status of the stage reported to piped.
+	Status model.StageStatus
+	// Logs are the stage logs written by the plugin.
+	Logs []*model.LogBlock
+}
+
+// ExecuteStage executes the stage of the given case.
+// The error is a gRPC status error as piped receives.
+func (h *StageHarness[Config, DeployTargetConfig, ApplicationConfigSpec]) ExecuteStage(ctx context.Context, c ExecuteStageCase[DeployTargetConfig]) (*ExecuteStageResult, error) {
+	h.t.Helper()
+
+	request, err := h.newExecuteStageRequest(c)
+	if err != nil {
+		h.t.Fatalf("failed to prepare the request to execute the stage: %s", err)
+	}
+	pluginType := (*sdk.Plugin[Config, DeployTargetConfig, ApplicationConfigSpec])(nil)
+	resp, err := testhook.ExecuteStage(ctx, pluginType, h.pluginName, h.plugin, h.config, c.DeployTargets, h.newClient(request), request, zaptest.NewLogger(h.t))
+	result := &ExecuteStageResult{
+		Status: resp.GetStatus(),
+		Logs:   h.Service.StageLogs(request.GetInput().GetDeployment().GetId(), DefaultStageID),
+	}
+	return result, err
+}
+
+// NewExecuteStageInput returns the input of ExecuteStage for the given case as the plugin receives it,
+// so that the functions taking the input can be tested without executing the whole stage.
+// The client of the input calls the Service, and the stage logs written through it are stored in the Service.
+// The deploy targets of the case are not used.
+func (h *StageHarness[Config, DeployTargetConfig, ApplicationConfigSpec]) NewExecuteStageInput(ctx context.Context, c ExecuteStageCase[DeployTargetConfig]) *sdk.ExecuteStageInput[ApplicationConfigSpec] {
+	h.t.Helper()
+
+	request, err := h.newExecuteStageRequest(c)
+	if err != nil {
+		h.t.Fatalf("failed to prepare the request to execute the stage: %s", err)
+	}
+	in := &sdk.ExecuteStageInput[ApplicationConfigSpec]{}
+	if err := testhook.NewExecuteStageInput(ctx, in, h.pluginName, h.newClient(request), request, zaptest.NewLogger(h.t)); err != nil {
+		h.t.Fatalf("failed to prepare the input to execute the stage: %s", err)
+	}
+	return in
+}
+
+// newClient creates the client of the stage of the request calling the Service, or the ChaosService if it's set.
+func (h *StageHarness[Config, DeployTargetConfig, ApplicationConfigSpec]) newClient(request *deployment.ExecuteStageRequest) *sdk.Client {
+	cfg := ClientConfig{
+		PluginName:    h.pluginName,
+		ApplicationID: request.GetInput().GetDeployment().GetApplicationId(),
+		DeploymentID:  request.GetInput().GetDeployment().GetId(),
+		StageID:       DefaultStageID,
+	}
+	if h.chaos != nil {
+		cfg.Service = h.chaos
+	}
+	return h.Service.NewClient(cfg)
+}
+
+func (h *StageHarness[Config, DeployTargetConfig, ApplicationConfigSpec]) newExecuteStageRequest(c ExecuteStageCase[DeployTargetConfig]) (*deployment.ExecuteStageRequest, error) {
+	filename := c.ApplicationConfigFilename
+	if filename == "" {
+		filename = model.DefaultApplicationConfigFilename
+	}
+
+	stageConfig, err := marshalStageConfig(c.StageConfig)
+	if err != nil {
+		return nil, err
+	}
+	target, err := newDeploymentSource(c.TargetApplicationDirectory, filename)
+	if err != nil {
+		return nil, err
+	}
+	var running *common.DeploymentSource
+	if c.RunningApplicationDirectory != "" {
+		if running, err = newDeploymentSource(c.RunningApplicationDirectory, filename); err != nil {
+			return nil, err
+		}
+	}
+
+	d := c.Deployment
+	if d == nil {
+		d = NewDeployment()
+	}
+
+	return &deployment.ExecuteStageRequest{
+		Input: &deployment.ExecutePluginInput{
+			Deployment:              d,
+			Stage:                   NewPipelineStage(c.StageName, c.StageIndex),
+			StageConfig:             stageConfig,
+			RunningDeploymentSource: running,
+			TargetDeploymentSource:  target,
+		},
+	}, nil
+}
+
+// fixtureTime is the time used in the fixtures to make them deterministic.
+var fixtureTime = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+// NewDeployment returns the deployment of DefaultDeploymentID and DefaultApplicationID
+// satisfying the validation of the requests from piped.
+func NewDeployment() *model.Deployment {
+	return &model.Deployment{
+		Id:              DefaultDeploymentID,
+		ApplicationId:   DefaultApplicationID,
+		ApplicationName: "application",
+		PipedId:         "piped-id",
+		ProjectId:       "project-id",
+		GitPath: &model.ApplicationGitPath{
+			Repo: &model.ApplicationGitRepository{Id: "repo-id"},
+			Path: "app",
+		},
+		Trigger: &model.DeploymentTrigger{
+			Commit: &model.Commit{
+				Hash:      "0123456789abcdef",
+				Message:   "commit message",
+				Author:    "author",
+				Branch:    "main",
+				CreatedAt: fixtureTime,
+			},
+			Timestamp: fixtureTime,
+		},
+		CreatedAt: fixtureTime,
+		UpdatedAt: fixtureTime,
+	}
+}
+
+// NewPipelineStage returns the stage of DefaultStageID with the given name and index
+// satisfying the validation of the requests from piped.
+func NewPipelineStage(name string, index int) *model.PipelineStage {
+	return &model.PipelineStage{
+		Id:        DefaultStageID,
+		Name:      name,
+		Index:     int32(index),
+		CreatedAt: fixtureTime,
+		UpdatedAt: fixtureTime,
+	}
+}
+
+func marshalStageConfig(c any) ([]byte, error) {
+	switch v := c.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case json.RawMessage:
+		return v, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal the stage config: %w", err)
+		}
+		return data, nil
+	}
+}
+
+func newDeploymentSource(dir, filename string) (*common.DeploymentSource, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("the application directory is not given")
+	}
+	cfg, err := os.ReadFile(filepath.Join(dir, filename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the application config: %w", err)
+	}
+	return &common.DeploymentSource{
+		ApplicationDirectory:      dir,
+		ApplicationConfig:         cfg,
+		ApplicationConfigFilename: filename,
+	}, nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+type testPluginConfig struct {
+	Prefix string `json:"prefix"`
+}
+
+type testDeployTargetConfig struct {
+	Region string `json:"region"`
+}
+
+type testApplicationSpec struct {
+	Replicas int `json:"replicas"`
+}
+
+type testStageConfig struct {
+	Fail bool `json:"fail"`
+}
+
+type testStagePlugin struct{}
+
+func (testStagePlugin) FetchDefinedStages() []string {
+	return []string{"TEST_STAGE"}
+}
+
+func (testStagePlugin) BuildPipelineSyncStages(context.Context, *testPluginConfig, *sdk.BuildPipelineSyncStagesInput) (*sdk.BuildPipelineSyncStagesResponse, error) {
+	return &sdk.BuildPipelineSyncStagesResponse{}, nil
+}
+
+func (testStagePlugin) ExecuteStage(ctx context.Context, config *testPluginConfig, dts []*sdk.DeployTarget[testDeployTargetConfig], input *sdk.ExecuteStageInput[testApplicationSpec]) (*sdk.ExecuteStageResponse, error) {
+	if input.Request.StageName != "TEST_STAGE" {
+		return nil, errors.New("unknown stage")
+	}
+	stageConfig, err := sdk.DecodeStageConfig[testStageConfig](input.Request.StageConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	lp, err := input.Client.StageLogPersister()
+	if err != nil {
+		return nil, err
+	}
+	for _, dt := range dts {
+		lp.Infof("%s deploying %d replicas to %s", config.Prefix, input.Request.TargetDeploymentSource.ApplicationConfig.Spec.Replicas, dt.Config.Region)
+	}
+	if err := input.Client.PutStageMetadata(ctx, "key", "value"); err != nil {
+		return nil, err
+	}
+	if stageConfig.Fail {
+		lp.Error("failed")
+		return &sdk.ExecuteStageResponse{Status: sdk.StageStatusFailure}, nil
+	}
+	return &sdk.ExecuteStageResponse{Status: sdk.StageStatusSuccess}, nil
+}
+
+func TestStageHarness_ExecuteStage(t *testing.T) {
+	t.Parallel()
+
+	deployTargets := []*sdk.DeployTarget[testDeployTargetConfig]{
+		{Name: "dt1", Config: testDeployTargetConfig{Region: "us-east-1"}},
+	}
+
+	tests := []struct {
+		name           string
+		stageCase      ExecuteStageCase[testDeployTargetConfig]
+		expectedStatus model.StageStatus
+		expectedLogs   []string
+		expectedCode   codes.Code
+	}{
+		{
+			name: "success",
+			stageCase: ExecuteStageCase[testDeployTargetConfig]{
+				StageName:                  "TEST_STAGE",
+				TargetApplicationDirectory: "testdata/app",
+				DeployTargets:              deployTargets,
+			},
+			expectedStatus: model.StageStatus_STAGE_SUCCESS,
+			expectedLogs:   []string{"[test] deploying 2 replicas to us-east-1"},
+		},
+		{
+			name: "failure",
+			stageCase: ExecuteStageCase[testDeployTargetConfig]{
+				StageName:                   "TEST_STAGE",
+				StageConfig:                 testStageConfig{Fail: true},
+				TargetApplicationDirectory:  "testdata/app",
+				RunningApplicationDirectory: "testdata/app",
+				DeployTargets:               deployTargets,
+			},
+			expectedStatus: model.StageStatus_STAGE_FAILURE,
+			expectedLogs:   []string{"[test] deploying 2 replicas to us-east-1", "failed"},
+		},
+		{
+			name: "invalid stage config",
+			stageCase: ExecuteStageCase[testDeployTargetConfig]{
+				StageName:                  "TEST_STAGE",
+				StageConfig:                []byte(`{"fail": "yes"}`),
+				TargetApplicationDirectory: "testdata/app",
+			},
+			expectedCode: codes.Internal,
+		},
+		{
+			name: "unknown stage",
+			stageCase: ExecuteStageCase[testDeployTargetConfig]{
+				StageName:                  "UNKNOWN",
+				TargetApplicationDirectory: "testdata/app",
+			},
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := NewStageHarness(t, "example", sdk.StagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](testStagePlugin{}), &testPluginConfig{Prefix: "[test]"})
+			result, err := h.ExecuteStage(context.Background(), tt.stageCase)
+			if tt.expectedCode != codes.OK {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedCode, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, result.Status)
+
+			logs := make([]string, 0, len(result.Logs))
+			for _, b := range result.Logs {
+				logs = append(logs, b.Log)
+			}
+			assert.Equal(t, tt.expectedLogs, logs)
+			assert.Equal(t, map[string]string{"key": "value"}, h.Service.StageMetadata(DefaultDeploymentID, DefaultStageID))
+		})
+	}
+}
+
+func TestStageHarness_NewExecuteStageInput(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := NewStageHarness(t, "example", sdk.StagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](testStagePlugin{}), &testPluginConfig{Prefix: "[test]"})
+	in := h.NewExecuteStageInput(ctx, ExecuteStageCase[testDeployTargetConfig]{
+		StageName:                  "TEST_STAGE",
+		StageIndex:                 1,
+		StageConfig:                testStageConfig{Fail: true},
+		TargetApplicationDirectory: "testdata/app",
+	})
+
+	assert.Equal(t, "TEST_STAGE", in.Request.StageName)
+	assert.Equal(t, 1, in.Request.StageIndex)
+	assert.JSONEq(t, `{"fail": true}`, string(in.Request.StageConfig))
+	assert.Equal(t, DefaultDeploymentID, in.Request.Deployment.ID)
+	assert.Equal(t, 2, in.Request.TargetDeploymentSource.ApplicationConfig.Spec.Replicas)
+	assert.Nil(t, in.Request.RunningDeploymentSource.ApplicationConfig)
+
+	// The client of the input calls the Service.
+	require.NoError(t, in.Client.PutStageMetadata(ctx, "key", "value"))
+	assert.Equal(t, map[string]string{"key": "value"}, h.Service.StageMetadata(DefaultDeploymentID, DefaultStageID))
+	lp, err := in.Client.StageLogPersister()
+	require.NoError(t, err)
+	lp.Info("info")
+	logs := h.Service.StageLogs(DefaultDeploymentID, DefaultStageID)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "info", logs[0].Log)
+}
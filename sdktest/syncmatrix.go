@@ -0,0 +1,247 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zaptest"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+// PipelineSyncScenario is the request to build the pipeline sync stages generated by PipelineSyncScenarios.
+type PipelineSyncScenario struct {
+	// Name describes the scenario, e.g. "rollback/reversed".
+	Name string
+	// Request is the request given to BuildPipelineSyncStages.
+	Request sdk.BuildPipelineSyncStagesRequest
+}
+
+type pipelineOrder struct {
+	name   string
+	stages []string
+}
+
+// PipelineSyncScenarios generates the requests to build the pipeline sync stages from the defined stages of the plugin,
+// with and without the rollback: every stage alone, all the stages in the defined order, and in the reversed order.
+// The stages are placed at the odd indexes, since the pipeline of piped may contain the stages of the other plugins in between.
+// The stage configs are taken from the given map by the stage name and marshaled as ExecuteStageCase.StageConfig.
+func PipelineSyncScenarios(definedStages []string, stageConfigs map[string]any) ([]PipelineSyncScenario, error) {
+	newRequest := func(rollback bool, names []string) (sdk.BuildPipelineSyncStagesRequest, error) {
+		stages := make([]sdk.StageConfig, 0, len(names))
+		for i, name := range names {
+			config, err := marshalStageConfig(stageConfigs[name])
+			if err != nil {
+				return sdk.BuildPipelineSyncStagesRequest{}, err
+			}
+			stages = append(stages, sdk.StageConfig{Index: 2*i + 1, Name: name, Config: config})
+		}
+		return sdk.BuildPipelineSyncStagesRequest{Rollback: rollback, Stages: stages}, nil
+	}
+
+	var scenarios []PipelineSyncScenario
+	for _, rollback := range []bool{false, true} {
+		prefix := "no-rollback"
+		if rollback {
+			prefix = "rollback"
+		}
+		reversed := slices.Clone(definedStages)
+		slices.Reverse(reversed)
+		orders := []pipelineOrder{
+			{name: "defined", stages: definedStages},
+			{name: "reversed", stages: reversed},
+		}
+		for _, name := range definedStages {
+			orders = append(orders, pipelineOrder{name: "only-" + name, stages: []string{name}})
+		}
+		for _, o := range orders {
+			request, err := newRequest(rollback, o.stages)
+			if err != nil {
+				return nil, err
+			}
+			scenarios = append(scenarios, PipelineSyncScenario{Name: prefix + "/" + o.name, Request: request})
+		}
+	}
+	return scenarios, nil
+}
+
+// AssertPipelineSyncStages asserts the invariants of the pipeline sync stages built for the request:
+//   - every stage is one of the defined stages
+//   - every stage has one of the indexes in the request
+//   - every requested stage has its non-rollback stage, in the order of the request
+//   - the rollback stages are returned only when they are requested
+//   - the response can be sent to piped
+func AssertPipelineSyncStages(t testing.TB, definedStages []string, req sdk.BuildPipelineSyncStagesRequest, resp *sdk.BuildPipelineSyncStagesResponse) bool {
+	t.Helper()
+
+	if resp == nil {
+		t.Errorf("the response is nil")
+		return false
+	}
+
+	positions := make(map[int]int, len(req.Stages))
+	for i, s := range req.Stages {
+		positions[s.Index] = i
+	}
+
+	ok := true
+	covered := make(map[int]bool, len(req.Stages))
+	last := -1
+	for _, s := range resp.Stages {
+		if !slices.Contains(definedStages, s.Name) {
+			t.Errorf("the stage %q at index %d is not one of the defined stages %v", s.Name, s.Index, definedStages)
+			ok = false
+		}
+		pos, found := positions[s.Index]
+		if !found {
+			t.Errorf("the stage %q has the index %d not in the request", s.Name, s.Index)
+			ok = false
+			continue
+		}
+		if s.Rollback {
+			if !req.Rollback {
+				t.Errorf("the rollback stage %q at index %d is returned while the rollback is not requested", s.Name, s.Index)
+				ok = false
+			}
+			continue
+		}
+		if pos < last {
+			t.Errorf("the stage %q at index %d is out of the order of the request", s.Name, s.Index)
+			ok = false
+		}
+		last = pos
+		covered[s.Index] = true
+	}
+	for _, s := range req.Stages {
+		if !covered[s.Index] {
+			t.Errorf("the requested stage %q at index %d is not built", s.Name, s.Index)
+			ok = false
+		}
+	}
+	if _, err := sdk.PipelineSyncStagesResponseToProto(time.Unix(fixtureTime, 0), req, resp); err != nil {
+		t.Errorf("failed to convert the response: %s", err)
+		ok = false
+	}
+	return ok
+}
+
+// AssertQuickSyncStages asserts the invariants of the quick sync stages built for the request:
+//   - at least one stage is returned and every stage is one of the defined stages
+//   - the rollback stages are returned only when they are requested
+func AssertQuickSyncStages(t testing.TB, definedStages []string, req sdk.BuildQuickSyncStagesRequest, resp *sdk.BuildQuickSyncStagesResponse) bool {
+	t.Helper()
+
+	if resp == nil {
+		t.Errorf("the response is nil")
+		return false
+	}
+
+	ok := true
+	var synced bool
+	for _, s := range resp.Stages {
+		if !slices.Contains(definedStages, s.Name) {
+			t.Errorf("the stage %q is not one of the defined stages %v", s.Name, definedStages)
+			ok = false
+		}
+		if s.Rollback && !req.Rollback {
+			t.Errorf("the rollback stage %q is returned while the rollback is not requested", s.Name)
+			ok = false
+		}
+		if !s.Rollback {
+			synced = true
+		}
+	}
+	if !synced {
+		t.Errorf("no stage to sync is returned")
+		ok = false
+	}
+	return ok
+}
+
+// SyncMatrixOption is the option for RunSyncMatrix.
+type SyncMatrixOption func(*syncMatrix)
+
+type syncMatrix struct {
+	stageConfigs map[string]any
+}
+
+// WithStageConfig sets the config of the stage given in the pipeline sync scenarios.
+// The []byte and json.RawMessage are passed as they are, and the other values are marshaled to JSON.
+func WithStageConfig(stageName string, config any) SyncMatrixOption {
+	return func(m *syncMatrix) {
+		m.stageConfigs[stageName] = config
+	}
+}
+
+// RunSyncMatrix builds the stages of the plugin for every scenario generated by PipelineSyncScenarios in a subtest,
+// and asserts the invariants by AssertPipelineSyncStages.
+// When the plugin is a DeploymentPlugin, the quick sync stages are built with and without the rollback as well,
+// and asserted by AssertQuickSyncStages.
+func RunSyncMatrix[Config, DeployTargetConfig, ApplicationConfigSpec any](t *testing.T, pluginName string, plugin sdk.StagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec], config *Config, opts ...SyncMatrixOption) {
+	t.Helper()
+
+	m := &syncMatrix{stageConfigs: make(map[string]any)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if config == nil {
+		config = new(Config)
+	}
+
+	definedStages := plugin.FetchDefinedStages()
+	scenarios, err := PipelineSyncScenarios(definedStages, m.stageConfigs)
+	if err != nil {
+		t.Fatalf("failed to generate the scenarios: %s", err)
+	}
+
+	service := NewPluginService()
+	for _, s := range scenarios {
+		t.Run("pipeline/"+s.Name, func(t *testing.T) {
+			resp, err := plugin.BuildPipelineSyncStages(context.Background(), config, &sdk.BuildPipelineSyncStagesInput{
+				Request: s.Request,
+				Client:  service.NewClient(ClientConfig{PluginName: pluginName}),
+				Logger:  zaptest.NewLogger(t),
+			})
+			if err != nil {
+				t.Fatalf("failed to build the pipeline sync stages: %s", err)
+			}
+			AssertPipelineSyncStages(t, definedStages, s.Request, resp)
+		})
+	}
+
+	deploymentPlugin, ok := plugin.(sdk.DeploymentPlugin[Config, DeployTargetConfig, ApplicationConfigSpec])
+	if !ok {
+		return
+	}
+	for _, rollback := range []bool{false, true} {
+		t.Run(fmt.Sprintf("quick/rollback=%t", rollback), func(t *testing.T) {
+			req := sdk.BuildQuickSyncStagesRequest{Rollback: rollback}
+			resp, err := deploymentPlugin.BuildQuickSyncStages(context.Background(), config, &sdk.BuildQuickSyncStagesInput{
+				Request: req,
+				Client:  service.NewClient(ClientConfig{PluginName: pluginName}),
+				Logger:  zaptest.NewLogger(t),
+			})
+			if err != nil {
+				t.Fatalf("failed to build the quick sync stages: %s", err)
+			}
+			AssertQuickSyncStages(t, definedStages, req, resp)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+// testSyncPlugin builds a stage for each requested stage, and a rollback stage at the first index.
+type testSyncPlugin struct {
+	testStagePlugin
+}
+
+func (testSyncPlugin) FetchDefinedStages() []string {
+	return []string{"SYNC", "VERIFY", "ROLLBACK"}
+}
+
+func (testSyncPlugin) BuildPipelineSyncStages(_ context.Context, _ *testPluginConfig, input *sdk.BuildPipelineSyncStagesInput) (*sdk.BuildPipelineSyncStagesResponse, error) {
+	stages := make([]sdk.PipelineStage, 0, len(input.Request.Stages)+1)
+	for _, s := range input.Request.Stages {
+		stages = append(stages, sdk.PipelineStage{Index: s.Index, Name: s.Name})
+	}
+	if input.Request.Rollback {
+		stages = append(stages, sdk.PipelineStage{Index: input.Request.Stages[0].Index, Name: "ROLLBACK", Rollback: true})
+	}
+	return &sdk.BuildPipelineSyncStagesResponse{Stages: stages}, nil
+}
+
+func (testSyncPlugin) DetermineVersions(context.Context, *testPluginConfig, *sdk.DetermineVersionsInput[testApplicationSpec]) (*sdk.DetermineVersionsResponse, error) {
+	return &sdk.DetermineVersionsResponse{}, nil
+}
+
+func (testSyncPlugin) DetermineStrategy(context.Context, *testPluginConfig, *sdk.DetermineStrategyInput[testApplicationSpec]) (*sdk.DetermineStrategyResponse, error) {
+	return &sdk.DetermineStrategyResponse{}, nil
+}
+
+func (testSyncPlugin) BuildQuickSyncStages(_ context.Context, _ *testPluginConfig, input *sdk.BuildQuickSyncStagesInput) (*sdk.BuildQuickSyncStagesResponse, error) {
+	stages := []sdk.QuickSyncStage{{Name: "SYNC"}}
+	if input.Request.Rollback {
+		stages = append(stages, sdk.QuickSyncStage{Name: "ROLLBACK", Rollback: true})
+	}
+	return &sdk.BuildQuickSyncStagesResponse{Stages: stages}, nil
+}
+
+func TestPipelineSyncScenarios(t *testing.T) {
+	t.Parallel()
+
+	scenarios, err := PipelineSyncScenarios([]string{"A", "B"}, map[string]any{"A": map[string]int{"replicas": 1}})
+	require.NoError(t, err)
+
+	var names []string
+	for _, s := range scenarios {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{
+		"no-rollback/defined", "no-rollback/reversed", "no-rollback/only-A", "no-rollback/only-B",
+		"rollback/defined", "rollback/reversed", "rollback/only-A", "rollback/only-B",
+	}, names)
+
+	assert.Equal(t, sdk.BuildPipelineSyncStagesRequest{
+		Rollback: true,
+		Stages: []sdk.StageConfig{
+			{Index: 1, Name: "B"},
+			{Index: 3, Name: "A", Config: []byte(`{"replicas":1}`)},
+		},
+	}, scenarios[5].Request)
+}
+
+func TestRunSyncMatrix(t *testing.T) {
+	t.Parallel()
+
+	RunSyncMatrix(t, "example", sdk.StagePlugin[testPluginConfig, testDeployTargetConfig, testApplicationSpec](testSyncPlugin{}), nil)
+}
+
+func TestAssertPipelineSyncStages(t *testing.T) {
+	t.Parallel()
+
+	definedStages := []string{"SYNC", "VERIFY", "ROLLBACK"}
+	req := sdk.BuildPipelineSyncStagesRequest{
+		Stages: []sdk.StageConfig{{Index: 1, Name: "SYNC"}, {Index: 3, Name: "VERIFY"}},
+	}
+
+	testcases := []struct {
+		name     string
+		rollback bool
+		stages   []sdk.PipelineStage
+		expected bool
+	}{
+		{
+			name:     "valid",
+			stages:   []sdk.PipelineStage{{Index: 1, Name: "SYNC"}, {Index: 3, Name: "VERIFY"}},
+			expected: true,
+		},
+		{
+			name:     "valid with rollback",
+			rollback: true,
+			stages:   []sdk.PipelineStage{{Index: 1, Name: "SYNC"}, {Index: 3, Name: "VERIFY"}, {Index: 1, Name: "ROLLBACK", Rollback: true}},
+			expected: true,
+		},
+		{
+			name:   "undefined stage",
+			stages: []sdk.PipelineStage{{Index: 1, Name: "SYNC"}, {Index: 3, Name: "UNKNOWN"}},
+		},
+		{
+			name:   "unknown index",
+			stages: []sdk.PipelineStage{{Index: 0, Name: "SYNC"}, {Index: 3, Name: "VERIFY"}},
+		},
+		{
+			name:   "missing stage",
+			stages: []sdk.PipelineStage{{Index: 1, Name: "SYNC"}},
+		},
+		{
+			name:   "out of order",
+			stages: []sdk.PipelineStage{{Index: 3, Name: "VERIFY"}, {Index: 1, Name: "SYNC"}},
+		},
+		{
+			name:   "rollback not requested",
+			stages: []sdk.PipelineStage{{Index: 1, Name: "SYNC"}, {Index: 3, Name: "VERIFY"}, {Index: 1, Name: "ROLLBACK", Rollback: true}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := req
+			req.Rollback = tc.rollback
+			ft := &fakeT{TB: t}
+			ok := AssertPipelineSyncStages(ft, definedStages, req, &sdk.BuildPipelineSyncStagesResponse{Stages: tc.stages})
+			assert.Equal(t, tc.expected, ok)
+			assert.Equal(t, !tc.expected, ft.failed)
+		})
+	}
+}
+
+func TestAssertQuickSyncStages(t *testing.T) {
+	t.Parallel()
+
+	definedStages := []string{"SYNC", "ROLLBACK"}
+
+	testcases := []struct {
+		name     string
+		rollback bool
+		stages   []sdk.QuickSyncStage
+		expected bool
+	}{
+		{
+			name:     "valid",
+			stages:   []sdk.QuickSyncStage{{Name: "SYNC"}},
+			expected: true,
+		},
+		{
+			name:     "valid with rollback",
+			rollback: true,
+			stages:   []sdk.QuickSyncStage{{Name: "SYNC"}, {Name: "ROLLBACK", Rollback: true}},
+			expected: true,
+		},
+		{
+			name: "no stage",
+		},
+		{
+			name:   "undefined stage",
+			stages: []sdk.QuickSyncStage{{Name: "UNKNOWN"}},
+		},
+		{
+			name:   "rollback not requested",
+			stages: []sdk.QuickSyncStage{{Name: "SYNC"}, {Name: "ROLLBACK", Rollback: true}},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ft := &fakeT{TB: t}
+			ok := AssertQuickSyncStages(ft, definedStages, sdk.BuildQuickSyncStagesRequest{Rollback: tc.rollback}, &sdk.BuildQuickSyncStagesResponse{Stages: tc.stages})
+			assert.Equal(t, tc.expected, ok)
+			assert.Equal(t, !tc.expected, ft.failed)
+		})
+	}
+}
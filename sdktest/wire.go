@@ -0,0 +1,222 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+// wirePluginName is the plugin name used to convert the livestate response in the round-trip assertions.
+const wirePluginName = "plugin"
+
+// AssertPlanPreviewRoundTrip asserts that the plan preview response is the same
+// after being encoded as it's sent to piped and decoded back.
+func AssertPlanPreviewRoundTrip(t testing.TB, resp *sdk.GetPlanPreviewResponse) bool {
+	t.Helper()
+
+	msg, ok := roundTrip(t, sdk.PlanPreviewResponseToProto(resp))
+	if !ok {
+		return false
+	}
+	return assertRoundTrip(t, resp, sdk.PlanPreviewResponseFromProto(msg))
+}
+
+// AssertLivestateRoundTrip asserts that the livestate response is the same
+// after being encoded as it's sent to piped and decoded back.
+// The times are compared in seconds, since the sub-second part is not sent to piped.
+func AssertLivestateRoundTrip(t testing.TB, resp *sdk.GetLivestateResponse) bool {
+	t.Helper()
+
+	msg, ok := roundTrip(t, sdk.LivestateResponseToProto(wirePluginName, time.Unix(fixtureTime, 0), resp))
+	if !ok {
+		return false
+	}
+	return assertRoundTrip(t, resp, sdk.LivestateResponseFromProto(msg))
+}
+
+// AssertPipelineSyncStagesRoundTrip asserts that the response to build the pipeline sync stages for the request is the same
+// after being encoded as it's sent to piped and decoded back.
+func AssertPipelineSyncStagesRoundTrip(t testing.TB, req sdk.BuildPipelineSyncStagesRequest, resp *sdk.BuildPipelineSyncStagesResponse) bool {
+	t.Helper()
+
+	converted, err := sdk.PipelineSyncStagesResponseToProto(time.Unix(fixtureTime, 0), req, resp)
+	if err != nil {
+		t.Errorf("failed to convert the response: %s", err)
+		return false
+	}
+	msg, ok := roundTrip(t, converted)
+	if !ok {
+		return false
+	}
+	return assertRoundTrip(t, resp, sdk.PipelineSyncStagesResponseFromProto(msg))
+}
+
+// AssertQuickSyncStagesRoundTrip asserts that the response to build the quick sync stages is the same
+// after being encoded as it's sent to piped and decoded back.
+func AssertQuickSyncStagesRoundTrip(t testing.TB, resp *sdk.BuildQuickSyncStagesResponse) bool {
+	t.Helper()
+
+	msg, ok := roundTrip(t, sdk.QuickSyncStagesResponseToProto(time.Unix(fixtureTime, 0), resp))
+	if !ok {
+		return false
+	}
+	return assertRoundTrip(t, resp, sdk.QuickSyncStagesResponseFromProto(msg))
+}
+
+// AssertDetermineVersionsRoundTrip asserts that the response to determine the versions is the same
+// after being encoded as it's sent to piped and decoded back.
+func AssertDetermineVersionsRoundTrip(t testing.TB, resp *sdk.DetermineVersionsResponse) bool {
+	t.Helper()
+
+	msg, ok := roundTrip(t, sdk.DetermineVersionsResponseToProto(resp))
+	if !ok {
+		return false
+	}
+	return assertRoundTrip(t, resp, sdk.DetermineVersionsResponseFromProto(msg))
+}
+
+// AssertDetermineStrategyRoundTrip asserts that the response to determine the strategy is the same
+// after being encoded as it's sent to piped and decoded back.
+func AssertDetermineStrategyRoundTrip(t testing.TB, resp *sdk.DetermineStrategyResponse) bool {
+	t.Helper()
+
+	converted, err := sdk.DetermineStrategyResponseToProto(resp)
+	if err != nil {
+		t.Errorf("failed to convert the response: %s", err)
+		return false
+	}
+	msg, ok := roundTrip(t, converted)
+	if !ok {
+		return false
+	}
+	return assertRoundTrip(t, resp, sdk.DetermineStrategyResponseFromProto(msg))
+}
+
+// AssertProtoGolden compares the message sent to piped with the golden file in the form of MarshalProto.
+// Use it with the XxxToProto functions of the SDK to snapshot what the plugin sends, e.g.
+//
+//	sdktest.AssertProtoGolden(t, "testdata/livestate.json", sdk.LivestateResponseToProto("example", now, resp))
+func AssertProtoGolden(t testing.TB, filename string, msg proto.Message) {
+	t.Helper()
+
+	data, err := MarshalProto(msg)
+	if err != nil {
+		t.Fatalf("failed to marshal the message: %s", err)
+	}
+	AssertGolden(t, filename, data)
+}
+
+// MarshalProto serializes the message into the indented JSON deterministically.
+func MarshalProto(msg proto.Message) ([]byte, error) {
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the message into JSON: %w", err)
+	}
+	// protojson randomizes the whitespaces on purpose, so normalize them.
+	var b bytes.Buffer
+	if err := json.Indent(&b, data, "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to indent the JSON: %w", err)
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// roundTrip encodes the message into the wire format and decodes it back.
+func roundTrip[M proto.Message](t testing.TB, msg M) (M, bool) {
+	t.Helper()
+
+	decoded := msg.ProtoReflect().New().Interface().(M)
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Errorf("failed to encode the message: %s", err)
+		return decoded, false
+	}
+	if err := proto.Unmarshal(data, decoded); err != nil {
+		t.Errorf("failed to decode the message: %s", err)
+		return decoded, false
+	}
+	return decoded, true
+}
+
+// assertRoundTrip compares the values treating the nil and the empty slices and maps as equal,
+// and the times in seconds.
+func assertRoundTrip(t testing.TB, expected, actual any) bool {
+	t.Helper()
+
+	return assert.Equal(t,
+		normalizeWire(reflect.ValueOf(expected)).Interface(),
+		normalizeWire(reflect.ValueOf(actual)).Interface(),
+		"the response changes after being sent to piped",
+	)
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// normalizeWire returns the copy of the value in the form which survives the round-trip.
+func normalizeWire(v reflect.Value) reflect.Value {
+	switch {
+	case !v.IsValid():
+		return v
+	case v.Type() == timeType:
+		return reflect.ValueOf(time.Unix(v.Interface().(time.Time).Unix(), 0).UTC())
+	}
+
+	out := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			p := reflect.New(v.Type().Elem())
+			p.Elem().Set(normalizeWire(v.Elem()))
+			out.Set(p)
+		}
+	case reflect.Slice:
+		if v.Len() > 0 {
+			s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+			for i := range v.Len() {
+				s.Index(i).Set(normalizeWire(v.Index(i)))
+			}
+			out.Set(s)
+		}
+	case reflect.Map:
+		if v.Len() > 0 {
+			m := reflect.MakeMapWithSize(v.Type(), v.Len())
+			for iter := v.MapRange(); iter.Next(); {
+				m.SetMapIndex(iter.Key(), normalizeWire(iter.Value()))
+			}
+			out.Set(m)
+		}
+	case reflect.Struct:
+		out.Set(v)
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				out.Field(i).Set(normalizeWire(v.Field(i)))
+			}
+		}
+	default:
+		out.Set(v)
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	sdk "github.com/pipe-cd/piped-plugin-sdk-go"
+)
+
+func TestAssertRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	AssertPlanPreviewRoundTrip(t, &sdk.GetPlanPreviewResponse{
+		Results: []sdk.PlanPreviewResult{
+			{DeployTarget: "dt", Summary: "summary", Details: []byte("details")},
+			// The empty details are nil on the wire.
+			{DeployTarget: "dt2", NoChange: true, Details: []byte{}},
+		},
+	})
+	AssertLivestateRoundTrip(t, &sdk.GetLivestateResponse{
+		LiveState: sdk.ApplicationLiveState{
+			Resources: []sdk.ResourceState{
+				{
+					ID:               "id",
+					ResourceMetadata: map[string]string{},
+					HealthStatus:     sdk.ResourceHealthStateHealthy,
+					// The sub-second part is ignored.
+					CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 6, time.FixedZone("JST", 9*60*60)),
+				},
+			},
+		},
+		SyncState: sdk.ApplicationSyncState{Status: sdk.ApplicationSyncStateSynced},
+	})
+	AssertPipelineSyncStagesRoundTrip(t,
+		sdk.BuildPipelineSyncStagesRequest{Stages: []sdk.StageConfig{{Index: 0, Name: "STAGE"}}},
+		&sdk.BuildPipelineSyncStagesResponse{
+			Stages: []sdk.PipelineStage{{Index: 0, Name: "STAGE", AvailableOperation: sdk.ManualOperationApprove}},
+		},
+	)
+	AssertQuickSyncStagesRoundTrip(t, &sdk.BuildQuickSyncStagesResponse{
+		Stages: []sdk.QuickSyncStage{{Name: "SYNC", Description: "sync"}},
+	})
+	AssertDetermineVersionsRoundTrip(t, &sdk.DetermineVersionsResponse{
+		Versions: []sdk.ArtifactVersion{{Version: "v1", Name: "image"}},
+	})
+	AssertDetermineStrategyRoundTrip(t, &sdk.DetermineStrategyResponse{Strategy: sdk.SyncStrategyQuickSync})
+}
+
+func TestAssertRoundTrip_Failure(t *testing.T) {
+	t.Parallel()
+
+	// The unknown manual operation is sent as MANUAL_OPERATION_UNKNOWN and decoded as none.
+	ft := &fakeT{TB: t}
+	ok := AssertQuickSyncStagesRoundTrip(ft, &sdk.BuildQuickSyncStagesResponse{
+		Stages: []sdk.QuickSyncStage{{Name: "SYNC", AvailableOperation: sdk.ManualOperation(99)}},
+	})
+	assert.False(t, ok)
+	assert.True(t, ft.failed)
+
+	// The stage not in the request can't be sent.
+	ft = &fakeT{TB: t}
+	ok = AssertPipelineSyncStagesRoundTrip(ft, sdk.BuildPipelineSyncStagesRequest{}, &sdk.BuildPipelineSyncStagesResponse{
+		Stages: []sdk.PipelineStage{{Index: 1, Name: "STAGE"}},
+	})
+	assert.False(t, ok)
+	assert.True(t, ft.failed)
+}
+
+func TestAssertProtoGolden(t *testing.T) {
+	t.Parallel()
+
+	resp := &sdk.GetLivestateResponse{
+		LiveState: sdk.ApplicationLiveState{
+			Resources: []sdk.ResourceState{
+				{
+					ID:               "id",
+					Name:             "name",
+					ResourceType:     "Pod",
+					ResourceMetadata: map[string]string{"b": "2", "a": "1"},
+					HealthStatus:     sdk.ResourceHealthStateHealthy,
+					DeployTarget:     "dt",
+					CreatedAt:        time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		SyncState: sdk.ApplicationSyncState{Status: sdk.ApplicationSyncStateSynced},
+	}
+	now := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	AssertProtoGolden(t, "testdata/golden/livestate.json", sdk.LivestateResponseToProto("example", now, resp))
+}
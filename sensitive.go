@@ -0,0 +1,478 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+)
+
+// sensitiveTag is the struct tag to mark the fields of the config types as sensitive, e.g.
+//
+//	Password string `json:"password" sensitive:"true"`
+//
+// The values of the sensitive fields and the Credential fields are masked in the logs written through the SDK,
+// the stage logs, and the error messages returned to piped.
+// The string fields, and the strings in the slices and the maps are supported.
+// The values shorter than minSensitiveValueLength (4) bytes are masked only in the config logged at startup,
+// since masking them everywhere would mask the unrelated parts of the logs.
+const sensitiveTag = "sensitive"
+
+// minSensitiveValueLength is the minimum length of the values to be masked.
+// The shorter values are not masked to avoid masking the unrelated parts of the logs.
+const minSensitiveValueLength = 4
+
+var credentialType = reflect.TypeFor[Credential]()
+
+// sensitiveValues returns the values of the sensitive fields in the given value.
+func sensitiveValues(v any) []string {
+	var values []string
+	collectSensitiveValues(reflect.ValueOf(v), false, &values)
+	return values
+}
+
+func collectSensitiveValues(v reflect.Value, sensitive bool, values *[]string) {
+	if !v.IsValid() {
+		return
+	}
+	if v.Type() == credentialType {
+		*values = append(*values, v.Interface().(Credential).Value())
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			collectSensitiveValues(v.Elem(), sensitive, values)
+		}
+	case reflect.String:
+		if sensitive {
+			*values = append(*values, v.String())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			collectSensitiveValues(v.Field(i), sensitive || t.Field(i).Tag.Get(sensitiveTag) == "true", values)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectSensitiveValues(v.Index(i), sensitive, values)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectSensitiveValues(iter.Value(), sensitive, values)
+		}
+	}
+}
+
+// sensitivePaths returns the JSON paths of the sensitive fields and the Credential fields in the given value, e.g. ["auth", "password"].
+// The path of a sensitive field covers all the values under it.
+func sensitivePaths(v any) [][]string {
+	var paths [][]string
+	collectSensitivePaths(reflect.ValueOf(v), nil, &paths)
+	return paths
+}
+
+func collectSensitivePaths(v reflect.Value, path []string, paths *[][]string) {
+	if !v.IsValid() {
+		return
+	}
+	if v.Type() == credentialType {
+		*paths = append(*paths, slices.Clone(path))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			collectSensitivePaths(v.Elem(), path, paths)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, ok := jsonFieldName(f)
+			if !ok {
+				continue
+			}
+			p := path
+			if name != "" {
+				p = append(slices.Clone(path), name)
+			}
+			if f.Tag.Get(sensitiveTag) == "true" {
+				*paths = append(*paths, p)
+				continue
+			}
+			collectSensitivePaths(v.Field(i), p, paths)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectSensitivePaths(v.Index(i), append(slices.Clone(path), strconv.Itoa(i)), paths)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectSensitivePaths(iter.Value(), append(slices.Clone(path), fmt.Sprint(iter.Key().Interface())), paths)
+		}
+	}
+}
+
+// jsonFieldName returns the name of the struct field in JSON, or false if it's not encoded.
+// The name is empty for the embedded struct, whose fields are encoded in the parent.
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	t := f.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if f.Anonymous && name == "" && t.Kind() == reflect.Struct {
+		return "", true
+	}
+	if !f.IsExported() {
+		return "", false
+	}
+	if name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+// maskSensitiveJSON encodes the given value in JSON with the values of the sensitive fields and the Credential fields masked.
+// Unlike redactor, it masks the fields structurally, so the values shorter than minSensitiveValueLength are masked as well.
+func maskSensitiveJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var obj any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	for _, p := range sensitivePaths(v) {
+		obj = maskJSONPath(obj, p)
+	}
+	return json.Marshal(obj)
+}
+
+// maskJSONPath masks the value at the given path in the decoded JSON value if it's present and not empty.
+func maskJSONPath(v any, path []string) any {
+	if len(path) == 0 {
+		if v == nil || v == "" {
+			return v
+		}
+		return maskedCredential
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		if e, ok := t[path[0]]; ok {
+			t[path[0]] = maskJSONPath(e, path[1:])
+		}
+	case []any:
+		if i, err := strconv.Atoi(path[0]); err == nil && i >= 0 && i < len(t) {
+			t[i] = maskJSONPath(t[i], path[1:])
+		}
+	}
+	return v
+}
+
+// redactor masks the sensitive values in the strings.
+// The zero value masks nothing.
+type redactor struct {
+	mu       sync.RWMutex
+	values   []string
+	replacer *strings.Replacer
+}
+
+// add adds the values to be masked to the current ones.
+func (r *redactor) add(values []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apply(append(slices.Clone(r.values), values...))
+}
+
+// set replaces the values to be masked.
+func (r *redactor) set(values []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apply(values)
+}
+
+// apply builds the replacer masking the given values. The caller must hold the lock.
+func (r *redactor) apply(values []string) {
+	var targets []string
+	for _, v := range values {
+		if len(v) < minSensitiveValueLength {
+			continue
+		}
+		targets = append(targets, v)
+		// Mask the value in the JSON outputs as well.
+		if escaped, _ := json.Marshal(v); string(escaped[1:len(escaped)-1]) != v {
+			targets = append(targets, string(escaped[1:len(escaped)-1]))
+		}
+	}
+	// Mask the longer values first, since one value may contain another.
+	slices.SortFunc(targets, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+	targets = slices.Compact(targets)
+
+	oldnew := make([]string, 0, len(targets)*2)
+	for _, t := range targets {
+		oldnew = append(oldnew, t, maskedCredential)
+	}
+
+	r.values = values
+	if len(oldnew) == 0 {
+		r.replacer = nil
+		return
+	}
+	r.replacer = strings.NewReplacer(oldnew...)
+}
+
+// empty returns true if the redactor masks nothing.
+func (r *redactor) empty() bool {
+	if r == nil {
+		return true
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.replacer == nil
+}
+
+// redact returns the string with the sensitive values masked.
+func (r *redactor) redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// redactError returns the error with the sensitive values masked in its message.
+// The code of the gRPC status error is kept.
+func (r *redactor) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	redacted := r.redact(msg)
+	if redacted == msg {
+		return err
+	}
+	if s, ok := status.FromError(err); ok {
+		return status.Error(s.Code(), r.redact(s.Message()))
+	}
+	return fmt.Errorf("%s", redacted)
+}
+
+// redactingCore is a zapcore.Core masking the sensitive values in the messages and the fields.
+type redactingCore struct {
+	zapcore.Core
+	redactor *redactor
+}
+
+func newRedactingCore(core zapcore.Core, r *redactor) zapcore.Core {
+	return &redactingCore{Core: core, redactor: r}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redactFields(fields)), redactor: c.redactor}
+}
+
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.redactor.redact(ent.Message)
+	return c.Core.Write(ent, c.redactFields(fields))
+}
+
+// redactFields returns the fields with the sensitive values masked.
+// The structured fields, e.g. the ones given by zap.Any, zap.Object or zap.Strings, are encoded as JSON to be masked,
+// and replaced with the masked string when they can't be encoded.
+func (c *redactingCore) redactFields(fields []zapcore.Field) []zapcore.Field {
+	if c.redactor.empty() {
+		return fields
+	}
+	redacted := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = c.redactor.redact(f.String)
+		case zapcore.ByteStringType:
+			if b, ok := f.Interface.([]byte); ok {
+				f.Interface = []byte(c.redactor.redact(string(b)))
+			}
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok {
+				f = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: c.redactor.redact(err.Error())}
+			}
+		case zapcore.StringerType:
+			if s, ok := f.Interface.(fmt.Stringer); ok {
+				f = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: c.redactor.redact(s.String())}
+			}
+		case zapcore.ReflectType, zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+			f = c.redactStructuredField(f)
+		case zapcore.InlineMarshalerType:
+			// The inlined fields are added to the entry one by one.
+			enc := zapcore.NewMapObjectEncoder()
+			f.AddTo(enc)
+			for _, k := range slices.Sorted(maps.Keys(enc.Fields)) {
+				redacted = append(redacted, c.redactStructuredField(zap.Any(k, enc.Fields[k])))
+			}
+			continue
+		}
+		redacted = append(redacted, f)
+	}
+	return redacted
+}
+
+// redactStructuredField encodes the field as JSON, and returns the field with the sensitive values masked in it.
+func (c *redactingCore) redactStructuredField(f zapcore.Field) zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	data, err := json.Marshal(enc.Fields[f.Key])
+	if err != nil {
+		return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: maskedCredential}
+	}
+	redacted := c.redactor.redact(string(data))
+	if !json.Valid([]byte(redacted)) {
+		return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: maskedCredential}
+	}
+	return zapcore.Field{Key: f.Key, Type: zapcore.ReflectType, Interface: json.RawMessage(redacted)}
+}
+
+// redactingLogPersister is a logPersister masking the sensitive values in the stage logs.
+type redactingLogPersister struct {
+	logPersister
+	redactor *redactor
+}
+
+func (p redactingLogPersister) StageLogPersister(deploymentID, stageID string) logpersister.StageLogPersister {
+	return redactingStageLogPersister{
+		base:     p.logPersister.StageLogPersister(deploymentID, stageID),
+		redactor: p.redactor,
+	}
+}
+
+// redactingStageLogPersister is a StageLogPersister masking the sensitive values in the stage logs.
+type redactingStageLogPersister struct {
+	base     logpersister.StageLogPersister
+	redactor *redactor
+}
+
+// The logs are forwarded as they are when there is nothing to mask,
+// so that the allocation-free path of the base StageLogPersister is kept.
+func (p redactingStageLogPersister) Write(log []byte) (int, error) {
+	if p.redactor.empty() {
+		return p.base.Write(log)
+	}
+	// Pass the redacted string as is to avoid copying it back to bytes.
+	p.base.Info(p.redactor.redact(string(log)))
+	// Report the length of the given log as written, since it's what the io.Writer expects.
+	return len(log), nil
+}
+
+func (p redactingStageLogPersister) Info(log string) {
+	p.base.Info(p.redactor.redact(log))
+}
+
+func (p redactingStageLogPersister) Infof(format string, a ...interface{}) {
+	if p.redactor.empty() {
+		p.base.Infof(format, a...)
+		return
+	}
+	p.base.Info(p.redactor.redact(fmt.Sprintf(format, a...)))
+}
+
+func (p redactingStageLogPersister) Success(log string) {
+	p.base.Success(p.redactor.redact(log))
+}
+
+func (p redactingStageLogPersister) Successf(format string, a ...interface{}) {
+	if p.redactor.empty() {
+		p.base.Successf(format, a...)
+		return
+	}
+	p.base.Success(p.redactor.redact(fmt.Sprintf(format, a...)))
+}
+
+func (p redactingStageLogPersister) Error(log string) {
+	p.base.Error(p.redactor.redact(log))
+}
+
+func (p redactingStageLogPersister) Errorf(format string, a ...interface{}) {
+	if p.redactor.empty() {
+		p.base.Errorf(format, a...)
+		return
+	}
+	p.base.Error(p.redactor.redact(fmt.Sprintf(format, a...)))
+}
+
+func (p redactingStageLogPersister) Complete(timeout time.Duration) error {
+	return p.base.Complete(timeout)
+}
+
+// redactingRegistrar registers the services masking the sensitive values in the errors returned to piped.
+type redactingRegistrar struct {
+	grpc.ServiceRegistrar
+	redactor *redactor
+}
+
+func (r redactingRegistrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	wrapped := *desc
+	wrapped.Methods = make([]grpc.MethodDesc, 0, len(desc.Methods))
+	for _, m := range desc.Methods {
+		handler := m.Handler
+		m.Handler = func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			resp, err := handler(srv, ctx, dec, interceptor)
+			return resp, r.redactor.redactError(err)
+		}
+		wrapped.Methods = append(wrapped.Methods, m)
+	}
+	r.ServiceRegistrar.RegisterService(&wrapped, impl)
+}
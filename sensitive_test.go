@@ -0,0 +1,333 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+)
+
+type sensitiveNested struct {
+	Keys []string `json:"keys" sensitive:"true"`
+}
+
+type sensitiveConfig struct {
+	Name     string                     `json:"name"`
+	Password string                     `json:"password" sensitive:"true"`
+	Token    Credential                 `json:"token"`
+	Nested   *sensitiveNested           `json:"nested"`
+	Headers  map[string]string          `json:"headers" sensitive:"true"`
+	Targets  map[string]sensitiveNested `json:"targets"`
+}
+
+func TestSensitiveValues(t *testing.T) {
+	t.Parallel()
+
+	cfg := &sensitiveConfig{
+		Name:     "public",
+		Password: "password",
+		Token:    Credential{value: "token-value"},
+		Nested:   &sensitiveNested{Keys: []string{"key-1"}},
+		Headers:  map[string]string{"Authorization": "Bearer header"},
+		Targets:  map[string]sensitiveNested{"dt": {Keys: []string{"key-2"}}},
+	}
+	assert.ElementsMatch(t, []string{"password", "token-value", "key-1", "Bearer header", "key-2"}, sensitiveValues(cfg))
+	assert.Empty(t, sensitiveValues(nil))
+}
+
+func TestMaskSensitiveJSON(t *testing.T) {
+	t.Parallel()
+
+	type embedded struct {
+		Secret string `json:"secret" sensitive:"true"`
+	}
+	type config struct {
+		sensitiveConfig
+		*embedded
+		Short string `json:"short" sensitive:"true"`
+		Empty string `json:"empty" sensitive:"true"`
+	}
+	cfg := &config{
+		sensitiveConfig: sensitiveConfig{
+			Name:     "public",
+			Password: "password",
+			Token:    Credential{value: "token-value"},
+			Nested:   &sensitiveNested{Keys: []string{"key-1"}},
+			Headers:  map[string]string{"Authorization": "Bearer header"},
+			Targets:  map[string]sensitiveNested{"dt": {Keys: []string{"key-2"}}},
+		},
+		embedded: &embedded{Secret: "s"},
+		Short:    "abc",
+	}
+
+	// The values shorter than minSensitiveValueLength are masked as well.
+	data, err := maskSensitiveJSON(cfg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "public",
+		"password": "******",
+		"token": "******",
+		"nested": {"keys": "******"},
+		"headers": "******",
+		"targets": {"dt": {"keys": "******"}},
+		"secret": "******",
+		"short": "******",
+		"empty": ""
+	}`, string(data))
+}
+
+func TestRedactor(t *testing.T) {
+	t.Parallel()
+
+	r := &redactor{}
+	assert.Equal(t, "password", r.redact("password"))
+
+	r.set([]string{"password", "pass", "abc", `quo"te`})
+	assert.Equal(t, "user:****** short:abc", r.redact("user:password short:abc"))
+	assert.Equal(t, "******", r.redact("pass"))
+	// The value escaped in JSON is masked as well.
+	assert.Equal(t, `{"v":"******"}`, r.redact(`{"v":"quo\"te"}`))
+
+	var nilRedactor *redactor
+	assert.Equal(t, "password", nilRedactor.redact("password"))
+}
+
+func TestRedactor_Add(t *testing.T) {
+	t.Parallel()
+
+	r := &redactor{}
+	r.add([]string{"password"})
+	r.add([]string{"secret"})
+	assert.Equal(t, "****** ******", r.redact("password secret"))
+
+	r.set([]string{"token"})
+	assert.Equal(t, "password ******", r.redact("password token"))
+}
+
+func TestRedactor_RedactError(t *testing.T) {
+	t.Parallel()
+
+	r := &redactor{}
+	r.set([]string{"secret"})
+
+	assert.NoError(t, r.redactError(nil))
+
+	err := errors.New("nothing to mask")
+	assert.Same(t, err, r.redactError(err))
+
+	assert.EqualError(t, r.redactError(fmt.Errorf("failed with secret")), "failed with ******")
+
+	redacted := r.redactError(status.Error(codes.InvalidArgument, "invalid secret"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(redacted))
+	assert.Equal(t, "invalid ******", status.Convert(redacted).Message())
+}
+
+func TestRedactingCore(t *testing.T) {
+	t.Parallel()
+
+	r := &redactor{}
+	r.set([]string{"secret"})
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core).WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newRedactingCore(core, r)
+	}))
+
+	logger.With(zap.String("with", "with secret")).Info("message with secret",
+		zap.String("string", "secret"),
+		zap.Error(errors.New("error with secret")),
+		zap.Stringer("stringer", Credential{value: "x", source: "secret"}),
+		zap.Int("int", 1),
+		zap.ByteString("bytes", []byte("bytes with secret")),
+		zap.Any("any", map[string]string{"key": "secret"}),
+		zap.Strings("strings", []string{"value", "secret"}),
+		zap.Object("object", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("password", "secret")
+			return nil
+		})),
+	)
+	logger.Debug("secret")
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "message with ******", entries[0].Message)
+	assert.Equal(t, map[string]any{
+		"with":     "with ******",
+		"string":   "******",
+		"error":    "error with ******",
+		"stringer": "******",
+		"int":      int64(1),
+		"bytes":    "bytes with ******",
+		"any":      json.RawMessage(`{"key":"******"}`),
+		"strings":  json.RawMessage(`["value","******"]`),
+		"object":   json.RawMessage(`{"password":"******"}`),
+	}, entries[0].ContextMap())
+}
+
+type recordingStageLogPersister struct {
+	logs []string
+}
+
+func (p *recordingStageLogPersister) Write(log []byte) (int, error) {
+	p.logs = append(p.logs, string(log))
+	return len(log), nil
+}
+func (p *recordingStageLogPersister) Info(log string)    { p.logs = append(p.logs, log) }
+func (p *recordingStageLogPersister) Success(log string) { p.logs = append(p.logs, log) }
+func (p *recordingStageLogPersister) Error(log string)   { p.logs = append(p.logs, log) }
+func (p *recordingStageLogPersister) Infof(format string, a ...interface{}) {
+	p.Info(fmt.Sprintf(format, a...))
+}
+func (p *recordingStageLogPersister) Successf(format string, a ...interface{}) {
+	p.Success(fmt.Sprintf(format, a...))
+}
+func (p *recordingStageLogPersister) Errorf(format string, a ...interface{}) {
+	p.Error(fmt.Sprintf(format, a...))
+}
+func (p *recordingStageLogPersister) Complete(time.Duration) error { return nil }
+
+type recordingLogPersister struct {
+	slp *recordingStageLogPersister
+}
+
+func (p recordingLogPersister) StageLogPersister(string, string) logpersister.StageLogPersister {
+	return p.slp
+}
+
+func TestRedactingLogPersister(t *testing.T) {
+	t.Parallel()
+
+	r := &redactor{}
+	r.set([]string{"secret"})
+
+	base := &recordingStageLogPersister{}
+	slp := redactingLogPersister{logPersister: recordingLogPersister{slp: base}, redactor: r}.StageLogPersister("deployment", "stage")
+
+	n, err := slp.Write([]byte("write secret"))
+	require.NoError(t, err)
+	assert.Equal(t, len("write secret"), n)
+	slp.Info("info secret")
+	slp.Infof("infof %s", "secret")
+	slp.Success("success secret")
+	slp.Successf("successf %s", "secret")
+	slp.Error("error secret")
+	slp.Errorf("errorf %s", "secret")
+
+	for _, log := range base.logs {
+		assert.NotContains(t, log, "secret")
+	}
+	assert.Equal(t, "infof ******", base.logs[2])
+
+	// The logs are forwarded as they are when there is nothing to mask.
+	base = &recordingStageLogPersister{}
+	slp = redactingLogPersister{logPersister: recordingLogPersister{slp: base}, redactor: &redactor{}}.StageLogPersister("deployment", "stage")
+	slp.Write([]byte("write"))
+	slp.Infof("infof %s", "value")
+	assert.Equal(t, []string{"write", "infof value"}, base.logs)
+}
+
+func BenchmarkRedactingStageLogPersister(b *testing.B) {
+	line := []byte(strings.Repeat("x", 100))
+	benchmarks := []struct {
+		name   string
+		values []string
+		log    func(sp logpersister.StageLogPersister)
+	}{
+		{
+			name: "Write",
+			log:  func(sp logpersister.StageLogPersister) { sp.Write(line) },
+		},
+		{
+			name: "Infof",
+			log:  func(sp logpersister.StageLogPersister) { sp.Infof("line %d of %s", 1, "stage") },
+		},
+		{
+			name:   "Write with sensitive values",
+			values: []string{"secret"},
+			log:    func(sp logpersister.StageLogPersister) { sp.Write(line) },
+		},
+		{
+			name:   "Infof with sensitive values",
+			values: []string{"secret"},
+			log:    func(sp logpersister.StageLogPersister) { sp.Infof("line %d of %s", 1, "stage") },
+		},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			r := &redactor{}
+			r.set(bm.values)
+			p := redactingLogPersister{logPersister: logpersister.NewPersister(newFakePluginServiceClient(), zap.NewNop()), redactor: r}
+			sp := p.StageLogPersister("deployment-1", "stage-1")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bm.log(sp)
+			}
+		})
+	}
+}
+
+type fakeServiceRegistrar struct {
+	desc *grpc.ServiceDesc
+}
+
+func (r *fakeServiceRegistrar) RegisterService(desc *grpc.ServiceDesc, _ any) {
+	r.desc = desc
+}
+
+func TestRedactingRegistrar(t *testing.T) {
+	t.Parallel()
+
+	r := &redactor{}
+	r.set([]string{"secret"})
+
+	desc := &grpc.ServiceDesc{
+		ServiceName: "test.Service",
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Fail",
+				Handler: func(any, context.Context, func(any) error, grpc.UnaryServerInterceptor) (any, error) {
+					return nil, status.Error(codes.Internal, "failed with secret")
+				},
+			},
+		},
+	}
+	fake := &fakeServiceRegistrar{}
+	redactingRegistrar{ServiceRegistrar: fake, redactor: r}.RegisterService(desc, nil)
+
+	require.Len(t, fake.desc.Methods, 1)
+	_, err := fake.desc.Methods[0].Handler(nil, context.Background(), nil, nil)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.False(t, strings.Contains(err.Error(), "secret"))
+	// The original descriptor is not modified.
+	_, err = desc.Methods[0].Handler(nil, context.Background(), nil, nil)
+	assert.Contains(t, err.Error(), "secret")
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	stageKey  = "stage"
+	statusKey = "status"
+
+	statusSuccess = "success"
+	statusFailure = "failure"
+	// statusError is the status of the stage which the plugin returned an error for.
+	statusError = "error"
+)
+
+var (
+	stageExecutionSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_stage_execution_seconds",
+			Help:    "Histogram of the seconds taken to execute a stage.",
+			Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600},
+		},
+		[]string{stageKey, statusKey},
+	)
+	stageExecutionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_stage_executions_total",
+			Help: "Total number of executed stages grouped by the status.",
+		},
+		[]string{stageKey, statusKey},
+	)
+	livestateSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_livestate_seconds",
+			Help:    "Histogram of the seconds taken to get the live state of an application.",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60},
+		},
+		[]string{statusKey},
+	)
+	planPreviewSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_plan_preview_seconds",
+			Help:    "Histogram of the seconds taken to get the plan preview of an application.",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60},
+		},
+		[]string{statusKey},
+	)
+	stageQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "plugin_stage_queue_depth",
+			Help: "Number of the stages waiting for the slot to be executed.",
+		},
+	)
+	stageQueueTimeoutsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "plugin_stage_queue_timeouts_total",
+			Help: "Total number of the stages rejected since they waited for the slot longer than the queue timeout.",
+		},
+	)
+)
+
+// stageExecuted records the status and the duration of a stage execution.
+func stageExecuted(stage string, resp *ExecuteStageResponse, err error, d time.Duration) {
+	status := statusError
+	if err == nil && resp != nil {
+		// e.g. STAGE_SUCCESS to success.
+		status = strings.ToLower(strings.TrimPrefix(resp.Status.toModelEnum().String(), "STAGE_"))
+	}
+	labels := prometheus.Labels{stageKey: stage, statusKey: status}
+	stageExecutionSeconds.With(labels).Observe(d.Seconds())
+	stageExecutionsTotal.With(labels).Inc()
+}
+
+// livestateGot records the duration to get the live state.
+func livestateGot(err error, d time.Duration) {
+	livestateSeconds.With(prometheus.Labels{statusKey: outcomeStatus(err)}).Observe(d.Seconds())
+}
+
+// planPreviewGot records the duration to get the plan preview.
+func planPreviewGot(err error, d time.Duration) {
+	planPreviewSeconds.With(prometheus.Labels{statusKey: outcomeStatus(err)}).Observe(d.Seconds())
+}
+
+func outcomeStatus(err error) string {
+	if err != nil {
+		return statusFailure
+	}
+	return statusSuccess
+}
+
+// registerServerMetrics registers the metrics of the requests from piped to the given registerer.
+func registerServerMetrics(r prometheus.Registerer) {
+	r.MustRegister(
+		stageExecutionSeconds,
+		stageExecutionsTotal,
+		livestateSeconds,
+		planPreviewSeconds,
+		stageQueueDepth,
+		stageQueueTimeoutsTotal,
+	)
+}
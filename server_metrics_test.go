@@ -0,0 +1,73 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStageExecuted(t *testing.T) {
+	t.Parallel()
+
+	testcases := []struct {
+		name     string
+		resp     *ExecuteStageResponse
+		err      error
+		expected string
+	}{
+		{name: "success", resp: &ExecuteStageResponse{Status: StageStatusSuccess}, expected: "success"},
+		{name: "failure", resp: &ExecuteStageResponse{Status: StageStatusFailure}, expected: "failure"},
+		{name: "exited", resp: &ExecuteStageResponse{Status: StageStatusExited}, expected: "exited"},
+		{name: "skipped", resp: &ExecuteStageResponse{Status: StageStatusSkipped}, expected: "skipped"},
+		{name: "invalid status", resp: &ExecuteStageResponse{}, expected: "failure"},
+		{name: "error", err: errors.New("error"), expected: "error"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Use the stage name unique to the case since the metrics are global.
+			stage := "TEST_STAGE_EXECUTED_" + tc.name
+			stageExecuted(stage, tc.resp, tc.err, time.Second)
+			labels := prometheus.Labels{stageKey: stage, statusKey: tc.expected}
+			assert.Equal(t, 1.0, testutil.ToFloat64(stageExecutionsTotal.With(labels)))
+		})
+	}
+}
+
+func TestRegisterServerMetrics(t *testing.T) {
+	t.Parallel()
+
+	stageExecuted("TEST_REGISTER_SERVER_METRICS", &ExecuteStageResponse{Status: StageStatusSuccess}, nil, time.Second)
+	livestateGot(nil, time.Second)
+	planPreviewGot(errors.New("error"), time.Second)
+
+	families, err := registerMetrics("example", "v0.0.1").Gather()
+	require.NoError(t, err)
+	names := make(map[string]bool, len(families))
+	for _, f := range families {
+		names[f.GetName()] = true
+	}
+	for _, name := range []string{"plugin_stage_execution_seconds", "plugin_stage_executions_total", "plugin_livestate_seconds", "plugin_plan_preview_seconds"} {
+		assert.True(t, names[name], "%s is not registered", name)
+	}
+}
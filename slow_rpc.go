@@ -0,0 +1,171 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+var (
+	slowRPCsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_slow_rpcs_total",
+			Help: "Total number of the RPCs from piped taking longer than the slow RPC threshold.",
+		},
+		[]string{methodKey},
+	)
+)
+
+// registerSlowRPCMetrics registers the metrics of the slow RPCs to the given registerer.
+func registerSlowRPCMetrics(r prometheus.Registerer) {
+	r.MustRegister(slowRPCsTotal)
+}
+
+// defaultSlowRPCThresholds are the thresholds of the methods which take longer than the others.
+var defaultSlowRPCThresholds = map[string]time.Duration{
+	// A stage may wait for the resources to be ready, or for the approval.
+	"ExecuteStage": 30 * time.Minute,
+}
+
+// slowRPCDetector warns about the RPCs from piped taking longer than the thresholds.
+type slowRPCDetector struct {
+	// threshold is the threshold of the methods not in methodThresholds.
+	threshold time.Duration
+	// methodThresholds are the thresholds keyed by the method name without the service name, e.g. "ExecuteStage".
+	// A zero threshold disables the detection for the method.
+	methodThresholds map[string]time.Duration
+	clock            clock.Clock
+	logger           *zap.Logger
+}
+
+// newSlowRPCDetector returns the detector with the given thresholds.
+// The method thresholds are given as the map from the method name to the duration string, e.g. "GetLivestate": "30s".
+func newSlowRPCDetector(threshold time.Duration, methodThresholds map[string]string, clk clock.Clock, logger *zap.Logger) (*slowRPCDetector, error) {
+	parsed, err := parseMethodDurations(defaultSlowRPCThresholds, methodThresholds, "slow RPC threshold")
+	if err != nil {
+		return nil, err
+	}
+	return &slowRPCDetector{
+		threshold:        threshold,
+		methodThresholds: parsed,
+		clock:            clock.OrReal(clk),
+		logger:           logger.Named("slow-rpc"),
+	}, nil
+}
+
+// thresholdOf returns the threshold of the method.
+func (d *slowRPCDetector) thresholdOf(method string) time.Duration {
+	if t, ok := d.methodThresholds[method]; ok {
+		return t
+	}
+	return d.threshold
+}
+
+// slowRPCRegistrar registers the services warning about the slow RPCs.
+// The warning is logged when the RPC exceeds the threshold, without waiting for it to complete,
+// so that the stuck RPCs are also visible.
+type slowRPCRegistrar struct {
+	grpc.ServiceRegistrar
+	detector *slowRPCDetector
+}
+
+func (r slowRPCRegistrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	wrapped := *desc
+	wrapped.Methods = make([]grpc.MethodDesc, 0, len(desc.Methods))
+	for _, m := range desc.Methods {
+		threshold := r.detector.thresholdOf(m.MethodName)
+		if threshold <= 0 {
+			wrapped.Methods = append(wrapped.Methods, m)
+			continue
+		}
+
+		handler := m.Handler
+		method := "/" + desc.ServiceName + "/" + m.MethodName
+		name := m.MethodName
+		m.Handler = func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			// Extract the identifiers once the request is decoded, since the request may be modified by the handler
+			// while the goroutine logging the warning reads them.
+			var fields atomic.Pointer[[]zap.Field]
+			decode := func(v any) error {
+				if err := dec(v); err != nil {
+					return err
+				}
+				f := slowRPCFields(ctx, method, v, threshold)
+				fields.Store(&f)
+				return nil
+			}
+			fieldsOf := func() []zap.Field {
+				if f := fields.Load(); f != nil {
+					return slices.Clone(*f)
+				}
+				return slowRPCFields(ctx, method, nil, threshold)
+			}
+
+			start := r.detector.clock.Now()
+			timer := r.detector.clock.NewTimer(threshold)
+			done := make(chan struct{})
+			slow := make(chan bool, 1)
+			go func() {
+				select {
+				case <-timer.C():
+					slowRPCsTotal.With(prometheus.Labels{methodKey: name}).Inc()
+					r.detector.logger.Warn("the RPC is taking longer than the threshold", fieldsOf()...)
+					slow <- true
+				case <-done:
+					slow <- false
+				}
+			}()
+
+			resp, err := handler(srv, ctx, decode, interceptor)
+			timer.Stop()
+			close(done)
+			if <-slow {
+				r.detector.logger.Info("the slow RPC has completed",
+					append(fieldsOf(),
+						zap.Duration("duration", r.detector.clock.Since(start)),
+						zap.Error(err),
+					)...,
+				)
+			}
+			return resp, err
+		}
+		wrapped.Methods = append(wrapped.Methods, m)
+	}
+	r.ServiceRegistrar.RegisterService(&wrapped, impl)
+}
+
+// slowRPCFields returns the log fields identifying the RPC.
+func slowRPCFields(ctx context.Context, method string, request any, threshold time.Duration) []zap.Field {
+	// Reuse the audit record to extract the identifiers from the request.
+	record := newAuditRecord(ctx, method, request, time.Time{}, 0, nil)
+	return []zap.Field{
+		zap.String("method", method),
+		zap.Duration("threshold", threshold),
+		zap.String("application-id", record.ApplicationID),
+		zap.String("deployment-id", record.DeploymentID),
+		zap.String("stage-id", record.StageID),
+		zap.String("requester", record.Requester),
+		zap.String(correlationIDKey, record.CorrelationID),
+	}
+}
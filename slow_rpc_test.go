@@ -0,0 +1,129 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+func TestNewSlowRPCDetector(t *testing.T) {
+	t.Parallel()
+
+	d, err := newSlowRPCDetector(time.Second, map[string]string{"GetLivestate": "1m", "FetchDefinedStages": "0s"}, nil, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, d.thresholdOf("DetermineVersions"))
+	assert.Equal(t, time.Minute, d.thresholdOf("GetLivestate"))
+	assert.Equal(t, time.Duration(0), d.thresholdOf("FetchDefinedStages"))
+	assert.Equal(t, 30*time.Minute, d.thresholdOf("ExecuteStage"))
+
+	_, err = newSlowRPCDetector(time.Second, map[string]string{"GetLivestate": "invalid"}, nil, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestSlowRPCRegistrar(t *testing.T) {
+	t.Parallel()
+
+	clk := clocktest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	core, logs := observer.New(zapcore.InfoLevel)
+	// Use the method names unique to the test since the metrics are global.
+	d, err := newSlowRPCDetector(time.Second, map[string]string{"TestSlowRPCRegistrarDisabled": "0s"}, clk, zap.New(core))
+	require.NoError(t, err)
+
+	proceed := make(chan struct{})
+	handler := func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+		if err := dec(&deployment.ExecuteStageRequest{}); err != nil {
+			return nil, err
+		}
+		<-proceed
+		return &deployment.ExecuteStageResponse{}, nil
+	}
+	desc := &grpc.ServiceDesc{
+		ServiceName: "test.Service",
+		Methods: []grpc.MethodDesc{
+			{MethodName: "TestSlowRPCRegistrar", Handler: handler},
+			{MethodName: "TestSlowRPCRegistrarDisabled", Handler: handler},
+		},
+	}
+	fake := &fakeServiceRegistrar{}
+	slowRPCRegistrar{ServiceRegistrar: fake, detector: d}.RegisterService(desc, nil)
+	require.Len(t, fake.desc.Methods, 2)
+
+	dec := func(v any) error {
+		v.(*deployment.ExecuteStageRequest).Input = &deployment.ExecutePluginInput{
+			Deployment: &model.Deployment{Id: "deployment-1", ApplicationId: "app-1"},
+			Stage:      &model.PipelineStage{Id: "stage-1"},
+		}
+		return nil
+	}
+	call := func(m grpc.MethodDesc) <-chan error {
+		ch := make(chan error, 1)
+		go func() {
+			_, err := m.Handler(nil, context.Background(), dec, nil)
+			ch <- err
+		}()
+		return ch
+	}
+
+	slow := testutil.ToFloat64(slowRPCsTotal.WithLabelValues("TestSlowRPCRegistrar"))
+
+	// The fast RPC is not warned.
+	ch := call(fake.desc.Methods[0])
+	clk.BlockUntil(1)
+	proceed <- struct{}{}
+	require.NoError(t, <-ch)
+	assert.Equal(t, 0, logs.Len())
+
+	// The slow RPC is warned before it completes.
+	ch = call(fake.desc.Methods[0])
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	require.Eventually(t, func() bool { return logs.Len() == 1 }, time.Second, time.Millisecond)
+	warning := logs.All()[0]
+	assert.Equal(t, zapcore.WarnLevel, warning.Level)
+	fields := warning.ContextMap()
+	assert.Equal(t, "/test.Service/TestSlowRPCRegistrar", fields["method"])
+	assert.Equal(t, "deployment-1", fields["deployment-id"])
+	assert.Equal(t, "stage-1", fields["stage-id"])
+	assert.Equal(t, slow+1, testutil.ToFloat64(slowRPCsTotal.WithLabelValues("TestSlowRPCRegistrar")))
+
+	clk.Advance(time.Second)
+	proceed <- struct{}{}
+	require.NoError(t, <-ch)
+	require.Equal(t, 2, logs.Len())
+	assert.Equal(t, "the slow RPC has completed", logs.All()[1].Message)
+	assert.Equal(t, 2*time.Second, logs.All()[1].ContextMap()["duration"])
+
+	// The RPC with the zero threshold is not watched.
+	ch = call(fake.desc.Methods[1])
+	proceed <- struct{}{}
+	require.NoError(t, <-ch)
+	assert.Equal(t, 0, clk.Waiters())
+	assert.Equal(t, 2, logs.Len())
+}
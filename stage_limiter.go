@@ -0,0 +1,143 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+// stageLimiter limits the number of the stages executed concurrently, in total and for each deploy target.
+// The stages exceeding the limits wait in the queue until the slots are released or the queue timeout expires.
+// A nil stageLimiter doesn't limit anything.
+type stageLimiter struct {
+	// global has the slots shared by all stages. It's nil when the total is not limited.
+	global chan struct{}
+	// perTarget is the number of the slots for each deploy target. It's zero when not limited.
+	perTarget int
+	// queueTimeout is how long a stage waits for the slots. It's zero when waiting until the request is canceled.
+	queueTimeout time.Duration
+	clock        clock.Clock
+
+	// queued is the number of the stages waiting for the slots.
+	queued atomic.Int64
+
+	mu      sync.Mutex
+	targets map[string]chan struct{}
+}
+
+// newStageLimiter returns a stageLimiter, or nil if neither limit is set.
+// The limits less than or equal to zero mean unlimited.
+func newStageLimiter(maxStages, maxStagesPerTarget int, queueTimeout time.Duration, clk clock.Clock) *stageLimiter {
+	if maxStages <= 0 && maxStagesPerTarget <= 0 {
+		return nil
+	}
+	l := &stageLimiter{
+		perTarget:    max(maxStagesPerTarget, 0),
+		queueTimeout: queueTimeout,
+		clock:        clock.OrReal(clk),
+		targets:      make(map[string]chan struct{}),
+	}
+	if maxStages > 0 {
+		l.global = make(chan struct{}, maxStages)
+	}
+	return l
+}
+
+// acquire waits for the slots to execute a stage on the given deploy targets.
+// It returns the function to release the slots, which must be called after the stage is done.
+// It returns ResourceExhausted when the queue timeout expires before getting the slots.
+func (l *stageLimiter) acquire(ctx context.Context, deployTargets []string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	// Acquire the slots in the consistent order to avoid deadlocks among the stages on the same deploy targets.
+	slots := make([]chan struct{}, 0, len(deployTargets)+1)
+	if l.perTarget > 0 {
+		names := slices.Sorted(slices.Values(deployTargets))
+		for _, name := range slices.Compact(names) {
+			slots = append(slots, l.target(name))
+		}
+	}
+	if l.global != nil {
+		slots = append(slots, l.global)
+	}
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := l.clock.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+
+	stageQueueDepth.Inc()
+	defer stageQueueDepth.Dec()
+	l.queued.Add(1)
+	defer l.queued.Add(-1)
+
+	acquired := make([]chan struct{}, 0, len(slots))
+	release := func() {
+		for _, s := range acquired {
+			<-s
+		}
+	}
+	for _, s := range slots {
+		select {
+		case s <- struct{}{}:
+			acquired = append(acquired, s)
+		case <-timeout:
+			release()
+			stageQueueTimeoutsTotal.Inc()
+			return nil, status.Errorf(codes.ResourceExhausted, "timed out after %v waiting for the slot to execute the stage", l.queueTimeout)
+		case <-ctx.Done():
+			release()
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+	return release, nil
+}
+
+// load returns the number of the stages executing and waiting, and the number of the slots shared by all stages.
+// The executing stages and the slots are zero when the total is not limited.
+func (l *stageLimiter) load() (running, queued, capacity int) {
+	if l == nil {
+		return 0, 0, 0
+	}
+	if l.global != nil {
+		running, capacity = len(l.global), cap(l.global)
+	}
+	return running, int(l.queued.Load()), capacity
+}
+
+// target returns the slots of the given deploy target.
+func (l *stageLimiter) target(name string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.targets[name]
+	if !ok {
+		s = make(chan struct{}, l.perTarget)
+		l.targets[name] = s
+	}
+	return s
+}
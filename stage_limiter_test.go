@@ -0,0 +1,132 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+// acquireAsync acquires the slots in a goroutine, and returns the channel receiving the result.
+func acquireAsync(ctx context.Context, l *stageLimiter, deployTargets ...string) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		release, err := l.acquire(ctx, deployTargets)
+		if err == nil {
+			release()
+		}
+		ch <- err
+	}()
+	return ch
+}
+
+func TestNewStageLimiter_Unlimited(t *testing.T) {
+	t.Parallel()
+
+	l := newStageLimiter(0, 0, time.Minute, nil)
+	assert.Nil(t, l)
+
+	release, err := l.acquire(context.Background(), []string{"dt"})
+	require.NoError(t, err)
+	release()
+}
+
+func TestStageLimiter_Global(t *testing.T) {
+	t.Parallel()
+
+	l := newStageLimiter(2, 0, 0, nil)
+	release1, err := l.acquire(context.Background(), []string{"dt-1"})
+	require.NoError(t, err)
+	release2, err := l.acquire(context.Background(), []string{"dt-2"})
+	require.NoError(t, err)
+
+	ch := acquireAsync(context.Background(), l, "dt-3")
+	select {
+	case err := <-ch:
+		t.Fatalf("acquired the slot over the limit: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release1()
+	require.NoError(t, <-ch)
+	release2()
+}
+
+func TestStageLimiter_PerDeployTarget(t *testing.T) {
+	t.Parallel()
+
+	l := newStageLimiter(0, 1, 0, nil)
+	release, err := l.acquire(context.Background(), []string{"dt-1", "dt-2"})
+	require.NoError(t, err)
+
+	// The other deploy target is not limited by the stage on dt-1 and dt-2.
+	require.NoError(t, <-acquireAsync(context.Background(), l, "dt-3"))
+	// The duplicated deploy targets don't take more than one slot.
+	ch := acquireAsync(context.Background(), l, "dt-2", "dt-2")
+	select {
+	case err := <-ch:
+		t.Fatalf("acquired the slot over the limit: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	require.NoError(t, <-ch)
+	require.NoError(t, <-acquireAsync(context.Background(), l, "dt-1", "dt-1"))
+}
+
+func TestStageLimiter_Canceled(t *testing.T) {
+	t.Parallel()
+
+	l := newStageLimiter(1, 0, 0, nil)
+	release, err := l.acquire(context.Background(), nil)
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := acquireAsync(ctx, l)
+	cancel()
+	assert.Equal(t, codes.Canceled, status.Code(<-ch))
+}
+
+// This test is not parallel since it checks the global metrics.
+func TestStageLimiter_QueueTimeout(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Now())
+	l := newStageLimiter(1, 1, time.Minute, clk)
+	release, err := l.acquire(context.Background(), []string{"dt"})
+	require.NoError(t, err)
+
+	timeouts := testutil.ToFloat64(stageQueueTimeoutsTotal)
+	ch := acquireAsync(context.Background(), l, "dt")
+	clk.BlockUntil(1)
+	assert.Equal(t, 1.0, testutil.ToFloat64(stageQueueDepth))
+
+	clk.Advance(time.Minute)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(<-ch))
+	assert.Equal(t, timeouts+1, testutil.ToFloat64(stageQueueTimeoutsTotal))
+	assert.Equal(t, 0.0, testutil.ToFloat64(stageQueueDepth))
+
+	// The slots acquired before the timeout are released.
+	release()
+	require.NoError(t, <-acquireAsync(context.Background(), l, "dt"))
+}
@@ -0,0 +1,172 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// TemplateData is the standard context of the templates rendered by RenderTemplate,
+// so that the stage configs and the generated files reference the deployment data in the same way across the plugins.
+//
+// For example, "{{ .AppName }}", "{{ .Commit }}", "{{ .Versions.image }}" or "{{ .DeployTarget.Name }}".
+type TemplateData struct {
+	// AppID is the ID of the application.
+	AppID string
+	// AppName is the name of the application.
+	AppName string
+	// Labels are the labels of the application.
+	Labels map[string]string
+	// DeploymentID is the ID of the deployment.
+	DeploymentID string
+	// Commit is the commit hash of the deployment source.
+	Commit string
+	// RepositoryURL is the remote URL of the repository of the application.
+	RepositoryURL string
+	// Versions are the versions of the artifacts keyed by their names.
+	Versions map[string]string
+	// DeployTarget is the deploy target being rendered for. Its fields are empty when not for a specific one.
+	DeployTarget TemplateDeployTarget
+	// Values are the additional values given by the plugin.
+	Values map[string]any
+}
+
+// TemplateDeployTarget is the deploy target in the TemplateData.
+type TemplateDeployTarget struct {
+	// Name is the name of the deploy target.
+	Name string
+	// Labels are the labels of the deploy target.
+	Labels map[string]string
+	// Config is the config of the deploy target decoded as a generic value, e.g. "{{ .DeployTarget.Config.region }}".
+	Config map[string]any
+}
+
+// NewTemplateData returns the TemplateData of the deployment.
+// The labels of the application config take precedence over the labels of the deployment.
+// The versions and the deploy target can be nil.
+func NewTemplateData[ApplicationConfigSpec, DeployTargetConfig any](
+	deployment Deployment,
+	source DeploymentSource[ApplicationConfigSpec],
+	versions []ArtifactVersion,
+	deployTarget *DeployTarget[DeployTargetConfig],
+) (TemplateData, error) {
+	data := TemplateData{
+		AppID:         deployment.ApplicationID,
+		AppName:       deployment.ApplicationName,
+		Labels:        make(map[string]string, len(deployment.Labels)),
+		DeploymentID:  deployment.ID,
+		Commit:        source.CommitHash,
+		RepositoryURL: deployment.RepositoryURL,
+		Versions:      make(map[string]string, len(versions)),
+	}
+	maps.Copy(data.Labels, deployment.Labels)
+	maps.Copy(data.Labels, source.ApplicationConfig.Labels())
+	for _, v := range versions {
+		data.Versions[v.Name] = v.Version
+	}
+	if deployTarget != nil {
+		cfg, err := toJSONObject(deployTarget.Config)
+		if err != nil {
+			return TemplateData{}, fmt.Errorf("failed to convert the config of the deploy target %s: %w", deployTarget.Name, err)
+		}
+		data.DeployTarget = TemplateDeployTarget{
+			Name:   deployTarget.Name,
+			Labels: deployTarget.Labels,
+			Config: cfg,
+		}
+	}
+	return data, nil
+}
+
+type templateOptions struct {
+	funcs template.FuncMap
+}
+
+// TemplateOption configures RenderTemplate.
+type TemplateOption func(*templateOptions)
+
+// WithTemplateFuncs adds the functions available in the templates, overriding the built-in ones with the same names.
+// For example, pass sprig.TxtFuncMap() to use the sprig functions.
+func WithTemplateFuncs(funcs template.FuncMap) TemplateOption {
+	return func(o *templateOptions) {
+		maps.Copy(o.funcs, funcs)
+	}
+}
+
+// templateFuncs returns the built-in functions available in the templates.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"toJson": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"toYaml": func(v any) (string, error) {
+			data, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(data), "\n"), err
+		},
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"nindent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return "\n" + pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"quote": func(v any) string {
+			return fmt.Sprintf("%q", fmt.Sprint(v))
+		},
+		"default": func(d, v any) any {
+			if v == nil || v == "" {
+				return d
+			}
+			return v
+		},
+		"required": func(msg string, v any) (any, error) {
+			if v == nil || v == "" {
+				return nil, errors.New(msg)
+			}
+			return v, nil
+		},
+	}
+}
+
+// RenderTemplate renders the Go template with the data.
+// Referring to a missing key of the data is an error, so that the typos are not rendered as empty values.
+// Use index for the optional keys, e.g. "{{ default 1 (index .Values "replicas") }}".
+// The built-in functions are toJson, toYaml, indent, nindent, quote, default and required.
+func RenderTemplate(name, text string, data TemplateData, opts ...TemplateOption) (string, error) {
+	o := &templateOptions{funcs: templateFuncs()}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	tmpl, err := template.New(name).Funcs(o.funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the template %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render the template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTemplateData(t *testing.T) {
+	t.Parallel()
+
+	type targetConfig struct {
+		Region string `json:"region"`
+	}
+	data, err := NewTemplateData[struct{}, targetConfig](
+		Deployment{
+			ID:              "deployment-1",
+			ApplicationID:   "app-1",
+			ApplicationName: "app",
+			RepositoryURL:   "git@github.com:org/repo.git",
+			Labels:          map[string]string{"env": "dev", "team": "a"},
+		},
+		DeploymentSource[struct{}]{CommitHash: "0123abc"},
+		[]ArtifactVersion{{Name: "image", Version: "v1.2.3"}},
+		&DeployTarget[targetConfig]{Name: "cluster-1", Labels: map[string]string{"tier": "1"}, Config: targetConfig{Region: "asia"}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, TemplateData{
+		AppID:         "app-1",
+		AppName:       "app",
+		Labels:        map[string]string{"env": "dev", "team": "a"},
+		DeploymentID:  "deployment-1",
+		Commit:        "0123abc",
+		RepositoryURL: "git@github.com:org/repo.git",
+		Versions:      map[string]string{"image": "v1.2.3"},
+		DeployTarget: TemplateDeployTarget{
+			Name:   "cluster-1",
+			Labels: map[string]string{"tier": "1"},
+			Config: map[string]any{"region": "asia"},
+		},
+	}, data)
+}
+
+func TestRenderTemplate(t *testing.T) {
+	t.Parallel()
+
+	data := TemplateData{
+		AppName:  "app",
+		Commit:   "0123abc",
+		Labels:   map[string]string{"env": "dev"},
+		Versions: map[string]string{"image": "v1.2.3"},
+		DeployTarget: TemplateDeployTarget{
+			Name:   "cluster-1",
+			Config: map[string]any{"region": "asia"},
+		},
+		Values: map[string]any{"replicas": 3},
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		opts     []TemplateOption
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "deployment data",
+			text:     "{{ .AppName }}@{{ .Commit }}:{{ .Versions.image }} on {{ .DeployTarget.Name }}/{{ .DeployTarget.Config.region }}",
+			expected: "app@0123abc:v1.2.3 on cluster-1/asia",
+		},
+		{
+			name:     "built-in functions",
+			text:     "labels:{{ toYaml .Labels | nindent 2 }}\nname: {{ quote .AppName }}\nreplicas: {{ default 1 (index .Values \"replicas\") }}\nport: {{ default 80 (index .Values \"port\") }}\njson: {{ toJson .Versions }}",
+			expected: "labels:\n  env: dev\nname: \"app\"\nreplicas: 3\nport: 80\njson: {\"image\":\"v1.2.3\"}",
+		},
+		{
+			name:     "custom functions",
+			text:     "{{ upper .AppName }}",
+			opts:     []TemplateOption{WithTemplateFuncs(template.FuncMap{"upper": strings.ToUpper})},
+			expected: "APP",
+		},
+		{
+			name:    "missing key",
+			text:    "{{ .Versions.missing }}",
+			wantErr: "failed to render the template test",
+		},
+		{
+			name:    "required",
+			text:    `{{ required "the namespace is required" (index .Values "namespace") }}`,
+			wantErr: "the namespace is required",
+		},
+		{
+			name:    "invalid template",
+			text:    "{{ .AppName",
+			wantErr: "failed to parse the template test",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := RenderTemplate("test", tt.text, data, tt.opts...)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
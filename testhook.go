@@ -0,0 +1,123 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"maps"
+	"net"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/common"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/internal/testhook"
+	"github.com/pipe-cd/piped-plugin-sdk-go/logpersister"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry"
+)
+
+// The hooks for the sdktest package. See the testhook package for the details.
+func init() {
+	testhook.NewClient = func(base pipedservice.PluginServiceClient, pluginName, applicationID, deploymentID, stageID string, slp logpersister.StageLogPersister, tr *toolregistry.ToolRegistry, clk clock.Clock) any {
+		return &Client{
+			base:              &pluginServiceClient{PluginServiceClient: base},
+			pluginName:        pluginName,
+			applicationID:     applicationID,
+			deploymentID:      deploymentID,
+			stageID:           stageID,
+			stageLogPersister: slp,
+			toolRegistry:      tr,
+			clock:             clk,
+		}
+	}
+	testhook.NewDeploymentSource = func(dst any, pluginName string, source *common.DeploymentSource) error {
+		return dst.(interface {
+			decodeForTest(string, *common.DeploymentSource) error
+		}).decodeForTest(pluginName, source)
+	}
+	testhook.NewExecuteStageInput = func(ctx context.Context, dst any, pluginName string, client any, request *deployment.ExecuteStageRequest, logger *zap.Logger) error {
+		return dst.(interface {
+			decodeForTest(context.Context, string, *Client, *deployment.ExecuteStageRequest, *zap.Logger) error
+		}).decodeForTest(ctx, pluginName, client.(*Client), request, logger)
+	}
+	testhook.InitDeployTargetRegistry = func(dst any, deployTargets any) {
+		dst.(interface{ initForTest(any) }).initForTest(deployTargets)
+	}
+	testhook.ExecuteStage = func(ctx context.Context, pluginType any, pluginName string, plugin, config, deployTargets, client any, request *deployment.ExecuteStageRequest, logger *zap.Logger) (*deployment.ExecuteStageResponse, error) {
+		return pluginType.(testPlugin).executeStageForTest(ctx, pluginName, plugin, config, deployTargets, client.(*Client), request, logger)
+	}
+	testhook.GetLivestate = func(ctx context.Context, pluginType any, pluginName string, plugin, config, deployTargets, client any, request *livestate.GetLivestateRequest, logger *zap.Logger) (*livestate.GetLivestateResponse, error) {
+		return pluginType.(testPlugin).getLivestateForTest(ctx, pluginName, plugin, config, deployTargets, client.(*Client), request, logger)
+	}
+	testhook.Serve = func(ctx context.Context, plugin any, lis net.Listener, service pipedservice.PluginServiceClient, persister testhook.StageLogPersisterProvider, config string, logger *zap.Logger) error {
+		return plugin.(testPlugin).serveForTest(ctx, lis, service, persister, config, logger)
+	}
+	testhook.Initializers = func(plugin any) any {
+		return plugin.(testPlugin).initializersForTest()
+	}
+}
+
+// testPlugin is implemented by every *Plugin to instantiate the generic functions for the hooks.
+type testPlugin interface {
+	executeStageForTest(ctx context.Context, pluginName string, plugin, config, deployTargets any, client *Client, request *deployment.ExecuteStageRequest, logger *zap.Logger) (*deployment.ExecuteStageResponse, error)
+	getLivestateForTest(ctx context.Context, pluginName string, plugin, config, deployTargets any, client *Client, request *livestate.GetLivestateRequest, logger *zap.Logger) (*livestate.GetLivestateResponse, error)
+	serveForTest(ctx context.Context, lis net.Listener, service pipedservice.PluginServiceClient, persister logPersister, config string, logger *zap.Logger) error
+	initializersForTest() any
+}
+
+// executeStageForTest executes the stage for testhook.ExecuteStage. The receiver may be nil.
+func (*Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) executeStageForTest(ctx context.Context, pluginName string, plugin, config, deployTargets any, client *Client, request *deployment.ExecuteStageRequest, logger *zap.Logger) (*deployment.ExecuteStageResponse, error) {
+	return executeStage(ctx, pluginName, plugin.(StagePlugin[Config, DeployTargetConfig, ApplicationConfigSpec]), config.(*Config), deployTargets.([]*DeployTarget[DeployTargetConfig]), client, request, logger)
+}
+
+// getLivestateForTest gets the live state for testhook.GetLivestate. The receiver may be nil.
+func (*Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) getLivestateForTest(ctx context.Context, pluginName string, plugin, config, deployTargets any, client *Client, request *livestate.GetLivestateRequest, logger *zap.Logger) (*livestate.GetLivestateResponse, error) {
+	return getLivestate(ctx, pluginName, plugin.(LivestatePlugin[Config, DeployTargetConfig, ApplicationConfigSpec]), config.(*Config), deployTargets.([]*DeployTarget[DeployTargetConfig]), client, request, logger)
+}
+
+// initializersForTest returns the initializers for testhook.Initializers.
+func (p *Plugin[Config, DeployTargetConfig, ApplicationConfigSpec]) initializersForTest() any {
+	return p.roleInitializers()
+}
+
+// decodeForTest converts the deployment source for testhook.NewDeploymentSource.
+func (d *DeploymentSource[Spec]) decodeForTest(pluginName string, source *common.DeploymentSource) error {
+	ds, err := newDeploymentSource[Spec](pluginName, source, placeholders{})
+	if err != nil {
+		return err
+	}
+	*d = ds
+	return nil
+}
+
+// decodeForTest converts the request for testhook.NewExecuteStageInput.
+func (in *ExecuteStageInput[ApplicationConfigSpec]) decodeForTest(ctx context.Context, pluginName string, client *Client, request *deployment.ExecuteStageRequest, logger *zap.Logger) error {
+	decoded, err := newExecuteStageInput[ApplicationConfigSpec](ctx, pluginName, client, request, logger)
+	if err != nil {
+		return err
+	}
+	*in = *decoded
+	return nil
+}
+
+// initForTest initializes the registry for testhook.InitDeployTargetRegistry.
+func (r *DeployTargetRegistry[DeployTargetConfig]) initForTest(deployTargets any) {
+	r.store = newDeployTargetStore(maps.Clone(deployTargets.(map[string]*DeployTarget[DeployTargetConfig])))
+	r.redactor = &redactor{}
+}
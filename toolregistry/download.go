@@ -0,0 +1,192 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/digest"
+)
+
+const (
+	// LibcGNU represents the GNU C library.
+	LibcGNU = "gnu"
+	// LibcMusl represents the musl C library.
+	LibcMusl = "musl"
+)
+
+// Platform represents the platform where the tools run.
+type Platform struct {
+	// OS is the operating system, e.g. "linux", "darwin".
+	OS string
+	// Arch is the architecture, e.g. "amd64", "arm64".
+	Arch string
+	// Libc is the variant of the C library, e.g. "gnu", "musl".
+	// It's empty when the operating system is not linux.
+	Libc string
+}
+
+// String returns the platform in the form of "<os>/<arch>" or "<os>/<arch>/<libc>".
+func (p Platform) String() string {
+	if p.Libc == "" {
+		return p.OS + "/" + p.Arch
+	}
+	return p.OS + "/" + p.Arch + "/" + p.Libc
+}
+
+// CurrentPlatform returns the platform where the plugin is running.
+// The plugin runs on the same host as piped, so the tools installed by piped run on this platform.
+func CurrentPlatform() Platform {
+	p := Platform{
+		OS:   runtime.GOOS,
+		Arch: runtime.GOARCH,
+	}
+	if p.OS == "linux" {
+		p.Libc = LibcGNU
+		if matches, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(matches) > 0 {
+			p.Libc = LibcMusl
+		}
+	}
+	return p
+}
+
+// Download describes a tool binary which is downloaded from a URL.
+type Download struct {
+	// URL is the template of the URL to download the binary.
+	// The following values are available in the template:
+	// {{ .Name }}, {{ .Version }}, {{ .OS }}, {{ .Arch }}, {{ .Libc }}.
+	// e.g. "https://example.com/{{ .Name }}/{{ .Version }}/{{ .Name }}_{{ .OS }}_{{ .Arch }}"
+	URL string
+	// Checksums is the map from the platform to the SHA256 checksum of the binary.
+	// The key is "<os>/<arch>/<libc>" or "<os>/<arch>", e.g. "linux/arm64/musl", "linux/amd64".
+	// The key including libc takes precedence over the one without it.
+	// The checksum is not verified when this is empty.
+	Checksums map[string]string
+	// Retries is the number of times curl retries the download on transient failures.
+	// The retried download resumes from where it was interrupted if the server supports range requests.
+	// The download is not retried by curl when this is zero.
+	Retries int
+}
+
+type downloadValues struct {
+	Name    string
+	Version string
+	OS      string
+	Arch    string
+	Libc    string
+}
+
+// URLFor returns the URL to download the binary of the given version for the given platform.
+func (d Download) URLFor(name, version string, p Platform) (string, error) {
+	t, err := template.New("download url").Option("missingkey=error").Parse(d.URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the download url: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, downloadValues{
+		Name:    name,
+		Version: version,
+		OS:      p.OS,
+		Arch:    p.Arch,
+		Libc:    p.Libc,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render the download url: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// ChecksumFor returns the checksum of the binary for the given platform.
+// It returns an error if the checksums are given but there is no checksum for the platform.
+func (d Download) ChecksumFor(p Platform) (string, error) {
+	if len(d.Checksums) == 0 {
+		return "", nil
+	}
+	if sum, ok := d.Checksums[p.String()]; ok {
+		return sum, nil
+	}
+	if sum, ok := d.Checksums[p.OS+"/"+p.Arch]; ok {
+		return sum, nil
+	}
+	return "", fmt.Errorf("no checksum is given for the platform %s", p)
+}
+
+// Script returns the install script to download the binary for the given platform.
+func (d Download) Script(name, version string, p Platform) (string, error) {
+	u, err := d.URLFor(name, version, p)
+	if err != nil {
+		return "", err
+	}
+	var flags string
+	if d.Retries > 0 {
+		flags = fmt.Sprintf(" --retry %d -C -", d.Retries)
+	}
+	// The script is rendered as a template by piped, so the url must not be interpreted as a template action.
+	return fmt.Sprintf("curl -fsSL%s '%s' -o {{ .OutPath }}", flags, escapeScript(u)), nil
+}
+
+// InstallToolFromDownload installs the tool downloaded as described by the given Download.
+// The checksum of the downloaded binary is verified when it's given for the current platform.
+func (r *ToolRegistry) InstallToolFromDownload(ctx context.Context, name, version string, d Download, opts ...InstallOption) (string, error) {
+	p := CurrentPlatform()
+
+	checksum, err := d.ChecksumFor(p)
+	if err != nil {
+		return "", err
+	}
+	script, err := d.Script(name, version, p)
+	if err != nil {
+		return "", err
+	}
+	u, err := d.URLFor(name, version, p)
+	if err != nil {
+		return "", err
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the download url: %w", err)
+	}
+
+	opts = append(opts, func(o *installOptions) {
+		o.checksum = checksum
+		o.host = parsed.Host
+	})
+	return r.InstallTool(ctx, name, version, script, opts...)
+}
+
+// escapeScript escapes the given string to be embedded in a single-quoted shell string inside the install script template.
+func escapeScript(s string) string {
+	s = strings.ReplaceAll(s, "'", `'\''`)
+	s = strings.ReplaceAll(s, "{{", `{{"{{"}}`)
+	return s
+}
+
+// verifyChecksum verifies the SHA256 checksum of the file at the given path.
+func verifyChecksum(path, expected string) error {
+	d, err := digest.File(path)
+	if err != nil {
+		return err
+	}
+	if actual := d.Hex(); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
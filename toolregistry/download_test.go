@@ -0,0 +1,183 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestDownload_URLFor(t *testing.T) {
+	t.Parallel()
+
+	d := Download{
+		URL: "https://example.com/{{ .Name }}/{{ .Version }}/{{ .Name }}_{{ .OS }}_{{ .Arch }}{{ if .Libc }}_{{ .Libc }}{{ end }}",
+	}
+
+	url, err := d.URLFor("tool", "v1.0.0", Platform{OS: "linux", Arch: "arm64", Libc: LibcMusl})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/tool/v1.0.0/tool_linux_arm64_musl", url)
+
+	url, err = d.URLFor("tool", "v1.0.0", Platform{OS: "darwin", Arch: "amd64"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/tool/v1.0.0/tool_darwin_amd64", url)
+
+	_, err = Download{URL: "{{ .Unknown }}"}.URLFor("tool", "v1.0.0", Platform{})
+	require.Error(t, err)
+}
+
+func TestDownload_ChecksumFor(t *testing.T) {
+	t.Parallel()
+
+	d := Download{
+		Checksums: map[string]string{
+			"linux/amd64":      "gnu-sum",
+			"linux/amd64/musl": "musl-sum",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		download  Download
+		platform  Platform
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "libc specific checksum",
+			download: d,
+			platform: Platform{OS: "linux", Arch: "amd64", Libc: LibcMusl},
+			expected: "musl-sum",
+		},
+		{
+			name:     "fallback to os and arch",
+			download: d,
+			platform: Platform{OS: "linux", Arch: "amd64", Libc: LibcGNU},
+			expected: "gnu-sum",
+		},
+		{
+			name:      "missing checksum",
+			download:  d,
+			platform:  Platform{OS: "linux", Arch: "arm64", Libc: LibcGNU},
+			expectErr: true,
+		},
+		{
+			name:     "no checksums",
+			download: Download{},
+			platform: Platform{OS: "linux", Arch: "arm64", Libc: LibcGNU},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sum, err := tt.download.ChecksumFor(tt.platform)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, sum)
+		})
+	}
+}
+
+func TestDownload_Script(t *testing.T) {
+	t.Parallel()
+
+	d := Download{URL: "https://example.com/{{ .Name }}?q='{{ .Version }}'"}
+	script, err := d.Script("tool", "v1", Platform{OS: "linux", Arch: "amd64"})
+	require.NoError(t, err)
+	assert.Equal(t, `curl -fsSL 'https://example.com/tool?q='\''v1'\''' -o {{ .OutPath }}`, script)
+
+	d.Retries = 3
+	script, err = d.Script("tool", "v1", Platform{OS: "linux", Arch: "amd64"})
+	require.NoError(t, err)
+	assert.Equal(t, `curl -fsSL --retry 3 -C - 'https://example.com/tool?q='\''v1'\''' -o {{ .OutPath }}`, script)
+}
+
+func TestToolRegistry_InstallToolFromDownload_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{dir: t.TempDir()}
+	r := NewToolRegistry(client, WithDownloadRateLimit(rate.Every(time.Hour), 1))
+	d := Download{URL: "https://example.com/{{ .Name }}"}
+
+	_, err := r.InstallToolFromDownload(context.Background(), "tool", "v1.0.0", d)
+	require.NoError(t, err)
+
+	// The second download from the same host should wait for the rate limit.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = r.InstallToolFromDownload(ctx, "tool", "v2.0.0", d)
+	require.Error(t, err)
+
+	// The download from another host should not be limited.
+	_, err = r.InstallToolFromDownload(context.Background(), "tool", "v2.0.0", Download{URL: "https://example.org/{{ .Name }}"})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), client.calls.Load())
+}
+
+func TestToolRegistry_InstallToolFromDownload(t *testing.T) {
+	t.Parallel()
+
+	const binary = "#!/bin/sh\n"
+	sum := sha256.Sum256([]byte(binary))
+	p := CurrentPlatform()
+
+	tests := []struct {
+		name      string
+		checksum  string
+		expectErr bool
+	}{
+		{
+			name:     "checksum matched",
+			checksum: hex.EncodeToString(sum[:]),
+		},
+		{
+			name:      "checksum mismatched",
+			checksum:  "invalid",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &fakeClient{dir: t.TempDir(), binary: binary}
+			r := NewToolRegistry(client)
+
+			_, err := r.InstallToolFromDownload(context.Background(), "tool", "v1.0.0", Download{
+				URL:       "https://example.com/{{ .Name }}",
+				Checksums: map[string]string{p.String(): tt.checksum},
+			})
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
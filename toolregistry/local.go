@@ -0,0 +1,105 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrymetrics"
+)
+
+// InstallToolFromPath installs the tool from the binary at the given local path,
+// e.g. a custom-built binary shipped in the plugin container image or a mounted volume.
+//
+// When the tools directory is configured, the binary is copied (or linked with WithSymlink) into it.
+// Otherwise, it's copied into the tools directory of piped through the install script.
+// WithSymlink requires the tools directory because piped changes the mode of the installed file.
+func (r *ToolRegistry) InstallToolFromPath(ctx context.Context, name, version, src string, opts ...InstallOption) (string, error) {
+	options := installOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return "", err
+	}
+	fi, err := os.Stat(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the local binary of the tool %s-%s: %w", name, version, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return "", fmt.Errorf("the local binary of the tool %s-%s is not a regular file: %s", name, version, src)
+	}
+
+	if r.toolsDir == "" {
+		if options.symlink {
+			return "", fmt.Errorf("the tools directory must be configured to install the tool %s-%s as a symbolic link", name, version)
+		}
+		script := fmt.Sprintf("cp '%s' {{ .OutPath }}", escapeScript(src))
+		return r.InstallTool(ctx, name, version, script, opts...)
+	}
+
+	key := newToolKey(name, version, src)
+	if path, ok := r.cachedPath(key); ok {
+		toolregistrymetrics.LookedUpCache(name, version, toolregistrymetrics.CacheHit)
+		return path, nil
+	}
+	toolregistrymetrics.LookedUpCache(name, version, toolregistrymetrics.CacheMiss)
+
+	start := r.clock.Now()
+	dst := filepath.Join(r.toolsDir, key.filename())
+	if options.symlink {
+		err = symlinkFile(src, dst)
+	} else {
+		err = copyFile(src, dst)
+	}
+	if err == nil && options.needsVerification() {
+		if err = options.verify(ctx, dst); err != nil {
+			os.Remove(dst)
+		}
+	}
+	if err != nil {
+		toolregistrymetrics.InstalledTool(name, version, toolregistrymetrics.StatusFailure, r.clock.Since(start))
+		return "", fmt.Errorf("failed to install the tool %s-%s from %s: %w", name, version, src, err)
+	}
+	toolregistrymetrics.InstalledTool(name, version, toolregistrymetrics.StatusSuccess, r.clock.Since(start))
+	toolregistrymetrics.InstalledBytes(name, version, fi.Size())
+
+	r.cache(key, dst)
+	return dst, nil
+}
+
+// symlinkFile creates a symbolic link at dst pointing to src.
+// The link is created with a temporary name first and then renamed to replace the existing one atomically.
+func symlinkFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	tmp := fmt.Sprintf("%s.tmp-%d", dst, time.Now().UnixNano())
+	if err := os.Symlink(src, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolRegistry_InstallToolFromPath(t *testing.T) {
+	t.Parallel()
+
+	src := filepath.Join(t.TempDir(), "custom-tool")
+	require.NoError(t, os.WriteFile(src, []byte("#!/bin/sh\n"), 0o755))
+
+	t.Run("copy", func(t *testing.T) {
+		t.Parallel()
+
+		client := &fakeClient{dir: t.TempDir()}
+		toolsDir := t.TempDir()
+		r := NewToolRegistry(client, WithToolsDir(toolsDir))
+
+		path, err := r.InstallToolFromPath(context.Background(), "tool", "v1.0.0", src)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(toolsDir, newToolKey("tool", "v1.0.0", src).filename()), path)
+		fi, err := os.Lstat(path)
+		require.NoError(t, err)
+		assert.True(t, fi.Mode().IsRegular())
+		assert.Equal(t, uint32(0), client.calls.Load())
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		t.Parallel()
+
+		client := &fakeClient{dir: t.TempDir()}
+		toolsDir := t.TempDir()
+		r := NewToolRegistry(client, WithToolsDir(toolsDir))
+
+		path, err := r.InstallToolFromPath(context.Background(), "tool", "v1.0.0", src, WithSymlink())
+		require.NoError(t, err)
+		target, err := os.Readlink(path)
+		require.NoError(t, err)
+		assert.Equal(t, src, target)
+	})
+
+	t.Run("through piped", func(t *testing.T) {
+		t.Parallel()
+
+		client := &fakeClient{dir: t.TempDir()}
+		r := NewToolRegistry(client)
+
+		_, err := r.InstallToolFromPath(context.Background(), "tool", "v1.0.0", src)
+		require.NoError(t, err)
+		assert.Equal(t, "cp '"+src+"' {{ .OutPath }}", client.script.Load())
+
+		_, err = r.InstallToolFromPath(context.Background(), "tool", "v2.0.0", src, WithSymlink())
+		require.Error(t, err)
+	})
+
+	t.Run("verification failed", func(t *testing.T) {
+		t.Parallel()
+
+		client := &fakeClient{dir: t.TempDir()}
+		toolsDir := t.TempDir()
+		r := NewToolRegistry(client, WithToolsDir(toolsDir))
+
+		_, err := r.InstallToolFromPath(context.Background(), "tool", "v1.0.0", src, func(o *installOptions) {
+			o.checksum = "invalid"
+		})
+		require.Error(t, err)
+		assert.NoFileExists(t, filepath.Join(toolsDir, newToolKey("tool", "v1.0.0", src).filename()))
+	})
+
+	t.Run("missing binary", func(t *testing.T) {
+		t.Parallel()
+
+		r := NewToolRegistry(&fakeClient{dir: t.TempDir()}, WithToolsDir(t.TempDir()))
+		_, err := r.InstallToolFromPath(context.Background(), "tool", "v1.0.0", filepath.Join(t.TempDir(), "missing"))
+		require.Error(t, err)
+	})
+}
@@ -15,31 +15,512 @@
 package toolregistry
 
 import (
+	"cmp"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
 
 	service "github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+	"github.com/pipe-cd/piped-plugin-sdk-go/retry"
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry/toolregistrymetrics"
+	"github.com/pipe-cd/piped-plugin-sdk-go/version"
 )
 
 type ToolRegistry struct {
 	client service.PluginServiceClient
+
+	// toolsDir is the directory to place the installed tools.
+	// When it's empty, the paths of the tools installed by piped are used as is.
+	toolsDir string
+	// isolatedPluginName is the name of the plugin which has its own sub directory in the tools directory.
+	isolatedPluginName string
+	// sharedToolsDirs are the read-only directories to look up the tools before installing them.
+	sharedToolsDirs []string
+
+	// retryAttempts is the maximum number of attempts to call the install API of piped.
+	retryAttempts int
+	// retryBaseInterval and retryMaxInterval configure the exponential backoff between the attempts.
+	retryBaseInterval time.Duration
+	retryMaxInterval  time.Duration
+
+	// downloadRateLimit and downloadBurst limit the rate of downloads per host.
+	// The downloads are not limited when downloadRateLimit is zero.
+	downloadRateLimit rate.Limit
+	downloadBurst     int
+	limiters          map[string]*rate.Limiter
+
+	// clock is used to wait between the install attempts and to record when the tools are used.
+	clock clock.Clock
+
+	// installed holds the tools installed through this registry.
+	// The key is the name and the version of the tool, and the digest of where it's installed from.
+	installed map[toolKey]*installedTool
+	mu        sync.Mutex
+}
+
+type toolKey struct {
+	name    string
+	version string
+	// source is the digest of the install script or the path of the local binary,
+	// so that the tool installed in a different way is not served from the cache.
+	source string
+}
+
+// newToolKey returns the cache key of the tool installed from the given source.
+func newToolKey(name, version, source string) toolKey {
+	sum := sha256.Sum256([]byte(source))
+	return toolKey{name: name, version: version, source: hex.EncodeToString(sum[:])}
+}
+
+// filename returns the filename of the tool in the tools directory.
+// It ends with the digest of the source, so that the tool installed in a different way is not found there.
+func (k toolKey) filename() string {
+	return toolFilename(k.name, k.version) + "-" + k.source[:12]
+}
+
+type installedTool struct {
+	path     string
+	lastUsed time.Time
+	// verified is true when the tool has passed the verification.
+	verified bool
+}
+
+// InstalledTool represents a tool installed through the registry.
+type InstalledTool struct {
+	// Name is the name of the tool.
+	Name string `json:"name"`
+	// Version is the version of the tool.
+	Version string `json:"version"`
+	// Path is the path of the installed binary.
+	Path string `json:"path"`
+	// Size is the size of the installed binary in bytes.
+	Size int64 `json:"size"`
+	// LastUsedAt is the time when the tool was requested last time.
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+// Option configures the ToolRegistry.
+type Option func(*ToolRegistry)
+
+// WithToolsDir configures the directory to place the installed tools.
+// The tools installed by piped are copied into this directory, so that they are not affected by other plugins.
+func WithToolsDir(dir string) Option {
+	return func(r *ToolRegistry) {
+		r.toolsDir = dir
+	}
 }
 
-func NewToolRegistry(client service.PluginServiceClient) *ToolRegistry {
-	return &ToolRegistry{
-		client: client,
+// WithPluginIsolation configures the registry to use a sub directory of the tools directory dedicated to the given plugin.
+// It has no effect without WithToolsDir.
+func WithPluginIsolation(pluginName string) Option {
+	return func(r *ToolRegistry) {
+		r.isolatedPluginName = pluginName
 	}
 }
 
-func (r *ToolRegistry) InstallTool(ctx context.Context, name, version, script string) (path string, err error) {
-	res, err := r.client.InstallTool(ctx, &service.InstallToolRequest{
-		Name:          name,
-		Version:       version,
-		InstallScript: script,
-	})
+// WithSharedToolsDirs configures the read-only directories shared among plugins.
+// A tool found in these directories as "<name>-<version>" is used without installing it,
+// regardless of the install script since the directories are provisioned outside the registry.
+// The directories are searched in the given order after the tools directory.
+func WithSharedToolsDirs(dirs ...string) Option {
+	return func(r *ToolRegistry) {
+		r.sharedToolsDirs = append(r.sharedToolsDirs, dirs...)
+	}
+}
+
+// WithInstallRetry configures the registry to retry installing a tool through piped when it fails,
+// e.g. because of a temporary network failure while downloading it.
+// The interval between the attempts grows exponentially from baseInterval up to maxInterval.
+func WithInstallRetry(attempts int, baseInterval, maxInterval time.Duration) Option {
+	return func(r *ToolRegistry) {
+		r.retryAttempts = attempts
+		r.retryBaseInterval = baseInterval
+		r.retryMaxInterval = maxInterval
+	}
+}
 
+// WithDownloadRateLimit configures the registry to limit the rate of downloads per host
+// to avoid being throttled by the download servers.
+// It only takes effect on InstallToolFromDownload.
+func WithDownloadRateLimit(limit rate.Limit, burst int) Option {
+	return func(r *ToolRegistry) {
+		r.downloadRateLimit = limit
+		r.downloadBurst = burst
+	}
+}
+
+// WithClock configures the clock used to wait between the install attempts and to record when the tools are used.
+// It's useful to control the time in tests.
+func WithClock(c clock.Clock) Option {
+	return func(r *ToolRegistry) {
+		r.clock = clock.OrReal(c)
+	}
+}
+
+func NewToolRegistry(client service.PluginServiceClient, opts ...Option) *ToolRegistry {
+	r := &ToolRegistry{
+		client:        client,
+		retryAttempts: 1,
+		limiters:      make(map[string]*rate.Limiter),
+		installed:     make(map[toolKey]*installedTool),
+		clock:         clock.Real,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.toolsDir != "" && r.isolatedPluginName != "" {
+		r.toolsDir = filepath.Join(r.toolsDir, r.isolatedPluginName)
+	}
+	return r
+}
+
+// InstallOption configures how a tool is installed.
+type InstallOption func(*installOptions)
+
+type installOptions struct {
+	verifyArgs []string
+	checksum   string
+	symlink    bool
+	// host is the host to download the tool from, which is used to limit the rate of downloads.
+	host string
+}
+
+// needsVerification returns true if the tool should be verified before being used.
+func (o *installOptions) needsVerification() bool {
+	return len(o.verifyArgs) > 0 || o.checksum != ""
+}
+
+// verify verifies the installed tool at the given path.
+func (o *installOptions) verify(ctx context.Context, path string) error {
+	if o.checksum != "" {
+		if err := verifyChecksum(path, o.checksum); err != nil {
+			return err
+		}
+	}
+	if len(o.verifyArgs) > 0 {
+		if err := verifyCommand(ctx, path, o.verifyArgs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithVerifyCommand configures the registry to run the binary with the given arguments (e.g. "version")
+// before using it for the first time, wherever it's found.
+// When the command fails, the binary is removed and the tool is installed again once,
+// so that corrupt or wrong-arch downloads are not used.
+func WithVerifyCommand(args ...string) InstallOption {
+	return func(o *installOptions) {
+		o.verifyArgs = args
+	}
+}
+
+// WithSymlink configures the registry to place a symbolic link to the local binary instead of copying it.
+// It only takes effect on InstallToolFromPath.
+func WithSymlink() InstallOption {
+	return func(o *installOptions) {
+		o.symlink = true
+	}
+}
+
+// InstallTool installs the tool through piped with the install script, and returns the path of the binary.
+// The installed tools are cached in memory and in the tools directory by the name, the version, and the install script,
+// so the tool is installed again when the script is changed.
+// Note that piped also caches the installed tools, but only by the name and the version.
+func (r *ToolRegistry) InstallTool(ctx context.Context, name, version, script string, opts ...InstallOption) (string, error) {
+	options := installOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	key := newToolKey(name, version, script)
+	path, err := r.installTool(ctx, key, script, options.host)
 	if err != nil {
 		return "", err
 	}
+	// Verify the tools once in the registry to avoid running the command on every call.
+	if !options.needsVerification() || r.isVerified(key) {
+		return path, nil
+	}
+	if err := options.verify(ctx, path); err == nil {
+		r.markVerified(key)
+		return path, nil
+	}
+
+	// Install the tool again once, since the binary may be corrupted or for another architecture.
+	r.removeTool(key, path)
+	if path, err = r.install(ctx, key, script, options.host); err != nil {
+		return "", err
+	}
+	if err := options.verify(ctx, path); err != nil {
+		r.removeTool(key, path)
+		return "", fmt.Errorf("failed to verify the installed tool %s-%s: %w", name, version, err)
+	}
+	r.markVerified(key)
+	return path, nil
+}
+
+// installTool installs the tool through piped unless it's found in the cache or the tools directories.
+func (r *ToolRegistry) installTool(ctx context.Context, key toolKey, script, host string) (string, error) {
+	name, version := key.name, key.version
+	if path, ok := r.cachedPath(key); ok {
+		toolregistrymetrics.LookedUpCache(name, version, toolregistrymetrics.CacheHit)
+		return path, nil
+	}
+	if path, ok := r.lookupDirs(key); ok {
+		toolregistrymetrics.LookedUpCache(name, version, toolregistrymetrics.CacheHit)
+		r.cache(key, path)
+		return path, nil
+	}
+	toolregistrymetrics.LookedUpCache(name, version, toolregistrymetrics.CacheMiss)
+	return r.install(ctx, key, script, host)
+}
 
-	return res.GetInstalledPath(), nil
+// install installs the tool through piped, places it into the tools directory if configured, and caches it.
+func (r *ToolRegistry) install(ctx context.Context, key toolKey, script, host string) (string, error) {
+	name, version := key.name, key.version
+	path, err := r.callInstallTool(ctx, name, version, script, host)
+	if err != nil {
+		return "", err
+	}
+	if fi, err := os.Stat(path); err == nil {
+		toolregistrymetrics.InstalledBytes(name, version, fi.Size())
+	}
+
+	if r.toolsDir != "" {
+		dst := filepath.Join(r.toolsDir, key.filename())
+		if err := copyFile(path, dst); err != nil {
+			return "", fmt.Errorf("failed to place the tool %s-%s into the tools directory: %w", name, version, err)
+		}
+		path = dst
+	}
+
+	r.cache(key, path)
+	return path, nil
+}
+
+// removeTool removes the cache entry and the binary of the tool which failed the verification.
+// The binaries in the shared tools directories are kept since they are read-only and used by other plugins.
+// The binary installed by piped is also removed when the tools directory is not configured,
+// so that piped installs it again.
+func (r *ToolRegistry) removeTool(k toolKey, path string) {
+	r.invalidate(k)
+	dir := filepath.Dir(path)
+	if slices.ContainsFunc(r.sharedToolsDirs, func(d string) bool { return filepath.Clean(d) == dir }) {
+		return
+	}
+	os.Remove(path)
+}
+
+// callInstallTool calls the install API of piped, retrying it with backoff on failure.
+func (r *ToolRegistry) callInstallTool(ctx context.Context, name, version, script, host string) (string, error) {
+	policy := retry.Policy{
+		MaxAttempts:     max(r.retryAttempts, 1),
+		InitialInterval: r.retryBaseInterval,
+		MaxInterval:     r.retryMaxInterval,
+		Jitter:          1,
+		Retryable:       isRetriable,
+		Clock:           r.clock,
+	}
+	return retry.DoValue(ctx, policy, func(ctx context.Context) (string, error) {
+		if err := r.waitDownload(ctx, host); err != nil {
+			return "", retry.Permanent(err)
+		}
+
+		start := r.clock.Now()
+		res, err := r.client.InstallTool(ctx, &service.InstallToolRequest{
+			Name:          name,
+			Version:       version,
+			InstallScript: script,
+		})
+		if err != nil {
+			toolregistrymetrics.InstalledTool(name, version, toolregistrymetrics.StatusFailure, r.clock.Since(start))
+			return "", err
+		}
+		toolregistrymetrics.InstalledTool(name, version, toolregistrymetrics.StatusSuccess, r.clock.Since(start))
+		return res.GetInstalledPath(), nil
+	})
+}
+
+// waitDownload blocks until a download from the given host is allowed by the rate limit.
+func (r *ToolRegistry) waitDownload(ctx context.Context, host string) error {
+	if host == "" || r.downloadRateLimit == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	l, ok := r.limiters[host]
+	if !ok {
+		l = rate.NewLimiter(r.downloadRateLimit, max(r.downloadBurst, 1))
+		r.limiters[host] = l
+	}
+	r.mu.Unlock()
+
+	return l.Wait(ctx)
+}
+
+// isRetriable returns true if the error returned by the install API of piped is worth retrying.
+func isRetriable(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.Canceled, codes.DeadlineExceeded, codes.PermissionDenied, codes.Unimplemented:
+		return false
+	default:
+		return true
+	}
+}
+
+// lookupDirs looks up the tool in the tools directory and the shared tools directories.
+func (r *ToolRegistry) lookupDirs(k toolKey) (string, bool) {
+	paths := make([]string, 0, len(r.sharedToolsDirs)+1)
+	if r.toolsDir != "" {
+		paths = append(paths, filepath.Join(r.toolsDir, k.filename()))
+	}
+	for _, dir := range r.sharedToolsDirs {
+		paths = append(paths, filepath.Join(dir, toolFilename(k.name, k.version)))
+	}
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// cache stores the installed path of the tool.
+func (r *ToolRegistry) cache(k toolKey, path string) {
+	r.mu.Lock()
+	r.installed[k] = &installedTool{
+		path:     path,
+		lastUsed: r.clock.Now(),
+	}
+	r.mu.Unlock()
+}
+
+// markVerified records that the cached tool has passed the verification.
+func (r *ToolRegistry) markVerified(k toolKey) {
+	r.mu.Lock()
+	if t, ok := r.installed[k]; ok {
+		t.verified = true
+	}
+	r.mu.Unlock()
+}
+
+// isVerified returns true if the cached tool has passed the verification.
+func (r *ToolRegistry) isVerified(k toolKey) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.installed[k]
+	return ok && t.verified
+}
+
+// invalidate removes the cache entry for the given tool.
+func (r *ToolRegistry) invalidate(k toolKey) {
+	r.mu.Lock()
+	delete(r.installed, k)
+	r.mu.Unlock()
+}
+
+// cachedPath returns the path of the tool installed before.
+// It returns false when the tool has not been installed yet or the installed binary has been removed.
+func (r *ToolRegistry) cachedPath(k toolKey) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.installed[k]
+	if !ok {
+		return "", false
+	}
+	if _, err := os.Stat(t.path); err != nil {
+		delete(r.installed, k)
+		return "", false
+	}
+	t.lastUsed = r.clock.Now()
+	return t.path, true
+}
+
+// List returns the tools installed through this registry sorted by the name and the version.
+// The versions are ordered by their precedence (e.g. "1.9.0" before "1.10.0") when they are semantic versions.
+// The tools whose binaries have been removed are not included.
+func (r *ToolRegistry) List() []InstalledTool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tools := make([]InstalledTool, 0, len(r.installed))
+	for k, t := range r.installed {
+		fi, err := os.Stat(t.path)
+		if err != nil {
+			continue
+		}
+		tools = append(tools, InstalledTool{
+			Name:       k.name,
+			Version:    k.version,
+			Path:       t.path,
+			Size:       fi.Size(),
+			LastUsedAt: t.lastUsed,
+		})
+	}
+	slices.SortFunc(tools, func(a, b InstalledTool) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), version.Compare(a.Version, b.Version))
+	})
+	return tools
+}
+
+// verifyCommand runs the installed binary with the given arguments and returns an error if it fails.
+func verifyCommand(ctx context.Context, path string, args []string) error {
+	out, err := exec.CommandContext(ctx, path, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w, output: %s", path, err, string(out))
+	}
+	return nil
+}
+
+// toolFilename returns the filename of the tool in the shared tools directories.
+// It's the same as the one used by piped.
+func toolFilename(name, version string) string {
+	return name + "-" + version
+}
+
+// copyFile copies the file at src to dst as an executable.
+// The file is written to a temporary file first and then renamed, so that a partially written file is never used.
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
 }
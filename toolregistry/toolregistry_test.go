@@ -0,0 +1,341 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	service "github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+// fakeClient installs the tools in the same way as piped.
+// It caches the installed tools by the name and the version, and returns the same path without installing them again
+// while the binary exists.
+type fakeClient struct {
+	service.PluginServiceClient
+	dir    string
+	binary string
+	err    error
+	calls  atomic.Uint32
+	script atomic.String
+
+	mu        sync.Mutex
+	installed map[string]bool
+}
+
+func (c *fakeClient) InstallTool(ctx context.Context, in *service.InstallToolRequest, opts ...grpc.CallOption) (*service.InstallToolResponse, error) {
+	c.calls.Inc()
+	c.script.Store(in.GetInstallScript())
+	if c.err != nil {
+		return nil, c.err
+	}
+	path := filepath.Join(c.dir, in.GetName()+"-"+in.GetVersion())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := os.Stat(path); err == nil && c.installed[path] {
+		return &service.InstallToolResponse{InstalledPath: path}, nil
+	}
+	binary := c.binary
+	if binary == "" {
+		binary = "#!/bin/sh\n"
+	}
+	if err := os.WriteFile(path, []byte(binary), 0o755); err != nil {
+		return nil, err
+	}
+	if c.installed == nil {
+		c.installed = make(map[string]bool)
+	}
+	c.installed[path] = true
+	return &service.InstallToolResponse{InstalledPath: path}, nil
+}
+
+func TestToolRegistry_InstallTool(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{dir: t.TempDir()}
+	r := NewToolRegistry(client)
+
+	path, err := r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(client.dir, "tool-v1.0.0"), path)
+	assert.Equal(t, uint32(1), client.calls.Load())
+
+	// The second call should be served from the cache.
+	path, err = r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(client.dir, "tool-v1.0.0"), path)
+	assert.Equal(t, uint32(1), client.calls.Load())
+
+	// A different version should not be served from the cache.
+	_, err = r.InstallTool(context.Background(), "tool", "v2.0.0", "script")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), client.calls.Load())
+
+	// A different install script should not be served from the cache.
+	_, err = r.InstallTool(context.Background(), "tool", "v1.0.0", "new script")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), client.calls.Load())
+	assert.Equal(t, "new script", client.script.Load())
+
+	// The cache entry should be invalidated when the binary is removed.
+	require.NoError(t, os.Remove(path))
+	_, err = r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(4), client.calls.Load())
+}
+
+func TestToolRegistry_InstallTool_Failure(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{dir: t.TempDir(), err: errors.New("failed")}
+	r := NewToolRegistry(client)
+
+	_, err := r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.Error(t, err)
+
+	_, err = r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.Error(t, err)
+	assert.Equal(t, uint32(2), client.calls.Load())
+}
+
+func TestToolRegistry_InstallTool_Verify(t *testing.T) {
+	t.Parallel()
+
+	// The binary fails the verification the first time it's run.
+	marker := filepath.Join(t.TempDir(), "marker")
+	flaky := "#!/bin/sh\nif [ -e " + marker + " ]; then exit 0; fi\ntouch " + marker + "\nexit 1\n"
+
+	tests := []struct {
+		name          string
+		binary        string
+		expectedCalls uint32
+		expectErr     bool
+	}{
+		{
+			name:          "verification succeeded",
+			binary:        "#!/bin/sh\nexit 0\n",
+			expectedCalls: 1,
+		},
+		{
+			name:          "verification succeeded after reinstalling",
+			binary:        flaky,
+			expectedCalls: 2,
+		},
+		{
+			name:          "verification failed",
+			binary:        "#!/bin/sh\nexit 1\n",
+			expectedCalls: 2,
+			expectErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &fakeClient{dir: t.TempDir(), binary: tt.binary}
+			r := NewToolRegistry(client)
+
+			path, err := r.InstallTool(context.Background(), "tool", "v1.0.0", "script", WithVerifyCommand("version"))
+			assert.Equal(t, tt.expectedCalls, client.calls.Load())
+			if tt.expectErr {
+				require.Error(t, err)
+				assert.NoFileExists(t, filepath.Join(client.dir, "tool-v1.0.0"))
+				// The tool is not cached, so it's installed and verified again.
+				_, err = r.InstallTool(context.Background(), "tool", "v1.0.0", "script", WithVerifyCommand("version"))
+				require.Error(t, err)
+				assert.Equal(t, 2*tt.expectedCalls, client.calls.Load())
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, path)
+
+			// The verified tool is not verified again.
+			_, err = r.InstallTool(context.Background(), "tool", "v1.0.0", "script", WithVerifyCommand("version"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCalls, client.calls.Load())
+		})
+	}
+}
+
+func TestToolRegistry_InstallTool_VerifyFound(t *testing.T) {
+	t.Parallel()
+
+	t.Run("tools directory", func(t *testing.T) {
+		t.Parallel()
+
+		client := &fakeClient{dir: t.TempDir()}
+		toolsDir := t.TempDir()
+		stale := filepath.Join(toolsDir, newToolKey("tool", "v1.0.0", "script").filename())
+		require.NoError(t, os.WriteFile(stale, []byte("#!/bin/sh\nexit 1\n"), 0o755))
+
+		r := NewToolRegistry(client, WithToolsDir(toolsDir))
+		path, err := r.InstallTool(context.Background(), "tool", "v1.0.0", "script", WithVerifyCommand("version"))
+		require.NoError(t, err)
+		assert.Equal(t, stale, path)
+		// The binary left in the tools directory is replaced with the one installed through piped.
+		assert.Equal(t, uint32(1), client.calls.Load())
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\n", string(content))
+	})
+
+	t.Run("shared tools directory", func(t *testing.T) {
+		t.Parallel()
+
+		client := &fakeClient{dir: t.TempDir()}
+		sharedDir := t.TempDir()
+		shared := filepath.Join(sharedDir, "tool-v1.0.0")
+		require.NoError(t, os.WriteFile(shared, []byte("#!/bin/sh\nexit 1\n"), 0o755))
+
+		r := NewToolRegistry(client, WithSharedToolsDirs(sharedDir))
+		path, err := r.InstallTool(context.Background(), "tool", "v1.0.0", "script", WithVerifyCommand("version"))
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(client.dir, "tool-v1.0.0"), path)
+		assert.Equal(t, uint32(1), client.calls.Load())
+		// The binary in the shared tools directory is kept.
+		assert.FileExists(t, shared)
+	})
+}
+
+func TestToolRegistry_InstallTool_ToolsDir(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{dir: t.TempDir()}
+	toolsDir := t.TempDir()
+	r := NewToolRegistry(client, WithToolsDir(toolsDir), WithPluginIsolation("plugin"))
+
+	want := filepath.Join(toolsDir, "plugin", newToolKey("tool", "v1.0.0", "script").filename())
+	path, err := r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.NoError(t, err)
+	assert.Equal(t, want, path)
+	assert.FileExists(t, path)
+	assert.Equal(t, uint32(1), client.calls.Load())
+
+	// Another registry with the same layout should find the tool without installing it.
+	r = NewToolRegistry(client, WithToolsDir(toolsDir), WithPluginIsolation("plugin"))
+	path, err = r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.NoError(t, err)
+	assert.Equal(t, want, path)
+	assert.Equal(t, uint32(1), client.calls.Load())
+
+	// The tool installed with a different script should not be found in the tools directory.
+	r = NewToolRegistry(client, WithToolsDir(toolsDir), WithPluginIsolation("plugin"))
+	path, err = r.InstallTool(context.Background(), "tool", "v1.0.0", "new script")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(toolsDir, "plugin", newToolKey("tool", "v1.0.0", "new script").filename()), path)
+	assert.Equal(t, uint32(2), client.calls.Load())
+}
+
+func TestToolRegistry_InstallTool_SharedToolsDirs(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{dir: t.TempDir()}
+	sharedDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(sharedDir, "tool-v1.0.0"), []byte("#!/bin/sh\n"), 0o755))
+
+	r := NewToolRegistry(client, WithToolsDir(t.TempDir()), WithSharedToolsDirs(sharedDir))
+
+	path, err := r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(sharedDir, "tool-v1.0.0"), path)
+	assert.Equal(t, uint32(0), client.calls.Load())
+
+	// The tool not found in the shared directory should be installed.
+	_, err = r.InstallTool(context.Background(), "tool", "v2.0.0", "script")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), client.calls.Load())
+}
+
+func TestToolRegistry_List(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{dir: t.TempDir()}
+	clk := clocktest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewToolRegistry(client, WithClock(clk))
+	assert.Empty(t, r.List())
+
+	for _, v := range []string{"v10.0.0", "v9.0.0"} {
+		_, err := r.InstallTool(context.Background(), "tool", v, "script")
+		require.NoError(t, err)
+	}
+	_, err := r.InstallTool(context.Background(), "another", "v1.0.0", "script")
+	require.NoError(t, err)
+
+	tools := r.List()
+	require.Len(t, tools, 3)
+	assert.Equal(t, "another", tools[0].Name)
+	assert.Equal(t, "tool", tools[1].Name)
+	// The versions are ordered by their precedence rather than lexically.
+	assert.Equal(t, "v9.0.0", tools[1].Version)
+	assert.Equal(t, "v10.0.0", tools[2].Version)
+	assert.Equal(t, filepath.Join(client.dir, "tool-v10.0.0"), tools[2].Path)
+	assert.Equal(t, int64(len("#!/bin/sh\n")), tools[2].Size)
+	assert.Equal(t, clk.Now(), tools[2].LastUsedAt)
+
+	// The removed tool should not be listed.
+	require.NoError(t, os.Remove(tools[0].Path))
+	assert.Len(t, r.List(), 2)
+}
+
+func TestToolRegistry_InstallTool_Retry(t *testing.T) {
+	t.Parallel()
+
+	client := &flakyClient{fakeClient: fakeClient{dir: t.TempDir()}, failures: 2}
+	r := NewToolRegistry(client, WithInstallRetry(3, time.Millisecond, 10*time.Millisecond))
+
+	path, err := r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(client.dir, "tool-v1.0.0"), path)
+	assert.Equal(t, uint32(3), client.calls.Load())
+
+	// The error which is not worth retrying should be returned immediately.
+	client = &flakyClient{fakeClient: fakeClient{dir: t.TempDir(), err: status.Error(codes.InvalidArgument, "invalid script")}}
+	r = NewToolRegistry(client, WithInstallRetry(3, time.Millisecond, 10*time.Millisecond))
+
+	_, err = r.InstallTool(context.Background(), "tool", "v1.0.0", "script")
+	require.Error(t, err)
+	assert.Equal(t, uint32(1), client.calls.Load())
+}
+
+// flakyClient fails the given number of times before succeeding.
+type flakyClient struct {
+	fakeClient
+	failures uint32
+}
+
+func (c *flakyClient) InstallTool(ctx context.Context, in *service.InstallToolRequest, opts ...grpc.CallOption) (*service.InstallToolResponse, error) {
+	if c.calls.Load() < c.failures {
+		c.calls.Inc()
+		return nil, errors.New("temporary failure")
+	}
+	return c.fakeClient.InstallTool(ctx, in, opts...)
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package toolregistrymetrics provides the prometheus metrics of the tool registry.
+package toolregistrymetrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	toolKey    = "tool"
+	versionKey = "version"
+	statusKey  = "status"
+	resultKey  = "result"
+)
+
+// Status represents the result of a tool installation.
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailure Status = "failure"
+)
+
+// CacheResult represents whether the installed tool was found in the cache or not.
+type CacheResult string
+
+const (
+	CacheHit  CacheResult = "hit"
+	CacheMiss CacheResult = "miss"
+)
+
+var (
+	installSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_tool_install_seconds",
+			Help:    "Histogram of the seconds taken to install a tool.",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300},
+		},
+		[]string{toolKey, versionKey, statusKey},
+	)
+	installFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_tool_install_failures_total",
+			Help: "Total number of failed tool installations.",
+		},
+		[]string{toolKey, versionKey},
+	)
+	installCacheTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_tool_install_cache_total",
+			Help: "Total number of tool installation requests grouped by the cache result.",
+		},
+		[]string{toolKey, versionKey, resultKey},
+	)
+	installedBytesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_tool_installed_bytes_total",
+			Help: "Total size in bytes of the tool binaries installed without hitting the cache.",
+		},
+		[]string{toolKey, versionKey},
+	)
+)
+
+// InstalledTool records the result and the duration of a tool installation.
+func InstalledTool(name, version string, s Status, d time.Duration) {
+	installSeconds.With(prometheus.Labels{
+		toolKey:    name,
+		versionKey: version,
+		statusKey:  string(s),
+	}).Observe(d.Seconds())
+
+	if s == StatusFailure {
+		installFailuresTotal.With(prometheus.Labels{
+			toolKey:    name,
+			versionKey: version,
+		}).Inc()
+	}
+}
+
+// LookedUpCache records whether the requested tool was found in the cache.
+func LookedUpCache(name, version string, r CacheResult) {
+	installCacheTotal.With(prometheus.Labels{
+		toolKey:    name,
+		versionKey: version,
+		resultKey:  string(r),
+	}).Inc()
+}
+
+// InstalledBytes records the size of the newly installed tool binary.
+func InstalledBytes(name, version string, n int64) {
+	installedBytesTotal.With(prometheus.Labels{
+		toolKey:    name,
+		versionKey: version,
+	}).Add(float64(n))
+}
+
+// Register registers all metrics of the tool registry to the given registerer.
+func Register(r prometheus.Registerer) {
+	r.MustRegister(
+		installSeconds,
+		installFailuresTotal,
+		installCacheTotal,
+		installedBytesTotal,
+	)
+}
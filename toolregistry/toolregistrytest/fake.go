@@ -0,0 +1,144 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistrytest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipecd/pkg/plugin/pipedservice"
+)
+
+// ToolRequest is the request to install a tool received by FakeToolRegistry.
+type ToolRequest struct {
+	Name    string
+	Version string
+}
+
+// FakeToolRegistry provides the ToolRegistry installing the fake tools under a temp dir.
+// Unlike NewTestToolRegistry, it never runs the install scripts, so it never touches the network.
+//
+// The tool installed by default is a shell script printing its name, version, and arguments, e.g. "kubectl 1.32.0 apply -f -".
+// Use SetStub or SetBinary to replace it with the one behaving as the tests expect.
+type FakeToolRegistry struct {
+	t   testing.TB
+	dir string
+
+	mu       sync.Mutex
+	stubs    map[string]string
+	binaries map[string]string
+	requests []ToolRequest
+}
+
+// NewFakeToolRegistry creates a new FakeToolRegistry.
+func NewFakeToolRegistry(t testing.TB) *FakeToolRegistry {
+	return &FakeToolRegistry{
+		t:        t,
+		dir:      t.TempDir(),
+		stubs:    make(map[string]string),
+		binaries: make(map[string]string),
+	}
+}
+
+// ToolRegistry returns a new ToolRegistry installing the fake tools.
+func (r *FakeToolRegistry) ToolRegistry(opts ...toolregistry.Option) *toolregistry.ToolRegistry {
+	return toolregistry.NewToolRegistry(&fakeToolClient{registry: r}, opts...)
+}
+
+// SetStub sets the shell script installed as the tool of the given name for all versions.
+// The script is run by /bin/sh, and the version is given as the TOOL_VERSION environment variable.
+func (r *FakeToolRegistry) SetStub(name, script string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stubs[name] = script
+}
+
+// SetBinary sets the binary at the given path installed as the tool of the given name for all versions.
+func (r *FakeToolRegistry) SetBinary(name, path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.binaries[name] = path
+}
+
+// Requests returns the requests to install the tools in the order received.
+// The tools already installed by the ToolRegistry are not requested again since they are cached.
+func (r *FakeToolRegistry) Requests() []ToolRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.requests)
+}
+
+// RequestedVersions returns the versions of the tool of the given name requested to install.
+func (r *FakeToolRegistry) RequestedVersions(name string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var versions []string
+	for _, req := range r.requests {
+		if req.Name == name {
+			versions = append(versions, req.Version)
+		}
+	}
+	return versions
+}
+
+// install places the fake tool and returns its path.
+func (r *FakeToolRegistry) install(name, version string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, ToolRequest{Name: name, Version: version})
+
+	target := filepath.Join(r.dir, name+"-"+version)
+	if src, ok := r.binaries[name]; ok {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the binary of the tool %s: %w", name, err)
+		}
+		if err := os.WriteFile(target, data, 0o755); err != nil {
+			return "", fmt.Errorf("failed to place the tool %s: %w", name, err)
+		}
+		return target, nil
+	}
+
+	script, ok := r.stubs[name]
+	if !ok {
+		script = fmt.Sprintf("echo %s %s \"$@\"", name, version)
+	}
+	content := fmt.Sprintf("#!/bin/sh\nTOOL_VERSION=%s\nexport TOOL_VERSION\n%s\n", version, script)
+	if err := os.WriteFile(target, []byte(content), 0o755); err != nil {
+		return "", fmt.Errorf("failed to place the tool %s: %w", name, err)
+	}
+	return target, nil
+}
+
+type fakeToolClient struct {
+	pipedservice.PluginServiceClient
+	registry *FakeToolRegistry
+}
+
+func (c *fakeToolClient) InstallTool(_ context.Context, in *pipedservice.InstallToolRequest, _ ...grpc.CallOption) (*pipedservice.InstallToolResponse, error) {
+	path, err := c.registry.install(in.GetName(), in.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	return &pipedservice.InstallToolResponse{InstalledPath: path}, nil
+}
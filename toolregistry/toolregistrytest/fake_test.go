@@ -0,0 +1,95 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistrytest
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/toolregistry"
+)
+
+func TestFakeToolRegistry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fake := NewFakeToolRegistry(t)
+	fake.SetStub("helm", `echo "helm version $TOOL_VERSION"`)
+	r := fake.ToolRegistry()
+
+	tests := []struct {
+		name     string
+		tool     string
+		version  string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "default stub",
+			tool:     "kubectl",
+			version:  "1.32.0",
+			args:     []string{"apply", "-f", "-"},
+			expected: "kubectl 1.32.0 apply -f -\n",
+		},
+		{
+			name:     "user-supplied stub",
+			tool:     "helm",
+			version:  "3.17.0",
+			expected: "helm version 3.17.0\n",
+		},
+	}
+	for _, tt := range tests {
+		path, err := r.InstallTool(ctx, tt.tool, tt.version, "curl https://example.com | sh")
+		require.NoError(t, err, tt.name)
+		out, err := exec.CommandContext(ctx, path, tt.args...).Output()
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, string(out), tt.name)
+	}
+
+	// The installed tools are cached, so it's not requested again.
+	_, err := r.InstallTool(ctx, "kubectl", "1.32.0", "curl https://example.com | sh")
+	require.NoError(t, err)
+	_, err = r.InstallToolFromDownload(ctx, "kubectl", "1.31.0", toolregistry.Download{URL: "https://example.com/kubectl-{{ .Version }}"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []ToolRequest{
+		{Name: "kubectl", Version: "1.32.0"},
+		{Name: "helm", Version: "3.17.0"},
+		{Name: "kubectl", Version: "1.31.0"},
+	}, fake.Requests())
+	assert.Equal(t, []string{"1.32.0", "1.31.0"}, fake.RequestedVersions("kubectl"))
+}
+
+func TestFakeToolRegistry_SetBinary(t *testing.T) {
+	t.Parallel()
+
+	src := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, os.WriteFile(src, []byte("#!/bin/sh\necho binary\n"), 0o755))
+
+	fake := NewFakeToolRegistry(t)
+	fake.SetBinary("tool", src)
+
+	path, err := fake.ToolRegistry().InstallTool(context.Background(), "tool", "1.0.0", "")
+	require.NoError(t, err)
+	out, err := exec.Command(path).Output()
+	require.NoError(t, err)
+	assert.Equal(t, "binary\n", string(out))
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ByteSize represents a size in bytes that can be marshaled/unmarshaled as a string in JSON.
+// It supports both numeric values (interpreted as bytes) and string values with the suffixes used in Kubernetes,
+// that is, the decimal suffixes (k, M, G, T, P, E) and the binary suffixes (Ki, Mi, Gi, Ti, Pi, Ei), e.g., "512Mi", "1.5G".
+type ByteSize int64
+
+// byteSizeSuffixes is the list of the suffixes and their multipliers.
+// The binary suffixes must come before the decimal ones to match the longest suffix first.
+var byteSizeSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"Ei", 1 << 60},
+	{"Pi", 1 << 50},
+	{"Ti", 1 << 40},
+	{"Gi", 1 << 30},
+	{"Mi", 1 << 20},
+	{"Ki", 1 << 10},
+	{"E", 1e18},
+	{"P", 1e15},
+	{"T", 1e12},
+	{"G", 1e9},
+	{"M", 1e6},
+	{"k", 1e3},
+}
+
+// Bytes returns the size in bytes.
+func (s ByteSize) Bytes() int64 {
+	return int64(s)
+}
+
+// String returns the string representation of the size with the largest suffix which represents it exactly,
+// e.g., "512Mi", "2G", "100".
+func (s ByteSize) String() string {
+	n := int64(s)
+	if n == 0 {
+		return "0"
+	}
+	suffix, multiplier := "", int64(1)
+	for _, u := range byteSizeSuffixes {
+		if n%u.multiplier == 0 && u.multiplier > multiplier {
+			suffix, multiplier = u.suffix, u.multiplier
+		}
+	}
+	return strconv.FormatInt(n/multiplier, 10) + suffix
+}
+
+// MarshalJSON marshals the ByteSize to a JSON string representation (e.g., "512Mi").
+func (s ByteSize) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// JSONSchema returns the JSON Schema of ByteSize accepting both numeric values and string values.
+func (ByteSize) JSONSchema() map[string]any {
+	return map[string]any{"type": []any{"string", "integer"}}
+}
+
+// UnmarshalJSON unmarshals a JSON value to ByteSize.
+// It accepts both numeric values (interpreted as bytes) and string values (e.g., "512Mi", "1G", "100").
+func (s *ByteSize) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch raw := v.(type) {
+	case float64:
+		if raw < 0 || raw != math.Trunc(raw) {
+			return fmt.Errorf("invalid byte size: %v", string(b))
+		}
+		*s = ByteSize(raw)
+		return nil
+	case string:
+		value, err := ParseByteSize(raw)
+		if err != nil {
+			return err
+		}
+		*s = value
+		return nil
+	default:
+		return fmt.Errorf("invalid byte size: %v", string(b))
+	}
+}
+
+// ParseByteSize parses the string representation of the size, e.g., "512Mi", "1.5G", "100".
+func ParseByteSize(s string) (ByteSize, error) {
+	raw := strings.TrimSpace(s)
+	multiplier := int64(1)
+	for _, u := range byteSizeSuffixes {
+		if strings.HasSuffix(raw, u.suffix) {
+			multiplier = u.multiplier
+			raw = strings.TrimSuffix(raw, u.suffix)
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q: %w", s, err)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("invalid byte size %q: must not be negative", s)
+	}
+	bytes := value * float64(multiplier)
+	if bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid byte size %q: too large", s)
+	}
+	if bytes != math.Trunc(bytes) {
+		return 0, fmt.Errorf("invalid byte size %q: must be a whole number of bytes", s)
+	}
+	return ByteSize(bytes), nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package unit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestByteSizeMarshal(t *testing.T) {
+	type wrapper struct {
+		Size ByteSize
+	}
+
+	testcases := []struct {
+		name     string
+		input    wrapper
+		expected string
+	}{
+		{
+			name:     "zero",
+			input:    wrapper{Size: 0},
+			expected: `{"Size":"0"}`,
+		},
+		{
+			name:     "bytes",
+			input:    wrapper{Size: 100},
+			expected: `{"Size":"100"}`,
+		},
+		{
+			name:     "binary suffix",
+			input:    wrapper{Size: 512 << 20},
+			expected: `{"Size":"512Mi"}`,
+		},
+		{
+			name:     "decimal suffix",
+			input:    wrapper{Size: 2e9},
+			expected: `{"Size":"2G"}`,
+		},
+		{
+			name:     "binary suffix is preferred",
+			input:    wrapper{Size: 1 << 30},
+			expected: `{"Size":"1Gi"}`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json.Marshal(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(got))
+		})
+	}
+}
+
+func TestByteSizeUnmarshal(t *testing.T) {
+	type wrapper struct {
+		Size ByteSize
+	}
+
+	testcases := []struct {
+		name        string
+		input       string
+		expected    *wrapper
+		expectedErr bool
+	}{
+		{
+			name:     "size as number",
+			input:    `{"Size": 1024}`,
+			expected: &wrapper{Size: 1024},
+		},
+		{
+			name:     "size as string without suffix",
+			input:    `{"Size": "1024"}`,
+			expected: &wrapper{Size: 1024},
+		},
+		{
+			name:     "binary suffix",
+			input:    `{"Size": "512Mi"}`,
+			expected: &wrapper{Size: 512 << 20},
+		},
+		{
+			name:     "decimal suffix",
+			input:    `{"Size": "10k"}`,
+			expected: &wrapper{Size: 10000},
+		},
+		{
+			name:     "fractional value",
+			input:    `{"Size": "1.5Gi"}`,
+			expected: &wrapper{Size: 3 << 29},
+		},
+		{
+			name:        "unknown suffix",
+			input:       `{"Size": "1GB"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "negative value",
+			input:       `{"Size": "-1Mi"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "fraction of a byte",
+			input:       `{"Size": "0.5"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "too large",
+			input:       `{"Size": "100Ei"}`,
+			expectedErr: true,
+		},
+		{
+			name:        "fractional number",
+			input:       `{"Size": 1.5}`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid type bool",
+			input:       `{"Size": true}`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := &wrapper{}
+			err := json.Unmarshal([]byte(tc.input), got)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if tc.expected != nil {
+				assert.Equal(t, tc.expected, got)
+			}
+		})
+	}
+}
@@ -34,6 +34,11 @@ func (d Duration) MarshalJSON() ([]byte, error) {
 	return json.Marshal(time.Duration(d).String())
 }
 
+// JSONSchema returns the JSON Schema of Duration accepting both numeric values and string values.
+func (Duration) JSONSchema() map[string]any {
+	return map[string]any{"type": []any{"string", "number"}}
+}
+
 // UnmarshalJSON unmarshals a JSON value to Duration.
 // It accepts both numeric values (interpreted as nanoseconds) and string values (e.g., "5m", "1h30m").
 func (d *Duration) UnmarshalJSON(b []byte) error {
@@ -48,7 +53,7 @@ func (d *Duration) UnmarshalJSON(b []byte) error {
 	case string:
 		value, err := time.ParseDuration(raw)
 		if err != nil {
-			return err
+			return fmt.Errorf("invalid duration: %w", err)
 		}
 		*d = Duration(value)
 		return nil
@@ -48,6 +48,11 @@ func (p Percentage) MarshalJSON() ([]byte, error) {
 	return json.Marshal(p.String())
 }
 
+// JSONSchema returns the JSON Schema of Percentage accepting both plain numbers and percentage strings.
+func (Percentage) JSONSchema() map[string]any {
+	return map[string]any{"type": []any{"string", "integer"}}
+}
+
 // UnmarshalJSON unmarshals a JSON string to Percentage.
 // It accepts both plain numbers (e.g., "50") and percentage strings (e.g., "50%").
 func (p *Percentage) UnmarshalJSON(b []byte) error {
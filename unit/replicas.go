@@ -59,6 +59,11 @@ func (r Replicas) MarshalJSON() ([]byte, error) {
 	return json.Marshal(r.String())
 }
 
+// JSONSchema returns the JSON Schema of Replicas accepting both numeric values and percentage strings.
+func (Replicas) JSONSchema() map[string]any {
+	return map[string]any{"type": []any{"string", "integer"}}
+}
+
 // UnmarshalJSON unmarshals a JSON value to Replicas.
 // It accepts numeric values, string numbers (e.g., "5"), and percentage strings (e.g., "50%").
 func (r *Replicas) UnmarshalJSON(b []byte) error {
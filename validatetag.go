@@ -0,0 +1,214 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateTag is the struct tag to declare the constraints of the field.
+// The rules are separated by commas, and all of them must be satisfied, e.g.
+//
+//	Endpoint string   `json:"endpoint" validate:"required,url"`
+//	Mode     string   `json:"mode" validate:"oneof=fast safe"`
+//	Replicas int      `json:"replicas" validate:"min=1,max=10"`
+//	Regions  []string `json:"regions" validate:"min=1"`
+//
+// The supported rules are:
+//   - required: the field must not be the zero value.
+//   - oneof=a b c: the value must be one of the space-separated values. The empty value is allowed.
+//   - min=N, max=N: the number must be in the range. For the strings, the slices, and the maps, the length is checked.
+//   - url: the string must be an absolute URL. The empty value is allowed.
+//
+// The nested structs, including the ones in the pointers, the slices, and the maps, are validated as well.
+// The errors don't include the values of the sensitive fields and the Credential fields, since they may be logged.
+const validateTag = "validate"
+
+// validateTags validates the given value by the `validate` struct tags.
+// All violations are returned together with the paths of the fields in JSON.
+func validateTags(v any) error {
+	var errs []error
+	validateValue(reflect.ValueOf(v), "", false, &errs)
+	return errors.Join(errs...)
+}
+
+func validateValue(v reflect.Value, path string, sensitive bool, errs *[]error) {
+	if !v.IsValid() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			validateValue(v.Elem(), path, sensitive, errs)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			fieldPath := path
+			if !f.Anonymous || name != "" {
+				if name == "" {
+					name = f.Name
+				}
+				fieldPath = joinFieldPath(path, name)
+			}
+			fieldSensitive := sensitive || f.Tag.Get(sensitiveTag) == "true" || f.Type == credentialType
+			if tag := f.Tag.Get(validateTag); tag != "" {
+				if err := validateField(v.Field(i), tag, fieldSensitive); err != nil {
+					*errs = append(*errs, fmt.Errorf("%s: %w", fieldPath, err))
+					continue
+				}
+			}
+			validateValue(v.Field(i), fieldPath, fieldSensitive, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), sensitive, errs)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+		})
+		for _, k := range keys {
+			validateValue(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k.Interface()), sensitive, errs)
+		}
+	}
+}
+
+func joinFieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// validateField validates the value of the field by the rules in the tag.
+// The value isn't included in the error when the field is sensitive.
+func validateField(v reflect.Value, tag string, sensitive bool) error {
+	for rule := range strings.SplitSeq(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		var err error
+		switch name {
+		case "":
+			continue
+		case "required":
+			if v.IsZero() {
+				return errors.New("must be set")
+			}
+		case "oneof":
+			err = validateOneOf(v, param, sensitive)
+		case "min":
+			err = validateRange(v, param, func(n, limit float64) bool { return n >= limit }, "at least", sensitive)
+		case "max":
+			err = validateRange(v, param, func(n, limit float64) bool { return n <= limit }, "at most", sensitive)
+		case "url":
+			err = validateURL(v, sensitive)
+		default:
+			err = fmt.Errorf("unknown validation rule %q", name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateOneOf(v reflect.Value, param string, sensitive bool) error {
+	v = reflect.Indirect(v)
+	if !v.IsValid() || v.IsZero() {
+		return nil
+	}
+	values := strings.Fields(param)
+	if slices.Contains(values, fmt.Sprint(v.Interface())) {
+		return nil
+	}
+	return fmt.Errorf("must be one of [%s]%s", strings.Join(values, ", "), butGot(sensitive, "%v", v.Interface()))
+}
+
+func validateRange(v reflect.Value, param string, ok func(n, limit float64) bool, word string, sensitive bool) error {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return fmt.Errorf("invalid parameter %q of the validation rule: %w", param, err)
+	}
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+
+	var n float64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	case reflect.String:
+		if n = float64(utf8.RuneCountInString(v.String())); !ok(n, limit) {
+			return fmt.Errorf("length must be %s %s, but got %d", word, param, int(n))
+		}
+		return nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if n = float64(v.Len()); !ok(n, limit) {
+			return fmt.Errorf("must have %s %s items, but got %d", word, param, int(n))
+		}
+		return nil
+	default:
+		return fmt.Errorf("the validation rule is not supported for %s", v.Type())
+	}
+	if !ok(n, limit) {
+		return fmt.Errorf("must be %s %s%s", word, param, butGot(sensitive, "%v", v.Interface()))
+	}
+	return nil
+}
+
+func validateURL(v reflect.Value, sensitive bool) error {
+	v = reflect.Indirect(v)
+	if !v.IsValid() || v.Kind() != reflect.String {
+		return nil
+	}
+	s := v.String()
+	if s == "" {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("must be an absolute URL%s", butGot(sensitive, "%q", s))
+	}
+	return nil
+}
+
+// butGot returns the part of the error message telling the invalid value, or an empty string for the sensitive fields.
+func butGot(sensitive bool, format string, v any) string {
+	if sensitive {
+		return ""
+	}
+	return fmt.Sprintf(", but got "+format, v)
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type taggedItem struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type taggedConfig struct {
+	Endpoint string                `json:"endpoint" validate:"required,url"`
+	Mode     string                `json:"mode,omitempty" validate:"oneof=fast safe"`
+	Replicas int                   `json:"replicas" validate:"min=1,max=10"`
+	Regions  []string              `json:"regions" validate:"min=1"`
+	Label    *string               `json:"label,omitempty" validate:"max=3"`
+	Items    []taggedItem          `json:"items,omitempty"`
+	ByName   map[string]taggedItem `json:"byName,omitempty"`
+	Nested   *taggedItem           `json:"nested,omitempty"`
+}
+
+func TestValidateTags(t *testing.T) {
+	t.Parallel()
+
+	valid := func() taggedConfig {
+		return taggedConfig{
+			Endpoint: "https://example.com",
+			Replicas: 1,
+			Regions:  []string{"us"},
+		}
+	}
+	long := "long"
+
+	testcases := []struct {
+		name        string
+		modify      func(c *taggedConfig)
+		expectedErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(c *taggedConfig) { c.Mode = "fast" },
+		},
+		{
+			name:        "required",
+			modify:      func(c *taggedConfig) { c.Endpoint = "" },
+			expectedErr: "endpoint: must be set",
+		},
+		{
+			name:        "url",
+			modify:      func(c *taggedConfig) { c.Endpoint = "example.com" },
+			expectedErr: `endpoint: must be an absolute URL, but got "example.com"`,
+		},
+		{
+			name:        "oneof",
+			modify:      func(c *taggedConfig) { c.Mode = "slow" },
+			expectedErr: "mode: must be one of [fast, safe], but got slow",
+		},
+		{
+			name:        "min and max",
+			modify:      func(c *taggedConfig) { c.Replicas = 11 },
+			expectedErr: "replicas: must be at most 10, but got 11",
+		},
+		{
+			name:        "length of slice",
+			modify:      func(c *taggedConfig) { c.Regions = nil },
+			expectedErr: "regions: must have at least 1 items, but got 0",
+		},
+		{
+			name:        "length of string in pointer",
+			modify:      func(c *taggedConfig) { c.Label = &long },
+			expectedErr: "label: length must be at most 3, but got 4",
+		},
+		{
+			name: "nested fields",
+			modify: func(c *taggedConfig) {
+				c.Items = []taggedItem{{Name: "a"}, {}}
+				c.ByName = map[string]taggedItem{"x": {}}
+				c.Nested = &taggedItem{}
+			},
+			expectedErr: "items[1].name: must be set\nbyName[x].name: must be set\nnested.name: must be set",
+		},
+		{
+			name: "aggregated",
+			modify: func(c *taggedConfig) {
+				c.Endpoint = ""
+				c.Replicas = 0
+			},
+			expectedErr: "endpoint: must be set\nreplicas: must be at least 1, but got 0",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := valid()
+			tc.modify(&c)
+			err := validateTags(&c)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestValidateTags_Sensitive(t *testing.T) {
+	t.Parallel()
+
+	type webhook struct {
+		URL string `json:"url" validate:"url"`
+	}
+	c := struct {
+		Webhook  string  `json:"webhook" sensitive:"true" validate:"url"`
+		Mode     string  `json:"mode" sensitive:"true" validate:"oneof=fast safe"`
+		Nested   webhook `json:"nested" sensitive:"true"`
+		Endpoint string  `json:"endpoint" validate:"url"`
+	}{
+		Webhook:  "hooks.example.com/secret-token",
+		Mode:     "secret-mode",
+		Nested:   webhook{URL: "nested-secret"},
+		Endpoint: "example.com",
+	}
+
+	// The values of the sensitive fields, including the nested ones, are not shown in the error.
+	err := validateTags(&c)
+	assert.EqualError(t, err, "webhook: must be an absolute URL\nmode: must be one of [fast, safe]\nnested.url: must be an absolute URL\n"+
+		`endpoint: must be an absolute URL, but got "example.com"`)
+}
+
+func TestValidateTags_UnknownRule(t *testing.T) {
+	t.Parallel()
+
+	c := struct {
+		Name string `validate:"email"`
+	}{}
+	assert.EqualError(t, validateTags(&c), `Name: unknown validation rule "email"`)
+}
+
+func TestValidate_TagsBeforeMethod(t *testing.T) {
+	t.Parallel()
+
+	c := &validatedTaggedConfig{}
+	assert.EqualError(t, validate(c), "name: must be set")
+	assert.False(t, c.called)
+
+	c.Name = "x"
+	assert.EqualError(t, validate(c), "validated")
+	assert.True(t, c.called)
+}
+
+type validatedTaggedConfig struct {
+	Name   string `json:"name" validate:"required"`
+	called bool
+}
+
+func (c *validatedTaggedConfig) Validate() error {
+	c.called = true
+	return errors.New("validated")
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"fmt"
+	"strings"
+)
+
+// prereleaseKeywords is the list of the prefixes of the image tag suffixes treated as prereleases.
+// The other suffixes are treated as the variants of the image, e.g. "alpine" or "debian-12-r4".
+var prereleaseKeywords = []string{"alpha", "beta", "rc", "pre", "preview", "dev", "snapshot", "canary", "nightly"}
+
+// SplitImage splits the given container image reference into the repository, the tag and the digest.
+// e.g. "ghcr.io/pipe-cd/piped:v0.50.0@sha256:abc" is split into "ghcr.io/pipe-cd/piped", "v0.50.0" and "sha256:abc".
+// The tag and the digest are empty if they are not specified.
+func SplitImage(image string) (repository, tag, digest string) {
+	repository = image
+	if i := strings.IndexByte(repository, '@'); i >= 0 {
+		repository, digest = repository[:i], repository[i+1:]
+	}
+	// The colon before the last slash is the separator of the registry port.
+	if i := strings.LastIndexByte(repository, ':'); i > strings.LastIndexByte(repository, '/') {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+	return repository, tag, digest
+}
+
+// ParseImageTag parses the given image tag heuristically as the version and the variant.
+// The tag must start with the version numbers optionally prefixed by "v".
+// The suffix following "-" is treated as the prerelease if it starts with a well-known keyword such as "rc" or "beta",
+// and the rest is returned as the variant, e.g.
+//
+//	"1.25-alpine"        -> 1.25.0, "alpine"
+//	"v2.3.1-debian-12-r4" -> 2.3.1, "debian-12-r4"
+//	"1.0.0-rc.1-alpine"  -> 1.0.0-rc.1, "alpine"
+func ParseImageTag(tag string) (Version, string, error) {
+	rest := strings.TrimPrefix(tag, "v")
+	end := strings.IndexFunc(rest, func(c rune) bool { return c != '.' && (c < '0' || c > '9') })
+	if end < 0 {
+		end = len(rest)
+	}
+	v, err := Parse(rest[:end])
+	if err != nil {
+		return Version{}, "", fmt.Errorf("%w: image tag %q doesn't start with a version", ErrInvalidVersion, tag)
+	}
+	v.original = tag
+
+	suffix := rest[end:]
+	if suffix == "" {
+		return v, "", nil
+	}
+	if suffix[0] != '-' || len(suffix) == 1 {
+		return Version{}, "", fmt.Errorf("%w: malformed image tag %q", ErrInvalidVersion, tag)
+	}
+	suffix = suffix[1:]
+
+	pre, variant, _ := strings.Cut(suffix, "-")
+	if !isPrereleaseKeyword(pre) {
+		return v, suffix, nil
+	}
+	if !validIdentifiers(pre, false) {
+		return Version{}, "", fmt.Errorf("%w: malformed prerelease in image tag %q", ErrInvalidVersion, tag)
+	}
+	v.Prerelease = strings.Split(pre, ".")
+	return v, variant, nil
+}
+
+// LatestImageTag returns the tag with the highest version among the given ones having the given variant.
+// The tags which can't be parsed by ParseImageTag (e.g. "latest") are ignored,
+// and the prereleases are considered only when includePrerelease is true.
+// It returns false if there is no such tag.
+func LatestImageTag(tags []string, variant string, includePrerelease bool) (string, bool) {
+	var (
+		latest Version
+		found  bool
+	)
+	for _, t := range tags {
+		v, vr, err := ParseImageTag(t)
+		if err != nil || vr != variant || (v.IsPrerelease() && !includePrerelease) {
+			continue
+		}
+		if !found || latest.LessThan(v) {
+			latest, found = v, true
+		}
+	}
+	if !found {
+		return "", false
+	}
+	return latest.Original(), true
+}
+
+func isPrereleaseKeyword(s string) bool {
+	s = strings.ToLower(s)
+	for _, k := range prereleaseKeywords {
+		if strings.HasPrefix(s, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitImage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		image      string
+		repository string
+		tag        string
+		digest     string
+	}{
+		{image: "nginx", repository: "nginx"},
+		{image: "nginx:1.25-alpine", repository: "nginx", tag: "1.25-alpine"},
+		{image: "ghcr.io/pipe-cd/piped:v0.50.0", repository: "ghcr.io/pipe-cd/piped", tag: "v0.50.0"},
+		{image: "localhost:5000/app", repository: "localhost:5000/app"},
+		{image: "localhost:5000/app:v1", repository: "localhost:5000/app", tag: "v1"},
+		{image: "app@sha256:abc", repository: "app", digest: "sha256:abc"},
+		{image: "localhost:5000/app:v1@sha256:abc", repository: "localhost:5000/app", tag: "v1", digest: "sha256:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			t.Parallel()
+			repository, tag, digest := SplitImage(tt.image)
+			assert.Equal(t, tt.repository, repository)
+			assert.Equal(t, tt.tag, tag)
+			assert.Equal(t, tt.digest, digest)
+		})
+	}
+}
+
+func TestParseImageTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tag         string
+		wantVersion string
+		wantVariant string
+		wantErr     bool
+	}{
+		{tag: "1.25", wantVersion: "1.25.0"},
+		{tag: "v2.3.1", wantVersion: "2.3.1"},
+		{tag: "1.25-alpine", wantVersion: "1.25.0", wantVariant: "alpine"},
+		{tag: "v2.3.1-debian-12-r4", wantVersion: "2.3.1", wantVariant: "debian-12-r4"},
+		{tag: "1.0.0-rc.1", wantVersion: "1.0.0-rc.1"},
+		{tag: "1.0.0-RC1-alpine", wantVersion: "1.0.0-RC1", wantVariant: "alpine"},
+		{tag: "1.0.0-beta.2-bookworm-slim", wantVersion: "1.0.0-beta.2", wantVariant: "bookworm-slim"},
+		{tag: "latest", wantErr: true},
+		{tag: "alpine-1.0", wantErr: true},
+		{tag: "1.2.3.4", wantErr: true},
+		{tag: "1.2.3_1", wantErr: true},
+		{tag: "1.2.3-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			t.Parallel()
+			v, variant, err := ParseImageTag(tt.tag)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidVersion)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantVersion, v.String())
+			assert.Equal(t, tt.wantVariant, variant)
+			assert.Equal(t, tt.tag, v.Original())
+		})
+	}
+}
+
+func TestLatestImageTag(t *testing.T) {
+	t.Parallel()
+
+	tags := []string{"latest", "1.24", "1.25", "1.25-alpine", "1.26-rc.1", "1.9-alpine", "1.24-alpine", "stable"}
+
+	got, ok := LatestImageTag(tags, "", false)
+	require.True(t, ok)
+	assert.Equal(t, "1.25", got)
+
+	got, ok = LatestImageTag(tags, "", true)
+	require.True(t, ok)
+	assert.Equal(t, "1.26-rc.1", got)
+
+	got, ok = LatestImageTag(tags, "alpine", false)
+	require.True(t, ok)
+	assert.Equal(t, "1.25-alpine", got)
+
+	_, ok = LatestImageTag(tags, "bookworm", false)
+	assert.False(t, ok)
+}
@@ -0,0 +1,280 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package version provides utilities to parse and compare the versions of artifacts and tools.
+//
+// The versions follow Semantic Versioning 2.0.0 (https://semver.org) with a few relaxations
+// commonly seen in the wild: a leading "v" is accepted, and the minor and the patch versions may be omitted.
+// The image tags are often not strict semantic versions (e.g. "1.25-alpine", "v2.3.1-debian-12-r4"),
+// so ParseImageTag provides a heuristic to split them into the version and the variant.
+package version
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidVersion is returned when the given string is not a valid version.
+var ErrInvalidVersion = errors.New("invalid version")
+
+// Version represents a semantic version.
+type Version struct {
+	// Major is the major version.
+	Major uint64
+	// Minor is the minor version.
+	Minor uint64
+	// Patch is the patch version.
+	Patch uint64
+	// Prerelease is the list of the dot-separated prerelease identifiers, e.g. ["rc", "1"] for "1.0.0-rc.1".
+	Prerelease []string
+	// Build is the build metadata, e.g. "20250101" for "1.0.0+20250101".
+	// It's ignored when comparing versions.
+	Build string
+
+	original string
+}
+
+// Parse parses the given string as a semantic version.
+// A leading "v" is accepted, and the omitted minor and patch versions are treated as 0, e.g. "v1.2" is parsed as "1.2.0".
+func Parse(s string) (Version, error) {
+	v := Version{original: s}
+	rest := strings.TrimPrefix(s, "v")
+
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		v.Build = rest[i+1:]
+		rest = rest[:i]
+		if !validIdentifiers(v.Build, false) {
+			return Version{}, fmt.Errorf("%w %q: malformed build metadata", ErrInvalidVersion, s)
+		}
+	}
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		pre := rest[i+1:]
+		rest = rest[:i]
+		if !validIdentifiers(pre, true) {
+			return Version{}, fmt.Errorf("%w %q: malformed prerelease", ErrInvalidVersion, s)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("%w %q: too many version numbers", ErrInvalidVersion, s)
+	}
+	nums := [3]uint64{}
+	for i, p := range parts {
+		if !isNumeric(p) {
+			return Version{}, fmt.Errorf("%w %q: malformed version number %q", ErrInvalidVersion, s, p)
+		}
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return Version{}, fmt.Errorf("%w %q: %w", ErrInvalidVersion, s, err)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+// MustParse is like Parse but panics if the given string is not a valid version.
+// It's intended for the versions known at compile time.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// IsValid reports whether the given string is a valid version.
+func IsValid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// String returns the canonical representation of the version without the leading "v", e.g. "1.2.0-rc.1+build".
+func (v Version) String() string {
+	var b strings.Builder
+	b.WriteString(strconv.FormatUint(v.Major, 10))
+	b.WriteByte('.')
+	b.WriteString(strconv.FormatUint(v.Minor, 10))
+	b.WriteByte('.')
+	b.WriteString(strconv.FormatUint(v.Patch, 10))
+	if len(v.Prerelease) > 0 {
+		b.WriteByte('-')
+		b.WriteString(strings.Join(v.Prerelease, "."))
+	}
+	if v.Build != "" {
+		b.WriteByte('+')
+		b.WriteString(v.Build)
+	}
+	return b.String()
+}
+
+// Original returns the string the version was parsed from.
+// It returns the canonical representation if the version was not created by Parse.
+func (v Version) Original() string {
+	if v.original == "" {
+		return v.String()
+	}
+	return v.original
+}
+
+// IsPrerelease reports whether the version is a prerelease.
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is less than, equal to or greater than o
+// in the precedence defined by Semantic Versioning. The build metadata is ignored.
+func (v Version) Compare(o Version) int {
+	if c := cmp.Or(cmp.Compare(v.Major, o.Major), cmp.Compare(v.Minor, o.Minor), cmp.Compare(v.Patch, o.Patch)); c != 0 {
+		return c
+	}
+	// A version without the prerelease has higher precedence than the one with it.
+	switch {
+	case len(v.Prerelease) == 0 && len(o.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		if c := compareIdentifier(v.Prerelease[i], o.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(v.Prerelease), len(o.Prerelease))
+}
+
+// LessThan reports whether v is less than o.
+func (v Version) LessThan(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+// Equal reports whether v and o have the same precedence.
+func (v Version) Equal(o Version) bool {
+	return v.Compare(o) == 0
+}
+
+// Compare compares the given version strings.
+// The valid versions are compared by their precedence and are ordered before the invalid ones,
+// which are compared lexically, so that it can be used to sort any list of versions.
+func Compare(a, b string) int {
+	va, errA := Parse(a)
+	vb, errB := Parse(b)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Or(va.Compare(vb), cmp.Compare(a, b))
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return cmp.Compare(a, b)
+	}
+}
+
+// Sort sorts the given version strings in ascending order using Compare.
+func Sort(versions []string) {
+	slices.SortStableFunc(versions, Compare)
+}
+
+// Latest returns the highest valid version among the given ones.
+// The prereleases are considered only when includePrerelease is true.
+// It returns false if there is no such version.
+func Latest(versions []string, includePrerelease bool) (string, bool) {
+	var (
+		latest Version
+		found  bool
+	)
+	for _, s := range versions {
+		v, err := Parse(s)
+		if err != nil || (v.IsPrerelease() && !includePrerelease) {
+			continue
+		}
+		if !found || latest.LessThan(v) {
+			latest, found = v, true
+		}
+	}
+	if !found {
+		return "", false
+	}
+	return latest.Original(), true
+}
+
+// compareIdentifier compares the prerelease identifiers.
+// The numeric identifiers are compared numerically and have lower precedence than the alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	na, nb := isDigits(a), isDigits(b)
+	switch {
+	case na && nb:
+		// The numeric identifiers have no leading zeros, so the longer one is greater.
+		return cmp.Or(cmp.Compare(len(a), len(b)), cmp.Compare(a, b))
+	case na:
+		return -1
+	case nb:
+		return 1
+	default:
+		return cmp.Compare(a, b)
+	}
+}
+
+// validIdentifiers reports whether s is a non-empty list of dot-separated identifiers of [0-9A-Za-z-].
+// The numeric identifiers must not have leading zeros if noLeadingZeros is true.
+func validIdentifiers(s string, noLeadingZeros bool) bool {
+	if s == "" {
+		return false
+	}
+	for id := range strings.SplitSeq(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, c := range id {
+			if !isAlnum(c) && c != '-' {
+				return false
+			}
+		}
+		if noLeadingZeros && isDigits(id) && !isNumeric(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumeric reports whether s is a non-empty string of digits without leading zeros.
+func isNumeric(s string) bool {
+	return isDigits(s) && (len(s) == 1 || s[0] != '0')
+}
+
+// isDigits reports whether s is a non-empty string of digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(c rune) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,156 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    Version
+		wantErr bool
+	}{
+		{input: "1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{input: "v1.2.3", want: Version{Major: 1, Minor: 2, Patch: 3}},
+		{input: "v1.2", want: Version{Major: 1, Minor: 2}},
+		{input: "1", want: Version{Major: 1}},
+		{input: "1.0.0-rc.1", want: Version{Major: 1, Prerelease: []string{"rc", "1"}}},
+		{input: "1.0.0-alpha-1+build.5", want: Version{Major: 1, Prerelease: []string{"alpha-1"}, Build: "build.5"}},
+		{input: "1.0.0+001", want: Version{Major: 1, Build: "001"}},
+		{input: "", wantErr: true},
+		{input: "v", wantErr: true},
+		{input: "1.2.3.4", wantErr: true},
+		{input: "01.2.3", wantErr: true},
+		{input: "1.2.x", wantErr: true},
+		{input: "1.0.0-", wantErr: true},
+		{input: "1.0.0-rc..1", wantErr: true},
+		{input: "1.0.0-01", wantErr: true},
+		{input: "1.0.0+", wantErr: true},
+		{input: "latest", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+			got, err := Parse(tt.input)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidVersion)
+				assert.False(t, IsValid(tt.input))
+				return
+			}
+			require.NoError(t, err)
+			tt.want.original = tt.input
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.input, got.Original())
+		})
+	}
+}
+
+func TestVersion_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "1.2.0", MustParse("v1.2").String())
+	assert.Equal(t, "1.0.0-rc.1+build", MustParse("v1.0.0-rc.1+build").String())
+	assert.Equal(t, "2.0.0", Version{Major: 2}.Original())
+	assert.Panics(t, func() { MustParse("invalid") })
+}
+
+func TestVersion_Compare(t *testing.T) {
+	t.Parallel()
+
+	// The example from the Semantic Versioning specification in ascending order.
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, b := MustParse(ordered[i]), MustParse(ordered[j])
+			switch {
+			case i < j:
+				assert.True(t, a.LessThan(b), "%s < %s", a, b)
+			case i > j:
+				assert.Equal(t, 1, a.Compare(b), "%s > %s", a, b)
+			default:
+				assert.True(t, a.Equal(b), "%s == %s", a, b)
+			}
+		}
+	}
+
+	// The build metadata and the leading "v" are ignored.
+	assert.True(t, MustParse("v1.0.0+a").Equal(MustParse("1.0.0+b")))
+}
+
+func TestSort(t *testing.T) {
+	t.Parallel()
+
+	versions := []string{"latest", "v1.10.0", "1.0.0", "v1.2.0-rc.1", "v1.2.0", "main", "v1.9.0"}
+	Sort(versions)
+	assert.Equal(t, []string{"1.0.0", "v1.2.0-rc.1", "v1.2.0", "v1.9.0", "v1.10.0", "latest", "main"}, versions)
+}
+
+func TestLatest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		versions          []string
+		includePrerelease bool
+		want              string
+		wantOK            bool
+	}{
+		{
+			name:     "stable only",
+			versions: []string{"v1.9.0", "v1.10.0", "v1.11.0-rc.1", "latest"},
+			want:     "v1.10.0",
+			wantOK:   true,
+		},
+		{
+			name:              "include prerelease",
+			versions:          []string{"v1.9.0", "v1.10.0", "v1.11.0-rc.1", "latest"},
+			includePrerelease: true,
+			want:              "v1.11.0-rc.1",
+			wantOK:            true,
+		},
+		{
+			name:     "no valid version",
+			versions: []string{"latest", "v1.0.0-beta"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := Latest(tt.versions, tt.includePrerelease)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
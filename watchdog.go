@@ -0,0 +1,213 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock"
+)
+
+const (
+	resourceKey = "resource"
+
+	resourceGoroutines = "goroutines"
+	resourceOpenFDs    = "open_fds"
+	resourceHeap       = "heap"
+
+	// heapMetric is the runtime metric of the bytes occupied by the live and the unswept objects in the heap.
+	heapMetric = "/memory/classes/heap/objects:bytes"
+)
+
+var (
+	watchdogWarningsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_watchdog_warnings_total",
+			Help: "Total number of the warnings by the resource watchdog grouped by the resource exceeding the threshold.",
+		},
+		[]string{resourceKey},
+	)
+	// The number of the goroutines and the open file descriptors are exported by the Go and the process collectors.
+	watchdogHeapGrowthRatio = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "plugin_watchdog_heap_growth_ratio",
+			Help: "Ratio of the heap growth from the smallest heap in the watchdog window.",
+		},
+	)
+)
+
+// registerWatchdogMetrics registers the metrics of the resource watchdog to the given registerer.
+func registerWatchdogMetrics(r prometheus.Registerer) {
+	r.MustRegister(
+		watchdogWarningsTotal,
+		watchdogHeapGrowthRatio,
+	)
+}
+
+// resourceSample is the usage of the resources at a point in time.
+type resourceSample struct {
+	Time       time.Time `json:"time"`
+	Goroutines int       `json:"goroutines"`
+	// OpenFDs is the number of the open file descriptors. It's -1 when not available on the platform.
+	OpenFDs   int    `json:"openFds"`
+	HeapBytes uint64 `json:"heapBytes"`
+}
+
+// resourceThresholds are the thresholds of the resource usage to warn about.
+// The zero values disable the warnings for the resources.
+type resourceThresholds struct {
+	Goroutines int `json:"goroutines"`
+	OpenFDs    int `json:"openFds"`
+	// HeapGrowth is the ratio of the heap growth from the smallest heap in the window, e.g. 0.5 for 50%.
+	// It's checked only after the window is filled, so that the heap growing at start is not warned.
+	HeapGrowth float64 `json:"heapGrowth"`
+}
+
+// resourceWatchdog samples the usage of the goroutines, the file descriptors, and the heap periodically,
+// and warns about the resources exceeding the thresholds, which are likely to be leaking in a long-running plugin.
+type resourceWatchdog struct {
+	interval   time.Duration
+	window     int
+	thresholds resourceThresholds
+	clock      clock.Clock
+	logger     *zap.Logger
+	// sample returns the current usage of the resources.
+	sample func(now time.Time) resourceSample
+
+	mu       sync.RWMutex
+	samples  []resourceSample
+	warnings []string
+}
+
+// newResourceWatchdog returns a watchdog sampling every interval, and keeping the given number of the samples as the window.
+func newResourceWatchdog(interval time.Duration, window int, thresholds resourceThresholds, clk clock.Clock, logger *zap.Logger) *resourceWatchdog {
+	return &resourceWatchdog{
+		interval:   interval,
+		window:     max(window, 1),
+		thresholds: thresholds,
+		clock:      clock.OrReal(clk),
+		logger:     logger.Named("resource-watchdog"),
+		sample:     sampleResources,
+	}
+}
+
+// Run samples the resources every interval until the context is done.
+func (w *resourceWatchdog) Run(ctx context.Context) error {
+	ticker := w.clock.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.check(w.sample(w.clock.Now()))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C():
+			w.check(w.sample(now))
+		}
+	}
+}
+
+// check adds the sample to the window, and warns about the resources exceeding the thresholds.
+func (w *resourceWatchdog) check(s resourceSample) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples = append(w.samples, s)
+	if len(w.samples) > w.window {
+		w.samples = slices.Delete(w.samples, 0, len(w.samples)-w.window)
+	}
+
+	var warnings []string
+	if t := w.thresholds.Goroutines; t > 0 && s.Goroutines > t {
+		warnings = append(warnings, resourceGoroutines)
+		w.logger.Warn("the number of goroutines exceeds the threshold", zap.Int("goroutines", s.Goroutines), zap.Int("threshold", t))
+	}
+	if t := w.thresholds.OpenFDs; t > 0 && s.OpenFDs > t {
+		warnings = append(warnings, resourceOpenFDs)
+		w.logger.Warn("the number of open file descriptors exceeds the threshold", zap.Int("open-fds", s.OpenFDs), zap.Int("threshold", t))
+	}
+
+	smallest := slices.MinFunc(w.samples, func(a, b resourceSample) int {
+		return cmp.Compare(a.HeapBytes, b.HeapBytes)
+	}).HeapBytes
+	var growth float64
+	if smallest > 0 {
+		growth = float64(s.HeapBytes)/float64(smallest) - 1
+	}
+	watchdogHeapGrowthRatio.Set(growth)
+	if t := w.thresholds.HeapGrowth; t > 0 && len(w.samples) == w.window && growth > t {
+		warnings = append(warnings, resourceHeap)
+		w.logger.Warn("the heap has grown more than the threshold in the window",
+			zap.Uint64("heap-bytes", s.HeapBytes),
+			zap.Uint64("smallest-heap-bytes", smallest),
+			zap.Float64("threshold", t),
+			zap.Duration("window", time.Duration(w.window)*w.interval),
+		)
+	}
+
+	for _, r := range warnings {
+		watchdogWarningsTotal.With(prometheus.Labels{resourceKey: r}).Inc()
+	}
+	w.warnings = warnings
+}
+
+// ServeHTTP writes the samples in the window, the thresholds, and the current warnings as JSON.
+func (w *resourceWatchdog) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(struct {
+		Thresholds resourceThresholds `json:"thresholds"`
+		Warnings   []string           `json:"warnings"`
+		Samples    []resourceSample   `json:"samples"`
+	}{
+		Thresholds: w.thresholds,
+		Warnings:   w.warnings,
+		Samples:    w.samples,
+	})
+}
+
+// sampleResources returns the current usage of the resources by the process.
+func sampleResources(now time.Time) resourceSample {
+	heap := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(heap)
+
+	s := resourceSample{
+		Time:       now,
+		Goroutines: runtime.NumGoroutine(),
+		OpenFDs:    -1,
+	}
+	if heap[0].Value.Kind() == metrics.KindUint64 {
+		s.HeapBytes = heap[0].Value.Uint64()
+	}
+	// The open file descriptors are available on Linux.
+	if entries, err := os.ReadDir("/proc/self/fd"); err == nil {
+		s.OpenFDs = len(entries)
+	}
+	return s
+}
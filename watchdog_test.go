@@ -0,0 +1,106 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/piped-plugin-sdk-go/clock/clocktest"
+)
+
+// This test is not parallel since it checks the global metrics.
+func TestResourceWatchdog_Check(t *testing.T) {
+	w := newResourceWatchdog(time.Minute, 3, resourceThresholds{Goroutines: 100, OpenFDs: 10, HeapGrowth: 1}, nil, zap.NewNop())
+	warnings := func(resource string) float64 {
+		return testutil.ToFloat64(watchdogWarningsTotal.WithLabelValues(resource))
+	}
+	goroutines, fds, heap := warnings(resourceGoroutines), warnings(resourceOpenFDs), warnings(resourceHeap)
+
+	w.check(resourceSample{Goroutines: 10, OpenFDs: 5, HeapBytes: 100})
+	assert.Empty(t, w.warnings)
+
+	// The heap growth is not checked until the window is filled.
+	w.check(resourceSample{Goroutines: 101, OpenFDs: 11, HeapBytes: 300})
+	assert.Equal(t, []string{resourceGoroutines, resourceOpenFDs}, w.warnings)
+	assert.Equal(t, 2.0, testutil.ToFloat64(watchdogHeapGrowthRatio))
+
+	w.check(resourceSample{Goroutines: 10, OpenFDs: 5, HeapBytes: 250})
+	assert.Equal(t, []string{resourceHeap}, w.warnings)
+
+	// The smallest heap has left the window.
+	w.check(resourceSample{Goroutines: 10, OpenFDs: 5, HeapBytes: 400})
+	assert.Empty(t, w.warnings)
+	assert.Len(t, w.samples, 3)
+	assert.InDelta(t, 0.6, testutil.ToFloat64(watchdogHeapGrowthRatio), 1e-9)
+
+	assert.Equal(t, goroutines+1, warnings(resourceGoroutines))
+	assert.Equal(t, fds+1, warnings(resourceOpenFDs))
+	assert.Equal(t, heap+1, warnings(resourceHeap))
+}
+
+func TestResourceWatchdog_Run(t *testing.T) {
+	t.Parallel()
+
+	clk := clocktest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	w := newResourceWatchdog(time.Minute, 10, resourceThresholds{}, clk, zap.NewNop())
+	sampled := make(chan time.Time)
+	w.sample = func(now time.Time) resourceSample {
+		sampled <- now
+		return resourceSample{Time: now, Goroutines: 1, OpenFDs: 1, HeapBytes: 1}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	assert.Equal(t, clk.Now(), <-sampled)
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	assert.Equal(t, clk.Now(), <-sampled)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/watchdog", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body struct {
+		Samples []resourceSample `json:"samples"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Len(t, body.Samples, 2)
+}
+
+func TestSampleResources(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	s := sampleResources(now)
+	assert.Equal(t, now, s.Time)
+	assert.Positive(t, s.Goroutines)
+	assert.NotZero(t, s.OpenFDs)
+	assert.Positive(t, s.HeapBytes)
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"maps"
+	"time"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/deployment"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/livestate"
+	"github.com/pipe-cd/pipecd/pkg/plugin/api/v1alpha1/planpreview"
+)
+
+// The functions in this file expose the conversions between the responses of the plugins and the messages sent to piped.
+// The XxxToProto functions do exactly what the SDK does before sending the responses,
+// and the XxxFromProto functions convert the messages back, so that the plugins can test
+// that nothing in their responses is dropped on the wire. See sdktest.AssertLivestateRoundTrip and the like.
+
+// PlanPreviewResponseToProto converts the response in the same way as GetPlanPreview sends it to piped.
+func PlanPreviewResponseToProto(r *GetPlanPreviewResponse) *planpreview.GetPlanPreviewResponse {
+	return r.toProto()
+}
+
+// PlanPreviewResponseFromProto converts the message sent to piped back to the response.
+func PlanPreviewResponseFromProto(r *planpreview.GetPlanPreviewResponse) *GetPlanPreviewResponse {
+	results := make([]PlanPreviewResult, 0, len(r.GetResults()))
+	for _, result := range r.GetResults() {
+		results = append(results, PlanPreviewResult{
+			DeployTarget: result.GetDeployTarget(),
+			Summary:      result.GetSummary(),
+			NoChange:     result.GetNoChange(),
+			Details:      result.GetDetails(),
+			DiffLanguage: result.GetDiffLanguage(),
+		})
+	}
+	return &GetPlanPreviewResponse{Results: results}
+}
+
+// LivestateResponseToProto converts the response in the same way as GetLivestate sends it to piped.
+// The now is used as the time when the states are updated.
+func LivestateResponseToProto(pluginName string, now time.Time, r *GetLivestateResponse) *livestate.GetLivestateResponse {
+	return r.toModel(pluginName, now)
+}
+
+// LivestateResponseFromProto converts the message sent to piped back to the response.
+// The times are in seconds on the wire, so the sub-second part of the CreatedAt is lost.
+func LivestateResponseFromProto(r *livestate.GetLivestateResponse) *GetLivestateResponse {
+	resources := make([]ResourceState, 0, len(r.GetApplicationLiveState().GetResources()))
+	for _, rs := range r.GetApplicationLiveState().GetResources() {
+		metadata := rs.GetResourceMetadata()
+		var key ResourceKey
+		if s, ok := metadata[ResourceKeyMetadataKey]; ok {
+			if k, err := ParseResourceKey(s); err == nil {
+				key = k
+				metadata = maps.Clone(metadata)
+				delete(metadata, ResourceKeyMetadataKey)
+				if len(metadata) == 0 {
+					metadata = nil
+				}
+			}
+		}
+		resources = append(resources, ResourceState{
+			ID:                rs.GetId(),
+			Key:               key,
+			ParentIDs:         rs.GetParentIds(),
+			Name:              rs.GetName(),
+			ResourceType:      rs.GetResourceType(),
+			ResourceMetadata:  metadata,
+			HealthStatus:      newResourceHealthStatus(rs.GetHealthStatus()),
+			HealthDescription: rs.GetHealthDescription(),
+			DeployTarget:      rs.GetDeployTarget(),
+			CreatedAt:         time.Unix(rs.GetCreatedAt(), 0),
+		})
+	}
+	return &GetLivestateResponse{
+		LiveState: ApplicationLiveState{Resources: resources},
+		SyncState: ApplicationSyncState{
+			Status:      newApplicationSyncStatus(r.GetSyncState().GetStatus()),
+			ShortReason: r.GetSyncState().GetShortReason(),
+			Reason:      r.GetSyncState().GetReason(),
+		},
+	}
+}
+
+// PipelineSyncStagesResponseToProto converts the response in the same way as BuildPipelineSyncStages sends it to piped.
+// It fails when the response has a stage whose index is not in the request.
+// The now is used as the time when the stages are created.
+func PipelineSyncStagesResponseToProto(now time.Time, request BuildPipelineSyncStagesRequest, r *BuildPipelineSyncStagesResponse) (*deployment.BuildPipelineSyncStagesResponse, error) {
+	stages := make([]*deployment.BuildPipelineSyncStagesRequest_StageConfig, 0, len(request.Stages))
+	for _, s := range request.Stages {
+		stages = append(stages, &deployment.BuildPipelineSyncStagesRequest_StageConfig{
+			Index:  int32(s.Index),
+			Name:   s.Name,
+			Config: s.Config,
+		})
+	}
+	return newPipelineSyncStagesResponse(now, &deployment.BuildPipelineSyncStagesRequest{Rollback: request.Rollback, Stages: stages}, r)
+}
+
+// PipelineSyncStagesResponseFromProto converts the message sent to piped back to the response.
+func PipelineSyncStagesResponseFromProto(r *deployment.BuildPipelineSyncStagesResponse) *BuildPipelineSyncStagesResponse {
+	stages := make([]PipelineStage, 0, len(r.GetStages()))
+	for _, s := range r.GetStages() {
+		stages = append(stages, PipelineStage{
+			Index:               int(s.GetIndex()),
+			Name:                s.GetName(),
+			Rollback:            s.GetRollback(),
+			Metadata:            s.GetMetadata(),
+			AvailableOperation:  newManualOperation(s.GetAvailableOperation()),
+			AuthorizedOperators: s.GetAuthorizedOperators(),
+		})
+	}
+	return &BuildPipelineSyncStagesResponse{Stages: stages}
+}
+
+// QuickSyncStagesResponseToProto converts the response in the same way as BuildQuickSyncStages sends it to piped.
+// The now is used as the time when the stages are created.
+func QuickSyncStagesResponseToProto(now time.Time, r *BuildQuickSyncStagesResponse) *deployment.BuildQuickSyncStagesResponse {
+	return newQuickSyncStagesResponse(now, r)
+}
+
+// QuickSyncStagesResponseFromProto converts the message sent to piped back to the response.
+func QuickSyncStagesResponseFromProto(r *deployment.BuildQuickSyncStagesResponse) *BuildQuickSyncStagesResponse {
+	stages := make([]QuickSyncStage, 0, len(r.GetStages()))
+	for _, s := range r.GetStages() {
+		stages = append(stages, QuickSyncStage{
+			Name:               s.GetName(),
+			Description:        s.GetDesc(),
+			Rollback:           s.GetRollback(),
+			Metadata:           s.GetMetadata(),
+			AvailableOperation: newManualOperation(s.GetAvailableOperation()),
+		})
+	}
+	return &BuildQuickSyncStagesResponse{Stages: stages}
+}
+
+// DetermineVersionsResponseToProto converts the response in the same way as DetermineVersions sends it to piped.
+func DetermineVersionsResponseToProto(r *DetermineVersionsResponse) *deployment.DetermineVersionsResponse {
+	return &deployment.DetermineVersionsResponse{Versions: r.toModel()}
+}
+
+// DetermineVersionsResponseFromProto converts the message sent to piped back to the response.
+func DetermineVersionsResponseFromProto(r *deployment.DetermineVersionsResponse) *DetermineVersionsResponse {
+	versions := make([]ArtifactVersion, 0, len(r.GetVersions()))
+	for _, v := range r.GetVersions() {
+		versions = append(versions, ArtifactVersion{
+			Version: v.GetVersion(),
+			Name:    v.GetName(),
+			URL:     v.GetUrl(),
+		})
+	}
+	return &DetermineVersionsResponse{Versions: versions}
+}
+
+// DetermineStrategyResponseToProto converts the response in the same way as DetermineStrategy sends it to piped.
+// It fails when the strategy is invalid.
+func DetermineStrategyResponseToProto(r *DetermineStrategyResponse) (*deployment.DetermineStrategyResponse, error) {
+	return newDetermineStrategyResponse(r)
+}
+
+// DetermineStrategyResponseFromProto converts the message sent to piped back to the response.
+func DetermineStrategyResponseFromProto(r *deployment.DetermineStrategyResponse) *DetermineStrategyResponse {
+	return &DetermineStrategyResponse{
+		Strategy: newSyncStrategy(r.GetSyncStrategy()),
+		Summary:  r.GetSummary(),
+	}
+}
+
+// newManualOperation converts the model.ManualOperation to the ManualOperation.
+func newManualOperation(o model.ManualOperation) ManualOperation {
+	switch o {
+	case model.ManualOperation_MANUAL_OPERATION_SKIP:
+		return ManualOperationSkip
+	case model.ManualOperation_MANUAL_OPERATION_APPROVE:
+		return ManualOperationApprove
+	default:
+		return ManualOperationNone
+	}
+}
+
+// newSyncStrategy converts the model.SyncStrategy to the SyncStrategy.
+// It returns the zero value, which is invalid, for the unknown strategy.
+func newSyncStrategy(s model.SyncStrategy) SyncStrategy {
+	switch s {
+	case model.SyncStrategy_QUICK_SYNC:
+		return SyncStrategyQuickSync
+	case model.SyncStrategy_PIPELINE:
+		return SyncStrategyPipelineSync
+	default:
+		return 0
+	}
+}
+
+// newResourceHealthStatus converts the model.ResourceState_HealthStatus to the ResourceHealthStatus.
+func newResourceHealthStatus(s model.ResourceState_HealthStatus) ResourceHealthStatus {
+	switch s {
+	case model.ResourceState_HEALTHY:
+		return ResourceHealthStateHealthy
+	case model.ResourceState_UNHEALTHY:
+		return ResourceHealthStateUnhealthy
+	default:
+		return ResourceHealthStateUnknown
+	}
+}
+
+// newApplicationSyncStatus converts the model.ApplicationSyncStatus to the ApplicationSyncStatus.
+func newApplicationSyncStatus(s model.ApplicationSyncStatus) ApplicationSyncStatus {
+	switch s {
+	case model.ApplicationSyncStatus_SYNCED:
+		return ApplicationSyncStateSynced
+	case model.ApplicationSyncStatus_OUT_OF_SYNC:
+		return ApplicationSyncStateOutOfSync
+	case model.ApplicationSyncStatus_INVALID_CONFIG:
+		return ApplicationSyncStateInvalidConfig
+	default:
+		return ApplicationSyncStateUnknown
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipecd/pkg/model"
+)
+
+func TestLivestateResponseFromProto(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := &GetLivestateResponse{
+		LiveState: ApplicationLiveState{
+			Resources: []ResourceState{
+				{
+					ID:                "id",
+					ParentIDs:         []string{"parent"},
+					Name:              "name",
+					ResourceType:      "Pod",
+					ResourceMetadata:  map[string]string{"key": "value"},
+					HealthStatus:      ResourceHealthStateUnhealthy,
+					HealthDescription: "crash loop",
+					DeployTarget:      "dt",
+					CreatedAt:         time.Unix(now.Unix(), 0),
+				},
+				{
+					ID:               "uid",
+					Key:              ResourceKey{Provider: "kubernetes", Kind: "apps/Deployment", Scope: "default", Name: "nginx", ID: "uid"},
+					Name:             "nginx",
+					ResourceMetadata: map[string]string{"key": "value"},
+					CreatedAt:        time.Unix(now.Unix(), 0),
+				},
+			},
+		},
+		SyncState: ApplicationSyncState{
+			Status:      ApplicationSyncStateOutOfSync,
+			ShortReason: "short",
+			Reason:      "reason",
+		},
+	}
+
+	msg := LivestateResponseToProto("plugin", now, resp)
+	assert.Equal(t, "plugin", msg.GetApplicationLiveState().GetResources()[0].GetPluginName())
+	assert.Equal(t, now.Unix(), msg.GetSyncState().GetTimestamp())
+	assert.Equal(t, "kubernetes:apps/Deployment:default:nginx#uid", msg.GetApplicationLiveState().GetResources()[1].GetResourceMetadata()[ResourceKeyMetadataKey])
+	assert.Equal(t, resp, LivestateResponseFromProto(msg))
+}
+
+func TestPipelineSyncStagesResponseFromProto(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	req := BuildPipelineSyncStagesRequest{
+		Stages: []StageConfig{{Index: 0, Name: "STAGE"}},
+	}
+	resp := &BuildPipelineSyncStagesResponse{
+		Stages: []PipelineStage{
+			{
+				Index:               0,
+				Name:                "STAGE",
+				Metadata:            map[string]string{"key": "value"},
+				AvailableOperation:  ManualOperationApprove,
+				AuthorizedOperators: []string{"user"},
+			},
+			{
+				Index:    0,
+				Name:     "ROLLBACK",
+				Rollback: true,
+			},
+		},
+	}
+
+	msg, err := PipelineSyncStagesResponseToProto(now, req, resp)
+	require.NoError(t, err)
+	assert.Equal(t, model.StageStatus_STAGE_NOT_STARTED_YET, msg.GetStages()[0].GetStatus())
+	assert.Equal(t, resp, PipelineSyncStagesResponseFromProto(msg))
+
+	// The stage not in the request is rejected as the SDK does.
+	resp.Stages[0].Index = 1
+	_, err = PipelineSyncStagesResponseToProto(now, req, resp)
+	assert.Error(t, err)
+}
+
+func TestQuickSyncStagesResponseFromProto(t *testing.T) {
+	t.Parallel()
+
+	resp := &BuildQuickSyncStagesResponse{
+		Stages: []QuickSyncStage{
+			{Name: "SYNC", Description: "sync", AvailableOperation: ManualOperationSkip},
+			{Name: "ROLLBACK", Rollback: true, Metadata: map[string]string{"key": "value"}},
+		},
+	}
+	assert.Equal(t, resp, QuickSyncStagesResponseFromProto(QuickSyncStagesResponseToProto(time.Now(), resp)))
+}
+
+func TestDetermineResponsesFromProto(t *testing.T) {
+	t.Parallel()
+
+	versions := &DetermineVersionsResponse{
+		Versions: []ArtifactVersion{{Version: "v1", Name: "image", URL: "https://example.com/image"}},
+	}
+	assert.Equal(t, versions, DetermineVersionsResponseFromProto(DetermineVersionsResponseToProto(versions)))
+
+	strategy := &DetermineStrategyResponse{Strategy: SyncStrategyPipelineSync, Summary: "summary"}
+	msg, err := DetermineStrategyResponseToProto(strategy)
+	require.NoError(t, err)
+	assert.Equal(t, strategy, DetermineStrategyResponseFromProto(msg))
+
+	_, err = DetermineStrategyResponseToProto(&DetermineStrategyResponse{})
+	assert.Error(t, err)
+}
+
+func TestPlanPreviewResponseFromProto(t *testing.T) {
+	t.Parallel()
+
+	resp := &GetPlanPreviewResponse{
+		Results: []PlanPreviewResult{
+			{DeployTarget: "dt", Summary: "summary", Details: []byte("details"), DiffLanguage: "hcl"},
+			{DeployTarget: "dt2", NoChange: true},
+		},
+	}
+	assert.Equal(t, resp, PlanPreviewResponseFromProto(PlanPreviewResponseToProto(resp)))
+}